@@ -0,0 +1,138 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/morrisonbrett/SummerRateChecker/internal/config"
+	"go.uber.org/zap"
+)
+
+// filePrefix and fileSuffix frame the timestamp in snapshot names, so names sort chronologically
+const (
+	filePrefix = "summer-backup-"
+	fileSuffix = ".tar.gz"
+	timeLayout = "20060102T150405Z"
+)
+
+// Snapshotter writes a consistent archive of the bot's data. FileStorage implements it.
+type Snapshotter interface {
+	Snapshot(w io.Writer) error
+}
+
+// Service takes snapshots on a schedule, keeps the newest few locally, and uploads each to S3 if configured
+type Service struct {
+	config     *config.Backup
+	source     Snapshotter
+	httpClient *http.Client
+	logger     *zap.SugaredLogger
+}
+
+func New(cfg *config.Backup, source Snapshotter, logger *zap.SugaredLogger) *Service {
+	return &Service{
+		config:     cfg,
+		source:     source,
+		httpClient: &http.Client{Timeout: 5 * time.Minute},
+		logger:     logger,
+	}
+}
+
+// Start runs a backup immediately and then every interval until ctx is cancelled
+func (s *Service) Start(ctx context.Context) {
+	interval := time.Duration(s.config.IntervalHours) * time.Hour
+	if interval <= 0 {
+		interval = 24 * time.Hour
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if name, err := s.RunOnce(ctx); err != nil {
+			s.logger.Errorf("Backup failed: %v", err)
+		} else {
+			s.logger.Infof("Backup %s completed", name)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce takes a snapshot, writes it locally, prunes old local snapshots, and uploads it.
+// The local copy is kept even if the upload fails.
+func (s *Service) RunOnce(ctx context.Context) (string, error) {
+	var buf bytes.Buffer
+	if err := s.source.Snapshot(&buf); err != nil {
+		return "", fmt.Errorf("failed to take snapshot: %w", err)
+	}
+
+	name := filePrefix + time.Now().UTC().Format(timeLayout) + fileSuffix
+	if err := os.MkdirAll(s.config.LocalDir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(s.config.LocalDir, name), buf.Bytes(), 0600); err != nil {
+		return "", fmt.Errorf("failed to write backup: %w", err)
+	}
+	s.prune()
+
+	if s.config.S3.Endpoint != "" && s.config.S3.Bucket != "" {
+		key := s.config.S3.Prefix + name
+		if err := s.upload(ctx, key, buf.Bytes()); err != nil {
+			return name, fmt.Errorf("saved locally but upload failed: %w", err)
+		}
+		s.logger.Infof("Uploaded backup to s3://%s/%s", s.config.S3.Bucket, key)
+	}
+
+	return name, nil
+}
+
+// prune deletes local snapshots beyond the configured count, oldest first
+func (s *Service) prune() {
+	if s.config.KeepLocal <= 0 {
+		return
+	}
+	snapshots, err := ListLocal(s.config.LocalDir)
+	if err != nil {
+		s.logger.Warnf("Failed to list backups for pruning: %v", err)
+		return
+	}
+	for len(snapshots) > s.config.KeepLocal {
+		if err := os.Remove(snapshots[0]); err != nil {
+			s.logger.Warnf("Failed to remove old backup %s: %v", snapshots[0], err)
+		}
+		snapshots = snapshots[1:]
+	}
+}
+
+// ListLocal returns the paths of the snapshots in dir, oldest first
+func ListLocal(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() && strings.HasPrefix(name, filePrefix) && strings.HasSuffix(name, fileSuffix) {
+			paths = append(paths, filepath.Join(dir, name))
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
@@ -1,157 +1,419 @@
-package bot
-
-import (
-	"fmt"
-	"time"
-
-	"github.com/bwmarrin/discordgo"
-	"github.com/morrisonbrett/SummerRateChecker/internal/commands"
-	"github.com/morrisonbrett/SummerRateChecker/internal/config"
-	"github.com/morrisonbrett/SummerRateChecker/internal/storage"
-	"go.uber.org/zap"
-)
-
-type Bot struct {
-	session      *discordgo.Session
-	config       *config.Config
-	storage      storage.Storage
-	logger       *zap.SugaredLogger
-	checkTrigger chan bool // Channel to trigger manual checks
-}
-
-func New(cfg *config.Config, store storage.Storage, logger *zap.SugaredLogger) (*Bot, error) {
-	session, err := discordgo.New("Bot " + cfg.Discord.Token)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create Discord session: %w", err)
-	}
-
-	bot := &Bot{
-		session:      session,
-		config:       cfg,
-		storage:      store,
-		logger:       logger,
-		checkTrigger: make(chan bool, 1), // Buffered channel for manual triggers
-	}
-
-	// Add required intents for slash commands and interactions
-	session.Identify.Intents = discordgo.IntentsGuildMessages |
-		discordgo.IntentsMessageContent |
-		discordgo.IntentsGuilds |
-		discordgo.IntentsGuildMessageReactions
-
-	// Add handlers
-	session.AddHandler(bot.interactionHandler)
-	session.AddHandler(bot.readyHandler) // Add ready handler
-
-	return bot, nil
-}
-
-func (b *Bot) Start() error {
-	// Open the session first
-	err := b.session.Open()
-	if err != nil {
-		return fmt.Errorf("failed to open Discord session: %w", err)
-	}
-
-	// Wait a moment for the session to be ready
-	time.Sleep(2 * time.Second)
-
-	// Get the first guild ID (since we're only in one server)
-	var guildID string
-	if len(b.session.State.Guilds) > 0 {
-		guildID = b.session.State.Guilds[0].ID
-		b.logger.Infof("Registering commands for guild: %s", guildID)
-	} else {
-		return fmt.Errorf("bot is not in any guilds")
-	}
-
-	// Now register slash commands after session is open
-	err = commands.RegisterCommands(b.session, b.session.State.User.ID, guildID)
-	if err != nil {
-		b.session.Close() // Clean up session if command registration fails
-		return fmt.Errorf("failed to register commands: %w", err)
-	}
-
-	b.logger.Info("Discord bot connected and commands registered")
-	return nil
-}
-
-func (b *Bot) Stop() error {
-	return b.session.Close()
-}
-
-func (b *Bot) GetCheckTrigger() <-chan bool {
-	return b.checkTrigger
-}
-
-func (b *Bot) interactionHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	// Only handle slash commands
-	if i.Type != discordgo.InteractionApplicationCommand {
-		return
-	}
-
-	// Create command context
-	ctx := &commands.CommandContext{
-		Config:  b.config,
-		Storage: b.storage,
-		Logger:  b.logger,
-		Trigger: b.checkTrigger,
-	}
-
-	// Handle the command
-	commands.HandleCommand(s, i, ctx)
-}
-
-func (b *Bot) readyHandler(s *discordgo.Session, r *discordgo.Ready) {
-	b.logger.Infof("Bot is ready! Logged in as %s#%s (ID: %s)", r.User.Username, r.User.Discriminator, r.User.ID)
-	b.logger.Infof("Connected to %d guilds:", len(r.Guilds))
-	for _, guild := range r.Guilds {
-		b.logger.Infof("  - %s (ID: %s)", guild.Name, guild.ID)
-	}
-
-	// Verify bot permissions
-	for _, guild := range r.Guilds {
-		member, err := s.GuildMember(guild.ID, r.User.ID)
-		if err != nil {
-			b.logger.Errorf("Failed to get member info for guild %s: %v", guild.Name, err)
-			continue
-		}
-
-		// Get guild roles
-		roles, err := s.GuildRoles(guild.ID)
-		if err != nil {
-			b.logger.Errorf("Failed to get roles for guild %s: %v", guild.Name, err)
-			continue
-		}
-
-		// Calculate bot permissions
-		var permissions int64
-		for _, roleID := range member.Roles {
-			for _, role := range roles {
-				if role.ID == roleID {
-					permissions |= role.Permissions
-				}
-			}
-		}
-
-		// Check required permissions
-		required := int64(discordgo.PermissionSendMessages |
-			discordgo.PermissionUseSlashCommands |
-			discordgo.PermissionReadMessageHistory |
-			discordgo.PermissionAddReactions)
-
-		missing := required &^ permissions
-		if missing != 0 {
-			b.logger.Warnf("Bot is missing permissions in guild %s: %v", guild.Name, missing)
-		} else {
-			b.logger.Infof("Bot has all required permissions in guild %s", guild.Name)
-		}
-	}
-}
-
-func (b *Bot) sendMessage(s *discordgo.Session, channelID, content string) {
-	_, err := s.ChannelMessageSend(channelID, content)
-	if err != nil {
-		b.logger.Errorf("Failed to send message: %v", err)
-	}
-}
+package bot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/morrisonbrett/SummerRateChecker/internal/commands"
+	"github.com/morrisonbrett/SummerRateChecker/internal/config"
+	"github.com/morrisonbrett/SummerRateChecker/internal/storage"
+	"github.com/morrisonbrett/SummerRateChecker/internal/types"
+	"go.uber.org/zap"
+)
+
+// readyTimeout bounds how long Start waits for the gateway's Ready event
+const readyTimeout = 30 * time.Second
+
+type Bot struct {
+	session   *discordgo.Session
+	config    *config.Config
+	storage   storage.Storage
+	logger    *zap.SugaredLogger
+	commands  chan types.MonitorCommand // Command bus to the monitor, e.g. manual checks
+	cooldowns *commands.Cooldowns
+
+	ready     chan struct{} // Closed on the first Ready event
+	readyOnce sync.Once
+	started   atomic.Bool // Set once Start has registered commands; later Ready events re-register
+	stopping  atomic.Bool // Set by Stop so the resulting disconnect isn't treated as an outage
+
+	outageMu       sync.Mutex
+	disconnectedAt time.Time   // When the gateway connection dropped; zero while connected
+	outageTimer    *time.Timer // Sends the ops notice once the outage lasts Discord.OutageAlertMinutes
+	outageNotified bool        // Whether the ops channel was told about the current outage
+}
+
+func New(cfg *config.Config, store storage.Storage, logger *zap.SugaredLogger) (*Bot, error) {
+	shardCount := cfg.Discord.ShardCount
+	if shardCount < 1 || cfg.Discord.ShardID < 0 || cfg.Discord.ShardID >= shardCount {
+		return nil, fmt.Errorf("invalid sharding config: shard_id %d must be between 0 and shard_count - 1 (shard_count %d)", cfg.Discord.ShardID, shardCount)
+	}
+
+	session, err := discordgo.New("Bot " + cfg.Discord.Token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Discord session: %w", err)
+	}
+	if shardCount > 1 {
+		session.ShardID = cfg.Discord.ShardID
+		session.ShardCount = shardCount
+		logger.Infof("Connecting as shard %d of %d", cfg.Discord.ShardID, shardCount)
+	}
+
+	bot := &Bot{
+		session:   session,
+		config:    cfg,
+		storage:   store,
+		logger:    logger,
+		commands:  make(chan types.MonitorCommand, 1), // Buffered so one command can wait while the monitor is busy
+		cooldowns: commands.NewCooldowns(),
+		ready:     make(chan struct{}),
+	}
+
+	// Add required intents for slash commands and interactions
+	session.Identify.Intents = discordgo.IntentsGuildMessages |
+		discordgo.IntentsMessageContent |
+		discordgo.IntentsGuilds |
+		discordgo.IntentsGuildMessageReactions |
+		discordgo.IntentsGuildScheduledEvents
+
+	// Add handlers
+	session.AddHandler(bot.interactionHandler)
+	session.AddHandler(bot.readyHandler) // Add ready handler
+	session.AddHandler(bot.disconnectHandler)
+	session.AddHandler(bot.resumedHandler)
+	session.AddHandler(bot.scheduledEventUpdateHandler)
+	session.AddHandler(bot.scheduledEventDeleteHandler)
+
+	return bot, nil
+}
+
+func (b *Bot) Start() error {
+	// Open the session first
+	err := b.session.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open Discord session: %w", err)
+	}
+
+	// Guild state is only populated once the gateway sends Ready
+	select {
+	case <-b.ready:
+	case <-time.After(readyTimeout):
+		b.session.Close()
+		return fmt.Errorf("timed out after %s waiting for Discord to report the session ready", readyTimeout)
+	}
+
+	if err := b.registerCommands(); err != nil {
+		b.session.Close() // Clean up session if command registration fails
+		return err
+	}
+	b.started.Store(true)
+
+	// A read-only bot can't change maintenance settings, so it leaves events to the instance that can
+	if !b.config.Discord.ReadOnly {
+		go b.followMaintenanceEvent()
+	}
+
+	b.logger.Info("Discord bot connected and commands registered")
+	return nil
+}
+
+// registerCommands registers slash commands in each configured guild, which the bot must already be a member of
+func (b *Bot) registerCommands() error {
+	guildIDs := b.config.Discord.Guilds()
+	if len(guildIDs) == 0 {
+		return fmt.Errorf("discord.guild_id is not set; set it to your server's ID")
+	}
+
+	for _, guildID := range guildIDs {
+		if !b.onThisShard(guildID) {
+			b.logger.Infof("Skipping guild %s, which is served by another shard", guildID)
+			continue
+		}
+		if _, err := b.session.State.Guild(guildID); err != nil {
+			return fmt.Errorf("bot is not a member of configured guild %s; invite it to that server or fix discord.guild_id/guild_ids", guildID)
+		}
+
+		b.logger.Infof("Registering commands for guild: %s", guildID)
+		if err := commands.RegisterCommands(b.session, b.session.State.User.ID, guildID, b.config.Discord.ReadOnly); err != nil {
+			return fmt.Errorf("failed to register commands in guild %s: %w", guildID, err)
+		}
+	}
+	return nil
+}
+
+// onThisShard reports whether Discord routes guildID to this process's shard,
+// using Discord's formula: (guild_id >> 22) % shard_count
+func (b *Bot) onThisShard(guildID string) bool {
+	if b.session.ShardCount <= 1 {
+		return true
+	}
+	id, err := strconv.ParseUint(guildID, 10, 64)
+	if err != nil {
+		return false
+	}
+	return int((id>>22)%uint64(b.session.ShardCount)) == b.session.ShardID
+}
+
+func (b *Bot) Stop() error {
+	b.stopping.Store(true)
+	b.outageMu.Lock()
+	if b.outageTimer != nil {
+		b.outageTimer.Stop()
+	}
+	b.outageMu.Unlock()
+	return b.session.Close()
+}
+
+// Commands is the command bus the monitor reads from
+func (b *Bot) Commands() <-chan types.MonitorCommand {
+	return b.commands
+}
+
+// SendCommand queues cmd for the monitor. It returns false if another command is already pending.
+func (b *Bot) SendCommand(cmd types.MonitorCommand) bool {
+	select {
+	case b.commands <- cmd:
+		return true
+	default:
+		return false
+	}
+}
+
+// TriggerCheck requests an immediate rate check of the vaults req covers. It returns false if
+// a command is already pending.
+func (b *Bot) TriggerCheck(req types.CheckRequest) bool {
+	return b.SendCommand(types.NewCheckCommand(req))
+}
+
+// CreateWebhook creates an alert webhook in channelID and returns its URL
+func (b *Bot) CreateWebhook(channelID string) (string, error) {
+	webhook, err := b.session.WebhookCreate(channelID, "SummerRateChecker", "")
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("https://discord.com/api/webhooks/%s/%s", webhook.ID, webhook.Token), nil
+}
+
+// DeleteWebhook deletes the webhook behind webhookURL
+func (b *Bot) DeleteWebhook(webhookURL string) error {
+	parts := strings.Split(webhookURL, "/")
+	if len(parts) < 2 {
+		return fmt.Errorf("invalid webhook URL")
+	}
+	return b.session.WebhookDelete(parts[len(parts)-2])
+}
+
+// ChannelGuild returns the ID of the server channelID is in
+func (b *Bot) ChannelGuild(channelID string) (string, error) {
+	channel, err := b.session.State.Channel(channelID)
+	if err != nil {
+		if channel, err = b.session.Channel(channelID); err != nil {
+			return "", err
+		}
+	}
+	return channel.GuildID, nil
+}
+
+// SendOpsMessage posts an operational notice to the configured ops channel.
+// Without one, the notice is only logged.
+func (b *Bot) SendOpsMessage(content string) {
+	channelID := b.config.Discord.OpsChannelID
+	if channelID == "" {
+		b.logger.Warnf("No ops channel configured for notice: %s", content)
+		return
+	}
+	if _, err := b.session.ChannelMessageSend(channelID, content); err != nil {
+		b.logger.Errorf("Failed to send ops notice: %v", err)
+	}
+}
+
+// SendDirectMessage DMs a webhook-style payload to a user
+func (b *Bot) SendDirectMessage(userID string, payload *types.DiscordWebhookPayload) error {
+	channel, err := b.session.UserChannelCreate(userID)
+	if err != nil {
+		return fmt.Errorf("failed to open DM channel: %w", err)
+	}
+
+	embeds := make([]*discordgo.MessageEmbed, 0, len(payload.Embeds))
+	for _, e := range payload.Embeds {
+		embeds = append(embeds, commands.ToMessageEmbed(e))
+	}
+
+	content := payload.Content
+	if content == "" {
+		content = "⚠️ Alert webhook delivery failed, sending directly instead:"
+	}
+
+	_, err = b.session.ChannelMessageSendComplex(channel.ID, &discordgo.MessageSend{
+		Content: content,
+		Embeds:  embeds,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send DM: %w", err)
+	}
+	return nil
+}
+
+func (b *Bot) interactionHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	// Create command context
+	ctx := &commands.CommandContext{
+		Config:    b.config,
+		Storage:   b.storage,
+		Logger:    b.logger,
+		Commands:  b.commands,
+		Cooldowns: b.cooldowns,
+	}
+
+	switch i.Type {
+	case discordgo.InteractionApplicationCommand:
+		commands.HandleCommand(s, i, ctx)
+	case discordgo.InteractionMessageComponent:
+		// Buttons on alert messages, e.g. Ack
+		commands.HandleComponent(s, i, ctx)
+	case discordgo.InteractionModalSubmit:
+		// Forms opened by buttons, e.g. in the /setup wizard
+		commands.HandleModal(s, i, ctx)
+	}
+}
+
+func (b *Bot) readyHandler(s *discordgo.Session, r *discordgo.Ready) {
+	// The first Ready unblocks Start, which registers the commands
+	reconnect := b.started.Load()
+	b.readyOnce.Do(func() { close(b.ready) })
+
+	b.logger.Infof("Bot is ready! Logged in as %s#%s (ID: %s)", r.User.Username, r.User.Discriminator, r.User.ID)
+	b.logger.Infof("Connected to %d guilds:", len(r.Guilds))
+	for _, guild := range r.Guilds {
+		b.logger.Infof("  - %s (ID: %s)", guild.Name, guild.ID)
+	}
+
+	// Verify bot permissions
+	for _, guild := range r.Guilds {
+		member, err := s.GuildMember(guild.ID, r.User.ID)
+		if err != nil {
+			b.logger.Errorf("Failed to get member info for guild %s: %v", guild.Name, err)
+			continue
+		}
+
+		// Get guild roles
+		roles, err := s.GuildRoles(guild.ID)
+		if err != nil {
+			b.logger.Errorf("Failed to get roles for guild %s: %v", guild.Name, err)
+			continue
+		}
+
+		// Calculate bot permissions
+		var permissions int64
+		for _, roleID := range member.Roles {
+			for _, role := range roles {
+				if role.ID == roleID {
+					permissions |= role.Permissions
+				}
+			}
+		}
+
+		// Check required permissions
+		required := int64(discordgo.PermissionSendMessages |
+			discordgo.PermissionUseSlashCommands |
+			discordgo.PermissionReadMessageHistory |
+			discordgo.PermissionAddReactions)
+
+		missing := required &^ permissions
+		if missing != 0 {
+			b.logger.Warnf("Bot is missing permissions in guild %s: %v", guild.Name, missing)
+		} else {
+			b.logger.Infof("Bot has all required permissions in guild %s", guild.Name)
+		}
+	}
+
+	// A Ready after startup means Discord started a new session, e.g. after a long outage
+	if reconnect {
+		b.reconnected("new session")
+	}
+}
+
+// disconnectHandler starts tracking a gateway outage. discordgo reconnects on its own; this only
+// makes sure someone hears about it if reconnecting takes a while.
+func (b *Bot) disconnectHandler(s *discordgo.Session, d *discordgo.Disconnect) {
+	if b.stopping.Load() || !b.started.Load() {
+		return
+	}
+
+	b.outageMu.Lock()
+	defer b.outageMu.Unlock()
+
+	if !b.disconnectedAt.IsZero() {
+		return // Already tracking this outage
+	}
+	b.disconnectedAt = time.Now()
+	b.logger.Warn("Disconnected from the Discord gateway, reconnecting")
+
+	if minutes := b.config.Discord.OutageAlertMinutes; minutes > 0 {
+		since := b.disconnectedAt
+		b.outageTimer = time.AfterFunc(time.Duration(minutes)*time.Minute, func() { b.notifyOutage(since) })
+	}
+}
+
+// notifyOutage tells the ops channel the gateway has been down a while. Messages go over the REST
+// API, which often still works when the gateway connection doesn't.
+func (b *Bot) notifyOutage(since time.Time) {
+	b.outageMu.Lock()
+	if b.disconnectedAt != since {
+		b.outageMu.Unlock()
+		return // Reconnected in the meantime
+	}
+	b.outageNotified = true
+	b.outageMu.Unlock()
+
+	b.SendOpsMessage(fmt.Sprintf(
+		"⚠️ The bot has been disconnected from the Discord gateway since <t:%d:t>. Slash commands won't respond until it reconnects; rate checks and webhook alerts continue.",
+		since.Unix(),
+	))
+}
+
+func (b *Bot) resumedHandler(s *discordgo.Session, r *discordgo.Resumed) {
+	if b.started.Load() {
+		b.reconnected("resumed")
+	}
+}
+
+// reconnected ends outage tracking and resyncs guild state and command registration,
+// which may have drifted while events weren't being received
+func (b *Bot) reconnected(how string) {
+	b.outageMu.Lock()
+	since, notified := b.disconnectedAt, b.outageNotified
+	b.disconnectedAt = time.Time{}
+	b.outageNotified = false
+	if b.outageTimer != nil {
+		b.outageTimer.Stop()
+		b.outageTimer = nil
+	}
+	b.outageMu.Unlock()
+
+	if !since.IsZero() {
+		downtime := time.Since(since).Round(time.Second)
+		b.logger.Infof("Reconnected to the Discord gateway (%s) after %s", how, downtime)
+		if notified {
+			b.SendOpsMessage(fmt.Sprintf("✅ Reconnected to the Discord gateway after %s", downtime))
+		}
+	}
+
+	for _, guildID := range b.config.Discord.Guilds() {
+		if !b.onThisShard(guildID) {
+			continue
+		}
+		guild, err := b.session.Guild(guildID)
+		if err != nil {
+			b.logger.Errorf("Failed to refresh guild %s after reconnect: %v", guildID, err)
+			continue
+		}
+		if err := b.session.State.GuildAdd(guild); err != nil {
+			b.logger.Errorf("Failed to update state for guild %s: %v", guildID, err)
+		}
+	}
+	if err := b.registerCommands(); err != nil {
+		b.logger.Errorf("Failed to re-register commands after reconnect: %v", err)
+	}
+}
+
+func (b *Bot) sendMessage(s *discordgo.Session, channelID, content string) {
+	_, err := s.ChannelMessageSend(channelID, content)
+	if err != nil {
+		b.logger.Errorf("Failed to send message: %v", err)
+	}
+}
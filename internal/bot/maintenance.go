@@ -0,0 +1,151 @@
+package bot
+
+import (
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/morrisonbrett/SummerRateChecker/internal/types"
+)
+
+// maintenanceSyncInterval is how often the maintenance event is started or ended to match its window
+const maintenanceSyncInterval = time.Minute
+
+// followMaintenanceEvent keeps the Discord scheduled event announcing a maintenance window in step
+// with it: started when the window opens and completed when it closes. Alert delivery follows the
+// window's times by itself; this keeps the announcement accurate.
+func (b *Bot) followMaintenanceEvent() {
+	ticker := time.NewTicker(maintenanceSyncInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if b.stopping.Load() {
+			return
+		}
+		b.syncMaintenanceEvent(time.Now())
+	}
+}
+
+func (b *Bot) syncMaintenanceEvent(now time.Time) {
+	settings := b.storage.GetSettings()
+	event := settings.MaintenanceEvent
+	if event == nil {
+		return
+	}
+
+	switch {
+	case !now.Before(settings.MaintenanceUntil):
+		// Discord may already have ended the event at its end time, so failures are expected
+		b.session.GuildScheduledEventEdit(event.GuildID, event.EventID, &discordgo.GuildScheduledEventParams{
+			Status: discordgo.GuildScheduledEventStatusCompleted,
+		})
+		// The window may have been moved or replaced during the call to Discord
+		ended := false
+		err := b.storage.UpdateSettings(func(settings *types.Settings) {
+			if announces(*settings, event.EventID) && !now.Before(settings.MaintenanceUntil) {
+				*settings = settings.WithoutMaintenance()
+				ended = true
+			}
+		})
+		if err != nil {
+			b.logger.Errorf("Failed to clear finished maintenance window: %v", err)
+			return
+		}
+		if ended {
+			b.logger.Info("Maintenance window ended")
+		}
+
+	case settings.InMaintenance(now):
+		current, err := b.session.GuildScheduledEvent(event.GuildID, event.EventID, false)
+		if err != nil || current.Status != discordgo.GuildScheduledEventStatusScheduled {
+			return
+		}
+		_, err = b.session.GuildScheduledEventEdit(event.GuildID, event.EventID, &discordgo.GuildScheduledEventParams{
+			Status: discordgo.GuildScheduledEventStatusActive,
+		})
+		if err != nil {
+			b.logger.Warnf("Failed to start maintenance event %s: %v", event.EventID, err)
+			return
+		}
+		b.logger.Info("Maintenance window started")
+	}
+}
+
+// scheduledEventUpdateHandler follows changes made to the maintenance event in Discord: moving it
+// moves the window, and ending or canceling it ends maintenance
+func (b *Bot) scheduledEventUpdateHandler(s *discordgo.Session, e *discordgo.GuildScheduledEventUpdate) {
+	settings, ok := b.maintenanceSettingsFor(e.ID)
+	if !ok {
+		return
+	}
+
+	from, until := settings.MaintenanceFrom, settings.MaintenanceUntil
+	switch e.Status {
+	case discordgo.GuildScheduledEventStatusCompleted, discordgo.GuildScheduledEventStatusCanceled:
+		b.endMaintenanceFromEvent(e.ID, "ended in Discord")
+		return
+	case discordgo.GuildScheduledEventStatusScheduled:
+		from = e.ScheduledStartTime
+	}
+	if e.ScheduledEndTime != nil {
+		until = *e.ScheduledEndTime
+	}
+	if from.Equal(settings.MaintenanceFrom) && until.Equal(settings.MaintenanceUntil) {
+		return
+	}
+
+	err := b.storage.UpdateSettings(func(settings *types.Settings) {
+		if announces(*settings, e.ID) {
+			settings.MaintenanceFrom = from
+			settings.MaintenanceUntil = until
+		}
+	})
+	if err != nil {
+		b.logger.Errorf("Failed to move maintenance window to match its event: %v", err)
+		return
+	}
+	b.logger.Infof("Maintenance window moved in Discord to %s - %s", from.Format(time.RFC3339), until.Format(time.RFC3339))
+}
+
+// scheduledEventDeleteHandler ends maintenance when its event is deleted in Discord
+func (b *Bot) scheduledEventDeleteHandler(s *discordgo.Session, e *discordgo.GuildScheduledEventDelete) {
+	if _, ok := b.maintenanceSettingsFor(e.ID); ok {
+		b.endMaintenanceFromEvent(e.ID, "deleted in Discord")
+	}
+}
+
+// maintenanceSettingsFor returns the settings if eventID announces the current maintenance window
+func (b *Bot) maintenanceSettingsFor(eventID string) (types.Settings, bool) {
+	if b.config.Discord.ReadOnly {
+		return types.Settings{}, false
+	}
+	settings := b.storage.GetSettings()
+	if !announces(settings, eventID) {
+		return types.Settings{}, false
+	}
+	return settings, true
+}
+
+// announces reports whether eventID announces the settings' maintenance window
+func announces(settings types.Settings, eventID string) bool {
+	return settings.MaintenanceEvent != nil && settings.MaintenanceEvent.EventID == eventID
+}
+
+// endMaintenanceFromEvent ends maintenance if eventID still announces the window
+func (b *Bot) endMaintenanceFromEvent(eventID, how string) {
+	ended := false
+	err := b.storage.UpdateSettings(func(settings *types.Settings) {
+		if announces(*settings, eventID) {
+			*settings = settings.WithoutMaintenance()
+			ended = true
+		}
+	})
+	if err != nil {
+		b.logger.Errorf("Failed to end maintenance after its event was %s: %v", how, err)
+		return
+	}
+	if !ended {
+		return
+	}
+	b.logger.Infof("Maintenance ended because its event was %s", how)
+	b.SendOpsMessage("🔧 Maintenance mode ended because its scheduled event was " + how + "; alerts will be delivered again")
+}
@@ -0,0 +1,207 @@
+// Package chart draws borrow rate line charts as PNG images for Discord attachments.
+// It uses only the standard library, so charts carry no text: the legend and scale go in
+// the message alongside, matched to the lines by Color.Swatch.
+package chart
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"time"
+)
+
+const (
+	width  = 1000
+	height = 500
+	margin = 24
+
+	// gridLines is roughly how many horizontal gridlines to draw; the step is rounded to a
+	// readable value, so the actual count varies
+	gridLines = 5
+)
+
+// Color is a line color with the emoji square that stands for it in a legend
+type Color struct {
+	RGBA   color.RGBA
+	Swatch string
+}
+
+// Palette holds the colors series are drawn in, in order. Charts draw at most len(Palette)
+// series so every line has a distinct legend swatch.
+var Palette = []Color{
+	{color.RGBA{0x3b, 0x82, 0xf6, 0xff}, "🟦"},
+	{color.RGBA{0xef, 0x44, 0x44, 0xff}, "🟥"},
+	{color.RGBA{0x22, 0xc5, 0x5e, 0xff}, "🟩"},
+	{color.RGBA{0xf9, 0x73, 0x16, 0xff}, "🟧"},
+	{color.RGBA{0xa8, 0x55, 0xf7, 0xff}, "🟪"},
+	{color.RGBA{0xea, 0xb3, 0x08, 0xff}, "🟨"},
+	{color.RGBA{0x92, 0x40, 0x0e, 0xff}, "🟫"},
+	{color.RGBA{0x1f, 0x29, 0x37, 0xff}, "⬛"},
+}
+
+var (
+	background = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	gridColor  = color.RGBA{0xe5, 0xe7, 0xeb, 0xff}
+)
+
+// Point is one rate in a series
+type Point struct {
+	Time  time.Time
+	Value float64
+}
+
+// Series is one line on the chart
+type Series struct {
+	Name   string
+	Points []Point // In time order
+}
+
+// Scale describes the chart's value axis, for a caption like "gridlines every 0.5%"
+type Scale struct {
+	Min, Max float64 // Values at the bottom and top edges of the plot
+	Step     float64 // Distance between gridlines
+}
+
+// Render draws series as lines over a shared time axis, in Palette order, and returns the PNG
+// with its value scale. Horizontal gridlines fall every Scale.Step and vertical ones at each UTC
+// midnight. Series beyond len(Palette) are not drawn.
+func Render(series []Series) ([]byte, Scale, error) {
+	if len(series) > len(Palette) {
+		series = series[:len(Palette)]
+	}
+
+	var start, end time.Time
+	low, high := math.Inf(1), math.Inf(-1)
+	for _, s := range series {
+		for _, p := range s.Points {
+			if start.IsZero() || p.Time.Before(start) {
+				start = p.Time
+			}
+			if p.Time.After(end) {
+				end = p.Time
+			}
+			low = math.Min(low, p.Value)
+			high = math.Max(high, p.Value)
+		}
+	}
+	if start.IsZero() {
+		return nil, Scale{}, fmt.Errorf("no points to chart")
+	}
+	if !end.After(start) {
+		end = start.Add(time.Hour)
+	}
+
+	scale := niceScale(low, high)
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), &image.Uniform{background}, image.Point{}, draw.Src)
+
+	plotW := float64(width - 2*margin)
+	plotH := float64(height - 2*margin)
+	x := func(t time.Time) int {
+		return margin + int(math.Round(float64(t.Sub(start))/float64(end.Sub(start))*plotW))
+	}
+	y := func(v float64) int {
+		return height - margin - int(math.Round((v-scale.Min)/(scale.Max-scale.Min)*plotH))
+	}
+
+	for v := scale.Min; v <= scale.Max+scale.Step/2; v += scale.Step {
+		gy := y(v)
+		for gx := margin; gx <= width-margin; gx++ {
+			img.SetRGBA(gx, gy, gridColor)
+		}
+	}
+
+	// Mark each UTC midnight, so a week's chart reads as seven days
+	for day := start.UTC().Truncate(24 * time.Hour).Add(24 * time.Hour); day.Before(end); day = day.Add(24 * time.Hour) {
+		gx := x(day)
+		for gy := margin; gy <= height-margin; gy++ {
+			img.SetRGBA(gx, gy, gridColor)
+		}
+	}
+
+	for i, s := range series {
+		c := Palette[i].RGBA
+		for j := 1; j < len(s.Points); j++ {
+			prev, cur := s.Points[j-1], s.Points[j]
+			drawLine(img, x(prev.Time), y(prev.Value), x(cur.Time), y(cur.Value), c)
+		}
+		if len(s.Points) == 1 {
+			p := s.Points[0]
+			drawLine(img, x(p.Time), y(p.Value), x(p.Time), y(p.Value), c)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, Scale{}, fmt.Errorf("failed to encode chart: %w", err)
+	}
+	return buf.Bytes(), scale, nil
+}
+
+// niceScale widens [low, high] to whole multiples of a 1, 2 or 5 step, so gridlines fall on
+// values that read well in a caption
+func niceScale(low, high float64) Scale {
+	span := high - low
+	if span <= 0 {
+		span = math.Max(math.Abs(high)*0.1, 0.1)
+	}
+	raw := span / gridLines
+	magnitude := math.Pow(10, math.Floor(math.Log10(raw)))
+	step := magnitude * 10
+	for _, m := range []float64{1, 2, 5} {
+		if raw <= m*magnitude {
+			step = m * magnitude
+			break
+		}
+	}
+
+	bottom := math.Floor(low/step) * step
+	top := math.Ceil(high/step) * step
+	if top <= bottom {
+		top = bottom + step
+	}
+	return Scale{Min: bottom, Max: top, Step: step}
+}
+
+// drawLine draws a 3px line from (x0, y0) to (x1, y1) with Bresenham's algorithm
+func drawLine(img *image.RGBA, x0, y0, x1, y1 int, c color.RGBA) {
+	dx, dy := abs(x1-x0), -abs(y1-y0)
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+	err := dx + dy
+	for {
+		for ox := -1; ox <= 1; ox++ {
+			for oy := -1; oy <= 1; oy++ {
+				img.SetRGBA(x0+ox, y0+oy, c)
+			}
+		}
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		e2 := 2 * err
+		if e2 >= dy {
+			err += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			err += dx
+			y0 += sy
+		}
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
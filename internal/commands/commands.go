@@ -1,554 +1,4747 @@
-package commands
-
-import (
-	"fmt"
-	"strings"
-
-	"github.com/bwmarrin/discordgo"
-	"github.com/morrisonbrett/SummerRateChecker/internal/config"
-	"github.com/morrisonbrett/SummerRateChecker/internal/morpho"
-	"github.com/morrisonbrett/SummerRateChecker/internal/storage"
-	"github.com/morrisonbrett/SummerRateChecker/internal/types"
-	"go.uber.org/zap"
-)
-
-// Command represents a slash command
-type Command struct {
-	Name        string
-	Description string
-	Options     []*discordgo.ApplicationCommandOption
-	Handler     func(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) error
-}
-
-// CommandContext holds dependencies needed by command handlers
-type CommandContext struct {
-	Config  *config.Config
-	Storage storage.Storage
-	Logger  *zap.SugaredLogger
-	Trigger chan bool
-}
-
-// All available commands
-var Commands = []*discordgo.ApplicationCommand{
-	{
-		Name:        "enroll",
-		Description: "Add a vault for monitoring",
-		Options: []*discordgo.ApplicationCommandOption{
-			{
-				Type:        discordgo.ApplicationCommandOptionString,
-				Name:        "url",
-				Description: "Full Summer.fi URL for your vault",
-				Required:    true,
-			},
-			{
-				Type:        discordgo.ApplicationCommandOptionString,
-				Name:        "nickname",
-				Description: "Nickname for the vault",
-				Required:    true,
-			},
-			{
-				Type:        discordgo.ApplicationCommandOptionNumber,
-				Name:        "threshold",
-				Description: "Alert threshold (0.1-100.0)",
-				Required:    true,
-			},
-			{
-				Type:        discordgo.ApplicationCommandOptionChannel,
-				Name:        "channel",
-				Description: "Channel to send alerts to (defaults to current channel)",
-				Required:    false,
-				ChannelTypes: []discordgo.ChannelType{
-					discordgo.ChannelTypeGuildText,
-				},
-			},
-		},
-	},
-	{
-		Name:        "unenroll",
-		Description: "Remove a vault from monitoring",
-		Options: []*discordgo.ApplicationCommandOption{
-			{
-				Type:        discordgo.ApplicationCommandOptionString,
-				Name:        "vault_id",
-				Description: "ID of the vault to remove",
-				Required:    true,
-			},
-		},
-	},
-	{
-		Name:        "list",
-		Description: "Show all enrolled vaults with their market pairs and rates",
-	},
-	{
-		Name:        "status",
-		Description: "Show current rates for all vaults",
-	},
-	{
-		Name:        "check",
-		Description: "Force an immediate rate check",
-	},
-	{
-		Name:        "threshold",
-		Description: "Update alert threshold for a vault",
-		Options: []*discordgo.ApplicationCommandOption{
-			{
-				Type:        discordgo.ApplicationCommandOptionString,
-				Name:        "vault_id",
-				Description: "ID of the vault to update",
-				Required:    true,
-			},
-			{
-				Type:        discordgo.ApplicationCommandOptionNumber,
-				Name:        "new_threshold",
-				Description: "New threshold value (0.1-100.0)",
-				Required:    true,
-			},
-		},
-	},
-	{
-		Name:        "interval",
-		Description: "Show current check interval",
-	},
-	{
-		Name:        "help",
-		Description: "Show help message with all available commands",
-	},
-}
-
-// RegisterCommands registers all slash commands with Discord
-func RegisterCommands(s *discordgo.Session, appID string, guildID string) error {
-	// Log the app ID and guild ID we're using
-	fmt.Printf("Registering commands for application ID: %s in guild: %s\n", appID, guildID)
-
-	// First, clean up any global commands (these should never exist)
-	fmt.Println("Checking for global commands...")
-	globalCommands, err := s.ApplicationCommands(appID, "")
-	if err != nil {
-		return fmt.Errorf("failed to get global commands: %w", err)
-	}
-
-	if len(globalCommands) > 0 {
-		fmt.Printf("Found %d global commands to remove\n", len(globalCommands))
-		for _, cmd := range globalCommands {
-			fmt.Printf("Removing global command: %s (ID: %s)\n", cmd.Name, cmd.ID)
-			err := s.ApplicationCommandDelete(appID, "", cmd.ID)
-			if err != nil {
-				return fmt.Errorf("failed to delete global command %s: %w", cmd.Name, err)
-			}
-		}
-	}
-
-	// Get existing guild commands
-	fmt.Printf("Checking guild commands for guild %s...\n", guildID)
-	existingCommands, err := s.ApplicationCommands(appID, guildID)
-	if err != nil {
-		return fmt.Errorf("failed to get guild commands: %w", err)
-	}
-
-	// Create maps for quick lookup
-	existingMap := make(map[string]*discordgo.ApplicationCommand)
-	for _, cmd := range existingCommands {
-		existingMap[cmd.Name] = cmd
-	}
-
-	// Track which commands we've processed
-	processedCommands := make(map[string]bool)
-
-	// Update or create commands as needed
-	fmt.Println("Updating commands...")
-	for _, newCmd := range Commands {
-		processedCommands[newCmd.Name] = true
-		existingCmd, exists := existingMap[newCmd.Name]
-
-		if !exists {
-			// Command doesn't exist, create it
-			fmt.Printf("Creating new command: %s\n", newCmd.Name)
-			_, err := s.ApplicationCommandCreate(appID, guildID, newCmd)
-			if err != nil {
-				return fmt.Errorf("failed to create command %s: %w", newCmd.Name, err)
-			}
-			continue
-		}
-
-		// Check if command needs updating by comparing relevant fields
-		if needsUpdate(existingCmd, newCmd) {
-			fmt.Printf("Updating command: %s\n", newCmd.Name)
-			_, err := s.ApplicationCommandEdit(appID, guildID, existingCmd.ID, newCmd)
-			if err != nil {
-				return fmt.Errorf("failed to update command %s: %w", newCmd.Name, err)
-			}
-		} else {
-			fmt.Printf("Command unchanged: %s\n", newCmd.Name)
-		}
-	}
-
-	// Remove any commands that no longer exist in our Commands list
-	for name, cmd := range existingMap {
-		if !processedCommands[name] {
-			fmt.Printf("Removing obsolete command: %s\n", name)
-			err := s.ApplicationCommandDelete(appID, guildID, cmd.ID)
-			if err != nil {
-				return fmt.Errorf("failed to delete obsolete command %s: %w", name, err)
-			}
-		}
-	}
-
-	fmt.Println("Command registration complete")
-	return nil
-}
-
-// needsUpdate checks if a command needs to be updated by comparing relevant fields
-func needsUpdate(existing, new *discordgo.ApplicationCommand) bool {
-	// Compare basic fields
-	if existing.Description != new.Description {
-		return true
-	}
-
-	// Compare options
-	if len(existing.Options) != len(new.Options) {
-		return true
-	}
-
-	// Create maps for option comparison
-	existingOpts := make(map[string]*discordgo.ApplicationCommandOption)
-	for _, opt := range existing.Options {
-		existingOpts[opt.Name] = opt
-	}
-
-	// Compare each option
-	for _, newOpt := range new.Options {
-		existingOpt, exists := existingOpts[newOpt.Name]
-		if !exists {
-			return true
-		}
-
-		// Compare option properties
-		if existingOpt.Type != newOpt.Type ||
-			existingOpt.Description != newOpt.Description ||
-			existingOpt.Required != newOpt.Required {
-			return true
-		}
-
-		// Compare channel types if present
-		if len(existingOpt.ChannelTypes) != len(newOpt.ChannelTypes) {
-			return true
-		}
-		for i, ct := range existingOpt.ChannelTypes {
-			if i >= len(newOpt.ChannelTypes) || ct != newOpt.ChannelTypes[i] {
-				return true
-			}
-		}
-	}
-
-	return false
-}
-
-// HandleCommand handles a slash command interaction
-func HandleCommand(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) {
-	// Defer the response in case the handler takes time
-	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
-	})
-
-	var err error
-	switch i.ApplicationCommandData().Name {
-	case "enroll":
-		err = handleEnroll(s, i, ctx)
-	case "unenroll":
-		err = handleUnenroll(s, i, ctx)
-	case "list":
-		err = handleList(s, i, ctx)
-	case "status":
-		err = handleStatus(s, i, ctx)
-	case "check":
-		err = handleCheck(s, i, ctx)
-	case "threshold":
-		err = handleThreshold(s, i, ctx)
-	case "interval":
-		err = handleInterval(s, i, ctx)
-	case "help":
-		err = handleHelp(s, i, ctx)
-	default:
-		err = fmt.Errorf("unknown command: %s", i.ApplicationCommandData().Name)
-	}
-
-	if err != nil {
-		// Send error response
-		errMsg := err.Error()
-		s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-			Content: &errMsg,
-		})
-		return
-	}
-}
-
-// Command handlers
-func handleEnroll(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) error {
-	options := i.ApplicationCommandData().Options
-	url := options[0].StringValue()
-	nickname := options[1].StringValue()
-	threshold := options[2].FloatValue()
-
-	// Validate threshold
-	if threshold < 0.1 || threshold > 100.0 {
-		return fmt.Errorf("threshold must be between 0.1 and 100.0")
-	}
-
-	// Get channel if provided, otherwise use current channel
-	channelID := i.ChannelID
-	if len(options) > 3 {
-		channelID = options[3].ChannelValue(s).ID
-	}
-
-	// Create a webhook for the channel
-	webhook, err := s.WebhookCreate(channelID, "SummerRateChecker", "")
-	if err != nil {
-		return fmt.Errorf("failed to create webhook for channel: %w", err)
-	}
-
-	urlInfo, err := morpho.ParseVaultURL(url)
-	if err != nil {
-		// Clean up webhook if URL parsing fails
-		s.WebhookDelete(webhook.ID)
-		return fmt.Errorf("invalid Summer.fi URL: %v", err)
-	}
-
-	vault := &types.VaultConfig{
-		VaultID:          urlInfo.VaultID,
-		Nickname:         nickname,
-		ThresholdPercent: threshold,
-		ChannelID:        channelID,
-		WebhookURL:       fmt.Sprintf("https://discord.com/api/webhooks/%s/%s", webhook.ID, webhook.Token),
-		MarketPair:       urlInfo.MarketPair,
-	}
-
-	err = ctx.Storage.AddVault(vault)
-	if err != nil {
-		// Clean up webhook if storage fails
-		s.WebhookDelete(webhook.ID)
-		return fmt.Errorf("failed to enroll vault: %w", err)
-	}
-
-	response := fmt.Sprintf(
-		"✅ Successfully enrolled vault `%s` (\"%s\")\n"+
-			"Market Pair: %s\n"+
-			"Threshold: %.1f%%\n"+
-			"Alerts will be sent to <#%s>",
-		urlInfo.VaultID, nickname, urlInfo.MarketPair, threshold, channelID,
-	)
-
-	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-		Content: &response,
-	})
-	return nil
-}
-
-func handleUnenroll(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) error {
-	vaultID := i.ApplicationCommandData().Options[0].StringValue()
-
-	vault, err := ctx.Storage.GetVault(vaultID)
-	if err != nil {
-		return fmt.Errorf("error checking vault: %w", err)
-	}
-
-	if vault == nil {
-		return fmt.Errorf("vault `%s` not found", vaultID)
-	}
-
-	// Delete the webhook if it exists
-	if vault.WebhookURL != "" {
-		// Extract webhook ID from URL
-		parts := strings.Split(vault.WebhookURL, "/")
-		if len(parts) >= 2 {
-			webhookID := parts[len(parts)-2]
-			if err := s.WebhookDelete(webhookID); err != nil {
-				ctx.Logger.Warnf("Failed to delete webhook for vault %s: %v", vaultID, err)
-			}
-		}
-	}
-
-	err = ctx.Storage.RemoveVault(vaultID)
-	if err != nil {
-		return fmt.Errorf("failed to unenroll vault: %w", err)
-	}
-
-	response := fmt.Sprintf("✅ Unenrolled vault `%s`", vaultID)
-	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-		Content: &response,
-	})
-	return nil
-}
-
-func handleList(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) error {
-	vaults, err := ctx.Storage.GetAllVaults()
-	if err != nil {
-		return fmt.Errorf("error retrieving vaults: %w", err)
-	}
-
-	if len(vaults) == 0 {
-		response := "No vaults enrolled"
-		s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-			Content: &response,
-		})
-		return nil
-	}
-
-	var response strings.Builder
-	response.WriteString("**Enrolled Vaults:**\n")
-	for _, vault := range vaults {
-		marketPair := vault.MarketPair
-		if marketPair == "" {
-			marketPair = "Unknown"
-		}
-		response.WriteString(fmt.Sprintf(
-			"`%s` - \"%s\" (%s) - %.1f%% threshold → <#%s>\n",
-			vault.VaultID, vault.Nickname, marketPair, vault.ThresholdPercent, vault.ChannelID,
-		))
-	}
-
-	content := response.String()
-	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-		Content: &content,
-	})
-	return nil
-}
-
-func handleStatus(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) error {
-	vaults, err := ctx.Storage.GetAllVaults()
-	if err != nil {
-		return fmt.Errorf("error retrieving vaults: %w", err)
-	}
-
-	if len(vaults) == 0 {
-		response := "No vaults enrolled"
-		s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-			Content: &response,
-		})
-		return nil
-	}
-
-	lastRates := ctx.Storage.GetAllLastRates()
-
-	var response strings.Builder
-	response.WriteString("**Current Status:**\n")
-	for _, vault := range vaults {
-		marketPair := vault.MarketPair
-		if marketPair == "" {
-			marketPair = "Unknown"
-		}
-		if rate, exists := lastRates[vault.VaultID]; exists {
-			response.WriteString(fmt.Sprintf(
-				"`%s` - \"%s\" (%s): %.2f%%\n",
-				vault.VaultID, vault.Nickname, marketPair, rate,
-			))
-		} else {
-			response.WriteString(fmt.Sprintf(
-				"`%s` - \"%s\" (%s): Not checked yet\n",
-				vault.VaultID, vault.Nickname, marketPair,
-			))
-		}
-	}
-
-	content := response.String()
-	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-		Content: &content,
-	})
-	return nil
-}
-
-func handleCheck(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) error {
-	select {
-	case ctx.Trigger <- true:
-		response := "🔄 Manual rate check triggered! Checking all vaults now..."
-		s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-			Content: &response,
-		})
-	default:
-		response := "🔄 Manual check already in progress, please wait..."
-		s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-			Content: &response,
-		})
-	}
-	return nil
-}
-
-func handleThreshold(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) error {
-	options := i.ApplicationCommandData().Options
-	vaultID := options[0].StringValue()
-	newThreshold := options[1].FloatValue()
-
-	// Validate threshold
-	if newThreshold < 0.1 || newThreshold > 100.0 {
-		return fmt.Errorf("threshold must be between 0.1 and 100.0")
-	}
-
-	vault, err := ctx.Storage.GetVault(vaultID)
-	if err != nil {
-		return fmt.Errorf("error checking vault: %w", err)
-	}
-
-	if vault == nil {
-		return fmt.Errorf("vault `%s` not found", vaultID)
-	}
-
-	vault.ThresholdPercent = newThreshold
-	err = ctx.Storage.AddVault(vault) // This updates the existing vault
-	if err != nil {
-		return fmt.Errorf("failed to update threshold: %w", err)
-	}
-
-	response := fmt.Sprintf(
-		"✅ Updated threshold for `%s` to %.1f%%",
-		vaultID, newThreshold,
-	)
-	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-		Content: &response,
-	})
-	return nil
-}
-
-func handleInterval(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) error {
-	response := fmt.Sprintf("Current check interval: %d minutes", ctx.Config.Monitor.CheckIntervalMinutes)
-	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-		Content: &response,
-	})
-	return nil
-}
-
-func handleHelp(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) error {
-	help := `**SummerRateChecker Commands:**
-
-🏦 **Vault Management:**
-• /enroll - Add a vault for monitoring
-  - Required: URL, nickname, threshold
-  - Optional: channel
-  - Example: [Command Format] /enroll url:<summer-fi-url> nickname:My WBTC Vault threshold:0.5
-• /unenroll - Remove a vault from monitoring
-• /list - Show all enrolled vaults
-• /threshold - Update alert threshold
-
-📊 **Monitoring:**
-• /status - Show current rates for all vaults
-• /check - Force an immediate rate check
-• /interval - Show current check interval
-
-ℹ️ **General:**
-• /help - Show this help message
-
-**Notes:**
-• Threshold is in percentage points (0.5 = alert on ±0.5% change)
-• You must provide the full Summer.fi URL when enrolling a vault
-• The URL format is: [URL Format] <summer-fi-url>
-  Example: [Example URL] <https://pro.summer.fi/ethereum/morphoblue/borrow/WBTC-USDC/1234#overview>
-
-Type "/" to see all available commands with their descriptions and options.`
-
-	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-		Content: &help,
-	})
-	return nil
-}
-
-func ptr[T any](v T) *T {
-	return &v
-}
+package commands
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/morrisonbrett/SummerRateChecker/internal/chart"
+	"github.com/morrisonbrett/SummerRateChecker/internal/config"
+	"github.com/morrisonbrett/SummerRateChecker/internal/integrity"
+	"github.com/morrisonbrett/SummerRateChecker/internal/monitor"
+	"github.com/morrisonbrett/SummerRateChecker/internal/morpho"
+	"github.com/morrisonbrett/SummerRateChecker/internal/rules"
+	"github.com/morrisonbrett/SummerRateChecker/internal/stats"
+	"github.com/morrisonbrett/SummerRateChecker/internal/storage"
+	"github.com/morrisonbrett/SummerRateChecker/internal/types"
+	"go.uber.org/zap"
+)
+
+// Command represents a slash command
+type Command struct {
+	Name        string
+	Description string
+	Options     []*discordgo.ApplicationCommandOption
+	Handler     func(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) error
+}
+
+// CommandContext holds dependencies needed by command handlers
+type CommandContext struct {
+	Config    *config.Config
+	Storage   storage.Storage
+	Logger    *zap.SugaredLogger
+	Commands  chan<- types.MonitorCommand // Command bus to the monitor
+	Cooldowns *Cooldowns                  // Shared across interactions
+}
+
+// adminPermission hides a command from members who can't manage the server
+var adminPermission int64 = discordgo.PermissionAdministrator
+
+// slackWebhookPrefix is how every Slack incoming webhook URL starts
+const slackWebhookPrefix = "https://hooks.slack.com/"
+
+// maxPreviewRate caps /preview's hypothetical rate, in percent
+const maxPreviewRate = 1000.0
+
+// maxMessageLength is Discord's limit on a message's content, in characters
+const maxMessageLength = 2000
+
+// maxNotesLength caps /note text so /list stays within Discord's message limit
+const maxNotesLength = 200
+
+// ephemeralCommands reply only to the invoking user, e.g. because the reply contains a secret
+var ephemeralCommands = map[string]bool{
+	"api-token-create": true,
+	"api-token-list":   true,
+	"setup":            true, // The wizard's buttons only make sense to whoever is running it
+}
+
+// readOnlyCommands only show data, so they're the ones registered when discord.read_only is set
+var readOnlyCommands = map[string]bool{
+	"list":        true,
+	"status":      true,
+	"history":     true,
+	"chart":       true,
+	"top":         true,
+	"leaderboard": true,
+	"portfolio":   true,
+	"savings":     true,
+	"market-info": true,
+	"diagnostics": true,
+	"help":        true,
+}
+
+// AvailableCommands returns the commands to register: all of them, or only those that show data
+// in read-only mode
+func AvailableCommands(readOnly bool) []*discordgo.ApplicationCommand {
+	if !readOnly {
+		return Commands
+	}
+	var available []*discordgo.ApplicationCommand
+	for _, cmd := range Commands {
+		if readOnlyCommands[cmd.Name] {
+			available = append(available, cmd)
+		}
+	}
+	return available
+}
+
+// All available commands
+var Commands = []*discordgo.ApplicationCommand{
+	{
+		Name:                     "setup",
+		Description:              "Walk through permissions, an alert channel, server defaults and a first vault (admins only)",
+		DefaultMemberPermissions: &adminPermission,
+	},
+	{
+		Name:        "enroll",
+		Description: "Add a vault for monitoring",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "url",
+				Description: "Summer.fi vault URL, or a Morpho market unique key (0x…) or pair like WBTC-USDC",
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "nickname",
+				Description: "Nickname for the vault",
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionNumber,
+				Name:        "threshold",
+				Description: "Alert threshold (0.01-100.0, defaults to the server's /setup default)",
+				Required:    false,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionChannel,
+				Name:        "channel",
+				Description: "Channel to send alerts to (defaults to the /setup alert channel, then the current channel)",
+				Required:    false,
+				ChannelTypes: []discordgo.ChannelType{
+					discordgo.ChannelTypeGuildText,
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionBoolean,
+				Name:        "create_channel",
+				Description: "Create a dedicated channel named after the nickname for this vault's alerts",
+				Required:    false,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "notes",
+				Description: "Context for this vault, e.g. \"main treasury loop, target LTV 60%\"",
+				Required:    false,
+				MaxLength:   maxNotesLength,
+			},
+		},
+	},
+	{
+		Name:        "rule",
+		Description: "Add a composite alert rule to a vault, or list its rules",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "vault_id",
+				Description: "ID of the vault",
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "expression",
+				Description: "e.g. borrowApy > 8 && utilization > 0.95 || change24h > 1.5 (omit to list rules)",
+				Required:    false,
+			},
+		},
+	},
+	{
+		Name:        "rule-remove",
+		Description: "Remove an alert rule from a vault",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "vault_id",
+				Description: "ID of the vault",
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "rule_id",
+				Description: "ID of the rule to remove",
+				Required:    true,
+			},
+		},
+	},
+	{
+		Name:                     "api-token-create",
+		Description:              "Create an HTTP API token (admins only)",
+		DefaultMemberPermissions: &adminPermission,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "name",
+				Description: "What the token is for, e.g. grafana",
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "scopes",
+				Description: "Comma-separated: read, manage-vaults, trigger-checks, or all",
+				Required:    true,
+			},
+		},
+	},
+	{
+		Name:                     "verify",
+		Description:              "Check stored data for inconsistencies (admins only)",
+		DefaultMemberPermissions: &adminPermission,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionBoolean,
+				Name:        "repair",
+				Description: "Prune orphaned data, look up missing market keys, and recreate missing webhooks",
+				Required:    false,
+			},
+		},
+	},
+	{
+		Name:                     "api-token-list",
+		Description:              "List HTTP API tokens (admins only)",
+		DefaultMemberPermissions: &adminPermission,
+	},
+	{
+		Name:                     "api-token-revoke",
+		Description:              "Revoke an HTTP API token (admins only)",
+		DefaultMemberPermissions: &adminPermission,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "token_id",
+				Description: "ID of the token to revoke",
+				Required:    true,
+			},
+		},
+	},
+	{
+		Name:        "auto-enroll",
+		Description: "Automatically enroll every market for a pair, including new ones",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "collateral",
+				Description: "Collateral asset symbol, e.g. cbBTC",
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "loan",
+				Description: "Loan asset symbol, e.g. USDC",
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionNumber,
+				Name:        "threshold",
+				Description: "Alert threshold for enrolled markets (0.01-100.0)",
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionChannel,
+				Name:        "channel",
+				Description: "Channel to send alerts to (defaults to current channel)",
+				Required:    false,
+				ChannelTypes: []discordgo.ChannelType{
+					discordgo.ChannelTypeGuildText,
+				},
+			},
+		},
+	},
+	{
+		Name:        "auto-enroll-remove",
+		Description: "Remove an auto-enroll rule (vaults it enrolled stay enrolled)",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "rule_id",
+				Description: "ID of the rule to remove",
+				Required:    true,
+			},
+		},
+	},
+	{
+		Name:        "unenroll",
+		Description: "Remove a vault from monitoring",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "vault_id",
+				Description: "ID of the vault to remove",
+				Required:    true,
+			},
+		},
+	},
+	{
+		Name:        "restore",
+		Description: "Re-enroll a recently unenrolled vault",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "vault_id",
+				Description: "ID of the vault to restore",
+				Required:    true,
+			},
+		},
+	},
+	{
+		Name:        "undo",
+		Description: "Revert your most recent enroll, unenroll, or threshold change",
+	},
+	{
+		Name:        "list",
+		Description: "Show all enrolled vaults with their market pairs and rates",
+	},
+	{
+		Name:        "status",
+		Description: "Show current rates for all vaults, or details for one",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "vault_id",
+				Description: "Show a detailed card for this vault",
+				Required:    false,
+			},
+		},
+	},
+	{
+		Name:        "history",
+		Description: "Show a vault's recent rate samples, or a summary over a window",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "vault_id",
+				Description: "ID of the vault",
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "view",
+				Description: "Individual samples or a min/max/average summary (default samples)",
+				Required:    false,
+				Choices: []*discordgo.ApplicationCommandOptionChoice{
+					{Name: "Samples", Value: "samples"},
+					{Name: "Summary", Value: "summary"},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "window",
+				Description: "How far back to look (default 24 hours)",
+				Required:    false,
+				Choices: []*discordgo.ApplicationCommandOptionChoice{
+					{Name: "24 hours", Value: "24h"},
+					{Name: "7 days", Value: "7d"},
+					{Name: "30 days", Value: "30d"},
+					{Name: "90 days", Value: "90d"},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionInteger,
+				Name:        "count",
+				Description: "Number of samples to show, newest first (default 10, max 25)",
+				Required:    false,
+			},
+		},
+	},
+	{
+		Name:        "chart",
+		Description: "Draw a vault's borrow rate over time",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "vault_id",
+				Description: "ID of the vault",
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "period",
+				Description: "How far back to draw (default 7 days)",
+				Required:    false,
+				Choices: []*discordgo.ApplicationCommandOptionChoice{
+					{Name: "24 hours", Value: "24h"},
+					{Name: "7 days", Value: "7d"},
+					{Name: "30 days", Value: "30d"},
+					{Name: "90 days", Value: "90d"},
+				},
+			},
+		},
+	},
+	{
+		Name:        "leaderboard",
+		Description: "Rank vaults by rate volatility or net change",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "metric",
+				Description: "What to rank by (default volatility)",
+				Required:    false,
+				Choices: []*discordgo.ApplicationCommandOptionChoice{
+					{Name: "Volatility", Value: "volatility"},
+					{Name: "Net change", Value: "change"},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "window",
+				Description: "How far back to look (default 7 days)",
+				Required:    false,
+				Choices: []*discordgo.ApplicationCommandOptionChoice{
+					{Name: "24 hours", Value: "24h"},
+					{Name: "7 days", Value: "7d"},
+					{Name: "30 days", Value: "30d"},
+				},
+			},
+		},
+	},
+	{
+		Name:        "top",
+		Description: "List the markets with the highest borrow rate, move or utilization",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "metric",
+				Description: "What to rank by (default borrow rate)",
+				Required:    false,
+				Choices: []*discordgo.ApplicationCommandOptionChoice{
+					{Name: "Borrow rate", Value: "rate"},
+					{Name: "24h change", Value: "change"},
+					{Name: "Utilization", Value: "utilization"},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionInteger,
+				Name:        "n",
+				Description: "How many to list (default 10, max 25)",
+				Required:    false,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "scope",
+				Description: "This server's enrolled vaults, or every market on Morpho (default enrolled)",
+				Required:    false,
+				Choices: []*discordgo.ApplicationCommandOptionChoice{
+					{Name: "Enrolled vaults", Value: "enrolled"},
+					{Name: "All markets", Value: "all"},
+				},
+			},
+		},
+	},
+	{
+		Name:        "portfolio",
+		Description: "Show each vault's debt and the interest it has accrued since enrollment",
+	},
+	{
+		Name:        "savings",
+		Description: "Estimate the savings of moving a vault's debt to another market",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "vault_id",
+				Description: "ID of the vault whose debt would move",
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "target_market",
+				Description: "Market pair (e.g. WBTC-USDC) or Morpho market unique key (0x...) to move to",
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionNumber,
+				Name:        "gas_usd",
+				Description: fmt.Sprintf("Gas cost of the migration in USD (default %.0f)", defaultMigrationGasUSD),
+				Required:    false,
+			},
+		},
+	},
+	{
+		Name:        "market-info",
+		Description: "Show a Morpho market's parameters and current state",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "market",
+				Description: "Market pair (e.g. WBTC-USDC) or Morpho market unique key (0x...)",
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionNumber,
+				Name:        "utilization",
+				Description: "Project the borrow rate at this utilization % (default 95, 99 and 100)",
+				Required:    false,
+			},
+		},
+	},
+	{
+		Name:        "watch-new",
+		Description: "Get alerted when a new Morpho market for a pair appears",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "collateral",
+				Description: "Collateral asset symbol, e.g. WBTC",
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "loan",
+				Description: "Loan asset symbol, e.g. USDC",
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionChannel,
+				Name:        "channel",
+				Description: "Channel to send alerts to (defaults to current channel)",
+				Required:    false,
+				ChannelTypes: []discordgo.ChannelType{
+					discordgo.ChannelTypeGuildText,
+				},
+			},
+		},
+	},
+	{
+		Name:        "unwatch-new",
+		Description: "Stop a new-market watch",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "watch_id",
+				Description: "ID of the watch to remove",
+				Required:    true,
+			},
+		},
+	},
+	{
+		Name:        "check",
+		Description: "Force an immediate rate check of all vaults, one vault, or a tagged group",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "vault_id",
+				Description: "Only check this vault",
+				Required:    false,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "tag",
+				Description: "Only check vaults with this /tag",
+				Required:    false,
+			},
+		},
+	},
+	{
+		Name:        "threshold",
+		Description: "Update alert threshold for a vault",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "vault_id",
+				Description: "ID of the vault to update",
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionNumber,
+				Name:        "new_threshold",
+				Description: "New threshold value (0.01-100.0)",
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "mode",
+				Description: "Percentage points (default) or a percentage of the baseline rate",
+				Required:    false,
+				Choices: []*discordgo.ApplicationCommandOptionChoice{
+					{Name: "Absolute (percentage points)", Value: string(types.ThresholdAbsolute)},
+					{Name: "Relative (% of baseline)", Value: string(types.ThresholdRelative)},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "rate",
+				Description: "Borrow APY to check the threshold against",
+				Required:    false,
+				Choices: []*discordgo.ApplicationCommandOptionChoice{
+					{Name: "Instantaneous (default)", Value: string(types.RateSpot)},
+					{Name: "24h average (less noisy)", Value: string(types.RateDailyAverage)},
+					{Name: "Trailing average over the given hours", Value: string(types.RateTrailingAverage)},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionInteger,
+				Name:        "hours",
+				Description: fmt.Sprintf("Trailing average window in hours (default %d)", types.DefaultAverageHours),
+				Required:    false,
+			},
+		},
+	},
+	{
+		Name:        "enable",
+		Description: "Resume checking a vault that was disabled after repeated failures",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "vault_id",
+				Description: "ID of the vault to enable",
+				Required:    true,
+			},
+		},
+	},
+	{
+		Name:        "pause",
+		Description: "Stop delivering a vault's alerts for a while, without unenrolling it",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "vault_id",
+				Description: "ID of the vault to pause, or \"all\" for every vault in this server",
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "duration",
+				Description: "How long to pause, e.g. 6h (default: until /resume)",
+				Required:    false,
+			},
+		},
+	},
+	{
+		Name:        "resume",
+		Description: "Deliver a paused vault's alerts again",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "vault_id",
+				Description: "ID of the vault to resume, or \"all\" for every vault in this server",
+				Required:    true,
+			},
+		},
+	},
+	{
+		Name:        "baseline",
+		Description: "Choose what a vault's alerts are measured against",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "vault_id",
+				Description: "ID of the vault to update",
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "strategy",
+				Description: "Baseline to compare each check against",
+				Required:    true,
+				Choices: []*discordgo.ApplicationCommandOptionChoice{
+					{Name: "Last alert rate (default)", Value: string(types.BaselineLastAlert)},
+					{Name: "Previous check", Value: string(types.BaselinePreviousCheck)},
+					{Name: "Daily open (UTC)", Value: string(types.BaselineDailyOpen)},
+				},
+			},
+		},
+	},
+	{
+		Name:        "reset-baseline",
+		Description: "Reset a vault's alert baseline to its current rate",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "vault_id",
+				Description: "ID of the vault to reset",
+				Required:    true,
+			},
+		},
+	},
+	{
+		Name:        "critical",
+		Description: "Set the borrow rate at which a vault's alerts become critical",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "vault_id",
+				Description: "ID of the vault to update",
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionNumber,
+				Name:        "rate",
+				Description: "Critical borrow rate in percent (0 to disable)",
+				Required:    true,
+			},
+		},
+	},
+	{
+		Name:        "band",
+		Description: "Set a vault's normal borrow rate range, which alert colors are graded against",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "vault_id",
+				Description: "ID of the vault to update",
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionNumber,
+				Name:        "low",
+				Description: "Bottom of the normal range in percent (omit both to use recent history)",
+				Required:    false,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionNumber,
+				Name:        "high",
+				Description: "Top of the normal range in percent (omit both to use recent history)",
+				Required:    false,
+			},
+		},
+	},
+	{
+		Name:        "tag",
+		Description: "Set or clear a vault's tags, used to /check a group of vaults",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "vault_id",
+				Description: "ID of the vault to update",
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "tags",
+				Description: "Comma-separated tags, e.g. \"treasury, eth\" (omit to clear)",
+				Required:    false,
+			},
+		},
+	},
+	{
+		Name:        "note",
+		Description: "Set or clear a vault's notes",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "vault_id",
+				Description: "ID of the vault to update",
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "text",
+				Description: "Notes to show in /list (omit to clear)",
+				Required:    false,
+				MaxLength:   maxNotesLength,
+			},
+		},
+	},
+	{
+		Name:        "debt",
+		Description: "Set a vault's debt in USD, used to estimate accrued interest",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "vault_id",
+				Description: "ID of the vault to update",
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionNumber,
+				Name:        "amount",
+				Description: "Debt in USD (omit to clear)",
+				Required:    false,
+			},
+		},
+	},
+	{
+		Name:        "precision",
+		Description: "Set how many decimal places a vault's rates are shown with",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "vault_id",
+				Description: "ID of the vault to update",
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionInteger,
+				Name:        "decimals",
+				Description: "Decimal places for rates",
+				Required:    true,
+				Choices: []*discordgo.ApplicationCommandOptionChoice{
+					{Name: "2 (e.g. 5.12%)", Value: 2},
+					{Name: "3 (e.g. 5.123%)", Value: 3},
+					{Name: "4 (e.g. 5.1234%)", Value: 4},
+					{Name: "Configured default", Value: 0},
+				},
+			},
+		},
+	},
+	{
+		Name:        "cooldown",
+		Description: "Set the minimum time between a vault's threshold alerts",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "vault_id",
+				Description: "ID of the vault to update",
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionInteger,
+				Name:        "minutes",
+				Description: "Minutes to wait after an alert before alerting again (0 to remove the cooldown)",
+				Required:    true,
+			},
+		},
+	},
+	{
+		Name:        "priority",
+		Description: "Set which vaults are checked first when the market API is struggling",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "vault_id",
+				Description: "ID of the vault to update",
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "level",
+				Description: "Check priority",
+				Required:    true,
+				Choices: []*discordgo.ApplicationCommandOptionChoice{
+					{Name: "High (checked first)", Value: string(types.PriorityHigh)},
+					{Name: "Normal", Value: string(types.PriorityNormal)},
+					{Name: "Low (checked less often under pressure)", Value: string(types.PriorityLow)},
+				},
+			},
+		},
+	},
+	{
+		Name:        "fallback",
+		Description: "Set who gets DMed when a vault's alert webhook keeps failing",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "vault_id",
+				Description: "ID of the vault to update",
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionUser,
+				Name:        "user",
+				Description: "User to DM (omit to disable the fallback)",
+				Required:    false,
+			},
+		},
+	},
+	{
+		Name:                     "slack",
+		Description:              "Mirror a vault's alerts to a Slack channel (admins only)",
+		DefaultMemberPermissions: &adminPermission,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "vault_id",
+				Description: "ID of the vault to update",
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "webhook_url",
+				Description: "Slack incoming webhook URL (omit to stop mirroring)",
+				Required:    false,
+			},
+		},
+	},
+	{
+		Name:        "preview",
+		Description: "Show the alert a vault would send if its rate moved to a given value, without sending it",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "vault_id",
+				Description: "ID of the vault",
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionNumber,
+				Name:        "hypothetical_rate",
+				Description: "Borrow rate in percent to preview the alert for, e.g. 7.5",
+				Required:    true,
+			},
+		},
+	},
+	{
+		Name:        "maintenance",
+		Description: "Silence all alert delivery for a planned period (rates are still recorded)",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "mode",
+				Description: "Turn maintenance mode on or off",
+				Required:    true,
+				Choices: []*discordgo.ApplicationCommandOptionChoice{
+					{Name: "on", Value: "on"},
+					{Name: "off", Value: "off"},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "duration",
+				Description: "How long to stay in maintenance, e.g. 30m or 4h (default 2h)",
+				Required:    false,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "starts_in",
+				Description: "Schedule the window to start later, e.g. 1h or 24h (default now)",
+				Required:    false,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionBoolean,
+				Name:        "event",
+				Description: "Announce the window as a Discord scheduled event (needs Manage Events)",
+				Required:    false,
+			},
+		},
+	},
+	{
+		Name:                     "pause-checks",
+		Description:              "Stop scheduled rate checks for a while, e.g. during an API outage (admins only)",
+		DefaultMemberPermissions: &adminPermission,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "mode",
+				Description: "Pause or resume scheduled checks",
+				Required:    true,
+				Choices: []*discordgo.ApplicationCommandOptionChoice{
+					{Name: "on", Value: "on"},
+					{Name: "off", Value: "off"},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "duration",
+				Description: "How long to pause, e.g. 30m or 4h (default 1h)",
+				Required:    false,
+			},
+		},
+	},
+	{
+		Name:        "ack",
+		Description: "Acknowledge an alert so it stops being re-pinged",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "alert_id",
+				Description: "ID shown in the alert's footer",
+				Required:    true,
+			},
+		},
+	},
+	{
+		Name:                     "critical-ping",
+		Description:              "Ping @here or @everyone, or use text-to-speech, on a vault's critical alerts (admins only)",
+		DefaultMemberPermissions: &adminPermission,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "vault_id",
+				Description: "ID of the vault to update",
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "mention",
+				Description: "Who critical alerts ping",
+				Required:    true,
+				Choices: []*discordgo.ApplicationCommandOptionChoice{
+					{Name: "Nobody", Value: "none"},
+					{Name: "@here", Value: string(types.MentionHere)},
+					{Name: "@everyone", Value: string(types.MentionEveryone)},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionBoolean,
+				Name:        "tts",
+				Description: "Read critical alerts aloud with text-to-speech (default off)",
+				Required:    false,
+			},
+		},
+	},
+	{
+		Name:                     "branding",
+		Description:              "Sign this server's alerts with your own name and icon (admins only)",
+		DefaultMemberPermissions: &adminPermission,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "name",
+				Description: "Name shown in embed footers instead of SummerRateChecker (omit to reset)",
+				Required:    false,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "icon_url",
+				Description: "Link to an image shown beside the footer, e.g. your logo (omit for none)",
+				Required:    false,
+			},
+		},
+	},
+	{
+		Name:        "escalation",
+		Description: "Set who is pinged when a vault's rate breach persists",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "vault_id",
+				Description: "ID of the vault to update",
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionRole,
+				Name:        "role",
+				Description: "Role to mention on escalations (omit to clear)",
+				Required:    false,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionUser,
+				Name:        "user",
+				Description: "User to DM on escalations (omit to clear)",
+				Required:    false,
+			},
+		},
+	},
+	{
+		Name:        "route",
+		Description: "Send a vault's warning or critical alerts to another channel",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "vault_id",
+				Description: "ID of the vault to update",
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "severity",
+				Description: "Which alerts to route",
+				Required:    true,
+				Choices: []*discordgo.ApplicationCommandOptionChoice{
+					{Name: "Warning", Value: string(types.SeverityWarning)},
+					{Name: "Critical", Value: string(types.SeverityCritical)},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionChannel,
+				Name:        "channel",
+				Description: "Channel to send them to (omit to send them to the vault's channel)",
+				Required:    false,
+				ChannelTypes: []discordgo.ChannelType{
+					discordgo.ChannelTypeGuildText,
+				},
+			},
+		},
+	},
+	{
+		Name:        "schedule",
+		Description: "Restrict when a vault's alerts are delivered (e.g. weekdays 8-20)",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "vault_id",
+				Description: "ID of the vault to update",
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "days",
+				Description: "Days like mon-fri or mon,wed,fri; 'all' for every day; 'off' to remove the schedule",
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionInteger,
+				Name:        "start_hour",
+				Description: "First hour alerts are delivered (0-23, default 8)",
+				Required:    false,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionInteger,
+				Name:        "end_hour",
+				Description: "Hour alerts stop being delivered (1-24, default 20)",
+				Required:    false,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "timezone",
+				Description: "IANA time zone, e.g. America/New_York (default UTC)",
+				Required:    false,
+			},
+		},
+	},
+	{
+		Name:        "diagnostics",
+		Description: "Show alert delivery stats per vault and sink",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "vault_id",
+				Description: "Only show this vault",
+				Required:    false,
+			},
+		},
+	},
+	{
+		Name:        "interval",
+		Description: "Show current check interval",
+	},
+	{
+		Name:        "help",
+		Description: "Show help message with all available commands",
+	},
+}
+
+// RegisterCommands registers all slash commands with Discord
+func RegisterCommands(s *discordgo.Session, appID string, guildID string, readOnly bool) error {
+	// Log the app ID and guild ID we're using
+	fmt.Printf("Registering commands for application ID: %s in guild: %s\n", appID, guildID)
+
+	// First, clean up any global commands (these should never exist)
+	fmt.Println("Checking for global commands...")
+	globalCommands, err := s.ApplicationCommands(appID, "")
+	if err != nil {
+		return fmt.Errorf("failed to get global commands: %w", err)
+	}
+
+	if len(globalCommands) > 0 {
+		fmt.Printf("Found %d global commands to remove\n", len(globalCommands))
+		for _, cmd := range globalCommands {
+			fmt.Printf("Removing global command: %s (ID: %s)\n", cmd.Name, cmd.ID)
+			err := s.ApplicationCommandDelete(appID, "", cmd.ID)
+			if err != nil {
+				return fmt.Errorf("failed to delete global command %s: %w", cmd.Name, err)
+			}
+		}
+	}
+
+	// Get existing guild commands
+	fmt.Printf("Checking guild commands for guild %s...\n", guildID)
+	existingCommands, err := s.ApplicationCommands(appID, guildID)
+	if err != nil {
+		return fmt.Errorf("failed to get guild commands: %w", err)
+	}
+
+	// Create maps for quick lookup
+	existingMap := make(map[string]*discordgo.ApplicationCommand)
+	for _, cmd := range existingCommands {
+		existingMap[cmd.Name] = cmd
+	}
+
+	// Track which commands we've processed
+	processedCommands := make(map[string]bool)
+
+	// Update or create commands as needed
+	fmt.Println("Updating commands...")
+	for _, newCmd := range AvailableCommands(readOnly) {
+		processedCommands[newCmd.Name] = true
+		existingCmd, exists := existingMap[newCmd.Name]
+
+		if !exists {
+			// Command doesn't exist, create it
+			fmt.Printf("Creating new command: %s\n", newCmd.Name)
+			_, err := s.ApplicationCommandCreate(appID, guildID, newCmd)
+			if err != nil {
+				return fmt.Errorf("failed to create command %s: %w", newCmd.Name, err)
+			}
+			continue
+		}
+
+		// Check if command needs updating by comparing relevant fields
+		if needsUpdate(existingCmd, newCmd) {
+			fmt.Printf("Updating command: %s\n", newCmd.Name)
+			_, err := s.ApplicationCommandEdit(appID, guildID, existingCmd.ID, newCmd)
+			if err != nil {
+				return fmt.Errorf("failed to update command %s: %w", newCmd.Name, err)
+			}
+		} else {
+			fmt.Printf("Command unchanged: %s\n", newCmd.Name)
+		}
+	}
+
+	// Remove any commands that no longer exist in our Commands list, or that read-only mode hides
+	for name, cmd := range existingMap {
+		if !processedCommands[name] {
+			fmt.Printf("Removing obsolete command: %s\n", name)
+			err := s.ApplicationCommandDelete(appID, guildID, cmd.ID)
+			if err != nil {
+				return fmt.Errorf("failed to delete obsolete command %s: %w", name, err)
+			}
+		}
+	}
+
+	fmt.Println("Command registration complete")
+	return nil
+}
+
+// needsUpdate checks if a command needs to be updated by comparing relevant fields
+func needsUpdate(existing, new *discordgo.ApplicationCommand) bool {
+	// Compare basic fields
+	if existing.Description != new.Description {
+		return true
+	}
+
+	// Compare options
+	if len(existing.Options) != len(new.Options) {
+		return true
+	}
+
+	// Create maps for option comparison
+	existingOpts := make(map[string]*discordgo.ApplicationCommandOption)
+	for _, opt := range existing.Options {
+		existingOpts[opt.Name] = opt
+	}
+
+	// Compare each option
+	for _, newOpt := range new.Options {
+		existingOpt, exists := existingOpts[newOpt.Name]
+		if !exists {
+			return true
+		}
+
+		// Compare option properties
+		if existingOpt.Type != newOpt.Type ||
+			existingOpt.Description != newOpt.Description ||
+			existingOpt.Required != newOpt.Required {
+			return true
+		}
+
+		// Compare channel types if present
+		if len(existingOpt.ChannelTypes) != len(newOpt.ChannelTypes) {
+			return true
+		}
+		for i, ct := range existingOpt.ChannelTypes {
+			if i >= len(newOpt.ChannelTypes) || ct != newOpt.ChannelTypes[i] {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// HandleCommand handles a slash command interaction
+func HandleCommand(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) {
+	// Defer the response in case the handler takes time
+	response := &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+	}
+	if ephemeralCommands[i.ApplicationCommandData().Name] {
+		response.Data = &discordgo.InteractionResponseData{Flags: discordgo.MessageFlagsEphemeral}
+	}
+	s.InteractionRespond(i.Interaction, response)
+
+	// Commands registered before read-only mode was turned on can still be invoked until Discord
+	// catches up
+	if ctx.Config.Discord.ReadOnly && !readOnlyCommands[i.ApplicationCommandData().Name] {
+		errMsg := "🔒 This bot is a read-only mirror; use /status or /list to view vaults"
+		s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: &errMsg,
+		})
+		return
+	}
+
+	var err error
+	switch i.ApplicationCommandData().Name {
+	case "setup":
+		err = handleSetup(s, i, ctx)
+	case "enroll":
+		err = handleEnroll(s, i, ctx)
+	case "rule":
+		err = handleRule(s, i, ctx)
+	case "rule-remove":
+		err = handleRuleRemove(s, i, ctx)
+	case "api-token-create":
+		err = handleAPITokenCreate(s, i, ctx)
+	case "verify":
+		err = handleVerify(s, i, ctx)
+	case "api-token-list":
+		err = handleAPITokenList(s, i, ctx)
+	case "api-token-revoke":
+		err = handleAPITokenRevoke(s, i, ctx)
+	case "auto-enroll":
+		err = handleAutoEnroll(s, i, ctx)
+	case "auto-enroll-remove":
+		err = handleAutoEnrollRemove(s, i, ctx)
+	case "unenroll":
+		err = handleUnenroll(s, i, ctx)
+	case "restore":
+		err = handleRestore(s, i, ctx)
+	case "undo":
+		err = handleUndo(s, i, ctx)
+	case "list":
+		err = handleList(s, i, ctx)
+	case "status":
+		err = handleStatus(s, i, ctx)
+	case "history":
+		err = handleHistory(s, i, ctx)
+	case "chart":
+		err = handleChart(s, i, ctx)
+	case "leaderboard":
+		err = handleLeaderboard(s, i, ctx)
+	case "top":
+		err = handleTop(s, i, ctx)
+	case "portfolio":
+		err = handlePortfolio(s, i, ctx)
+	case "savings":
+		err = handleSavings(s, i, ctx)
+	case "market-info":
+		err = handleMarketInfo(s, i, ctx)
+	case "watch-new":
+		err = handleWatchNew(s, i, ctx)
+	case "unwatch-new":
+		err = handleUnwatchNew(s, i, ctx)
+	case "check":
+		err = handleCheck(s, i, ctx)
+	case "threshold":
+		err = handleThreshold(s, i, ctx)
+	case "enable":
+		err = handleEnable(s, i, ctx)
+	case "pause":
+		err = handlePause(s, i, ctx)
+	case "resume":
+		err = handleResume(s, i, ctx)
+	case "baseline":
+		err = handleBaseline(s, i, ctx)
+	case "reset-baseline":
+		err = handleResetBaseline(s, i, ctx)
+	case "critical":
+		err = handleCritical(s, i, ctx)
+	case "band":
+		err = handleBand(s, i, ctx)
+	case "tag":
+		err = handleTag(s, i, ctx)
+	case "note":
+		err = handleNote(s, i, ctx)
+	case "debt":
+		err = handleDebt(s, i, ctx)
+	case "precision":
+		err = handlePrecision(s, i, ctx)
+	case "cooldown":
+		err = handleCooldown(s, i, ctx)
+	case "priority":
+		err = handlePriority(s, i, ctx)
+	case "fallback":
+		err = handleFallback(s, i, ctx)
+	case "slack":
+		err = handleSlack(s, i, ctx)
+	case "preview":
+		err = handlePreview(s, i, ctx)
+	case "maintenance":
+		err = handleMaintenance(s, i, ctx)
+	case "pause-checks":
+		err = handlePauseChecks(s, i, ctx)
+	case "ack":
+		err = handleAck(s, i, ctx)
+	case "critical-ping":
+		err = handleCriticalPing(s, i, ctx)
+	case "branding":
+		err = handleBranding(s, i, ctx)
+	case "escalation":
+		err = handleEscalation(s, i, ctx)
+	case "route":
+		err = handleRoute(s, i, ctx)
+	case "schedule":
+		err = handleSchedule(s, i, ctx)
+	case "diagnostics":
+		err = handleDiagnostics(s, i, ctx)
+	case "interval":
+		err = handleInterval(s, i, ctx)
+	case "help":
+		err = handleHelp(s, i, ctx)
+	default:
+		err = fmt.Errorf("unknown command: %s", i.ApplicationCommandData().Name)
+	}
+
+	if err != nil {
+		// Send error response
+		errMsg := err.Error()
+		s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: &errMsg,
+		})
+		return
+	}
+}
+
+// Command handlers
+func handleEnroll(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) error {
+	guild := ctx.Storage.GetSettings().Guild(i.GuildID)
+
+	// Threshold and channel are optional, so look options up by name rather than position
+	var url, nickname, channelID, notes string
+	var createChannel bool
+	threshold := guild.DefaultThreshold
+	for _, option := range i.ApplicationCommandData().Options {
+		switch option.Name {
+		case "url":
+			url = option.StringValue()
+		case "nickname":
+			nickname = option.StringValue()
+		case "threshold":
+			threshold = option.FloatValue()
+		case "channel":
+			channelID = option.ChannelValue(s).ID
+		case "create_channel":
+			createChannel = option.BoolValue()
+		case "notes":
+			notes = strings.TrimSpace(option.StringValue())
+		}
+	}
+	if threshold == 0 {
+		return fmt.Errorf("threshold is required until a server default is set with /setup")
+	}
+	if createChannel && channelID != "" {
+		return fmt.Errorf("choose either channel or create_channel, not both")
+	}
+
+	vault := &types.VaultConfig{
+		Nickname:         nickname,
+		ThresholdPercent: threshold,
+		ChannelID:        channelID,
+		EnrollSource:     types.EnrollSourceCommand,
+		Notes:            notes,
+	}
+
+	if createChannel {
+		channel, err := createVaultChannel(s, i, ctx, nickname)
+		if err != nil {
+			return err
+		}
+		vault.ChannelID = channel.ID
+		vault.ChannelCreated = true
+	}
+
+	// Fall back to the server's alert channel, then the current channel
+	if vault.ChannelID == "" {
+		vault.ChannelID = guild.AlertChannelID
+	}
+	if vault.ChannelID == "" {
+		vault.ChannelID = i.ChannelID
+	}
+
+	if err := enrollVault(s, i, ctx, url, vault); err != nil {
+		// Don't leave an empty channel behind
+		if vault.ChannelCreated {
+			s.ChannelDelete(vault.ChannelID)
+		}
+		return err
+	}
+
+	response := fmt.Sprintf(
+		"✅ Successfully enrolled vault `%s` (\"%s\")\n"+
+			"Market Pair: %s\n"+
+			"Threshold: %s\n"+
+			"Alerts will be sent to <#%s>",
+		vault.VaultID, vault.Nickname, vault.MarketPair, vault.DescribeThreshold(), vault.ChannelID,
+	)
+	if !strings.Contains(url, "summer.fi") && !morpho.IsMarketKey(url) {
+		// A pair could match several markets, so say which one was picked
+		response += fmt.Sprintf("\nResolved %s to the largest market, `%s`; enroll by unique key to pick another", strings.TrimSpace(url), vault.MorphoMarketKey)
+	}
+
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &response,
+	})
+	return nil
+}
+
+// enrollVault validates and stores a new vault for target, creating its webhook in vault.ChannelID.
+// The caller sets the nickname, threshold and channel; the rest is filled in here.
+// It backs both /enroll and the /setup wizard.
+func enrollVault(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext, target string, vault *types.VaultConfig) error {
+	if err := types.ValidateThreshold(vault.ThresholdPercent); err != nil {
+		return err
+	}
+
+	if err := resolveEnrollTarget(ctx, target, vault); err != nil {
+		return err
+	}
+
+	vault.EnrolledBy = interactionUserID(i)
+	vault.EnrolledByName = interactionUserName(i)
+	vault.GuildID = i.GuildID
+	// Checked before the webhook is created, so a rejected enrollment leaves nothing behind
+	quota := storage.NewQuota(ctx.Config)
+	if err := quota.Check(ctx.Storage, vault); err != nil {
+		return err
+	}
+
+	// Re-enrolling would orphan the trashed vault's webhook and history
+	for _, trashed := range ctx.Storage.GetTrashedVaults() {
+		if trashed.VaultID == vault.VaultID {
+			return fmt.Errorf("vault `%s` was unenrolled recently; use `/restore %s` to bring it back", vault.VaultID, vault.VaultID)
+		}
+	}
+
+	// Create a webhook for the channel
+	webhook, err := s.WebhookCreate(vault.ChannelID, "SummerRateChecker", "")
+	if err != nil {
+		return fmt.Errorf("failed to create webhook for channel: %w", err)
+	}
+
+	vault.WebhookURL = fmt.Sprintf("https://discord.com/api/webhooks/%s/%s", webhook.ID, webhook.Token)
+	vault.FallbackUserID = interactionUserID(i) // DM the enrolling user if the webhook breaks
+
+	err = storage.EnrollVault(ctx.Storage, vault, quota)
+	if err != nil {
+		// Clean up webhook if storage fails
+		s.WebhookDelete(webhook.ID)
+		var quotaErr *storage.QuotaError
+		if errors.As(err, &quotaErr) {
+			return err
+		}
+		return fmt.Errorf("failed to enroll vault: %w", err)
+	}
+	recordAudit(ctx, types.NewAuditEntry(interactionUserID(i), types.AuditEnroll, vault))
+	return nil
+}
+
+// resolveEnrollTarget fills in the vault's ID, pair and market from what /enroll was given: a
+// Summer.fi position URL, keyed by network and protocol as well as position number, or a Morpho
+// market unique key (0x…) or pair (e.g. WBTC-USDC) for a market without a position page. A
+// market has no Summer.fi vault ID, so its unique key doubles as the vault ID.
+func resolveEnrollTarget(ctx *CommandContext, target string, vault *types.VaultConfig) error {
+	target = strings.TrimSpace(target)
+	if strings.Contains(target, "summer.fi") || strings.HasPrefix(target, "http") {
+		urlInfo, err := morpho.ParseVaultURL(target)
+		if err != nil {
+			return fmt.Errorf("invalid Summer.fi URL: %v", err)
+		}
+		vault.VaultID = types.VaultKey(urlInfo.Network, urlInfo.Protocol, urlInfo.VaultID)
+		vault.MarketPair = urlInfo.MarketPair
+		return nil
+	}
+
+	if strings.HasPrefix(target, "0x") && !morpho.IsMarketKey(target) {
+		return fmt.Errorf("invalid market unique key: should be 0x followed by 64 hex digits")
+	}
+	if !strings.HasPrefix(target, "0x") && !strings.Contains(target, "-") {
+		return fmt.Errorf("expected a Summer.fi URL, a Morpho market unique key (0x…), or a market pair like WBTC-USDC")
+	}
+
+	client := morpho.NewClient(ctx.Config.Morpho.APIURL, ctx.Logger)
+	market, err := client.GetMarketInfo(context.Background(), target, types.EthereumChainID)
+	if err != nil {
+		return fmt.Errorf("failed to look up market: %w", err)
+	}
+	vault.VaultID = market.UniqueKey
+	vault.MorphoMarketKey = market.UniqueKey
+	vault.MarketPair = market.MarketPair()
+	return nil
+}
+
+// createVaultChannel creates a dedicated alert channel named after the vault's nickname, in the
+// same category as the server's /setup alert channel if there is one
+func createVaultChannel(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext, nickname string) (*discordgo.Channel, error) {
+	channels, err := s.GuildChannels(i.GuildID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list channels: %w", err)
+	}
+
+	alertChannelID := ctx.Storage.GetSettings().Guild(i.GuildID).AlertChannelID
+	var parentID string
+	taken := make(map[string]bool, len(channels))
+	for _, channel := range channels {
+		taken[channel.Name] = true
+		if channel.ID == alertChannelID {
+			parentID = channel.ParentID
+		}
+	}
+
+	// Two vaults can share a nickname, so number the channel rather than reuse another vault's
+	base := channelName(nickname)
+	name := base
+	for n := 2; taken[name]; n++ {
+		name = fmt.Sprintf("%s-%d", base, n)
+	}
+
+	channel, err := createAlertChannel(s, i.GuildID, name, fmt.Sprintf("Borrow rate alerts for %s", nickname), parentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create channel: %w", err)
+	}
+	return channel, nil
+}
+
+// channelName turns a nickname into a Discord channel name: lowercase words joined by dashes
+func channelName(nickname string) string {
+	words := strings.FieldsFunc(strings.ToLower(nickname), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	// Leave room for a "-N" suffix within Discord's 100 character limit
+	name := []rune(strings.Join(words, "-"))
+	if len(name) > 90 {
+		name = name[:90]
+	}
+	if len(name) == 0 {
+		return "vault-alerts"
+	}
+	return string(name)
+}
+
+func handleAPITokenCreate(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) error {
+	options := i.ApplicationCommandData().Options
+	name := strings.TrimSpace(options[0].StringValue())
+
+	scopes, err := types.ParseTokenScopes(options[1].StringValue())
+	if err != nil {
+		return err
+	}
+
+	token, secret, err := types.NewAPIToken(name, scopes, interactionUserID(i))
+	if err != nil {
+		return err
+	}
+	if err := ctx.Storage.AddAPIToken(token); err != nil {
+		return fmt.Errorf("failed to save token: %w", err)
+	}
+
+	response := fmt.Sprintf(
+		"🔑 Created token `%s` (%s) with scopes %s\n```\n%s\n```\nSend it as `Authorization: Bearer <token>`. It won't be shown again.",
+		token.ID, name, formatScopes(scopes), secret,
+	)
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &response,
+	})
+	return nil
+}
+
+func handleAPITokenList(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) error {
+	tokens := ctx.Storage.GetAPITokens()
+
+	var response string
+	if len(tokens) == 0 {
+		response = "No API tokens. Create one with `/api-token-create`."
+	} else {
+		var sb strings.Builder
+		sb.WriteString("🔑 **API tokens:**\n")
+		for _, token := range tokens {
+			lastUsed := "never used"
+			if !token.LastUsedAt.IsZero() {
+				lastUsed = fmt.Sprintf("last used <t:%d:R>", token.LastUsedAt.Unix())
+			}
+			sb.WriteString(fmt.Sprintf("• `%s` %s: %s, %s\n", token.ID, token.Name, formatScopes(token.Scopes), lastUsed))
+		}
+		response = sb.String()
+	}
+
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &response,
+	})
+	return nil
+}
+
+func handleAPITokenRevoke(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) error {
+	tokenID := strings.TrimSpace(i.ApplicationCommandData().Options[0].StringValue())
+
+	var token *types.APIToken
+	for _, t := range ctx.Storage.GetAPITokens() {
+		if t.ID == tokenID {
+			token = t
+			break
+		}
+	}
+	if token == nil {
+		return fmt.Errorf("token `%s` not found", tokenID)
+	}
+
+	if err := ctx.Storage.RemoveAPIToken(tokenID); err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+
+	response := fmt.Sprintf("✅ Revoked API token `%s` (%s)", tokenID, token.Name)
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &response,
+	})
+	return nil
+}
+
+func handleVerify(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) error {
+	options := i.ApplicationCommandData().Options
+	repair := len(options) > 0 && options[0].BoolValue()
+
+	issues, err := integrity.Check(ctx.Storage)
+	if err != nil {
+		return err
+	}
+
+	var response string
+	switch {
+	case len(issues) == 0:
+		response = "✅ No integrity issues found"
+	case !repair:
+		response = fmt.Sprintf("⚠️ Found %d issue(s):\n%s\nRun `/verify repair:true` to fix what can be fixed.", len(issues), formatIssues(issues))
+	default:
+		client := morpho.NewClient(ctx.Config.Morpho.APIURL, ctx.Logger)
+		remaining, err := integrity.Repair(context.Background(), ctx.Storage, issues, client, sessionWebhooks{s})
+		if err != nil {
+			return err
+		}
+		response = fmt.Sprintf("🔧 Repaired %d of %d issue(s)", len(issues)-len(remaining), len(issues))
+		if len(remaining) > 0 {
+			response += fmt.Sprintf("; still needs attention:\n%s", formatIssues(remaining))
+		}
+	}
+
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &response,
+	})
+	return nil
+}
+
+// formatIssues lists integrity issues, truncated to fit a Discord message
+func formatIssues(issues []integrity.Issue) string {
+	var sb strings.Builder
+	for n, issue := range issues {
+		if n == 20 {
+			sb.WriteString(fmt.Sprintf("…and %d more\n", len(issues)-n))
+			break
+		}
+		sb.WriteString(fmt.Sprintf("• `%s` `%s`: %s\n", issue.Kind, issue.VaultID, issue.Detail))
+	}
+	return sb.String()
+}
+
+// sessionWebhooks creates alert webhooks through the bot's session
+type sessionWebhooks struct {
+	s *discordgo.Session
+}
+
+func (w sessionWebhooks) CreateWebhook(channelID string) (string, error) {
+	webhook, err := w.s.WebhookCreate(channelID, "SummerRateChecker", "")
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("https://discord.com/api/webhooks/%s/%s", webhook.ID, webhook.Token), nil
+}
+
+// formatScopes renders token scopes as inline code
+func formatScopes(scopes []types.TokenScope) string {
+	parts := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		parts = append(parts, "`"+string(scope)+"`")
+	}
+	return strings.Join(parts, ", ")
+}
+
+func handleAutoEnroll(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) error {
+	options := i.ApplicationCommandData().Options
+	collateral := strings.TrimSpace(options[0].StringValue())
+	loan := strings.TrimSpace(options[1].StringValue())
+	threshold := options[2].FloatValue()
+
+	if err := types.ValidateThreshold(threshold); err != nil {
+		return err
+	}
+
+	// Get channel if provided, otherwise use current channel
+	channelID := i.ChannelID
+	if len(options) > 3 {
+		channelID = options[3].ChannelValue(s).ID
+	}
+
+	// The rule's webhook is created up front and shared by every vault it enrolls
+	webhook, err := s.WebhookCreate(channelID, "SummerRateChecker", "")
+	if err != nil {
+		return fmt.Errorf("failed to create webhook for channel: %w", err)
+	}
+
+	rule := types.NewEnrollRule(collateral, loan, threshold, channelID,
+		fmt.Sprintf("https://discord.com/api/webhooks/%s/%s", webhook.ID, webhook.Token), i.GuildID, interactionUserID(i))
+	if err := ctx.Storage.AddEnrollRule(rule); err != nil {
+		s.WebhookDelete(webhook.ID)
+		return fmt.Errorf("failed to save rule: %w", err)
+	}
+
+	response := fmt.Sprintf(
+		"🤖 Rule `%s`: every %s market will be enrolled with a %s%% threshold → <#%s>. Matches are enrolled on the next check.",
+		rule.ID, rule.Pair(), types.FormatThreshold(threshold), channelID,
+	)
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &response,
+	})
+	return nil
+}
+
+func handleAutoEnrollRemove(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) error {
+	ruleID := strings.TrimSpace(i.ApplicationCommandData().Options[0].StringValue())
+
+	var rule *types.EnrollRule
+	for _, r := range ctx.Storage.GetEnrollRules() {
+		if r.ID == ruleID {
+			rule = r
+			break
+		}
+	}
+	if rule == nil {
+		return fmt.Errorf("rule `%s` not found", ruleID)
+	}
+
+	if err := ctx.Storage.RemoveEnrollRule(ruleID); err != nil {
+		return fmt.Errorf("failed to remove rule: %w", err)
+	}
+
+	// Vaults the rule enrolled keep using its webhook until they're unenrolled
+	if !storage.WebhookInUse(ctx.Storage, rule.WebhookURL) {
+		deleteWebhook(s, ctx, rule.WebhookURL)
+	}
+
+	response := fmt.Sprintf("✅ Removed auto-enroll rule `%s` for %s", ruleID, rule.Pair())
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &response,
+	})
+	return nil
+}
+
+// deleteWebhook removes the Discord webhook behind webhookURL
+func deleteWebhook(s *discordgo.Session, ctx *CommandContext, webhookURL string) {
+	if webhookURL == "" {
+		return
+	}
+	// Extract webhook ID from URL
+	parts := strings.Split(webhookURL, "/")
+	if len(parts) >= 2 {
+		webhookID := parts[len(parts)-2]
+		if err := s.WebhookDelete(webhookID); err != nil {
+			ctx.Logger.Warnf("Failed to delete webhook %s: %v", webhookID, err)
+		}
+	}
+}
+
+func handleUnenroll(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) error {
+	vaultID := i.ApplicationCommandData().Options[0].StringValue()
+
+	vault, err := ctx.Storage.GetVault(vaultID)
+	if err != nil {
+		return fmt.Errorf("error checking vault: %w", err)
+	}
+
+	if vault == nil {
+		return fmt.Errorf("vault `%s` not found", vaultID)
+	}
+
+	// The vault stays in the trash, webhook and history included, until the monitor purges it
+	err = ctx.Storage.TrashVault(vaultID)
+	if err != nil {
+		return fmt.Errorf("failed to unenroll vault: %w", err)
+	}
+	recordAudit(ctx, types.NewAuditEntry(interactionUserID(i), types.AuditUnenroll, vault))
+
+	response := fmt.Sprintf(
+		"✅ Unenrolled vault `%s`. Changed your mind? `/restore %s` within %d hours brings it back with its history.",
+		vaultID, vaultID, ctx.Config.Monitor.UnenrollGraceHours,
+	)
+	// The channel holds past alerts, so it's left for the server to delete
+	if vault.ChannelCreated {
+		response += fmt.Sprintf("\n<#%s> was created for this vault and is kept; delete it once you no longer need its alerts.", vault.ChannelID)
+	}
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &response,
+	})
+	return nil
+}
+
+func handleRestore(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) error {
+	vaultID := i.ApplicationCommandData().Options[0].StringValue()
+
+	if existing, err := ctx.Storage.GetVault(vaultID); err == nil && existing != nil {
+		return fmt.Errorf("vault `%s` is already enrolled", vaultID)
+	}
+
+	vault, err := storage.RestoreVaultWithin(ctx.Storage, vaultID, storage.NewQuota(ctx.Config))
+	var quotaErr *storage.QuotaError
+	if errors.As(err, &quotaErr) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("vault `%s` isn't in the trash; unenrolled vaults are deleted after %d hours", vaultID, ctx.Config.Monitor.UnenrollGraceHours)
+	}
+
+	response := fmt.Sprintf("♻️ Restored vault `%s` (\"%s\"); alerts resume on the next check in <#%s>", vaultID, vault.Nickname, vault.ChannelID)
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &response,
+	})
+	return nil
+}
+
+func handleUndo(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) error {
+	userID := interactionUserID(i)
+
+	// Each /undo steps back through the user's changes that are still in effect
+	var entry *types.AuditEntry
+	for _, candidate := range ctx.Storage.GetAuditLog(0) {
+		if candidate.UserID == userID && !candidate.Undone() {
+			entry = candidate
+			break
+		}
+	}
+	if entry == nil {
+		return fmt.Errorf("you have no recent changes to undo")
+	}
+
+	var err error
+	switch entry.Action {
+	case types.AuditEnroll:
+		// Soft delete, so an accidental /undo can itself be reverted with /restore
+		err = ctx.Storage.TrashVault(entry.VaultID)
+	case types.AuditUnenroll:
+		_, err = storage.RestoreVaultWithin(ctx.Storage, entry.VaultID, storage.NewQuota(ctx.Config))
+	case types.AuditThreshold:
+		err = ctx.Storage.UpdateVault(entry.VaultID, func(stored *types.VaultConfig) error {
+			if stored.ThresholdPercent != entry.NewThreshold || stored.ThresholdMode != entry.NewThresholdMode {
+				return fmt.Errorf("the threshold was changed again since, to %s", stored.DescribeThreshold())
+			}
+			stored.SetThreshold(entry.OldThreshold, entry.OldThresholdMode, interactionUserID(i), interactionUserName(i))
+			return nil
+		})
+	default:
+		err = fmt.Errorf("%s can't be undone", entry.Action)
+	}
+
+	// A change that can no longer be reverted is marked too, so the next /undo moves past it
+	if markErr := ctx.Storage.MarkAuditUndone(entry.ID); markErr != nil {
+		ctx.Logger.Errorf("Failed to mark audit entry %s undone: %v", entry.ID, markErr)
+	}
+	if err != nil {
+		return fmt.Errorf("couldn't undo: you %s <t:%d:R>, but %v. Run /undo again to go further back.", entry.Describe(), entry.Timestamp.Unix(), err)
+	}
+
+	response := fmt.Sprintf("↩️ Undone: you %s <t:%d:R>", entry.Describe(), entry.Timestamp.Unix())
+	if entry.Action == types.AuditEnroll {
+		response += fmt.Sprintf("\nThe vault is unenrolled; `/restore %s` brings it back.", entry.VaultID)
+	}
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &response,
+	})
+	return nil
+}
+
+func handleList(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) error {
+	vaults, err := ctx.Storage.GetAllVaults()
+	if err != nil {
+		return fmt.Errorf("error retrieving vaults: %w", err)
+	}
+
+	if len(vaults) == 0 {
+		response := "No vaults enrolled"
+		s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: &response,
+		})
+		return nil
+	}
+
+	// Vaults not checked within two intervals are flagged so silent failures stand out
+	settings := ctx.Storage.GetSettings()
+	now := time.Now()
+
+	var response strings.Builder
+	response.WriteString("**Enrolled Vaults:**\n")
+	for _, vault := range vaults {
+		marketPair := vault.MarketPair
+		if marketPair == "" {
+			marketPair = "Unknown"
+		}
+		checked := "never checked"
+		if !vault.LastCheckedAt.IsZero() {
+			checked = fmt.Sprintf("checked <t:%d:R>", vault.LastCheckedAt.Unix())
+		}
+		if vault.Disabled {
+			checked = fmt.Sprintf("⏸️ disabled after %d failures", vault.FailureCount)
+		} else if vault.AlertsPaused(now) {
+			checked = "🔕 alerts paused, " + checked
+		} else if vault.IsStale(now, 2*vaultInterval(vault, settings, ctx)) {
+			checked = "⚠️ " + checked
+		}
+		volatility := ""
+		if index, ok := volatilityIndex(vault, ctx); ok {
+			volatility = fmt.Sprintf(" (σ7d %.3f pp)", index)
+		}
+		response.WriteString(fmt.Sprintf(
+			"`%s` - \"%s\" (%s) - %s threshold%s → <#%s> - %s - by %s\n",
+			vault.VaultID, vault.Nickname, marketPair, vault.DescribeThreshold(), volatility, vault.ChannelID, checked, vault.DescribeEnrollment(),
+		))
+		if len(vault.Tags) > 0 {
+			response.WriteString(fmt.Sprintf("  🏷️ %s\n", strings.Join(vault.Tags, ", ")))
+		}
+		if vault.Notes != "" {
+			response.WriteString(fmt.Sprintf("  📝 %s\n", vault.Notes))
+		}
+	}
+
+	content := response.String()
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &content,
+	})
+	return nil
+}
+
+func handleStatus(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) error {
+	if options := i.ApplicationCommandData().Options; len(options) > 0 {
+		return handleVaultStatus(s, i, ctx, options[0].StringValue())
+	}
+
+	vaults, err := ctx.Storage.GetAllVaults()
+	if err != nil {
+		return fmt.Errorf("error retrieving vaults: %w", err)
+	}
+
+	if len(vaults) == 0 {
+		response := "No vaults enrolled"
+		s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: &response,
+		})
+		return nil
+	}
+
+	lastRates := ctx.Storage.GetAllLastRates()
+
+	var response strings.Builder
+	response.WriteString("**Current Status:**\n")
+	for _, vault := range vaults {
+		marketPair := vault.MarketPair
+		if marketPair == "" {
+			marketPair = "Unknown"
+		}
+		if rate, exists := lastRates[vault.VaultID]; exists {
+			response.WriteString(fmt.Sprintf(
+				"`%s` - \"%s\" (%s): %s\n",
+				vault.VaultID, vault.Nickname, marketPair, formatVaultRate(vault, rate, ctx),
+			))
+		} else {
+			response.WriteString(fmt.Sprintf(
+				"`%s` - \"%s\" (%s): Not checked yet\n",
+				vault.VaultID, vault.Nickname, marketPair,
+			))
+		}
+	}
+
+	content := response.String()
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &content,
+	})
+	return nil
+}
+
+// Limits for /history's sample list, which has to fit in one message
+const (
+	defaultHistorySamples = 10
+	maxHistorySamples     = 25
+)
+
+// historyWindows are the windows /history and /leaderboard offer
+var historyWindows = map[string]time.Duration{
+	"24h": 24 * time.Hour,
+	"7d":  7 * 24 * time.Hour,
+	"30d": 30 * 24 * time.Hour,
+	"90d": 90 * 24 * time.Hour,
+}
+
+func handleHistory(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) error {
+	var vaultID string
+	view, window, count := "samples", "24h", defaultHistorySamples
+	for _, opt := range i.ApplicationCommandData().Options {
+		switch opt.Name {
+		case "vault_id":
+			vaultID = opt.StringValue()
+		case "view":
+			view = opt.StringValue()
+		case "window":
+			window = opt.StringValue()
+		case "count":
+			count = int(opt.IntValue())
+		}
+	}
+	if count < 1 || count > maxHistorySamples {
+		return fmt.Errorf("count must be between 1 and %d", maxHistorySamples)
+	}
+
+	vault, err := ctx.Storage.GetVault(vaultID)
+	if err != nil {
+		return fmt.Errorf("error checking vault: %w", err)
+	}
+
+	if vault == nil {
+		return fmt.Errorf("vault `%s` not found", vaultID)
+	}
+
+	history := ctx.Storage.GetRateHistory(vaultID, time.Now().Add(-historyWindows[window]))
+	if len(history) == 0 {
+		response := fmt.Sprintf("No rate history for `%s` in the last %s yet", vaultID, window)
+		s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: &response,
+		})
+		return nil
+	}
+
+	var response strings.Builder
+	if view == "summary" {
+		summary, _ := stats.Summarize(history)
+		var supplyTotal float64
+		for _, sample := range history {
+			supplyTotal += sample.SupplyRate
+		}
+		response.WriteString(fmt.Sprintf("**Rate History: %s (%s)** - last %s, %d samples\n", vault.Nickname, vault.MarketPair, window, len(history)))
+		response.WriteString(fmt.Sprintf("Borrow: low %s, high %s, average %s, net %s\n",
+			formatVaultRate(vault, summary.Low, ctx), formatVaultRate(vault, summary.High, ctx),
+			formatVaultRate(vault, summary.Mean, ctx), vaultRateFormat(vault, ctx).Points(summary.NetChange)))
+		response.WriteString(fmt.Sprintf("Supply: average %s\n", formatVaultRate(vault, supplyTotal/float64(len(history)), ctx)))
+	} else {
+		if len(history) > count {
+			history = history[len(history)-count:]
+		}
+		response.WriteString(fmt.Sprintf("**Rate History: %s (%s)** - last %d samples\n", vault.Nickname, vault.MarketPair, len(history)))
+		for n := len(history) - 1; n >= 0; n-- {
+			sample := history[n]
+			response.WriteString(fmt.Sprintf("<t:%d:f> - borrow %s, supply %s\n",
+				sample.Timestamp.Unix(), formatVaultRate(vault, sample.BorrowRate, ctx), formatVaultRate(vault, sample.SupplyRate, ctx)))
+		}
+	}
+
+	content := response.String()
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &content,
+	})
+	return nil
+}
+
+// chartFile is the name /chart attaches its image under
+const chartFile = "rate-chart.png"
+
+func handleChart(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) error {
+	var vaultID string
+	period := "7d"
+	for _, opt := range i.ApplicationCommandData().Options {
+		switch opt.Name {
+		case "vault_id":
+			vaultID = opt.StringValue()
+		case "period":
+			period = opt.StringValue()
+		}
+	}
+
+	vault, err := ctx.Storage.GetVault(vaultID)
+	if err != nil {
+		return fmt.Errorf("error checking vault: %w", err)
+	}
+
+	if vault == nil {
+		return fmt.Errorf("vault `%s` not found", vaultID)
+	}
+
+	history := ctx.Storage.GetRateHistory(vaultID, time.Now().Add(-historyWindows[period]))
+	if len(history) < 2 {
+		response := fmt.Sprintf("Not enough rate history for `%s` in the last %s to chart yet", vaultID, period)
+		s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: &response,
+		})
+		return nil
+	}
+
+	points := make([]chart.Point, 0, len(history))
+	for _, sample := range history {
+		points = append(points, chart.Point{Time: sample.Timestamp, Value: sample.BorrowRate})
+	}
+	image, scale, err := chart.Render([]chart.Series{{Name: vault.Nickname, Points: points}})
+	if err != nil {
+		return fmt.Errorf("failed to draw chart: %w", err)
+	}
+
+	summary, _ := stats.Summarize(history)
+	format := vaultRateFormat(vault, ctx)
+	embed := &discordgo.MessageEmbed{
+		Title: fmt.Sprintf("📈 Borrow Rate: %s (%s)", vault.Nickname, vault.MarketPair),
+		Description: fmt.Sprintf("Last %s, %d samples\nNow %s · low %s · high %s · average %s · net %s",
+			period, len(history), formatVaultRate(vault, history[len(history)-1].BorrowRate, ctx),
+			formatVaultRate(vault, summary.Low, ctx), formatVaultRate(vault, summary.High, ctx),
+			formatVaultRate(vault, summary.Mean, ctx), format.Points(summary.NetChange)),
+		Color: 0x3498db, // Blue for reports
+		Image: &discordgo.MessageEmbedImage{URL: "attachment://" + chartFile},
+		Footer: brandedFooter(i, ctx, fmt.Sprintf("%s • Gridlines every %s from %s to %s", types.DefaultFooter,
+			format.Rate(scale.Step), format.Rate(scale.Min), format.Rate(scale.Max))),
+	}
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Embeds: &[]*discordgo.MessageEmbed{embed},
+		Files: []*discordgo.File{
+			{Name: chartFile, ContentType: "image/png", Reader: bytes.NewReader(image)},
+		},
+	})
+	return nil
+}
+
+func handleLeaderboard(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) error {
+	metric, window := "volatility", "7d"
+	for _, opt := range i.ApplicationCommandData().Options {
+		switch opt.Name {
+		case "metric":
+			metric = opt.StringValue()
+		case "window":
+			window = opt.StringValue()
+		}
+	}
+
+	since := time.Now().Add(-historyWindows[window])
+
+	vaults, err := ctx.Storage.GetAllVaults()
+	if err != nil {
+		return fmt.Errorf("error retrieving vaults: %w", err)
+	}
+
+	type entry struct {
+		vault *types.VaultConfig
+		score float64
+	}
+	var entries []entry
+	for _, vault := range vaults {
+		history := ctx.Storage.GetRateHistory(vault.VaultID, since)
+		if metric == "change" {
+			if summary, ok := stats.Summarize(history); ok && len(history) > 1 {
+				entries = append(entries, entry{vault, summary.NetChange})
+			}
+		} else if volatility, ok := stats.Volatility(history); ok {
+			entries = append(entries, entry{vault, volatility})
+		}
+	}
+
+	if len(entries) == 0 {
+		response := fmt.Sprintf("Not enough rate history in the last %s to rank vaults yet", window)
+		s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: &response,
+		})
+		return nil
+	}
+
+	// Largest moves first, in either direction
+	sort.Slice(entries, func(a, b int) bool {
+		return math.Abs(entries[a].score) > math.Abs(entries[b].score)
+	})
+	if len(entries) > 10 {
+		entries = entries[:10]
+	}
+
+	medals := []string{"🥇", "🥈", "🥉"}
+	var response strings.Builder
+	if metric == "change" {
+		response.WriteString(fmt.Sprintf("**Biggest Movers (%s):**\n", window))
+	} else {
+		response.WriteString(fmt.Sprintf("**Most Volatile Vaults (%s):**\n", window))
+	}
+	for rank, e := range entries {
+		place := fmt.Sprintf("%d.", rank+1)
+		if rank < len(medals) {
+			place = medals[rank]
+		}
+		value := fmt.Sprintf("σ %.3f pp per check", e.score)
+		if metric == "change" {
+			value = vaultRateFormat(e.vault, ctx).Points(e.score)
+		}
+		response.WriteString(fmt.Sprintf("%s `%s` - \"%s\" (%s): %s\n",
+			place, e.vault.VaultID, e.vault.Nickname, e.vault.MarketPair, value))
+	}
+
+	content := response.String()
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &content,
+	})
+	return nil
+}
+
+const (
+	defaultTopCount = 10
+	maxTopCount     = 25
+
+	// minTopSupplyUSD leaves empty and test markets out of /top scope:all, since their rates and
+	// utilization say nothing about where borrowing actually happens
+	minTopSupplyUSD = 10_000
+)
+
+func handleTop(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) error {
+	metric, scope, count := "rate", "enrolled", defaultTopCount
+	for _, opt := range i.ApplicationCommandData().Options {
+		switch opt.Name {
+		case "metric":
+			metric = opt.StringValue()
+		case "n":
+			count = int(opt.IntValue())
+		case "scope":
+			scope = opt.StringValue()
+		}
+	}
+	if count < 1 || count > maxTopCount {
+		return fmt.Errorf("n must be between 1 and %d", maxTopCount)
+	}
+
+	type entry struct {
+		name  string
+		score float64
+		value string
+	}
+	var entries []entry
+	if scope == "all" {
+		client := morpho.NewClient(ctx.Config.Morpho.APIURL, ctx.Logger)
+		markets, err := client.ListMarkets(context.Background(), types.EthereumChainID)
+		if err != nil {
+			return fmt.Errorf("failed to list markets: %w", err)
+		}
+		format := ctx.Config.Monitor.RateFormat()
+		for _, market := range markets {
+			if market.SupplyUSD < minTopSupplyUSD {
+				continue
+			}
+			name := fmt.Sprintf("%s `%s`", market.MarketPair(), market.UniqueKey)
+			switch metric {
+			case "change":
+				// Markets outside the bot have no recorded history, so measure against the API's daily average
+				if market.BorrowAverages.Daily == 0 {
+					continue
+				}
+				change := market.BorrowRate - market.BorrowAverages.Daily
+				entries = append(entries, entry{name, change, format.Points(change)})
+			case "utilization":
+				entries = append(entries, entry{name, market.Utilization, fmt.Sprintf("%.1f%%", market.Utilization)})
+			default:
+				entries = append(entries, entry{name, market.BorrowRate, format.Rate(market.BorrowRate)})
+			}
+		}
+	} else {
+		vaults, err := ctx.Storage.GetAllVaults()
+		if err != nil {
+			return fmt.Errorf("error retrieving vaults: %w", err)
+		}
+		since := time.Now().Add(-24 * time.Hour)
+		for _, vault := range vaults {
+			if !vaultInGuild(vault, i.GuildID, ctx) {
+				continue
+			}
+			history := ctx.Storage.GetRateHistory(vault.VaultID, since)
+			if len(history) == 0 {
+				continue
+			}
+			latest := history[len(history)-1]
+			name := fmt.Sprintf("`%s` - \"%s\" (%s)", vault.VaultID, vault.Nickname, vault.MarketPair)
+			switch metric {
+			case "change":
+				if len(history) < 2 {
+					continue
+				}
+				change := latest.BorrowRate - history[0].BorrowRate
+				entries = append(entries, entry{name, change, vaultRateFormat(vault, ctx).Points(change)})
+			case "utilization":
+				if latest.SupplyUSD <= 0 {
+					continue
+				}
+				utilization := latest.BorrowUSD / latest.SupplyUSD * 100
+				entries = append(entries, entry{name, utilization, fmt.Sprintf("%.1f%%", utilization)})
+			default:
+				entries = append(entries, entry{name, latest.BorrowRate, formatVaultRate(vault, latest.BorrowRate, ctx)})
+			}
+		}
+	}
+
+	if len(entries) == 0 {
+		response := "No enrolled vaults in this server have been checked in the last 24 hours"
+		if scope == "all" {
+			response = "No Morpho markets to rank right now"
+		}
+		s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: &response,
+		})
+		return nil
+	}
+
+	// Highest first; changes rank by size, in either direction
+	sort.Slice(entries, func(a, b int) bool {
+		if metric == "change" {
+			return math.Abs(entries[a].score) > math.Abs(entries[b].score)
+		}
+		return entries[a].score > entries[b].score
+	})
+	if len(entries) > count {
+		entries = entries[:count]
+	}
+
+	title := "Highest Borrow Rates"
+	switch metric {
+	case "change":
+		title = "Biggest Borrow Rate Moves (24h)"
+		if scope == "all" {
+			title = "Furthest From 24h Average Borrow Rate"
+		}
+	case "utilization":
+		title = "Highest Utilization"
+	}
+	source := "enrolled vaults"
+	if scope == "all" {
+		source = "all markets"
+	}
+
+	var response strings.Builder
+	response.WriteString(fmt.Sprintf("**%s - %s:**\n", title, source))
+	for rank, e := range entries {
+		response.WriteString(fmt.Sprintf("%d. %s: %s\n", rank+1, e.name, e.value))
+	}
+
+	content := response.String()
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &content,
+	})
+	return nil
+}
+
+// handlePortfolio lists vaults with a known debt and estimates the interest each has accrued
+// since enrollment from the recorded borrow rates. Debt is treated as constant over that time.
+func handlePortfolio(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) error {
+	vaults, err := ctx.Storage.GetAllVaults()
+	if err != nil {
+		return fmt.Errorf("error retrieving vaults: %w", err)
+	}
+
+	now := time.Now()
+	var totalDebt, totalInterest float64
+	var response strings.Builder
+	response.WriteString("**Portfolio:**\n")
+	for _, vault := range vaults {
+		debt := vault.Debt()
+		if debt <= 0 {
+			continue
+		}
+		interest := stats.AccruedInterest(ctx.Storage.GetRateHistory(vault.VaultID, vault.CreatedAt), debt, now)
+		totalDebt += debt
+		totalInterest += interest
+
+		rate := "not checked yet"
+		if lastRate, ok := ctx.Storage.GetLastRate(vault.VaultID); ok {
+			rate = formatVaultRate(vault, lastRate, ctx)
+		}
+		response.WriteString(fmt.Sprintf("`%s` - \"%s\": %s debt at %s, ~%s interest since <t:%d:d>\n",
+			vault.VaultID, vault.Nickname, types.FormatUSD(debt), rate, types.FormatUSD(interest), vault.CreatedAt.Unix()))
+	}
+
+	if totalDebt == 0 {
+		response.Reset()
+		response.WriteString("No vault has a known debt. Set one with `/debt`, or set `summerfi.api_url` to fetch it from Summer.fi.")
+	} else {
+		response.WriteString(fmt.Sprintf("\n**Total:** %s debt, ~%s interest accrued", types.FormatUSD(totalDebt), types.FormatUSD(totalInterest)))
+	}
+
+	content := response.String()
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &content,
+	})
+	return nil
+}
+
+// defaultMigrationGasUSD is a rough mainnet cost of moving a position between markets: repaying,
+// withdrawing, supplying and borrowing, or one flash-loan transaction doing all four
+const defaultMigrationGasUSD = 50.0
+
+func handleSavings(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) error {
+	options := i.ApplicationCommandData().Options
+	vaultID := options[0].StringValue()
+	target := strings.TrimSpace(options[1].StringValue())
+
+	gas := defaultMigrationGasUSD
+	if len(options) > 2 {
+		gas = options[2].FloatValue()
+	}
+	if gas < 0 {
+		return fmt.Errorf("gas cost can't be negative")
+	}
+
+	vault, err := ctx.Storage.GetVault(vaultID)
+	if err != nil {
+		return fmt.Errorf("error checking vault: %w", err)
+	}
+
+	if vault == nil {
+		return fmt.Errorf("vault `%s` not found", vaultID)
+	}
+
+	debt := vault.Debt()
+	if debt <= 0 {
+		return fmt.Errorf("vault `%s` has no known debt; set one with `/debt`", vaultID)
+	}
+
+	// Compare against markets on the vault's own chain
+	chainID, ok := vault.ChainID()
+	if !ok {
+		return fmt.Errorf("vault `%s` is on a network I can't look markets up on", vaultID)
+	}
+
+	client := morpho.NewClient(ctx.Config.Morpho.APIURL, ctx.Logger)
+	targetInfo, err := client.GetMarketInfo(context.Background(), target, chainID)
+	if err != nil {
+		return fmt.Errorf("failed to look up market `%s`: %w", target, err)
+	}
+	if targetInfo.UniqueKey == vault.MorphoMarketKey {
+		return fmt.Errorf("vault `%s` is already in that market", vaultID)
+	}
+
+	// Prefer the vault's live rate; the last checked one may be an hour old
+	var currentRate float64
+	if vault.MorphoMarketKey != "" {
+		if info, err := client.GetMarketInfo(context.Background(), vault.MorphoMarketKey, chainID); err == nil {
+			currentRate = info.BorrowRate
+		}
+	}
+	if currentRate == 0 {
+		lastRate, ok := ctx.Storage.GetLastRate(vaultID)
+		if !ok {
+			return fmt.Errorf("vault `%s` hasn't been checked yet", vaultID)
+		}
+		currentRate = lastRate
+	}
+
+	annualSavings := debt * (currentRate - targetInfo.BorrowRate) / 100
+
+	var breakeven string
+	switch {
+	case annualSavings <= 0:
+		breakeven = "Never: the target market is not cheaper at current rates"
+	case gas == 0:
+		breakeven = "Immediately"
+	default:
+		days := gas / (annualSavings / 365)
+		breakeven = fmt.Sprintf("~%.0f days to recover %s of gas", math.Ceil(days), types.FormatUSD(gas))
+	}
+
+	// Moving to a pricier market shows the extra cost as negative savings
+	formatSavings := func(amount float64) string {
+		if amount < 0 {
+			return "-" + types.FormatUSD(-amount)
+		}
+		return types.FormatUSD(amount)
+	}
+
+	color := 0x00ff00 // Green when moving saves money
+	if annualSavings <= 0 {
+		color = 0xff0000
+	}
+	embed := &discordgo.MessageEmbed{
+		Title:       fmt.Sprintf("Savings Estimate: %s → %s", vault.Nickname, targetInfo.MarketPair()),
+		Description: fmt.Sprintf("Moving %s of debt to `%s` at current rates", types.FormatUSD(debt), targetInfo.UniqueKey),
+		Color:       color,
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "Current Borrow APY", Value: formatVaultRate(vault, currentRate, ctx), Inline: true},
+			{Name: "Target Borrow APY", Value: formatVaultRate(vault, targetInfo.BorrowRate, ctx), Inline: true},
+			{Name: "Annual Savings", Value: formatSavings(annualSavings), Inline: true},
+			{Name: "Monthly Savings", Value: formatSavings(annualSavings / 12), Inline: true},
+			{Name: "Breakeven", Value: breakeven, Inline: false},
+		},
+		Footer: brandedFooter(i, ctx, types.DefaultFooter+" • Rates change; this assumes they hold for a year"),
+	}
+	if loan := vault.LoanSymbol(); loan != "" && !strings.EqualFold(loan, targetInfo.LoanSymbol) {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:   "⚠️ Different Loan Asset",
+			Value:  fmt.Sprintf("The target market lends %s, not %s, so the debt would have to be swapped as well", targetInfo.LoanSymbol, loan),
+			Inline: false,
+		})
+	}
+	if targetInfo.BorrowUSD > 0 && targetInfo.SupplyUSD-targetInfo.BorrowUSD < debt {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:   "⚠️ Limited Liquidity",
+			Value:  fmt.Sprintf("Only %s is available to borrow in the target market", types.FormatUSD(targetInfo.SupplyUSD-targetInfo.BorrowUSD)),
+			Inline: false,
+		})
+	}
+
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Embeds: &[]*discordgo.MessageEmbed{embed},
+	})
+	return nil
+}
+
+func handleMarketInfo(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) error {
+	options := i.ApplicationCommandData().Options
+	market := options[0].StringValue()
+
+	projections := []float64{95, 99, 100}
+	if len(options) > 1 {
+		utilization := options[1].FloatValue()
+		if utilization <= 0 || utilization > 100 {
+			return fmt.Errorf("utilization must be between 0 and 100")
+		}
+		projections = []float64{utilization}
+	}
+
+	client := morpho.NewClient(ctx.Config.Morpho.APIURL, ctx.Logger)
+	info, err := client.GetMarketInfo(context.Background(), market, types.EthereumChainID)
+	if err != nil {
+		return fmt.Errorf("failed to look up market `%s`: %w", market, err)
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:       fmt.Sprintf("Market Info: %s", info.MarketPair()),
+		Description: fmt.Sprintf("`%s`", info.UniqueKey),
+		Color:       0x3498db,
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "Borrow APY", Value: ctx.Config.Monitor.RateFormat().Rate(info.BorrowRate), Inline: true},
+			{Name: "Supply APY", Value: ctx.Config.Monitor.RateFormat().Rate(info.SupplyRate), Inline: true},
+			{Name: "Utilization", Value: fmt.Sprintf("%.2f%%", info.Utilization), Inline: true},
+			{Name: "Average Borrow APY", Value: info.BorrowAverages.Describe(ctx.Config.Monitor.RateFormat()), Inline: false},
+			{Name: "Average Supply APY", Value: info.SupplyAverages.Describe(ctx.Config.Monitor.RateFormat()), Inline: false},
+			{Name: "Total Supply", Value: types.FormatUSD(info.SupplyUSD), Inline: true},
+			{Name: "Total Borrow", Value: types.FormatUSD(info.BorrowUSD), Inline: true},
+			{Name: "LLTV", Value: fmt.Sprintf("%.1f%%", info.LLTV), Inline: true},
+			{Name: "Oracle", Value: fmt.Sprintf("`%s`", info.OracleAddress), Inline: false},
+			{Name: "IRM", Value: fmt.Sprintf("`%s`", info.IRMAddress), Inline: false},
+		},
+		Footer: brandedFooter(i, ctx, types.DefaultFooter),
+	}
+
+	if morpho.IsAdaptiveCurveIRM(info.IRMAddress) {
+		var lines strings.Builder
+		for _, utilization := range projections {
+			if projected, ok := morpho.ProjectBorrowRate(info.BorrowRate, info.Utilization, utilization); ok {
+				lines.WriteString(fmt.Sprintf("At %.0f%% utilization: ~%s\n", utilization, ctx.Config.Monitor.RateFormat().Rate(projected)))
+			}
+		}
+		if lines.Len() > 0 {
+			embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+				Name:   "Projected Borrow APY",
+				Value:  lines.String() + "_Instantaneous, before the IRM adapts_",
+				Inline: false,
+			})
+		}
+	}
+
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Embeds: &[]*discordgo.MessageEmbed{embed},
+	})
+	return nil
+}
+
+func handleWatchNew(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) error {
+	options := i.ApplicationCommandData().Options
+	collateral := strings.ToUpper(strings.TrimSpace(options[0].StringValue()))
+	loan := strings.ToUpper(strings.TrimSpace(options[1].StringValue()))
+
+	// Get channel if provided, otherwise use current channel
+	channelID := i.ChannelID
+	if len(options) > 2 {
+		channelID = options[2].ChannelValue(s).ID
+	}
+
+	// Seed the watch with the markets that already exist so only new ones alert
+	client := morpho.NewClient(ctx.Config.Morpho.APIURL, ctx.Logger)
+	markets, err := client.ListMarkets(context.Background(), types.EthereumChainID)
+	if err != nil {
+		return fmt.Errorf("failed to list markets: %w", err)
+	}
+
+	webhook, err := s.WebhookCreate(channelID, "SummerRateChecker", "")
+	if err != nil {
+		return fmt.Errorf("failed to create webhook for channel: %w", err)
+	}
+
+	watch := types.NewMarketWatch(collateral, loan, channelID,
+		fmt.Sprintf("https://discord.com/api/webhooks/%s/%s", webhook.ID, webhook.Token))
+	for _, market := range markets {
+		if watch.Matches(market) {
+			watch.KnownMarkets = append(watch.KnownMarkets, market.UniqueKey)
+		}
+	}
+
+	if err := ctx.Storage.AddMarketWatch(watch); err != nil {
+		s.WebhookDelete(webhook.ID)
+		return fmt.Errorf("failed to save watch: %w", err)
+	}
+
+	response := fmt.Sprintf(
+		"👀 Watching for new %s markets (watch `%s`, %d existing) → <#%s>",
+		watch.Pair(), watch.ID, len(watch.KnownMarkets), channelID,
+	)
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &response,
+	})
+	return nil
+}
+
+func handleUnwatchNew(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) error {
+	watchID := strings.TrimSpace(i.ApplicationCommandData().Options[0].StringValue())
+
+	var watch *types.MarketWatch
+	for _, w := range ctx.Storage.GetMarketWatches() {
+		if w.ID == watchID {
+			watch = w
+			break
+		}
+	}
+	if watch == nil {
+		return fmt.Errorf("watch `%s` not found", watchID)
+	}
+
+	deleteWebhook(s, ctx, watch.WebhookURL)
+
+	if err := ctx.Storage.RemoveMarketWatch(watchID); err != nil {
+		return fmt.Errorf("failed to remove watch: %w", err)
+	}
+
+	response := fmt.Sprintf("✅ Stopped watching for new %s markets", watch.Pair())
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &response,
+	})
+	return nil
+}
+
+// handleVaultStatus shows a detailed card for a single vault
+func handleVaultStatus(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext, vaultID string) error {
+	vault, err := ctx.Storage.GetVault(vaultID)
+	if err != nil {
+		return fmt.Errorf("error checking vault: %w", err)
+	}
+
+	if vault == nil {
+		return fmt.Errorf("vault `%s` not found", vaultID)
+	}
+
+	marketPair := vault.MarketPair
+	if marketPair == "" {
+		marketPair = "Unknown"
+	}
+
+	borrow, supply, baseline := "Not checked yet", "Not checked yet", "Not set"
+	nextCheck := "After the first check"
+	lastRate, checked := ctx.Storage.GetLastRate(vaultID)
+	if checked {
+		borrow = formatVaultRate(vault, lastRate, ctx)
+		nextCheck = "Overdue"
+		baseline = formatVaultRate(vault, monitor.ComparisonBaseline(ctx.Storage, vault, lastRate), ctx)
+	}
+	strategy, _ := types.ParseBaselineStrategy(string(vault.BaselineStrategy))
+	baseline = fmt.Sprintf("%s (%s)", baseline, strategy.Describe())
+
+	interval := vaultInterval(vault, ctx.Storage.GetSettings(), ctx)
+	if history := ctx.Storage.GetRateHistory(vaultID, time.Now().Add(-2*interval)); len(history) > 0 {
+		// The last rate is the daily average for vaults that check against it, so show the sample
+		borrow = formatVaultRate(vault, history[len(history)-1].BorrowRate, ctx)
+		supply = formatVaultRate(vault, history[len(history)-1].SupplyRate, ctx)
+	}
+	if !vault.LastCheckedAt.IsZero() && !vault.IsStale(time.Now(), 2*interval) {
+		nextCheck = fmt.Sprintf("<t:%d:R>", vault.LastCheckedAt.Add(interval).Unix())
+	}
+	if vault.Priority != "" {
+		nextCheck += fmt.Sprintf(" (%s priority)", vault.Priority)
+	}
+
+	lastAlert := "Never"
+	for _, alert := range ctx.Storage.GetRecentAlerts(0) {
+		if alert.VaultID == vaultID {
+			lastAlert = fmt.Sprintf("<t:%d:R> (%s → %s)", alert.Timestamp.Unix(),
+				formatVaultRate(vault, alert.PreviousRate, ctx), formatVaultRate(vault, alert.CurrentRate, ctx))
+			break
+		}
+	}
+
+	delivery := fmt.Sprintf("<#%s>", vault.ChannelID)
+	if vault.WebhookURL == "" {
+		delivery += " ⚠️ no webhook"
+	}
+	if vault.FallbackUserID != "" {
+		delivery += fmt.Sprintf("\nFallback DM: <@%s>", vault.FallbackUserID)
+	}
+	if vault.SlackWebhookURL != "" {
+		delivery += "\nSlack: mirrored"
+	}
+	for _, severity := range []types.Severity{types.SeverityWarning, types.SeverityCritical} {
+		if route := vault.Routes[severity]; route != nil {
+			delivery += fmt.Sprintf("\n%s alerts: <#%s>", severity.Label(), route.ChannelID)
+		}
+	}
+	if vault.AlertSchedule != nil {
+		delivery += fmt.Sprintf("\nSchedule: %s", vault.AlertSchedule)
+	}
+	if vault.AlertsPaused(time.Now()) {
+		if vault.PausedUntil.IsZero() {
+			delivery += "\n🔕 Paused until `/resume`"
+		} else {
+			delivery += fmt.Sprintf("\n🔕 Paused until <t:%d:f>", vault.PausedUntil.Unix())
+		}
+	}
+	if vault.CooldownMinutes > 0 {
+		delivery += fmt.Sprintf("\nCooldown: %d minutes between alerts", vault.CooldownMinutes)
+		if ends := vault.CooldownEnds(); time.Now().Before(ends) {
+			delivery += fmt.Sprintf(", next <t:%d:R>", ends.Unix())
+		}
+	}
+
+	threshold := vault.DescribeThreshold()
+	if vault.RateSource != "" && vault.RateSource != types.RateSpot {
+		threshold += ", checked against the " + vault.DescribeRateSource()
+	}
+	if set := vault.AlertThreshold(); !set.SetAt.IsZero() {
+		threshold += fmt.Sprintf("\nSet <t:%d:R> by %s", set.SetAt.Unix(), set.SetBy)
+	}
+	if vault.CriticalRate > 0 {
+		threshold += fmt.Sprintf("\nCritical at %s", formatVaultRate(vault, vault.CriticalRate, ctx))
+		if vault.CriticalMention != types.MentionNone {
+			threshold += ", pings " + vault.CriticalMention.Tag()
+		}
+		if vault.CriticalTTS {
+			threshold += ", read aloud"
+		}
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:       fmt.Sprintf("Vault Status: %s", vault.Nickname),
+		Description: fmt.Sprintf("`%s` (%s)", vault.VaultID, marketPair),
+		Color:       0x3498db,
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "Borrow Rate", Value: borrow, Inline: true},
+			{Name: "Supply Rate", Value: supply, Inline: true},
+			{Name: "Baseline", Value: baseline, Inline: true},
+			{Name: "Threshold", Value: threshold, Inline: true},
+			{Name: "Last Alert", Value: lastAlert, Inline: true},
+			{Name: "Next Check", Value: nextCheck, Inline: true},
+			{Name: "Delivery", Value: delivery, Inline: false},
+			{Name: "Enrolled By", Value: fmt.Sprintf("%s <t:%d:R>", vault.DescribeEnrollment(), vault.CreatedAt.Unix()), Inline: false},
+		},
+		Footer: brandedFooter(i, ctx, types.DefaultFooter),
+	}
+	if index, ok := volatilityIndex(vault, ctx); ok {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:   "Volatility (7d)",
+			Value:  fmt.Sprintf("σ %.3f pp of the hourly borrow rate", index),
+			Inline: true,
+		})
+	}
+	if vault.BorrowAverages.Known() {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:   "Average Borrow APY",
+			Value:  vault.BorrowAverages.Describe(vaultRateFormat(vault, ctx)),
+			Inline: false,
+		})
+	}
+	if vault.Position != nil {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:   "Summer.fi Position",
+			Value:  describePosition(vault.Position, vault.LLTV),
+			Inline: false,
+		})
+	}
+
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Embeds: &[]*discordgo.MessageEmbed{embed},
+	})
+	return nil
+}
+
+// describePosition formats a vault's Summer.fi position for /status. lltv is the market's, or 0
+// before it's known.
+func describePosition(position *types.Position, lltv float64) string {
+	lines := []string{position.Describe()}
+	if lltv > 0 && position.CollateralUSD > 0 {
+		lines = append(lines, fmt.Sprintf("%.2f points of headroom below the %.1f%% LLTV (a %.1f%% price fall)",
+			position.Headroom(lltv), lltv, position.PriceBuffer(lltv)))
+	}
+	if position.LiquidationPrice > 0 {
+		lines = append(lines, fmt.Sprintf("Liquidated if %s falls to $%.2f", position.CollateralSymbol, position.LiquidationPrice))
+	}
+	if len(position.Automations) > 0 {
+		lines = append(lines, "⚙️ Automation: "+strings.Join(position.DescribeAutomations(), ", "))
+	}
+	lines = append(lines, fmt.Sprintf("Updated <t:%d:R>", position.FetchedAt.Unix()))
+	return strings.Join(lines, "\n")
+}
+
+func handleCheck(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) error {
+	// Each check queries every vault, so one user can't run them back to back. The cooldown starts
+	// once a check is actually sent, so a typo or a busy monitor doesn't use it up.
+	cooldownKey := "check:" + interactionUserID(i)
+	if wait, ok := ctx.Cooldowns.Check(cooldownKey); !ok {
+		return fmt.Errorf("⏳ Please wait %s before running /check again", wait.Round(time.Second))
+	}
+
+	var req types.CheckRequest
+	for _, option := range i.ApplicationCommandData().Options {
+		switch option.Name {
+		case "vault_id":
+			req.VaultID = option.StringValue()
+		case "tag":
+			req.Tag = types.NormalizeTag(option.StringValue())
+		}
+	}
+
+	// Catch typos here rather than triggering a check that covers nothing
+	if req.Scoped() {
+		vaults, err := ctx.Storage.GetAllVaults()
+		if err != nil {
+			return fmt.Errorf("error retrieving vaults: %w", err)
+		}
+		matched := 0
+		for _, vault := range vaults {
+			if req.Matches(vault) {
+				matched++
+			}
+		}
+		if matched == 0 {
+			return fmt.Errorf("no enrolled vaults match %s", req.Describe())
+		}
+	}
+
+	reply := make(chan types.CommandResult, 1)
+	cmd := types.NewCheckCommand(req)
+	cmd.Reply = reply
+
+	select {
+	case ctx.Commands <- cmd:
+		ctx.Cooldowns.Record(cooldownKey, time.Duration(ctx.Config.Limits.CheckCooldownSeconds)*time.Second)
+		response := fmt.Sprintf("🔄 Manual rate check triggered! Checking %s now...", req.Describe())
+		s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: &response,
+		})
+		go reportCheck(s, i, ctx, cmd.ID, reply)
+	default:
+		response := "🔄 Another manual check or command is already pending, please wait..."
+		s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: &response,
+		})
+	}
+	return nil
+}
+
+// checkReportTimeout stays under Discord's 15 minute limit for editing an interaction response
+const checkReportTimeout = 14 * time.Minute
+
+// reportCheck edits the /check response with the outcome once the monitor finishes the check
+func reportCheck(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext, commandID uint64, reply <-chan types.CommandResult) {
+	var response string
+	select {
+	case result := <-reply:
+		if result.ID != commandID {
+			ctx.Logger.Warnf("/check expected a reply to command #%d, got #%d", commandID, result.ID)
+		}
+		summary := result.Check
+		response = "✅ Manual rate check finished: " + summary.Describe()
+		if result.Err != nil {
+			response = fmt.Sprintf("❌ Manual rate check didn't run: %v", result.Err)
+		} else if summary.Err != nil {
+			response = "❌ Manual rate check failed: " + summary.Describe()
+		} else if summary.Failed > 0 {
+			response += "\nSee `/list` for vaults that couldn't be fetched."
+		}
+	case <-time.After(checkReportTimeout):
+		response = "⌛ Manual rate check is still running; alerts will be posted as usual when it finishes."
+	}
+
+	if _, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &response,
+	}); err != nil {
+		ctx.Logger.Warnf("Failed to report /check result: %v", err)
+	}
+}
+
+func handleThreshold(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) error {
+	options := i.ApplicationCommandData().Options
+	vaultID := options[0].StringValue()
+	newThreshold := options[1].FloatValue()
+
+	if err := types.ValidateThreshold(newThreshold); err != nil {
+		return err
+	}
+
+	vault, err := ctx.Storage.GetVault(vaultID)
+	if err != nil {
+		return fmt.Errorf("error checking vault: %w", err)
+	}
+
+	if vault == nil {
+		return fmt.Errorf("vault `%s` not found", vaultID)
+	}
+
+	var mode types.ThresholdMode
+	var source types.RateSource
+	var hours int
+	var setMode, setSource bool
+	for _, opt := range options[2:] {
+		switch opt.Name {
+		case "mode":
+			if mode, err = types.ParseThresholdMode(opt.StringValue()); err != nil {
+				return err
+			}
+			setMode = true
+		case "rate":
+			if source, err = types.ParseRateSource(opt.StringValue()); err != nil {
+				return err
+			}
+			setSource = true
+		case "hours":
+			hours = int(opt.IntValue())
+			if hours < 1 || hours > types.MaxAverageHours {
+				return fmt.Errorf("hours must be between 1 and %d", types.MaxAverageHours)
+			}
+		}
+	}
+
+	var description string
+	entry := types.NewAuditEntry(interactionUserID(i), types.AuditThreshold, vault)
+	err = ctx.Storage.UpdateVault(vaultID, func(stored *types.VaultConfig) error {
+		entry.OldThreshold, entry.OldThresholdMode = stored.ThresholdPercent, stored.ThresholdMode
+		newMode := stored.ThresholdMode
+		if setMode {
+			newMode = mode
+		}
+		stored.SetThreshold(newThreshold, newMode, interactionUserID(i), interactionUserName(i))
+		if setSource {
+			stored.RateSource = source
+		}
+		if hours > 0 {
+			stored.AverageHours = hours
+		}
+		entry.NewThreshold, entry.NewThresholdMode = stored.ThresholdPercent, stored.ThresholdMode
+		description = fmt.Sprintf("%s, checked against the %s", stored.DescribeThreshold(), stored.DescribeRateSource())
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update threshold: %w", err)
+	}
+	recordAudit(ctx, entry)
+
+	response := fmt.Sprintf(
+		"✅ Updated threshold for `%s` to %s",
+		vaultID, description,
+	)
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &response,
+	})
+	return nil
+}
+
+func handleRule(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) error {
+	options := i.ApplicationCommandData().Options
+	vaultID := options[0].StringValue()
+
+	vault, err := ctx.Storage.GetVault(vaultID)
+	if err != nil {
+		return fmt.Errorf("error checking vault: %w", err)
+	}
+
+	if vault == nil {
+		return fmt.Errorf("vault `%s` not found", vaultID)
+	}
+
+	var response string
+	if len(options) < 2 {
+		if len(vault.Rules) == 0 {
+			response = fmt.Sprintf("`%s` has no rules. Variables: %s", vaultID, strings.Join(rules.VariableNames(), ", "))
+		} else {
+			var sb strings.Builder
+			sb.WriteString(fmt.Sprintf("📐 **Rules for %s:**\n", vault.Nickname))
+			for _, rule := range vault.Rules {
+				state := ""
+				if rule.Active {
+					state = " 🔴 active"
+				}
+				sb.WriteString(fmt.Sprintf("• `%s`: `%s`%s\n", rule.ID, rule.Expression, state))
+			}
+			response = sb.String()
+		}
+		s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: &response,
+		})
+		return nil
+	}
+
+	expr, err := rules.Parse(options[1].StringValue())
+	if err != nil {
+		return fmt.Errorf("invalid rule: %w", err)
+	}
+
+	rule := types.NewAlertRule(expr.String())
+	err = ctx.Storage.UpdateVault(vaultID, func(stored *types.VaultConfig) error {
+		stored.Rules = append(stored.Rules, rule)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add rule: %w", err)
+	}
+
+	response = fmt.Sprintf("✅ Added rule `%s` to `%s`: alerts when `%s` becomes true", rule.ID, vaultID, rule.Expression)
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &response,
+	})
+	return nil
+}
+
+func handleRuleRemove(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) error {
+	options := i.ApplicationCommandData().Options
+	vaultID := options[0].StringValue()
+	ruleID := strings.TrimSpace(options[1].StringValue())
+
+	vault, err := ctx.Storage.GetVault(vaultID)
+	if err != nil {
+		return fmt.Errorf("error checking vault: %w", err)
+	}
+
+	if vault == nil {
+		return fmt.Errorf("vault `%s` not found", vaultID)
+	}
+
+	err = ctx.Storage.UpdateVault(vaultID, func(stored *types.VaultConfig) error {
+		if stored.FindRule(ruleID) == nil {
+			return fmt.Errorf("rule `%s` not found on `%s`", ruleID, vaultID)
+		}
+		remaining := make([]*types.AlertRule, 0, len(stored.Rules)-1)
+		for _, rule := range stored.Rules {
+			if rule.ID != ruleID {
+				remaining = append(remaining, rule)
+			}
+		}
+		stored.Rules = remaining
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	response := fmt.Sprintf("✅ Removed rule `%s` from `%s`", ruleID, vaultID)
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &response,
+	})
+	return nil
+}
+
+func handleEnable(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) error {
+	vaultID := i.ApplicationCommandData().Options[0].StringValue()
+
+	vault, err := ctx.Storage.GetVault(vaultID)
+	if err != nil {
+		return fmt.Errorf("error checking vault: %w", err)
+	}
+
+	if vault == nil {
+		return fmt.Errorf("vault `%s` not found", vaultID)
+	}
+
+	err = ctx.Storage.UpdateVault(vaultID, func(stored *types.VaultConfig) error {
+		if !stored.Disabled {
+			return fmt.Errorf("vault `%s` is not disabled", vaultID)
+		}
+		stored.Disabled = false
+		stored.FailureCount = 0
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	response := fmt.Sprintf("✅ Re-enabled `%s`; it will be checked on the next cycle", vaultID)
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &response,
+	})
+	return nil
+}
+
+func handlePause(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) error {
+	var target string
+	var duration time.Duration
+	for _, option := range i.ApplicationCommandData().Options {
+		switch option.Name {
+		case "vault_id":
+			target = strings.TrimSpace(option.StringValue())
+		case "duration":
+			parsed, err := time.ParseDuration(option.StringValue())
+			if err != nil || parsed <= 0 {
+				return fmt.Errorf("invalid duration %q, use a value like 30m or 6h", option.StringValue())
+			}
+			duration = parsed
+		}
+	}
+
+	vaultIDs, err := pauseTargets(i, ctx, target)
+	if err != nil {
+		return err
+	}
+
+	var until time.Time
+	if duration > 0 {
+		until = time.Now().Add(duration)
+	}
+	for _, vaultID := range vaultIDs {
+		err := ctx.Storage.UpdateVault(vaultID, func(stored *types.VaultConfig) error {
+			stored.Paused = true
+			stored.PausedUntil = until
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to pause `%s`: %w", vaultID, err)
+		}
+	}
+
+	response := fmt.Sprintf("⏸️ Paused alerts for %s until `/resume`", describePauseTargets(target, vaultIDs))
+	if !until.IsZero() {
+		response = fmt.Sprintf("⏸️ Paused alerts for %s until <t:%d:f>", describePauseTargets(target, vaultIDs), until.Unix())
+	}
+	response += ". Rates are still checked and recorded."
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &response,
+	})
+	return nil
+}
+
+func handleResume(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) error {
+	target := strings.TrimSpace(i.ApplicationCommandData().Options[0].StringValue())
+
+	vaultIDs, err := pauseTargets(i, ctx, target)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var resumed []string
+	for _, vaultID := range vaultIDs {
+		wasPaused := false
+		err := ctx.Storage.UpdateVault(vaultID, func(stored *types.VaultConfig) error {
+			wasPaused = stored.AlertsPaused(now)
+			stored.Paused = false
+			stored.PausedUntil = time.Time{}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to resume `%s`: %w", vaultID, err)
+		}
+		if wasPaused {
+			resumed = append(resumed, vaultID)
+		}
+	}
+	if len(resumed) == 0 {
+		return fmt.Errorf("%s not paused", describePauseTargets(target, vaultIDs))
+	}
+
+	response := fmt.Sprintf("▶️ Resumed alerts for %s", describePauseTargets(target, resumed))
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &response,
+	})
+	return nil
+}
+
+// pauseTargets returns the IDs of the vaults /pause or /resume apply to: one vault, or with "all"
+// every vault in the server the command was run in
+func pauseTargets(i *discordgo.InteractionCreate, ctx *CommandContext, target string) ([]string, error) {
+	if !strings.EqualFold(target, "all") {
+		vault, err := ctx.Storage.GetVault(target)
+		if err != nil {
+			return nil, fmt.Errorf("error checking vault: %w", err)
+		}
+		if vault == nil {
+			return nil, fmt.Errorf("vault `%s` not found", target)
+		}
+		return []string{vault.VaultID}, nil
+	}
+
+	vaults, err := ctx.Storage.GetAllVaults()
+	if err != nil {
+		return nil, fmt.Errorf("error checking vaults: %w", err)
+	}
+	var vaultIDs []string
+	for _, vault := range vaults {
+		if vaultInGuild(vault, i.GuildID, ctx) {
+			vaultIDs = append(vaultIDs, vault.VaultID)
+		}
+	}
+	if len(vaultIDs) == 0 {
+		return nil, fmt.Errorf("no vaults are enrolled in this server")
+	}
+	return vaultIDs, nil
+}
+
+// describePauseTargets names the vaults in a /pause or /resume reply
+func describePauseTargets(target string, vaultIDs []string) string {
+	if strings.EqualFold(target, "all") {
+		return fmt.Sprintf("all %d vaults in this server", len(vaultIDs))
+	}
+	return fmt.Sprintf("`%s`", vaultIDs[0])
+}
+
+func handleBaseline(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) error {
+	options := i.ApplicationCommandData().Options
+	vaultID := options[0].StringValue()
+
+	strategy, err := types.ParseBaselineStrategy(options[1].StringValue())
+	if err != nil {
+		return err
+	}
+
+	vault, err := ctx.Storage.GetVault(vaultID)
+	if err != nil {
+		return fmt.Errorf("error checking vault: %w", err)
+	}
+
+	if vault == nil {
+		return fmt.Errorf("vault `%s` not found", vaultID)
+	}
+
+	err = ctx.Storage.UpdateVault(vaultID, func(stored *types.VaultConfig) error {
+		stored.BaselineStrategy = strategy
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update baseline: %w", err)
+	}
+
+	response := fmt.Sprintf("✅ Alerts for `%s` will now compare against the %s rate", vaultID, strategy.Describe())
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &response,
+	})
+	return nil
+}
+
+func handleResetBaseline(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) error {
+	vaultID := i.ApplicationCommandData().Options[0].StringValue()
+
+	vault, err := ctx.Storage.GetVault(vaultID)
+	if err != nil {
+		return fmt.Errorf("error checking vault: %w", err)
+	}
+
+	if vault == nil {
+		return fmt.Errorf("vault `%s` not found", vaultID)
+	}
+
+	currentRate, exists := ctx.Storage.GetLastRate(vaultID)
+	if !exists {
+		return fmt.Errorf("no rate has been recorded for `%s` yet", vaultID)
+	}
+
+	var previousBaseline float64
+	err = ctx.Storage.UpdateVault(vaultID, func(stored *types.VaultConfig) error {
+		previousBaseline = stored.LastAlertRate
+		stored.LastAlertRate = currentRate
+
+		// The user has acted on the breach, so stop tracking it against the old level
+		stored.BreachBaseline = 0
+		stored.BreachChecks = 0
+		stored.BreachEscalated = false
+		stored.OpenAlertMessages = nil
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to reset baseline: %w", err)
+	}
+
+	response := fmt.Sprintf(
+		"✅ Reset the alert baseline for `%s` from %s to the current rate of %s",
+		vaultID, formatVaultRate(vault, previousBaseline, ctx), formatVaultRate(vault, currentRate, ctx),
+	)
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &response,
+	})
+	return nil
+}
+
+func handleCritical(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) error {
+	options := i.ApplicationCommandData().Options
+	vaultID := options[0].StringValue()
+	criticalRate := options[1].FloatValue()
+
+	// Validate critical rate
+	if criticalRate < 0 || criticalRate > 1000.0 {
+		return fmt.Errorf("critical rate must be between 0 and 1000.0")
+	}
+
+	vault, err := ctx.Storage.GetVault(vaultID)
+	if err != nil {
+		return fmt.Errorf("error checking vault: %w", err)
+	}
+
+	if vault == nil {
+		return fmt.Errorf("vault `%s` not found", vaultID)
+	}
+
+	// An open critical alert is left active for the monitor to resolve, so its messages and
+	// PagerDuty/Opsgenie incidents are closed the same way as on recovery
+	err = ctx.Storage.UpdateVault(vaultID, func(stored *types.VaultConfig) error {
+		stored.CriticalRate = criticalRate
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update critical rate: %w", err)
+	}
+
+	response := fmt.Sprintf(
+		"✅ Critical alerts for `%s` will fire when the borrow rate reaches %s",
+		vaultID, formatVaultRate(vault, criticalRate, ctx),
+	)
+	if criticalRate == 0 {
+		response = fmt.Sprintf("✅ Disabled critical alerts for `%s`", vaultID)
+		if vault.CriticalActive {
+			select {
+			case ctx.Commands <- types.NewResolveCriticalCommand(vaultID):
+				response += "; its open critical alert is being resolved"
+			default:
+				response += "; its open critical alert will be resolved on the next check"
+			}
+		}
+	}
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &response,
+	})
+	return nil
+}
+
+func handleBand(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) error {
+	var vaultID string
+	var band *types.RateBand
+	for _, option := range i.ApplicationCommandData().Options {
+		switch option.Name {
+		case "vault_id":
+			vaultID = option.StringValue()
+		case "low", "high":
+			if band == nil {
+				band = &types.RateBand{Low: -1, High: -1}
+			}
+			if option.Name == "low" {
+				band.Low = option.FloatValue()
+			} else {
+				band.High = option.FloatValue()
+			}
+		}
+	}
+	if band != nil && (band.Low < 0 || band.High <= band.Low) {
+		return fmt.Errorf("give both low and high, with 0 <= low < high")
+	}
+
+	vault, err := ctx.Storage.GetVault(vaultID)
+	if err != nil {
+		return fmt.Errorf("error checking vault: %w", err)
+	}
+
+	if vault == nil {
+		return fmt.Errorf("vault `%s` not found", vaultID)
+	}
+
+	err = ctx.Storage.UpdateVault(vaultID, func(stored *types.VaultConfig) error {
+		stored.Band = band
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update band: %w", err)
+	}
+
+	response := fmt.Sprintf("✅ Alert colors for `%s` will be graded against its recent average ± 2 standard deviations", vaultID)
+	if band != nil {
+		response = fmt.Sprintf("✅ Alert colors for `%s` will be graded against a normal range of %s – %s",
+			vaultID, formatVaultRate(vault, band.Low, ctx), formatVaultRate(vault, band.High, ctx))
+	}
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &response,
+	})
+	return nil
+}
+
+func handleTag(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) error {
+	options := i.ApplicationCommandData().Options
+	vaultID := options[0].StringValue()
+
+	var tags []string
+	if len(options) > 1 {
+		tags = types.ParseTags(options[1].StringValue())
+	}
+
+	vault, err := ctx.Storage.GetVault(vaultID)
+	if err != nil {
+		return fmt.Errorf("error checking vault: %w", err)
+	}
+
+	if vault == nil {
+		return fmt.Errorf("vault `%s` not found", vaultID)
+	}
+
+	err = ctx.Storage.UpdateVault(vaultID, func(stored *types.VaultConfig) error {
+		stored.Tags = tags
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update tags: %w", err)
+	}
+
+	response := fmt.Sprintf("✅ Tags for `%s` set to: %s", vaultID, strings.Join(tags, ", "))
+	if len(tags) == 0 {
+		response = fmt.Sprintf("✅ Tags for `%s` cleared", vaultID)
+	}
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &response,
+	})
+	return nil
+}
+
+func handleNote(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) error {
+	options := i.ApplicationCommandData().Options
+	vaultID := options[0].StringValue()
+
+	var notes string
+	if len(options) > 1 {
+		notes = strings.TrimSpace(options[1].StringValue())
+	}
+
+	vault, err := ctx.Storage.GetVault(vaultID)
+	if err != nil {
+		return fmt.Errorf("error checking vault: %w", err)
+	}
+
+	if vault == nil {
+		return fmt.Errorf("vault `%s` not found", vaultID)
+	}
+
+	err = ctx.Storage.UpdateVault(vaultID, func(stored *types.VaultConfig) error {
+		stored.Notes = notes
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update notes: %w", err)
+	}
+
+	response := fmt.Sprintf("✅ Notes for `%s` set to: %s", vaultID, notes)
+	if notes == "" {
+		response = fmt.Sprintf("✅ Notes for `%s` cleared", vaultID)
+	}
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &response,
+	})
+	return nil
+}
+
+func handleDebt(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) error {
+	options := i.ApplicationCommandData().Options
+	vaultID := options[0].StringValue()
+
+	var amount float64
+	if len(options) > 1 {
+		amount = options[1].FloatValue()
+	}
+	if amount < 0 {
+		return fmt.Errorf("debt can't be negative")
+	}
+
+	vault, err := ctx.Storage.GetVault(vaultID)
+	if err != nil {
+		return fmt.Errorf("error checking vault: %w", err)
+	}
+
+	if vault == nil {
+		return fmt.Errorf("vault `%s` not found", vaultID)
+	}
+
+	err = ctx.Storage.UpdateVault(vaultID, func(stored *types.VaultConfig) error {
+		stored.DebtUSD = amount
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update debt: %w", err)
+	}
+
+	response := fmt.Sprintf("✅ Debt for `%s` set to %s", vaultID, types.FormatUSD(amount))
+	if amount == 0 {
+		response = fmt.Sprintf("✅ Debt for `%s` cleared", vaultID)
+	}
+	if vault.Position != nil {
+		response += "\nNote: this vault has Summer.fi position data, whose debt is used instead"
+	}
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &response,
+	})
+	return nil
+}
+
+func handlePrecision(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) error {
+	options := i.ApplicationCommandData().Options
+	vaultID := options[0].StringValue()
+	decimals := int(options[1].IntValue())
+
+	if decimals != 0 && (decimals < types.MinRateDecimals || decimals > types.MaxRateDecimals) {
+		return fmt.Errorf("decimals must be between %d and %d", types.MinRateDecimals, types.MaxRateDecimals)
+	}
+
+	vault, err := ctx.Storage.GetVault(vaultID)
+	if err != nil {
+		return fmt.Errorf("error checking vault: %w", err)
+	}
+
+	if vault == nil {
+		return fmt.Errorf("vault `%s` not found", vaultID)
+	}
+
+	err = ctx.Storage.UpdateVault(vaultID, func(stored *types.VaultConfig) error {
+		stored.RateDecimals = decimals
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update precision: %w", err)
+	}
+
+	vault.RateDecimals = decimals
+	response := fmt.Sprintf("✅ Rates for `%s` will be shown with %d decimal places", vaultID, vault.Decimals(ctx.Config.Monitor.RateDecimals))
+	if decimals == 0 {
+		response += " (the configured default)"
+	}
+	if rate, ok := ctx.Storage.GetLastRate(vaultID); ok {
+		response += fmt.Sprintf(", e.g. %s", formatVaultRate(vault, rate, ctx))
+	}
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &response,
+	})
+	return nil
+}
+
+func handlePriority(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) error {
+	options := i.ApplicationCommandData().Options
+	vaultID := options[0].StringValue()
+
+	priority, err := types.ParsePriority(options[1].StringValue())
+	if err != nil {
+		return err
+	}
+
+	vault, err := ctx.Storage.GetVault(vaultID)
+	if err != nil {
+		return fmt.Errorf("error checking vault: %w", err)
+	}
+
+	if vault == nil {
+		return fmt.Errorf("vault `%s` not found", vaultID)
+	}
+
+	err = ctx.Storage.UpdateVault(vaultID, func(stored *types.VaultConfig) error {
+		// Normal is the default, so store it as unset
+		stored.Priority = priority
+		if priority == types.PriorityNormal {
+			stored.Priority = ""
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update priority: %w", err)
+	}
+
+	response := fmt.Sprintf("✅ `%s` now has %s priority", vaultID, priority)
+	if ctx.Config.Monitor.CallBudget == 0 {
+		response += "; it only takes effect while the market API is failing, since `call_budget` is unlimited"
+	}
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &response,
+	})
+	return nil
+}
+
+func handleFallback(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) error {
+	options := i.ApplicationCommandData().Options
+	vaultID := options[0].StringValue()
+
+	var userID string
+	if len(options) > 1 {
+		userID = options[1].UserValue(s).ID
+	}
+
+	vault, err := ctx.Storage.GetVault(vaultID)
+	if err != nil {
+		return fmt.Errorf("error checking vault: %w", err)
+	}
+
+	if vault == nil {
+		return fmt.Errorf("vault `%s` not found", vaultID)
+	}
+
+	err = ctx.Storage.UpdateVault(vaultID, func(stored *types.VaultConfig) error {
+		stored.FallbackUserID = userID
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update fallback: %w", err)
+	}
+
+	response := fmt.Sprintf("✅ Disabled fallback delivery for `%s`", vaultID)
+	if userID != "" {
+		response = fmt.Sprintf("✅ Alerts for `%s` will be DMed to <@%s> if the webhook keeps failing", vaultID, userID)
+	}
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &response,
+	})
+	return nil
+}
+
+func handleSlack(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) error {
+	options := i.ApplicationCommandData().Options
+	vaultID := options[0].StringValue()
+
+	var webhookURL string
+	if len(options) > 1 {
+		webhookURL = strings.TrimSpace(options[1].StringValue())
+	}
+	if webhookURL != "" && !strings.HasPrefix(webhookURL, slackWebhookPrefix) {
+		return fmt.Errorf("webhook_url must be a Slack incoming webhook URL starting with %s", slackWebhookPrefix)
+	}
+
+	vault, err := ctx.Storage.GetVault(vaultID)
+	if err != nil {
+		return fmt.Errorf("error checking vault: %w", err)
+	}
+
+	if vault == nil || !vaultInGuild(vault, i.GuildID, ctx) {
+		return fmt.Errorf("vault `%s` not found", vaultID)
+	}
+
+	err = ctx.Storage.UpdateVault(vaultID, func(stored *types.VaultConfig) error {
+		stored.SlackWebhookURL = webhookURL
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update Slack webhook: %w", err)
+	}
+
+	response := fmt.Sprintf("✅ Stopped mirroring alerts for `%s` to Slack", vaultID)
+	if webhookURL != "" {
+		response = fmt.Sprintf("✅ Alerts for `%s` will also be posted to Slack", vaultID)
+	}
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &response,
+	})
+	return nil
+}
+
+func handlePreview(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) error {
+	options := i.ApplicationCommandData().Options
+	vaultID := options[0].StringValue()
+	rate := options[1].FloatValue()
+
+	if rate < 0 || rate > maxPreviewRate {
+		return fmt.Errorf("hypothetical_rate must be between 0 and %.0f", maxPreviewRate)
+	}
+
+	vault, err := ctx.Storage.GetVault(vaultID)
+	if err != nil {
+		return fmt.Errorf("error checking vault: %w", err)
+	}
+
+	if vault == nil || !vaultInGuild(vault, i.GuildID, ctx) {
+		return fmt.Errorf("vault `%s` not found", vaultID)
+	}
+
+	preview, err := monitor.PreviewAlert(ctx.Storage, ctx.Config, vault, rate)
+	if err != nil {
+		return err
+	}
+
+	format := vaultRateFormat(vault, ctx)
+	verdict := fmt.Sprintf("would raise a %s alert in <#%s>", strings.ToLower(preview.Alert.Severity.Label()), preview.ChannelID)
+	if !preview.Alerts {
+		verdict = "wouldn't raise an alert; this is what one would look like"
+	}
+	lines := []string{fmt.Sprintf("🔍 **Preview, not sent:** a move from %s to %s %s.",
+		format.Rate(preview.Baseline), format.Rate(rate), verdict)}
+	for _, note := range preview.Notes {
+		lines = append(lines, "• "+note)
+	}
+	if content := preview.Payload.Content; content != "" {
+		if preview.Payload.TTS {
+			content += " (read aloud)"
+		}
+		lines = append(lines, "The alert's message, shown here without pinging anyone:", "> "+content)
+	}
+	lines = append(lines, "Projections and other protocols' rates are fetched when a real alert is sent, so they aren't shown.")
+	response := strings.Join(lines, "\n")
+
+	embeds := make([]*discordgo.MessageEmbed, 0, len(preview.Payload.Embeds))
+	for _, e := range preview.Payload.Embeds {
+		embeds = append(embeds, ToMessageEmbed(e))
+	}
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content:         &response,
+		Embeds:          &embeds,
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+	return nil
+}
+
+// ToMessageEmbed converts the webhook embed type into discordgo's equivalent
+func ToMessageEmbed(e types.DiscordEmbed) *discordgo.MessageEmbed {
+	embed := &discordgo.MessageEmbed{
+		Title:       e.Title,
+		Description: e.Description,
+		Color:       e.Color,
+		Timestamp:   e.Timestamp,
+	}
+	for _, f := range e.Fields {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:   f.Name,
+			Value:  f.Value,
+			Inline: f.Inline,
+		})
+	}
+	if e.Footer != nil {
+		embed.Footer = &discordgo.MessageEmbedFooter{Text: e.Footer.Text, IconURL: e.Footer.IconURL}
+	}
+	return embed
+}
+
+func handleMaintenance(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) error {
+	// Everything but the mode is optional, so look options up by name rather than position
+	var mode string
+	var startsIn time.Duration
+	var announce bool
+	duration := 2 * time.Hour
+	for _, option := range i.ApplicationCommandData().Options {
+		switch option.Name {
+		case "mode":
+			mode = option.StringValue()
+		case "duration":
+			parsed, err := time.ParseDuration(option.StringValue())
+			if err != nil || parsed <= 0 {
+				return fmt.Errorf("invalid duration %q, use a value like 30m or 4h", option.StringValue())
+			}
+			duration = parsed
+		case "starts_in":
+			parsed, err := time.ParseDuration(option.StringValue())
+			if err != nil || parsed <= 0 {
+				return fmt.Errorf("invalid start %q, use a value like 1h or 24h", option.StringValue())
+			}
+			startsIn = parsed
+		case "event":
+			announce = option.BoolValue()
+		}
+	}
+
+	settings := ctx.Storage.GetSettings()
+	now := time.Now()
+
+	var response string
+	if mode == "off" {
+		switch {
+		case settings.InMaintenance(now):
+			response = "✅ Maintenance mode ended; alerts will be delivered again"
+		case settings.MaintenanceScheduled(now):
+			response = "✅ Scheduled maintenance canceled"
+		default:
+			return fmt.Errorf("maintenance mode is not active or scheduled")
+		}
+		settings = settings.WithoutMaintenance()
+	} else {
+		// A new window replaces the current one, along with its event
+		settings = settings.WithoutMaintenance()
+		if startsIn > 0 {
+			settings.MaintenanceFrom = now.Add(startsIn)
+		}
+		settings.MaintenanceUntil = now.Add(startsIn + duration)
+		settings.MaintenanceBy = interactionUserID(i)
+		if startsIn > 0 {
+			response = fmt.Sprintf(
+				"🔧 Maintenance scheduled from <t:%d:f> until <t:%d:f>. Alerts will be recorded but not delivered during the window.",
+				settings.MaintenanceFrom.Unix(), settings.MaintenanceUntil.Unix(),
+			)
+		} else {
+			response = fmt.Sprintf(
+				"🔧 Maintenance mode on until <t:%d:f>. Alerts are recorded but not delivered; rates and history are still tracked.",
+				settings.MaintenanceUntil.Unix(),
+			)
+		}
+
+		if announce {
+			event, err := createMaintenanceEvent(s, i.GuildID, settings, now)
+			if err != nil {
+				response += fmt.Sprintf("\n⚠️ Couldn't create a scheduled event (the bot needs the Manage Events permission): %v", err)
+			} else {
+				settings.MaintenanceEvent = event
+				response += "\n📅 Announced as a scheduled event; editing or canceling it in Discord changes the window too"
+			}
+		}
+	}
+
+	// Only the window is replaced, so server defaults changed meanwhile aren't lost
+	var previous types.Settings
+	err := ctx.Storage.UpdateSettings(func(current *types.Settings) {
+		previous = *current
+		*current = current.WithMaintenance(settings)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update maintenance mode: %w", err)
+	}
+	// Saved first, so the bot doesn't mistake the event ending for someone ending it in Discord
+	endMaintenanceEvent(s, ctx, previous, now)
+
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &response,
+	})
+	return nil
+}
+
+// defaultChecksPause is how long /pause-checks pauses scheduled checks without a duration
+const defaultChecksPause = time.Hour
+
+// pauseReplyTimeout bounds the wait for the monitor to confirm a pause. It handles the command
+// between ticks, so this only runs out if the monitor is stuck.
+const pauseReplyTimeout = 10 * time.Second
+
+func handlePauseChecks(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) error {
+	var mode string
+	duration := defaultChecksPause
+	for _, option := range i.ApplicationCommandData().Options {
+		switch option.Name {
+		case "mode":
+			mode = option.StringValue()
+		case "duration":
+			parsed, err := time.ParseDuration(option.StringValue())
+			if err != nil || parsed <= 0 {
+				return fmt.Errorf("invalid duration %q, use a value like 30m or 4h", option.StringValue())
+			}
+			duration = parsed
+		}
+	}
+	if mode == "off" {
+		duration = 0
+	}
+
+	reply := make(chan types.CommandResult, 1)
+	cmd := types.NewPauseCommand(duration)
+	cmd.Reply = reply
+	select {
+	case ctx.Commands <- cmd:
+	default:
+		return fmt.Errorf("another manual check or command is already pending, please try again shortly")
+	}
+
+	var response string
+	select {
+	case result := <-reply:
+		if result.Err != nil {
+			return result.Err
+		}
+		response = "⏯️ " + strings.ToUpper(result.Message[:1]) + result.Message[1:]
+		if duration > 0 {
+			response += ". Manual `/check` still runs; run `/pause-checks off` to resume early"
+		}
+	case <-time.After(pauseReplyTimeout):
+		return fmt.Errorf("the monitor didn't confirm the pause; check the logs")
+	}
+
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &response,
+	})
+	return nil
+}
+
+// createMaintenanceEvent announces a maintenance window as a Discord scheduled event. Discord won't
+// create an event that starts in the past, so a window that has already begun gets an event
+// starting a minute out, which is then started straight away.
+func createMaintenanceEvent(s *discordgo.Session, guildID string, settings types.Settings, now time.Time) (*types.MaintenanceEvent, error) {
+	start := settings.MaintenanceFrom
+	started := !start.After(now)
+	if started {
+		start = now.Add(time.Minute)
+	}
+	end := settings.MaintenanceUntil
+	if !end.After(start) {
+		end = start.Add(time.Minute)
+	}
+
+	event, err := s.GuildScheduledEventCreate(guildID, &discordgo.GuildScheduledEventParams{
+		Name:               "Rate alert maintenance",
+		Description:        "SummerRateChecker keeps recording borrow rates but doesn't deliver alerts during this window.",
+		ScheduledStartTime: &start,
+		ScheduledEndTime:   &end,
+		EntityType:         discordgo.GuildScheduledEventEntityTypeExternal,
+		EntityMetadata:     &discordgo.GuildScheduledEventEntityMetadata{Location: "SummerRateChecker"},
+		PrivacyLevel:       discordgo.GuildScheduledEventPrivacyLevelGuildOnly,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if started {
+		// If this fails the bot starts the event on its next maintenance sync
+		s.GuildScheduledEventEdit(guildID, event.ID, &discordgo.GuildScheduledEventParams{
+			Status: discordgo.GuildScheduledEventStatusActive,
+		})
+	}
+	return &types.MaintenanceEvent{GuildID: guildID, EventID: event.ID}, nil
+}
+
+// endMaintenanceEvent completes the scheduled event of a window that has started, or cancels one
+// that hasn't. An event that can't change status, e.g. because it was never started, is deleted.
+func endMaintenanceEvent(s *discordgo.Session, ctx *CommandContext, settings types.Settings, now time.Time) {
+	event := settings.MaintenanceEvent
+	if event == nil {
+		return
+	}
+
+	status := discordgo.GuildScheduledEventStatusCanceled
+	if settings.InMaintenance(now) {
+		status = discordgo.GuildScheduledEventStatusCompleted
+	}
+	_, err := s.GuildScheduledEventEdit(event.GuildID, event.EventID, &discordgo.GuildScheduledEventParams{Status: status})
+	if err == nil {
+		return
+	}
+	if err := s.GuildScheduledEventDelete(event.GuildID, event.EventID); err != nil {
+		ctx.Logger.Warnf("Failed to end maintenance event %s: %v", event.EventID, err)
+	}
+}
+
+func handleAck(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) error {
+	alertID := strings.TrimSpace(i.ApplicationCommandData().Options[0].StringValue())
+
+	alert, err := ctx.Storage.AcknowledgeAlert(alertID, interactionUserID(i))
+	if err != nil {
+		return fmt.Errorf("alert `%s` not found", alertID)
+	}
+
+	response := ackMessage(alert)
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &response,
+	})
+	return nil
+}
+
+// HandleComponent handles button presses on alert messages
+func HandleComponent(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) {
+	customID := i.MessageComponentData().CustomID
+	if ctx.Config.Discord.ReadOnly {
+		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: "🔒 This bot is a read-only mirror",
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		})
+		return
+	}
+	if strings.HasPrefix(customID, setupPrefix) {
+		handleSetupComponent(s, i, ctx, customID)
+		return
+	}
+	if !strings.HasPrefix(customID, types.AckButtonPrefix) {
+		return
+	}
+
+	alertID := strings.TrimPrefix(customID, types.AckButtonPrefix)
+	alert, err := ctx.Storage.AcknowledgeAlert(alertID, interactionUserID(i))
+	if err != nil {
+		ctx.Logger.Errorf("Failed to acknowledge alert %s: %v", alertID, err)
+		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: fmt.Sprintf("Alert `%s` is no longer tracked", alertID),
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		})
+		return
+	}
+
+	// Replace the button with who acknowledged the alert
+	content := ackMessage(alert)
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:    content,
+			Components: []discordgo.MessageComponent{},
+		},
+	})
+}
+
+// HandleModal handles a submitted modal form
+func HandleModal(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) {
+	if ctx.Config.Discord.ReadOnly {
+		return
+	}
+	if strings.HasPrefix(i.ModalSubmitData().CustomID, setupPrefix) {
+		handleSetupModal(s, i, ctx)
+	}
+}
+
+func ackMessage(alert *types.RateChangeAlert) string {
+	return fmt.Sprintf("👍 Alert `%s` for **%s** acknowledged by <@%s> <t:%d:R>",
+		alert.ID, alert.Nickname, alert.AckedBy, alert.AckedAt.Unix())
+}
+
+func handleCriticalPing(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) error {
+	options := i.ApplicationCommandData().Options
+	vaultID := options[0].StringValue()
+
+	mention, err := types.ParseCriticalMention(options[1].StringValue())
+	if err != nil {
+		return err
+	}
+	tts := len(options) > 2 && options[2].BoolValue()
+
+	vault, err := ctx.Storage.GetVault(vaultID)
+	if err != nil {
+		return fmt.Errorf("error checking vault: %w", err)
+	}
+
+	if vault == nil {
+		return fmt.Errorf("vault `%s` not found", vaultID)
+	}
+
+	err = ctx.Storage.UpdateVault(vaultID, func(stored *types.VaultConfig) error {
+		stored.CriticalMention = mention
+		stored.CriticalTTS = tts
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update critical alert pings: %w", err)
+	}
+
+	var parts []string
+	if mention != types.MentionNone {
+		parts = append(parts, "ping "+mention.Tag())
+	}
+	if tts {
+		parts = append(parts, "be read aloud")
+	}
+	response := fmt.Sprintf("✅ Critical alerts for `%s` will no longer ping the channel or use text-to-speech", vaultID)
+	if len(parts) > 0 {
+		response = fmt.Sprintf("✅ Critical alerts for `%s` will %s", vaultID, strings.Join(parts, " and "))
+		if vault.CriticalRate == 0 {
+			response += fmt.Sprintf("\n⚠️ No critical level is set yet; use `/critical %s <rate>`", vaultID)
+		}
+	}
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &response,
+	})
+	return nil
+}
+
+// maxFooterText keeps a /branding name short enough to leave room for the alert ID after it
+const maxFooterText = 64
+
+func handleBranding(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) error {
+	var name, iconURL string
+	for _, option := range i.ApplicationCommandData().Options {
+		switch option.Name {
+		case "name":
+			name = strings.TrimSpace(option.StringValue())
+		case "icon_url":
+			iconURL = strings.TrimSpace(option.StringValue())
+		}
+	}
+
+	if len([]rune(name)) > maxFooterText {
+		return fmt.Errorf("name must be at most %d characters", maxFooterText)
+	}
+	if iconURL != "" {
+		parsed, err := url.Parse(iconURL)
+		if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+			return fmt.Errorf("icon_url must be an http(s) link to an image")
+		}
+	}
+
+	err := updateGuildSettings(i, ctx, func(guild *types.GuildSettings) {
+		guild.FooterText = name
+		guild.FooterIconURL = iconURL
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update branding: %w", err)
+	}
+
+	response := fmt.Sprintf("✅ Alerts and embeds in this server are signed %q again", types.DefaultFooter)
+	if name != "" || iconURL != "" {
+		text, _ := ctx.Storage.GetSettings().Guild(i.GuildID).BrandFooter(types.DefaultFooter)
+		response = fmt.Sprintf("✅ Alerts and embeds in this server are now signed %q", text)
+		if iconURL != "" {
+			response += " with your icon"
+		}
+	}
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &response,
+	})
+	return nil
+}
+
+// brandedFooter returns an embed footer with the server's /branding applied
+func brandedFooter(i *discordgo.InteractionCreate, ctx *CommandContext, text string) *discordgo.MessageEmbedFooter {
+	text, iconURL := ctx.Storage.GetSettings().Guild(i.GuildID).BrandFooter(text)
+	return &discordgo.MessageEmbedFooter{Text: text, IconURL: iconURL}
+}
+
+func handleEscalation(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) error {
+	options := i.ApplicationCommandData().Options
+	vaultID := options[0].StringValue()
+
+	var roleID, userID string
+	for _, opt := range options[1:] {
+		switch opt.Name {
+		case "role":
+			roleID = opt.RoleValue(s, i.GuildID).ID
+		case "user":
+			userID = opt.UserValue(s).ID
+		}
+	}
+
+	vault, err := ctx.Storage.GetVault(vaultID)
+	if err != nil {
+		return fmt.Errorf("error checking vault: %w", err)
+	}
+
+	if vault == nil {
+		return fmt.Errorf("vault `%s` not found", vaultID)
+	}
+
+	err = ctx.Storage.UpdateVault(vaultID, func(stored *types.VaultConfig) error {
+		stored.EscalationRoleID = roleID
+		stored.EscalationUserID = userID
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update escalation: %w", err)
+	}
+
+	var targets []string
+	if roleID != "" {
+		targets = append(targets, fmt.Sprintf("mention <@&%s>", roleID))
+	}
+	if userID != "" {
+		targets = append(targets, fmt.Sprintf("DM <@%s>", userID))
+	}
+
+	response := fmt.Sprintf("✅ Escalations for `%s` will be posted without a ping", vaultID)
+	if len(targets) > 0 {
+		response = fmt.Sprintf("✅ Escalations for `%s` will %s", vaultID, strings.Join(targets, " and "))
+	}
+
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &response,
+	})
+	return nil
+}
+
+func handleRoute(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) error {
+	var vaultID, channelID string
+	var severity types.Severity
+	for _, opt := range i.ApplicationCommandData().Options {
+		switch opt.Name {
+		case "vault_id":
+			vaultID = opt.StringValue()
+		case "severity":
+			severity = types.Severity(opt.StringValue())
+		case "channel":
+			channelID = opt.ChannelValue(s).ID
+		}
+	}
+
+	vault, err := ctx.Storage.GetVault(vaultID)
+	if err != nil {
+		return fmt.Errorf("error checking vault: %w", err)
+	}
+
+	if vault == nil {
+		return fmt.Errorf("vault `%s` not found", vaultID)
+	}
+
+	// Routing to the vault's own channel is the same as clearing the route
+	if channelID == vault.ChannelID {
+		channelID = ""
+	}
+
+	var route *types.ChannelRoute
+	if channelID != "" {
+		webhook, err := s.WebhookCreate(channelID, "SummerRateChecker", "")
+		if err != nil {
+			return fmt.Errorf("failed to create webhook for channel: %w", err)
+		}
+		route = &types.ChannelRoute{
+			ChannelID:  channelID,
+			WebhookURL: fmt.Sprintf("https://discord.com/api/webhooks/%s/%s", webhook.ID, webhook.Token),
+		}
+	}
+
+	var old *types.ChannelRoute
+	err = ctx.Storage.UpdateVault(vaultID, func(stored *types.VaultConfig) error {
+		old = stored.Routes[severity]
+		if route == nil {
+			delete(stored.Routes, severity)
+			return nil
+		}
+		if stored.Routes == nil {
+			stored.Routes = make(map[types.Severity]*types.ChannelRoute)
+		}
+		stored.Routes[severity] = route
+		return nil
+	})
+	if err != nil {
+		if route != nil {
+			deleteWebhook(s, ctx, route.WebhookURL)
+		}
+		return fmt.Errorf("failed to update route: %w", err)
+	}
+
+	if old != nil && !storage.WebhookInUse(ctx.Storage, old.WebhookURL) {
+		deleteWebhook(s, ctx, old.WebhookURL)
+	}
+
+	response := fmt.Sprintf("✅ %s alerts for `%s` will be posted to the vault's channel <#%s>",
+		severity.Label(), vaultID, vault.ChannelID)
+	if route != nil {
+		response = fmt.Sprintf("✅ %s alerts for `%s` will be posted to <#%s>", severity.Label(), vaultID, channelID)
+	}
+
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &response,
+	})
+	return nil
+}
+
+func handleSchedule(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) error {
+	options := i.ApplicationCommandData().Options
+	vaultID := options[0].StringValue()
+	daysSpec := options[1].StringValue()
+
+	vault, err := ctx.Storage.GetVault(vaultID)
+	if err != nil {
+		return fmt.Errorf("error checking vault: %w", err)
+	}
+
+	if vault == nil {
+		return fmt.Errorf("vault `%s` not found", vaultID)
+	}
+
+	var response string
+	var schedule *types.AlertSchedule
+	if strings.EqualFold(strings.TrimSpace(daysSpec), "off") {
+		response = fmt.Sprintf("✅ Removed the alert schedule for `%s`; alerts are delivered at any time", vaultID)
+	} else {
+		days, err := types.ParseWeekdays(daysSpec)
+		if err != nil {
+			return fmt.Errorf("invalid days: %w", err)
+		}
+
+		schedule = &types.AlertSchedule{Days: days, StartHour: 8, EndHour: 20}
+		for _, opt := range options[2:] {
+			switch opt.Name {
+			case "start_hour":
+				schedule.StartHour = int(opt.IntValue())
+			case "end_hour":
+				schedule.EndHour = int(opt.IntValue())
+			case "timezone":
+				schedule.Timezone = opt.StringValue()
+			}
+		}
+
+		// Validate schedule
+		if schedule.StartHour < 0 || schedule.StartHour > 23 || schedule.EndHour < 1 || schedule.EndHour > 24 {
+			return fmt.Errorf("start_hour must be 0-23 and end_hour must be 1-24")
+		}
+		if schedule.StartHour >= schedule.EndHour {
+			return fmt.Errorf("start_hour must be before end_hour")
+		}
+		if schedule.Timezone != "" {
+			if _, err := time.LoadLocation(schedule.Timezone); err != nil {
+				return fmt.Errorf("unknown timezone %q", schedule.Timezone)
+			}
+		}
+
+		response = fmt.Sprintf(
+			"✅ Alerts for `%s` will only be delivered %s; alerts outside that window are summarized when it opens (critical alerts are always delivered)",
+			vaultID, schedule,
+		)
+	}
+
+	err = ctx.Storage.UpdateVault(vaultID, func(stored *types.VaultConfig) error {
+		stored.AlertSchedule = schedule
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update schedule: %w", err)
+	}
+
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &response,
+	})
+	return nil
+}
+
+func handleDiagnostics(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) error {
+	options := i.ApplicationCommandData().Options
+
+	var vaults []*types.VaultConfig
+	if len(options) > 0 {
+		vaultID := options[0].StringValue()
+		vault, err := ctx.Storage.GetVault(vaultID)
+		if err != nil {
+			return fmt.Errorf("error checking vault: %w", err)
+		}
+		if vault == nil {
+			return fmt.Errorf("vault `%s` not found", vaultID)
+		}
+		vaults = []*types.VaultConfig{vault}
+	} else {
+		var err error
+		vaults, err = ctx.Storage.GetAllVaults()
+		if err != nil {
+			return fmt.Errorf("error retrieving vaults: %w", err)
+		}
+	}
+
+	if len(vaults) == 0 {
+		response := "No vaults enrolled"
+		s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: &response,
+		})
+		return nil
+	}
+
+	var response strings.Builder
+	response.WriteString("**Delivery Diagnostics:**\n")
+	for n, vault := range vaults {
+		block := diagnoseVault(vault, ctx)
+		// Leave room for the note below, so the reply stays within Discord's limit
+		if response.Len()+len(block) > maxMessageLength-100 {
+			response.WriteString(fmt.Sprintf("…and %d more vault(s); pass a vault ID to see one", len(vaults)-n))
+			break
+		}
+		response.WriteString(block)
+	}
+
+	content := truncateMessage(response.String())
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &content,
+	})
+	return nil
+}
+
+// diagnoseVault describes the vault's enrollment and each sink's delivery record for /diagnostics
+func diagnoseVault(vault *types.VaultConfig, ctx *CommandContext) string {
+	var response strings.Builder
+	response.WriteString(fmt.Sprintf("`%s` - \"%s\"\n", vault.VaultID, vault.Nickname))
+	response.WriteString(fmt.Sprintf("  • Enrolled by %s <t:%d:R>\n", vault.DescribeEnrollment(), vault.CreatedAt.Unix()))
+	if vault.Notes != "" {
+		response.WriteString(fmt.Sprintf("  • Notes: %s\n", vault.Notes))
+	}
+
+	stats := ctx.Storage.GetDeliveryStats(vault.VaultID)
+	if len(stats) == 0 {
+		response.WriteString("  • No deliveries yet\n")
+		return response.String()
+	}
+	for _, st := range stats {
+		outcome := "✅"
+		if !st.LastSuccess {
+			outcome = "❌"
+		}
+		status := ""
+		if st.LastStatusCode != 0 {
+			status = fmt.Sprintf(" HTTP %d", st.LastStatusCode)
+		}
+		response.WriteString(fmt.Sprintf(
+			"  • %s: %d ok / %d failed — last %s%s in %dms <t:%d:R>\n",
+			st.Sink, st.Successes, st.Failures, outcome, status, st.LastLatencyMs, st.LastAttemptAt.Unix(),
+		))
+		if st.LastError != "" {
+			response.WriteString(fmt.Sprintf("    last error: %s\n", st.LastError))
+		}
+	}
+	return response.String()
+}
+
+// splitMessage splits content into Discord messages, breaking between paragraphs where it can and
+// otherwise between lines
+func splitMessage(content string) []string {
+	var parts []string
+	for _, paragraph := range strings.Split(content, "\n\n") {
+		if len(paragraph) <= maxMessageLength {
+			parts = append(parts, paragraph)
+			continue
+		}
+		parts = append(parts, packMessages(strings.Split(paragraph, "\n"), "\n")...)
+	}
+	return packMessages(parts, "\n\n")
+}
+
+// packMessages joins parts with sep into as few messages as fit Discord's limit. A part too long
+// for a message on its own is truncated.
+func packMessages(parts []string, sep string) []string {
+	var messages []string
+	current := ""
+	for _, part := range parts {
+		part = truncateMessage(part)
+		if current != "" && len(current)+len(sep)+len(part) > maxMessageLength {
+			messages = append(messages, current)
+			current = ""
+		}
+		if current == "" {
+			current = part
+		} else {
+			current += sep + part
+		}
+	}
+	if current != "" {
+		messages = append(messages, current)
+	}
+	return messages
+}
+
+// truncateMessage cuts content to fit in a Discord message
+func truncateMessage(content string) string {
+	if len(content) <= maxMessageLength {
+		return content
+	}
+	cut := maxMessageLength - len("…")
+	for cut > 0 && !utf8.RuneStart(content[cut]) {
+		cut--
+	}
+	return content[:cut] + "…"
+}
+
+func handleInterval(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) error {
+	response := fmt.Sprintf("Current check interval: %d minutes", ctx.Config.Monitor.CheckIntervalMinutes)
+	if minutes := ctx.Storage.GetSettings().Guild(i.GuildID).CheckIntervalMinutes; minutes > 0 {
+		response += fmt.Sprintf("\nThis server's vaults are checked every %d minutes (set with /setup)", minutes)
+	}
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &response,
+	})
+	return nil
+}
+
+// maxCooldownMinutes caps /cooldown at a week, beyond which a vault is better paused
+const maxCooldownMinutes = 7 * 24 * 60
+
+func handleCooldown(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) error {
+	options := i.ApplicationCommandData().Options
+	vaultID := options[0].StringValue()
+	minutes := int(options[1].IntValue())
+
+	if minutes < 0 || minutes > maxCooldownMinutes {
+		return fmt.Errorf("minutes must be between 0 and %d", maxCooldownMinutes)
+	}
+
+	vault, err := ctx.Storage.GetVault(vaultID)
+	if err != nil {
+		return fmt.Errorf("error checking vault: %w", err)
+	}
+
+	if vault == nil {
+		return fmt.Errorf("vault `%s` not found", vaultID)
+	}
+
+	err = ctx.Storage.UpdateVault(vaultID, func(stored *types.VaultConfig) error {
+		stored.CooldownMinutes = minutes
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update cooldown: %w", err)
+	}
+
+	var response string
+	if minutes == 0 {
+		response = fmt.Sprintf("✅ Removed the alert cooldown for `%s`", vaultID)
+	} else {
+		response = fmt.Sprintf("✅ `%s` will wait at least %d minutes between threshold alerts", vaultID, minutes)
+		vault.CooldownMinutes = minutes
+		if ends := vault.CooldownEnds(); time.Now().Before(ends) {
+			response += fmt.Sprintf(" (next alert possible <t:%d:R>)", ends.Unix())
+		}
+	}
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &response,
+	})
+	return nil
+}
+
+// handleHelp lists the commands. The list is longer than a Discord message allows, so it's sent
+// as the response plus follow-up messages.
+func handleHelp(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) error {
+	chunks := splitMessage(helpText(ctx.Config.Discord.ReadOnly))
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &chunks[0],
+	})
+	for _, chunk := range chunks[1:] {
+		if _, err := s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{Content: chunk}); err != nil {
+			return fmt.Errorf("failed to send the rest of the help: %w", err)
+		}
+	}
+	return nil
+}
+
+// helpText returns /help's command list, trimmed to the read-only commands if readOnly is set
+func helpText(readOnly bool) string {
+	help := `**SummerRateChecker Commands:**
+
+🏦 **Vault Management:**
+• /setup - Guided setup: permissions, alert channel, server defaults and a first vault (admins only)
+• /enroll - Add a vault for monitoring
+  - Required: URL (or a Morpho market unique key or pair), nickname, threshold (unless /setup set a default)
+  - Optional: channel, or create_channel:true for a dedicated channel named after the nickname; notes
+  - Example: [Command Format] /enroll url:<summer-fi-url> nickname:My WBTC Vault threshold:0.5
+• /rule - Add or list composite alert rules for a vault
+• /rule-remove - Remove an alert rule
+• /verify - Check stored data for inconsistencies and optionally repair them (admins only)
+• /api-token-create - Create a scoped HTTP API token (admins only)
+• /api-token-list - List HTTP API tokens (admins only)
+• /api-token-revoke - Revoke an HTTP API token (admins only)
+• /auto-enroll - Enroll every market for a pair automatically
+• /auto-enroll-remove - Remove an auto-enroll rule
+• /unenroll - Remove a vault from monitoring
+• /restore - Undo a recent /unenroll
+• /undo - Revert your most recent enroll, unenroll, or threshold change
+• /list - Show all enrolled vaults
+• /threshold - Update alert threshold, optionally against a 24h or trailing average APY
+• /enable - Resume checking a vault disabled after repeated failures
+• /pause - Stop delivering a vault's alerts (or all vaults') for a while, still recording rates
+• /resume - Deliver a paused vault's alerts again
+• /baseline - Choose what alerts are measured against
+• /reset-baseline - Compare future checks against the current rate
+• /critical - Set the rate at which alerts become critical
+• /critical-ping - Ping @here/@everyone or use text-to-speech on critical alerts (admins only)
+• /branding - Sign this server's alerts with your own name and icon (admins only)
+• /band - Set the normal rate range alert colors are graded against
+• /tag - Set or clear a vault's tags
+• /note - Set or clear a vault's notes
+• /debt - Set a vault's debt for interest estimates
+• /precision - Show a vault's rates with 2-4 decimal places
+• /cooldown - Set the minimum time between a vault's threshold alerts
+• /priority - Check a vault first, or less often, when the market API is struggling
+• /fallback - Set who gets DMed if a vault's webhook keeps failing
+• /slack - Mirror a vault's alerts to a Slack channel (admins only)
+• /preview - Show the alert a vault would send at a given rate, without sending it
+• /maintenance - Silence all alerts for a planned period, now or later, optionally announced as a scheduled event
+• /pause-checks - Stop or resume scheduled rate checks for a while (admins only)
+• /ack - Acknowledge an alert by its ID
+• /escalation - Set who is pinged when a rate breach persists
+• /route - Send a vault's warning or critical alerts to another channel
+• /schedule - Restrict a vault's alerts to business hours
+
+📊 **Monitoring:**
+• /status - Show current rates for all vaults, or details for one
+• /history - Show a vault's recent rate samples, or a min/max/average summary over a window
+• /chart - Draw a vault's borrow rate over the last day, week, month or quarter
+• /leaderboard - Rank vaults by volatility or net change
+• /top - List the top vaults, or markets, by borrow rate, 24h change or utilization
+• /portfolio - Show each vault's debt and interest accrued since enrollment
+• /savings - Estimate the savings of moving a vault's debt to another market
+• /market-info - Show a market's LLTV, IRM, oracle, size and utilization, and average APYs
+• /watch-new - Get alerted when a new market for a pair appears
+• /unwatch-new - Stop a new-market watch
+• /check - Force an immediate rate check (optionally one vault_id or tag)
+• /interval - Show current check interval
+• /diagnostics - Show alert delivery stats per vault and sink
+
+ℹ️ **General:**
+• /help - Show this help message
+
+**Notes:**
+• Threshold is in percentage points (0.5 = alert on ±0.5% change)
+• You must provide the full Summer.fi URL when enrolling a vault
+• Vaults outside Ethereum Morpho Blue are referred to as network:protocol:id, e.g. base:morphoblue:1234, as shown by /list
+• The URL format is: [URL Format] <summer-fi-url>
+  Example: [Example URL] <https://pro.summer.fi/ethereum/morphoblue/borrow/WBTC-USDC/1234#overview>
+
+Type "/" to see all available commands with their descriptions and options.`
+	if readOnly {
+		help = readOnlyHelp(help)
+	}
+	return help
+}
+
+// readOnlyHelp trims help to the commands registered in read-only mode, dropping sections left empty
+func readOnlyHelp(help string) string {
+	var kept []string
+	skipping := false
+	for _, line := range strings.Split(help, "\n") {
+		if strings.HasPrefix(line, "• /") {
+			name, _, _ := strings.Cut(strings.TrimPrefix(line, "• /"), " ")
+			skipping = !readOnlyCommands[name]
+		} else if !strings.HasPrefix(line, "  ") {
+			skipping = false
+		}
+		if !skipping {
+			kept = append(kept, line)
+		}
+	}
+
+	var lines []string
+	for n, line := range kept {
+		// A section heading followed by a blank line lost all its commands
+		if strings.HasSuffix(line, ":**") && n+1 < len(kept) && kept[n+1] == "" && !strings.HasPrefix(line, "**SummerRateChecker") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return strings.ReplaceAll(strings.Join(lines, "\n"), "\n\n\n", "\n\n") +
+		"\n\n🔒 This bot is a read-only mirror, so commands that change vaults are turned off."
+}
+
+// recordAudit adds entry to the audit log. A failure is logged rather than failing the command,
+// since the change itself has already been made.
+func recordAudit(ctx *CommandContext, entry *types.AuditEntry) {
+	if err := ctx.Storage.RecordAudit(entry); err != nil {
+		ctx.Logger.Errorf("Failed to record audit entry for %s: %v", entry.Describe(), err)
+	}
+}
+
+// vaultRateFormat returns the format the vault's rates are shown in
+func vaultRateFormat(vault *types.VaultConfig, ctx *CommandContext) types.RateFormat {
+	return vault.RateFormat(ctx.Config.Monitor.RateFormat())
+}
+
+// formatVaultRate formats a rate in the vault's format
+func formatVaultRate(vault *types.VaultConfig, rate float64, ctx *CommandContext) string {
+	return vaultRateFormat(vault, ctx).Rate(rate)
+}
+
+// volatilityIndex returns the vault's market volatility over the last week of its rate history
+func volatilityIndex(vault *types.VaultConfig, ctx *CommandContext) (float64, bool) {
+	return stats.VolatilityIndex(ctx.Storage.GetRateHistory(vault.VaultID, time.Now().Add(-stats.VolatilityWindow)))
+}
+
+// vaultInterval returns how often the vault is checked, given its server's /setup interval
+func vaultInterval(vault *types.VaultConfig, settings types.Settings, ctx *CommandContext) time.Duration {
+	return settings.CheckInterval(vault.Guild(ctx.Config.Discord.GuildID), ctx.Config.Monitor.CheckIntervalMinutes)
+}
+
+// interactionUserID returns the ID of the user who invoked the interaction, in a guild or a DM
+func interactionUserID(i *discordgo.InteractionCreate) string {
+	if i.Member != nil && i.Member.User != nil {
+		return i.Member.User.ID
+	}
+	if i.User != nil {
+		return i.User.ID
+	}
+	return ""
+}
+
+// interactionUserName returns the name the invoking user goes by in the server
+func interactionUserName(i *discordgo.InteractionCreate) string {
+	user := i.User
+	if i.Member != nil {
+		if i.Member.Nick != "" {
+			return i.Member.Nick
+		}
+		user = i.Member.User
+	}
+	if user == nil {
+		return ""
+	}
+	if user.GlobalName != "" {
+		return user.GlobalName
+	}
+	return user.Username
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
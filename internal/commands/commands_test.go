@@ -0,0 +1,47 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHelpFitsInMessages(t *testing.T) {
+	for _, readOnly := range []bool{false, true} {
+		help := helpText(readOnly)
+		chunks := splitMessage(help)
+		if len(chunks) == 0 {
+			t.Fatalf("readOnly=%v: help split into no messages", readOnly)
+		}
+		for n, chunk := range chunks {
+			if len(chunk) > maxMessageLength {
+				t.Errorf("readOnly=%v: message %d is %d characters, over Discord's %d", readOnly, n, len(chunk), maxMessageLength)
+			}
+		}
+		// Nothing is lost in the split; only the separators between messages are dropped
+		if strings.ReplaceAll(strings.Join(chunks, ""), "\n", "") != strings.ReplaceAll(help, "\n", "") {
+			t.Errorf("readOnly=%v: split help doesn't match the original", readOnly)
+		}
+	}
+}
+
+func TestSplitMessage(t *testing.T) {
+	short := "**Heading:**\n• one\n• two"
+	if got := splitMessage(short); len(got) != 1 || got[0] != short {
+		t.Errorf("splitMessage(short) = %q, want it unchanged", got)
+	}
+
+	line := strings.Repeat("x", 300)
+	paragraph := strings.TrimSuffix(strings.Repeat(line+"\n", 10), "\n") // One paragraph over the limit
+	chunks := splitMessage("intro\n\n" + paragraph)
+	if len(chunks) < 2 {
+		t.Fatalf("splitMessage split %d characters into %d message(s)", len(paragraph), len(chunks))
+	}
+	for n, chunk := range chunks {
+		if len(chunk) > maxMessageLength {
+			t.Errorf("message %d is %d characters", n, len(chunk))
+		}
+		if strings.HasPrefix(chunk, "\n") || strings.HasSuffix(chunk, "\n") {
+			t.Errorf("message %d wasn't split between lines: %q", n, chunk)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package commands
+
+import (
+	"sync"
+	"time"
+
+	"github.com/morrisonbrett/SummerRateChecker/internal/types"
+)
+
+// Cooldowns tracks when rate-limited commands may next be run, per key (e.g. command and user)
+type Cooldowns struct {
+	mu    sync.Mutex
+	until map[string]time.Time
+}
+
+func NewCooldowns() *Cooldowns {
+	return &Cooldowns{until: make(map[string]time.Time)}
+}
+
+// Check reports whether key is off cooldown. Otherwise it returns how long is left. It doesn't
+// start a cooldown; call Record once the command has actually run.
+func (c *Cooldowns) Check(key string) (time.Duration, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if until, ok := c.until[key]; ok && now.Before(until) {
+		return until.Sub(now), false
+	}
+	return 0, true
+}
+
+// Record starts a cooldown of period for key. A period of zero or less does nothing.
+func (c *Cooldowns) Record(key string, period time.Duration) {
+	if period <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Forget expired cooldowns so the map doesn't grow with every user who ever ran a command
+	now := time.Now()
+	for k, until := range c.until {
+		if !now.Before(until) {
+			delete(c.until, k)
+		}
+	}
+	c.until[key] = now.Add(period)
+}
+
+// vaultInGuild reports whether vault belongs to guildID. Vaults enrolled before the server was
+// recorded belong to the primary configured server.
+func vaultInGuild(vault *types.VaultConfig, guildID string, ctx *CommandContext) bool {
+	return vault.Guild(ctx.Config.Discord.GuildID) == guildID
+}
@@ -0,0 +1,361 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/morrisonbrett/SummerRateChecker/internal/types"
+)
+
+// Custom IDs of the /setup wizard's buttons and modals
+const (
+	setupPrefix         = "setup:"
+	setupCreateChannel  = setupPrefix + "create-channel"
+	setupUseChannel     = setupPrefix + "use-channel"
+	setupDefaults       = setupPrefix + "defaults"
+	setupEnroll         = setupPrefix + "enroll"
+	setupDefaultsModal  = setupPrefix + "defaults-modal"
+	setupEnrollModal    = setupPrefix + "enroll-modal"
+	setupAlertChannel   = "rate-alerts"
+	setupFieldThreshold = "threshold"
+	setupFieldInterval  = "interval"
+	setupFieldURL       = "url"
+	setupFieldNickname  = "nickname"
+)
+
+// setupPermissions are the channel permissions the bot needs, in the order /setup lists them
+var setupPermissions = []struct {
+	permission int64
+	name       string
+	reason     string
+}{
+	{discordgo.PermissionViewChannel, "View Channel", "to see the alert channel"},
+	{discordgo.PermissionSendMessages, "Send Messages", "to reply to commands"},
+	{discordgo.PermissionEmbedLinks, "Embed Links", "to post alert embeds"},
+	{discordgo.PermissionManageWebhooks, "Manage Webhooks", "to create the webhook each vault alerts through"},
+	{discordgo.PermissionManageChannels, "Manage Channels", "only to create #" + setupAlertChannel},
+}
+
+// alertChannelPermissions are granted to the bot on channels it creates, so alerts keep working
+// even if the server's roles are tightened later
+const alertChannelPermissions = discordgo.PermissionViewChannel |
+	discordgo.PermissionSendMessages |
+	discordgo.PermissionEmbedLinks |
+	discordgo.PermissionManageWebhooks
+
+func handleSetup(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) error {
+	content, components := setupMessage(s, i, ctx, "")
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content:    &content,
+		Components: &components,
+	})
+	return nil
+}
+
+// setupMessage renders the wizard's current state for the interaction's server, with notice
+// (the result of the last step) above it
+func setupMessage(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext, notice string) (string, []discordgo.MessageComponent) {
+	guild := ctx.Storage.GetSettings().Guild(i.GuildID)
+
+	var sb strings.Builder
+	if notice != "" {
+		sb.WriteString(notice + "\n\n")
+	}
+	sb.WriteString("**SummerRateChecker setup**\n\n")
+
+	// Permissions are checked where alerts will go
+	channelID := guild.AlertChannelID
+	if channelID == "" {
+		channelID = i.ChannelID
+	}
+	sb.WriteString(fmt.Sprintf("**1. Permissions** in <#%s>\n", channelID))
+	permissions, err := s.UserChannelPermissions(s.State.User.ID, channelID)
+	if err != nil {
+		ctx.Logger.Warnf("Failed to check permissions in channel %s: %v", channelID, err)
+		sb.WriteString("⚠️ Couldn't check permissions; make sure the bot can see this channel\n")
+	} else {
+		for _, p := range setupPermissions {
+			if permissions&p.permission == p.permission {
+				sb.WriteString(fmt.Sprintf("✅ %s\n", p.name))
+			} else {
+				sb.WriteString(fmt.Sprintf("❌ %s - needed %s\n", p.name, p.reason))
+			}
+		}
+	}
+
+	sb.WriteString("\n**2. Alert channel:** ")
+	if guild.AlertChannelID != "" {
+		sb.WriteString(fmt.Sprintf("<#%s>\n", guild.AlertChannelID))
+	} else {
+		sb.WriteString("not set; alerts go to the channel /enroll is run in\n")
+	}
+
+	sb.WriteString("**3. Defaults:** ")
+	if guild.DefaultThreshold > 0 {
+		sb.WriteString(fmt.Sprintf("%s%% threshold", types.FormatThreshold(guild.DefaultThreshold)))
+	} else {
+		sb.WriteString("no default threshold")
+	}
+	interval := ctx.Storage.GetSettings().CheckInterval(i.GuildID, ctx.Config.Monitor.CheckIntervalMinutes)
+	sb.WriteString(fmt.Sprintf(", checked every %d minutes\n", int(interval.Minutes())))
+
+	enrolled := 0
+	if vaults, err := ctx.Storage.GetAllVaults(); err == nil {
+		for _, vault := range vaults {
+			if vaultInGuild(vault, i.GuildID, ctx) {
+				enrolled++
+			}
+		}
+	}
+	sb.WriteString("**4. Vaults:** ")
+	if enrolled > 0 {
+		sb.WriteString(fmt.Sprintf("%d enrolled in this server\n", enrolled))
+	} else {
+		sb.WriteString("none yet; enroll your first one below\n")
+	}
+
+	components := []discordgo.MessageComponent{
+		discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.Button{Label: "Create #" + setupAlertChannel, Style: discordgo.PrimaryButton, CustomID: setupCreateChannel},
+			discordgo.Button{Label: "Use this channel", Style: discordgo.SecondaryButton, CustomID: setupUseChannel},
+			discordgo.Button{Label: "Set defaults", Style: discordgo.SecondaryButton, CustomID: setupDefaults},
+			discordgo.Button{Label: "Enroll a vault", Style: discordgo.SuccessButton, CustomID: setupEnroll},
+		}},
+	}
+	return sb.String(), components
+}
+
+// handleSetupComponent handles a click on one of the wizard's buttons
+func handleSetupComponent(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext, customID string) {
+	guild := ctx.Storage.GetSettings().Guild(i.GuildID)
+
+	switch customID {
+	case setupDefaults:
+		threshold := ""
+		if guild.DefaultThreshold > 0 {
+			threshold = strconv.FormatFloat(guild.DefaultThreshold, 'f', -1, 64)
+		}
+		interval := ctx.Storage.GetSettings().CheckInterval(i.GuildID, ctx.Config.Monitor.CheckIntervalMinutes)
+		respondModal(s, i, setupDefaultsModal, "Server defaults",
+			discordgo.TextInput{CustomID: setupFieldThreshold, Label: "Default alert threshold (0.01-100.0)", Style: discordgo.TextInputShort, Value: threshold, Placeholder: "0.5"},
+			discordgo.TextInput{CustomID: setupFieldInterval, Label: "Check interval in minutes", Style: discordgo.TextInputShort, Value: strconv.Itoa(int(interval.Minutes())), Required: true},
+		)
+		return
+	case setupEnroll:
+		threshold := ""
+		if guild.DefaultThreshold > 0 {
+			threshold = strconv.FormatFloat(guild.DefaultThreshold, 'f', -1, 64)
+		}
+		respondModal(s, i, setupEnrollModal, "Enroll a vault",
+			discordgo.TextInput{CustomID: setupFieldURL, Label: "Summer.fi URL", Style: discordgo.TextInputShort, Required: true},
+			discordgo.TextInput{CustomID: setupFieldNickname, Label: "Nickname", Style: discordgo.TextInputShort, Required: true, MaxLength: 100},
+			discordgo.TextInput{CustomID: setupFieldThreshold, Label: "Alert threshold (0.01-100.0)", Style: discordgo.TextInputShort, Value: threshold, Required: true},
+		)
+		return
+	}
+
+	// The remaining steps call Discord, so acknowledge the click before doing the work
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredMessageUpdate,
+	})
+
+	var notice string
+	switch customID {
+	case setupCreateChannel:
+		channel, err := findOrCreateAlertChannel(s, i.GuildID, setupAlertChannel)
+		if err != nil {
+			notice = fmt.Sprintf("❌ Failed to create #%s: %v", setupAlertChannel, err)
+			break
+		}
+		if err := updateGuildSettings(i, ctx, func(g *types.GuildSettings) { g.AlertChannelID = channel.ID }); err != nil {
+			notice = fmt.Sprintf("❌ Failed to save the alert channel: %v", err)
+			break
+		}
+		notice = fmt.Sprintf("✅ New vaults will alert in <#%s>", channel.ID)
+	case setupUseChannel:
+		if err := updateGuildSettings(i, ctx, func(g *types.GuildSettings) { g.AlertChannelID = i.ChannelID }); err != nil {
+			notice = fmt.Sprintf("❌ Failed to save the alert channel: %v", err)
+			break
+		}
+		notice = fmt.Sprintf("✅ New vaults will alert in <#%s>", i.ChannelID)
+	default:
+		ctx.Logger.Warnf("Unknown setup button %q", customID)
+		return
+	}
+
+	editSetupMessage(s, i, ctx, notice)
+}
+
+// handleSetupModal handles a submitted wizard form
+func handleSetupModal(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext) {
+	data := i.ModalSubmitData()
+	values := modalValues(data)
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredMessageUpdate,
+	})
+
+	var notice string
+	switch data.CustomID {
+	case setupDefaultsModal:
+		notice = saveSetupDefaults(i, ctx, values)
+	case setupEnrollModal:
+		notice = setupEnrollVault(s, i, ctx, values)
+	default:
+		ctx.Logger.Warnf("Unknown setup form %q", data.CustomID)
+		return
+	}
+
+	editSetupMessage(s, i, ctx, notice)
+}
+
+// saveSetupDefaults stores the server's default threshold and interval, returning the wizard notice
+func saveSetupDefaults(i *discordgo.InteractionCreate, ctx *CommandContext, values map[string]string) string {
+	var threshold float64
+	if values[setupFieldThreshold] != "" {
+		parsed, err := types.ParseThreshold(values[setupFieldThreshold])
+		if err != nil {
+			return "❌ Default " + err.Error()
+		}
+		threshold = parsed
+	}
+
+	global := ctx.Config.Monitor.CheckIntervalMinutes
+	interval, err := strconv.Atoi(values[setupFieldInterval])
+	if err != nil || interval < global {
+		return fmt.Sprintf("❌ Check interval must be a whole number of minutes, at least the global %d", global)
+	}
+	if interval == global {
+		interval = 0
+	}
+
+	err = updateGuildSettings(i, ctx, func(g *types.GuildSettings) {
+		g.DefaultThreshold = threshold
+		g.CheckIntervalMinutes = interval
+	})
+	if err != nil {
+		return fmt.Sprintf("❌ Failed to save defaults: %v", err)
+	}
+	return "✅ Defaults saved"
+}
+
+// setupEnrollVault enrolls the vault from the wizard's form in the server's alert channel,
+// returning the wizard notice
+func setupEnrollVault(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext, values map[string]string) string {
+	threshold, err := types.ParseThreshold(values[setupFieldThreshold])
+	if err != nil {
+		return "❌ " + err.Error()
+	}
+
+	vault := &types.VaultConfig{
+		Nickname:         values[setupFieldNickname],
+		ThresholdPercent: threshold,
+		ChannelID:        ctx.Storage.GetSettings().Guild(i.GuildID).AlertChannelID,
+		EnrollSource:     types.EnrollSourceSetup,
+	}
+	if vault.ChannelID == "" {
+		vault.ChannelID = i.ChannelID
+	}
+
+	if err := enrollVault(s, i, ctx, values[setupFieldURL], vault); err != nil {
+		return "❌ " + err.Error()
+	}
+	return fmt.Sprintf("✅ Enrolled `%s` (\"%s\", %s) at %s; alerts go to <#%s>. Try `/status %s` to see its current rates.",
+		vault.VaultID, vault.Nickname, vault.MarketPair, vault.DescribeThreshold(), vault.ChannelID, vault.VaultID)
+}
+
+// editSetupMessage re-renders the wizard in place after a deferred update
+func editSetupMessage(s *discordgo.Session, i *discordgo.InteractionCreate, ctx *CommandContext, notice string) {
+	content, components := setupMessage(s, i, ctx, notice)
+	if _, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content:    &content,
+		Components: &components,
+	}); err != nil {
+		ctx.Logger.Errorf("Failed to update setup message: %v", err)
+	}
+}
+
+// updateGuildSettings applies update to the interaction's server defaults and records who changed them
+func updateGuildSettings(i *discordgo.InteractionCreate, ctx *CommandContext, update func(guild *types.GuildSettings)) error {
+	return ctx.Storage.UpdateSettings(func(settings *types.Settings) {
+		guild := settings.Guild(i.GuildID)
+		update(&guild)
+		guild.SetupBy = interactionUserID(i)
+		guild.SetupAt = time.Now()
+		*settings = settings.WithGuild(i.GuildID, guild)
+	})
+}
+
+// findOrCreateAlertChannel returns the server's text channel called name, creating it if needed
+func findOrCreateAlertChannel(s *discordgo.Session, guildID, name string) (*discordgo.Channel, error) {
+	channels, err := s.GuildChannels(guildID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list channels: %w", err)
+	}
+	for _, channel := range channels {
+		if channel.Type == discordgo.ChannelTypeGuildText && channel.Name == name {
+			return channel, nil
+		}
+	}
+	return createAlertChannel(s, guildID, name, "Borrow rate alerts from SummerRateChecker", "")
+}
+
+// createAlertChannel creates a text channel for alerts, under the category parentID if set. The
+// channel is read-only for @everyone so alerts aren't buried in chatter.
+func createAlertChannel(s *discordgo.Session, guildID, name, topic, parentID string) (*discordgo.Channel, error) {
+	return s.GuildChannelCreateComplex(guildID, discordgo.GuildChannelCreateData{
+		Name:     name,
+		Type:     discordgo.ChannelTypeGuildText,
+		Topic:    topic,
+		ParentID: parentID,
+		PermissionOverwrites: []*discordgo.PermissionOverwrite{
+			{
+				ID:    guildID, // The @everyone role shares the server's ID
+				Type:  discordgo.PermissionOverwriteTypeRole,
+				Allow: discordgo.PermissionViewChannel | discordgo.PermissionReadMessageHistory,
+				Deny:  discordgo.PermissionSendMessages,
+			},
+			{
+				ID:    s.State.User.ID,
+				Type:  discordgo.PermissionOverwriteTypeMember,
+				Allow: alertChannelPermissions,
+			},
+		},
+	})
+}
+
+// respondModal opens a form with one text input per row
+func respondModal(s *discordgo.Session, i *discordgo.InteractionCreate, customID, title string, inputs ...discordgo.TextInput) {
+	rows := make([]discordgo.MessageComponent, 0, len(inputs))
+	for _, input := range inputs {
+		rows = append(rows, discordgo.ActionsRow{Components: []discordgo.MessageComponent{input}})
+	}
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			CustomID:   customID,
+			Title:      title,
+			Components: rows,
+		},
+	})
+}
+
+// modalValues returns a submitted form's text inputs by custom ID
+func modalValues(data discordgo.ModalSubmitInteractionData) map[string]string {
+	values := make(map[string]string)
+	for _, component := range data.Components {
+		row, ok := component.(*discordgo.ActionsRow)
+		if !ok {
+			continue
+		}
+		for _, field := range row.Components {
+			if input, ok := field.(*discordgo.TextInput); ok {
+				values[input.CustomID] = strings.TrimSpace(input.Value)
+			}
+		}
+	}
+	return values
+}
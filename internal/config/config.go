@@ -1,86 +1,407 @@
-package config
-
-import (
-	"fmt"
-	"strings"
-
-	"github.com/joho/godotenv"
-	"github.com/spf13/viper"
-)
-
-type Config struct {
-	Discord Discord `mapstructure:"discord"`
-	Morpho  Morpho  `mapstructure:"morpho"`
-	Monitor Monitor `mapstructure:"monitor"`
-}
-
-type Discord struct {
-	Token   string `mapstructure:"token"`
-	GuildID string `mapstructure:"guild_id"`
-}
-
-type Morpho struct {
-	APIURL string `mapstructure:"api_url"`
-}
-
-type Monitor struct {
-	CheckIntervalMinutes int `mapstructure:"check_interval_minutes"`
-}
-
-func Load() (*Config, error) {
-	// Load .env file if it exists
-	godotenv.Load()
-
-	// Set up viper
-	viper.SetConfigName("config")
-	viper.SetConfigType("toml")
-	viper.AddConfigPath(".")
-	viper.AddConfigPath("./config")
-
-	// Set up environment variable mapping
-	viper.SetEnvPrefix("SUMMER")
-	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
-	viper.AutomaticEnv()
-
-	// Set defaults
-	viper.SetDefault("morpho.api_url", "https://blue-api.morpho.org/graphql")
-	viper.SetDefault("monitor.check_interval_minutes", 60)
-
-	// Read config file
-	if err := viper.ReadInConfig(); err != nil {
-		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
-			return nil, err
-		}
-		// Config file not found is OK - we can use env vars
-		fmt.Println("No config file found, using environment variables")
-	} else {
-		fmt.Printf("Using config file: %s\n", viper.ConfigFileUsed())
-	}
-
-	var config Config
-	if err := viper.Unmarshal(&config); err != nil {
-		return nil, err
-	}
-
-	// Debug: print token validation
-	token := strings.TrimSpace(config.Discord.Token)
-	config.Discord.Token = token // Clean up any whitespace
-
-	fmt.Printf("Token length: %d\n", len(token))
-	fmt.Printf("Token starts with: %s\n", func() string {
-		if len(token) > 3 {
-			return token[:3]
-		}
-		return token
-	}())
-
-	// Validate token format
-	if len(token) < 50 {
-		fmt.Println("WARNING: Token seems too short")
-	}
-	if !strings.Contains(token, ".") {
-		fmt.Println("WARNING: Token doesn't contain expected dots")
-	}
-
-	return &config, nil
-}
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/morrisonbrett/SummerRateChecker/internal/types"
+	"github.com/spf13/viper"
+)
+
+type Config struct {
+	Profile       string        `mapstructure:"-"` // The profile layered over config.toml, if any
+	Discord       Discord       `mapstructure:"discord"`
+	Morpho        Morpho        `mapstructure:"morpho"`
+	SummerFi      SummerFi      `mapstructure:"summerfi"`
+	Compare       Compare       `mapstructure:"compare"`
+	Monitor       Monitor       `mapstructure:"monitor"`
+	HTTP          HTTP          `mapstructure:"http"`
+	HomeAssistant HomeAssistant `mapstructure:"homeassistant"`
+	Notify        Notify        `mapstructure:"notify"`
+	Backup        Backup        `mapstructure:"backup"`
+	Limits        Limits        `mapstructure:"limits"`
+	Mirror        Mirror        `mapstructure:"mirror"`
+	Report        Report        `mapstructure:"report"`
+}
+
+type Discord struct {
+	Token        string   `mapstructure:"token"`
+	GuildID      string   `mapstructure:"guild_id"`
+	GuildIDs     []string `mapstructure:"guild_ids"`      // Additional servers to register commands in (optional)
+	OpsChannelID string   `mapstructure:"ops_channel_id"` // Channel for operational notices, e.g. storage recovery (optional)
+
+	OutageAlertMinutes int `mapstructure:"outage_alert_minutes"` // Notify the ops channel when the gateway stays disconnected this long (0 disables)
+
+	// ReadOnly registers only the commands that show data, and turns off the HTTP write endpoints,
+	// so the bot can mirror another instance's data into a public server
+	ReadOnly bool `mapstructure:"read_only"`
+
+	// Sharding splits guilds across gateway connections, one bot process per shard
+	ShardID    int `mapstructure:"shard_id"`
+	ShardCount int `mapstructure:"shard_count"` // 1 disables sharding
+}
+
+// Guilds returns the configured server IDs, guild_id first, without duplicates
+func (d Discord) Guilds() []string {
+	var guilds []string
+	seen := make(map[string]bool)
+	for _, id := range append([]string{d.GuildID}, d.GuildIDs...) {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		guilds = append(guilds, id)
+	}
+	return guilds
+}
+
+type Morpho struct {
+	APIURL string `mapstructure:"api_url"`
+}
+
+// SummerFi enables position metadata from Summer.fi's API, shown in /status and usable in rules
+type SummerFi struct {
+	APIURL string `mapstructure:"api_url"` // Summer.fi positions GraphQL endpoint (empty disables)
+}
+
+// Compare adds other protocols' borrow rates for the same loan asset to alerts
+type Compare struct {
+	APIURL    string   `mapstructure:"api_url"`   // DefiLlama yields API, e.g. https://yields.llama.fi (empty disables)
+	Protocols []string `mapstructure:"protocols"` // DefiLlama project slugs to compare, e.g. aave-v3, spark
+}
+
+type Monitor struct {
+	CheckIntervalMinutes  int     `mapstructure:"check_interval_minutes"`
+	EscalateAfterChecks   int     `mapstructure:"escalate_after_checks"`   // 0 disables escalation
+	CriticalRepingMinutes int     `mapstructure:"critical_reping_minutes"` // Re-ping unacknowledged critical alerts after this long (0 disables)
+	FailureAlertAfter     int     `mapstructure:"failure_alert_after"`     // Warn the channel after this many consecutive fetch failures (0 disables)
+	DisableAfterFailures  int     `mapstructure:"disable_after_failures"`  // Stop checking a vault after this many consecutive failures (0 disables)
+	ProjectionUtilization float64 `mapstructure:"projection_utilization"`  // Add a borrow rate projection at this utilization % to alerts (0 disables)
+	LiquidityAlertPercent float64 `mapstructure:"liquidity_alert_percent"` // Alert when total supply or borrow moves this much within the window (0 disables)
+	LiquidityWindowMin    int     `mapstructure:"liquidity_window_minutes"`
+	KinkAlerts            bool    `mapstructure:"kink_alerts"`          // Warn when utilization nears the IRM's target, where rates rise steeply
+	KinkWarningMargin     float64 `mapstructure:"kink_warning_margin"`  // Warn this many utilization points before the target (0 = on crossing it)
+	HeadroomMargin        float64 `mapstructure:"headroom_margin"`      // Warn when a position's LTV is within this many points of the market's LLTV (0 disables)
+	UnenrollGraceHours    int     `mapstructure:"unenroll_grace_hours"` // How long /restore can bring back an unenrolled vault before it's deleted
+	RateDecimals          int     `mapstructure:"rate_decimals"`        // Decimal places rates are shown with (2-4); vaults can override it with /precision
+	RateUnit              string  `mapstructure:"rate_unit"`            // Show rates in percent or bps (basis points)
+	Locale                string  `mapstructure:"locale"`               // Number separators: en (1,234.56), de (1.234,56) or fr (1 234,56)
+	CallBudget            int     `mapstructure:"call_budget"`          // Most market fetches per scheduled check; the rest wait, lowest priority first (0 = unlimited)
+	LowPriorityBackoff    int     `mapstructure:"low_priority_backoff"` // Under API pressure, check low-priority vaults every this many intervals
+}
+
+// RateFormat returns how rates are shown by default; vaults can change the decimals with /precision.
+// Load has already validated the unit and locale.
+func (m Monitor) RateFormat() types.RateFormat {
+	unit, _ := types.ParseRateUnit(m.RateUnit)
+	locale, _ := types.ParseLocale(m.Locale)
+	return types.RateFormat{Decimals: m.RateDecimals, Unit: unit, Locale: locale}
+}
+
+type HTTP struct {
+	Enabled     bool   `mapstructure:"enabled"`
+	ListenAddr  string `mapstructure:"listen_addr"`
+	APIToken    string `mapstructure:"api_token"`    // Bearer token granting every scope; scoped tokens are created with /api-token-create
+	RequireAuth bool   `mapstructure:"require_auth"` // Require a token with the read scope for Grafana, metrics, events, and GraphQL
+	OAuth       OAuth  `mapstructure:"oauth"`
+}
+
+// OAuth enables Discord login for the web dashboard
+type OAuth struct {
+	ClientID     string `mapstructure:"client_id"`
+	ClientSecret string `mapstructure:"client_secret"`
+	RedirectURL  string `mapstructure:"redirect_url"` // e.g. https://rates.example.com/auth/callback
+}
+
+type HomeAssistant struct {
+	Enabled         bool   `mapstructure:"enabled"`
+	BrokerURL       string `mapstructure:"broker_url"`
+	ClientID        string `mapstructure:"client_id"`
+	Username        string `mapstructure:"username"`
+	Password        string `mapstructure:"password"`
+	DiscoveryPrefix string `mapstructure:"discovery_prefix"`
+	TopicPrefix     string `mapstructure:"topic_prefix"`
+}
+
+// Limits keep one user from overloading a shared instance
+type Limits struct {
+	CheckCooldownSeconds int `mapstructure:"check_cooldown_seconds"` // Minimum time between one user's /check runs (0 disables)
+	MaxVaultsPerUser     int `mapstructure:"max_vaults_per_user"`    // Vaults one user may enroll per server (0 = unlimited)
+	MaxVaultsPerGuild    int `mapstructure:"max_vaults_per_guild"`   // Vaults that may be enrolled in one server (0 = unlimited)
+}
+
+// Mirror runs the bot as a read-only view of another instance's data directory, e.g. to serve its
+// vaults in another server. The other instance checks rates and sends alerts; a mirror does neither.
+type Mirror struct {
+	DataDir       string `mapstructure:"data_dir"`       // The other instance's data directory, on shared storage (empty disables mirror mode)
+	ReloadSeconds int    `mapstructure:"reload_seconds"` // How often to re-read it
+}
+
+// Enabled reports whether the bot runs as a mirror
+func (m Mirror) Enabled() bool {
+	return m.DataDir != ""
+}
+
+// Report posts a weekly report to each server, with a chart of the week's borrow rates
+type Report struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Weekday string `mapstructure:"weekday"`  // Day the report is posted, e.g. "monday"
+	Hour    int    `mapstructure:"hour"`     // UTC hour the report is posted (0-23)
+	GroupBy string `mapstructure:"group_by"` // "vault" charts one line per vault, "tag" one per /tag group
+}
+
+// Day parses Weekday
+func (r Report) Day() (time.Weekday, error) {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.EqualFold(r.Weekday, day.String()) {
+			return day, nil
+		}
+	}
+	return 0, fmt.Errorf("report.weekday must be a day of the week, got %q", r.Weekday)
+}
+
+// Backup configures periodic storage snapshots, kept locally and optionally uploaded off-host
+type Backup struct {
+	Enabled       bool     `mapstructure:"enabled"`
+	IntervalHours int      `mapstructure:"interval_hours"`
+	LocalDir      string   `mapstructure:"local_dir"`
+	KeepLocal     int      `mapstructure:"keep_local"` // Number of local snapshots to keep
+	S3            BackupS3 `mapstructure:"s3"`
+}
+
+// BackupS3 uploads snapshots to an S3-compatible bucket (AWS, R2, MinIO, or GCS with HMAC keys)
+type BackupS3 struct {
+	Endpoint  string `mapstructure:"endpoint"` // e.g. https://s3.us-east-1.amazonaws.com
+	Region    string `mapstructure:"region"`
+	Bucket    string `mapstructure:"bucket"`
+	Prefix    string `mapstructure:"prefix"`
+	AccessKey string `mapstructure:"access_key"`
+	SecretKey string `mapstructure:"secret_key"`
+	PathStyle bool   `mapstructure:"path_style"` // Use endpoint/bucket/key instead of bucket.endpoint/key
+}
+
+// Notify configures alert sinks beyond the per-vault Discord webhooks
+type Notify struct {
+	PagerDuty PagerDuty `mapstructure:"pagerduty"`
+	Opsgenie  Opsgenie  `mapstructure:"opsgenie"`
+	Matrix    Matrix    `mapstructure:"matrix"`
+	Twilio    Twilio    `mapstructure:"twilio"`
+	Webhook   Webhook   `mapstructure:"webhook"`
+	Slack     Slack     `mapstructure:"slack"`
+}
+
+type PagerDuty struct {
+	RoutingKey string `mapstructure:"routing_key"`
+}
+
+type Opsgenie struct {
+	APIKey string `mapstructure:"api_key"`
+	APIURL string `mapstructure:"api_url"`
+}
+
+type Matrix struct {
+	HomeserverURL string `mapstructure:"homeserver_url"`
+	AccessToken   string `mapstructure:"access_token"`
+	RoomID        string `mapstructure:"room_id"`
+	MinSeverity   string `mapstructure:"min_severity"` // "warning" or "critical"
+}
+
+// Twilio sends SMS for critical alerts only
+type Twilio struct {
+	AccountSID string   `mapstructure:"account_sid"`
+	AuthToken  string   `mapstructure:"auth_token"`
+	FromNumber string   `mapstructure:"from_number"`
+	ToNumbers  []string `mapstructure:"to_numbers"`
+}
+
+// Webhook posts alerts to any HTTP endpoint, for automation
+type Webhook struct {
+	URL         string `mapstructure:"url"`
+	Format      string `mapstructure:"format"`       // "json" (the versioned alert schema), "discord" (embeds), "both", or "simple" (flat, for IFTTT and Zapier)
+	MinSeverity string `mapstructure:"min_severity"` // "warning" or "critical"
+	AuthHeader  string `mapstructure:"auth_header"`  // Sent as the Authorization header, e.g. "Bearer ..." (optional)
+}
+
+// Slack mirrors alerts to a channel through an incoming webhook. Vaults can add their own with /slack.
+type Slack struct {
+	WebhookURL  string `mapstructure:"webhook_url"`
+	MinSeverity string `mapstructure:"min_severity"` // "warning" or "critical"
+}
+
+// profile is the config profile chosen with SetProfile
+var profile string
+
+// SetProfile chooses a named profile, e.g. "dev", whose config.<name>.toml is layered over
+// config.toml by Load. It takes precedence over SUMMER_PROFILE.
+func SetProfile(name string) {
+	profile = strings.TrimSpace(name)
+}
+
+// activeProfile returns the profile set with SetProfile, or else SUMMER_PROFILE
+func activeProfile() string {
+	if profile != "" {
+		return profile
+	}
+	return strings.TrimSpace(os.Getenv("SUMMER_PROFILE"))
+}
+
+// Load reads the configuration in layers, each overriding the one before: defaults, config.toml,
+// config.<profile>.toml when a profile is active, then SUMMER_* environment variables. A profile
+// file only needs the keys that differ, so a test bot and a production bot can share the rest.
+func Load() (*Config, error) {
+	// Load .env file if it exists
+	godotenv.Load()
+
+	// Set up viper
+	viper.SetConfigName("config")
+	viper.SetConfigType("toml")
+	viper.AddConfigPath(".")
+	viper.AddConfigPath("./config")
+
+	// Set up environment variable mapping
+	viper.SetEnvPrefix("SUMMER")
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	viper.AutomaticEnv()
+
+	// Set defaults
+	viper.SetDefault("discord.outage_alert_minutes", 5)
+	viper.SetDefault("discord.read_only", false)
+	viper.SetDefault("discord.shard_id", 0)
+	viper.SetDefault("discord.shard_count", 1)
+	viper.SetDefault("morpho.api_url", "https://blue-api.morpho.org/graphql")
+	viper.SetDefault("summerfi.api_url", "")
+	viper.SetDefault("compare.api_url", "")
+	viper.SetDefault("compare.protocols", []string{"aave-v3", "spark"})
+	viper.SetDefault("monitor.check_interval_minutes", 60)
+	viper.SetDefault("monitor.escalate_after_checks", 3)
+	viper.SetDefault("monitor.critical_reping_minutes", 30)
+	viper.SetDefault("monitor.failure_alert_after", 3)
+	viper.SetDefault("monitor.disable_after_failures", 24)
+	viper.SetDefault("monitor.projection_utilization", 0)
+	viper.SetDefault("monitor.liquidity_alert_percent", 10)
+	viper.SetDefault("monitor.liquidity_window_minutes", 60)
+	viper.SetDefault("monitor.kink_alerts", true)
+	viper.SetDefault("monitor.kink_warning_margin", 0)
+	viper.SetDefault("monitor.headroom_margin", 5)
+	viper.SetDefault("monitor.unenroll_grace_hours", 72)
+	viper.SetDefault("monitor.rate_decimals", 2)
+	viper.SetDefault("monitor.rate_unit", "percent")
+	viper.SetDefault("monitor.locale", "en")
+	viper.SetDefault("monitor.call_budget", 0)
+	viper.SetDefault("monitor.low_priority_backoff", 4)
+	viper.SetDefault("http.enabled", false)
+	viper.SetDefault("http.listen_addr", "127.0.0.1:8080")
+	viper.SetDefault("http.api_token", "")
+	viper.SetDefault("http.require_auth", false)
+	viper.SetDefault("http.oauth.client_id", "")
+	viper.SetDefault("http.oauth.client_secret", "")
+	viper.SetDefault("http.oauth.redirect_url", "")
+	viper.SetDefault("mirror.data_dir", "")
+	viper.SetDefault("mirror.reload_seconds", 60)
+	viper.SetDefault("report.enabled", false)
+	viper.SetDefault("report.weekday", "monday")
+	viper.SetDefault("report.hour", 9)
+	viper.SetDefault("report.group_by", "vault")
+	viper.SetDefault("backup.enabled", false)
+	viper.SetDefault("backup.interval_hours", 24)
+	viper.SetDefault("backup.local_dir", "data/backups")
+	viper.SetDefault("backup.keep_local", 7)
+	viper.SetDefault("backup.s3.region", "us-east-1")
+	viper.SetDefault("backup.s3.prefix", "summer-rate-checker/")
+	viper.SetDefault("limits.check_cooldown_seconds", 60)
+	viper.SetDefault("limits.max_vaults_per_user", 25)
+	viper.SetDefault("limits.max_vaults_per_guild", 100)
+	viper.SetDefault("homeassistant.enabled", false)
+	viper.SetDefault("homeassistant.client_id", "summer-rate-checker")
+	viper.SetDefault("homeassistant.discovery_prefix", "homeassistant")
+	viper.SetDefault("homeassistant.topic_prefix", "summer_rate_checker")
+	viper.SetDefault("notify.opsgenie.api_url", "https://api.opsgenie.com")
+	viper.SetDefault("notify.matrix.homeserver_url", "https://matrix.org")
+	viper.SetDefault("notify.matrix.min_severity", "warning")
+	viper.SetDefault("notify.webhook.format", "json")
+	viper.SetDefault("notify.webhook.min_severity", "warning")
+	viper.SetDefault("notify.slack.min_severity", "warning")
+
+	// Read config file
+	if err := viper.ReadInConfig(); err != nil {
+		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
+			return nil, err
+		}
+		// Config file not found is OK - we can use env vars
+		fmt.Println("No config file found, using environment variables")
+	} else {
+		fmt.Printf("Using config file: %s\n", viper.ConfigFileUsed())
+	}
+
+	// Layer the profile's file over the base one. Unlike config.toml, it must exist if asked for.
+	active := activeProfile()
+	if active != "" {
+		viper.SetConfigName("config." + active)
+		if err := viper.MergeInConfig(); err != nil {
+			return nil, fmt.Errorf("failed to load config profile %q: %w", active, err)
+		}
+		fmt.Printf("Using config profile %s: %s\n", active, viper.ConfigFileUsed())
+	}
+
+	var config Config
+	if err := viper.Unmarshal(&config); err != nil {
+		return nil, err
+	}
+	config.Profile = active
+
+	if _, err := types.ParseRateUnit(config.Monitor.RateUnit); err != nil {
+		return nil, fmt.Errorf("monitor.rate_unit: %w", err)
+	}
+	if _, err := types.ParseLocale(config.Monitor.Locale); err != nil {
+		return nil, fmt.Errorf("monitor.locale: %w", err)
+	}
+
+	if config.Report.Enabled {
+		if _, err := config.Report.Day(); err != nil {
+			return nil, err
+		}
+		if config.Report.Hour < 0 || config.Report.Hour > 23 {
+			return nil, fmt.Errorf("report.hour must be between 0 and 23, got %d", config.Report.Hour)
+		}
+		if config.Report.GroupBy != "vault" && config.Report.GroupBy != "tag" {
+			return nil, fmt.Errorf("report.group_by must be vault or tag, got %q", config.Report.GroupBy)
+		}
+	}
+
+	switch config.Notify.Webhook.Format {
+	case "json", "discord", "both", "simple":
+	default:
+		return nil, fmt.Errorf("notify.webhook.format must be json, discord, both, or simple, got %q", config.Notify.Webhook.Format)
+	}
+
+	// A mirror can't change the data it follows, so it only offers the read-only commands
+	if config.Mirror.Enabled() {
+		config.Discord.ReadOnly = true
+	}
+
+	// Debug: print token validation
+	token := strings.TrimSpace(config.Discord.Token)
+	config.Discord.Token = token // Clean up any whitespace
+
+	fmt.Printf("Token length: %d\n", len(token))
+	fmt.Printf("Token starts with: %s\n", func() string {
+		if len(token) > 3 {
+			return token[:3]
+		}
+		return token
+	}())
+
+	// Validate token format
+	if len(token) < 50 {
+		fmt.Println("WARNING: Token seems too short")
+	}
+	if !strings.Contains(token, ".") {
+		fmt.Println("WARNING: Token doesn't contain expected dots")
+	}
+
+	return &config, nil
+}
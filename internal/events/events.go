@@ -0,0 +1,88 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Type identifies the kind of event published on the broker
+type Type string
+
+const (
+	TypeRateUpdate Type = "rate_update"
+	TypeAlert      Type = "alert"
+)
+
+// subscriberBuffer is how many events a slow subscriber may fall behind before events are dropped
+const subscriberBuffer = 64
+
+// Event is a single message delivered to subscribers
+type Event struct {
+	Type      Type        `json:"type"`
+	Timestamp time.Time   `json:"timestamp"`
+	Data      interface{} `json:"data"`
+}
+
+// RateUpdate is the payload of a TypeRateUpdate event
+type RateUpdate struct {
+	VaultID    string  `json:"vault_id"`
+	Nickname   string  `json:"nickname"`
+	MarketPair string  `json:"market_pair,omitempty"`
+	BorrowRate float64 `json:"borrow_rate"`
+	SupplyRate float64 `json:"supply_rate"`
+}
+
+// Broker fans published events out to all current subscribers
+type Broker struct {
+	mu          sync.Mutex
+	subscribers map[chan Event]struct{}
+}
+
+func NewBroker() *Broker {
+	return &Broker{
+		subscribers: make(map[chan Event]struct{}),
+	}
+}
+
+// Subscribe registers a new subscriber. Call the returned function to unsubscribe.
+func (b *Broker) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+
+	b.mu.Lock()
+	b.subscribers[ch] = struct{}{}
+	b.mu.Unlock()
+
+	unsubscribe := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subscribers[ch]; ok {
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+
+	return ch, unsubscribe
+}
+
+// Publish delivers an event to every subscriber without blocking.
+// Subscribers that are too far behind miss the event rather than stalling the monitor.
+func (b *Broker) Publish(eventType Type, data interface{}) {
+	if b == nil {
+		return
+	}
+
+	event := Event{
+		Type:      eventType,
+		Timestamp: time.Now(),
+		Data:      data,
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
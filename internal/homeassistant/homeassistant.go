@@ -0,0 +1,235 @@
+package homeassistant
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/morrisonbrett/SummerRateChecker/internal/config"
+	"github.com/morrisonbrett/SummerRateChecker/internal/events"
+	"github.com/morrisonbrett/SummerRateChecker/internal/types"
+	"go.uber.org/zap"
+)
+
+// publishTimeout bounds how long a single MQTT publish may block
+const publishTimeout = 10 * time.Second
+
+var nodeIDPattern = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// Bridge publishes each vault as Home Assistant entities using MQTT discovery
+type Bridge struct {
+	config *config.HomeAssistant
+	client mqtt.Client
+	logger *zap.SugaredLogger
+
+	mu         sync.Mutex
+	discovered map[string]bool
+	states     map[string]*vaultState
+}
+
+// vaultState is the JSON document published on each vault's state topic
+type vaultState struct {
+	BorrowRate      float64 `json:"borrow_rate"`
+	SupplyRate      float64 `json:"supply_rate"`
+	Change          float64 `json:"change"`
+	Alert           string  `json:"alert"`
+	MarketPair      string  `json:"market_pair,omitempty"`
+	LastAlertAt     string  `json:"last_alert_at,omitempty"`
+	LastAlertChange float64 `json:"last_alert_change,omitempty"`
+}
+
+// discoveryConfig is a Home Assistant MQTT discovery payload
+type discoveryConfig struct {
+	Name                string          `json:"name"`
+	UniqueID            string          `json:"unique_id"`
+	StateTopic          string          `json:"state_topic"`
+	ValueTemplate       string          `json:"value_template"`
+	JSONAttributesTopic string          `json:"json_attributes_topic,omitempty"`
+	UnitOfMeasurement   string          `json:"unit_of_measurement,omitempty"`
+	StateClass          string          `json:"state_class,omitempty"`
+	DeviceClass         string          `json:"device_class,omitempty"`
+	PayloadOn           string          `json:"payload_on,omitempty"`
+	PayloadOff          string          `json:"payload_off,omitempty"`
+	Device              discoveryDevice `json:"device"`
+}
+
+type discoveryDevice struct {
+	Identifiers  []string `json:"identifiers"`
+	Name         string   `json:"name"`
+	Manufacturer string   `json:"manufacturer"`
+	Model        string   `json:"model,omitempty"`
+}
+
+func New(cfg *config.HomeAssistant, logger *zap.SugaredLogger) *Bridge {
+	opts := mqtt.NewClientOptions().
+		AddBroker(cfg.BrokerURL).
+		SetClientID(cfg.ClientID).
+		SetUsername(cfg.Username).
+		SetPassword(cfg.Password).
+		SetAutoReconnect(true)
+
+	return &Bridge{
+		config:     cfg,
+		client:     mqtt.NewClient(opts),
+		logger:     logger,
+		discovered: make(map[string]bool),
+		states:     make(map[string]*vaultState),
+	}
+}
+
+// Start connects to the broker and publishes entity updates from broker events until it is closed
+func (b *Bridge) Start(broker *events.Broker) error {
+	token := b.client.Connect()
+	if !token.WaitTimeout(publishTimeout) {
+		return fmt.Errorf("timed out connecting to MQTT broker %s", b.config.BrokerURL)
+	}
+	if err := token.Error(); err != nil {
+		return fmt.Errorf("failed to connect to MQTT broker: %w", err)
+	}
+
+	b.logger.Infof("Connected to MQTT broker %s for Home Assistant discovery", b.config.BrokerURL)
+
+	updates, _ := broker.Subscribe()
+	go func() {
+		for event := range updates {
+			b.handleEvent(event)
+		}
+	}()
+
+	return nil
+}
+
+func (b *Bridge) Stop() {
+	b.client.Disconnect(250)
+}
+
+func (b *Bridge) handleEvent(event events.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch data := event.Data.(type) {
+	case events.RateUpdate:
+		state, exists := b.states[data.VaultID]
+		if !exists {
+			state = &vaultState{}
+			b.states[data.VaultID] = state
+		}
+		if exists {
+			state.Change = data.BorrowRate - state.BorrowRate
+		}
+		state.BorrowRate = data.BorrowRate
+		state.SupplyRate = data.SupplyRate
+		state.MarketPair = data.MarketPair
+		state.Alert = "OFF" // Cleared each cycle; an alert event later in the cycle turns it back on
+
+		if !b.discovered[data.VaultID] {
+			if err := b.publishDiscovery(data.VaultID, data.Nickname, data.MarketPair); err != nil {
+				b.logger.Errorf("Failed to publish Home Assistant discovery for %s: %v", data.VaultID, err)
+				return
+			}
+			b.discovered[data.VaultID] = true
+		}
+		b.publishState(data.VaultID, state)
+
+	case *types.RateChangeAlert:
+		state, exists := b.states[data.VaultID]
+		if !exists {
+			return
+		}
+		state.Alert = "ON"
+		state.LastAlertAt = data.Timestamp.Format(time.RFC3339)
+		state.LastAlertChange = data.ChangePercent
+		b.publishState(data.VaultID, state)
+	}
+}
+
+func (b *Bridge) publishDiscovery(vaultID, nickname, marketPair string) error {
+	nodeID := b.nodeID(vaultID)
+	stateTopic := b.stateTopic(vaultID)
+	device := discoveryDevice{
+		Identifiers:  []string{nodeID},
+		Name:         nickname,
+		Manufacturer: "SummerRateChecker",
+		Model:        marketPair,
+	}
+
+	entities := map[string]discoveryConfig{
+		"sensor/" + nodeID + "/borrow_rate": {
+			Name:                "Borrow Rate",
+			UniqueID:            nodeID + "_borrow_rate",
+			StateTopic:          stateTopic,
+			ValueTemplate:       "{{ value_json.borrow_rate }}",
+			JSONAttributesTopic: stateTopic,
+			UnitOfMeasurement:   "%",
+			StateClass:          "measurement",
+			Device:              device,
+		},
+		"sensor/" + nodeID + "/supply_rate": {
+			Name:              "Supply Rate",
+			UniqueID:          nodeID + "_supply_rate",
+			StateTopic:        stateTopic,
+			ValueTemplate:     "{{ value_json.supply_rate }}",
+			UnitOfMeasurement: "%",
+			StateClass:        "measurement",
+			Device:            device,
+		},
+		"sensor/" + nodeID + "/change": {
+			Name:              "Rate Change",
+			UniqueID:          nodeID + "_change",
+			StateTopic:        stateTopic,
+			ValueTemplate:     "{{ value_json.change }}",
+			UnitOfMeasurement: "pp",
+			StateClass:        "measurement",
+			Device:            device,
+		},
+		"binary_sensor/" + nodeID + "/alert": {
+			Name:          "Rate Alert",
+			UniqueID:      nodeID + "_alert",
+			StateTopic:    stateTopic,
+			ValueTemplate: "{{ value_json.alert }}",
+			DeviceClass:   "problem",
+			PayloadOn:     "ON",
+			PayloadOff:    "OFF",
+			Device:        device,
+		},
+	}
+
+	for path, entity := range entities {
+		topic := fmt.Sprintf("%s/%s/config", b.config.DiscoveryPrefix, path)
+		if err := b.publish(topic, entity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *Bridge) publishState(vaultID string, state *vaultState) {
+	if err := b.publish(b.stateTopic(vaultID), state); err != nil {
+		b.logger.Errorf("Failed to publish Home Assistant state for %s: %v", vaultID, err)
+	}
+}
+
+// publish sends payload as retained JSON so Home Assistant restores entities after a restart
+func (b *Bridge) publish(topic string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal MQTT payload: %w", err)
+	}
+
+	token := b.client.Publish(topic, 1, true, data)
+	if !token.WaitTimeout(publishTimeout) {
+		return fmt.Errorf("timed out publishing to %s", topic)
+	}
+	return token.Error()
+}
+
+func (b *Bridge) nodeID(vaultID string) string {
+	return "summer_rate_checker_" + nodeIDPattern.ReplaceAllString(vaultID, "_")
+}
+
+func (b *Bridge) stateTopic(vaultID string) string {
+	return fmt.Sprintf("%s/%s/state", b.config.TopicPrefix, nodeIDPattern.ReplaceAllString(vaultID, "_"))
+}
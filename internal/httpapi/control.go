@@ -0,0 +1,174 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/morrisonbrett/SummerRateChecker/internal/morpho"
+	"github.com/morrisonbrett/SummerRateChecker/internal/storage"
+	"github.com/morrisonbrett/SummerRateChecker/internal/types"
+)
+
+// Controller performs actions that need the Discord session. The bot implements it.
+type Controller interface {
+	TriggerCheck(req types.CheckRequest) bool
+	CreateWebhook(channelID string) (string, error)
+	DeleteWebhook(webhookURL string) error
+	ChannelGuild(channelID string) (string, error)
+}
+
+type createVaultRequest struct {
+	URL       string  `json:"url"`
+	Nickname  string  `json:"nickname"`
+	Threshold float64 `json:"threshold"`
+	ChannelID string  `json:"channel_id"`
+}
+
+// SetController enables the write endpoints
+func (s *Server) SetController(controller Controller) {
+	s.controller = controller
+}
+
+func (s *Server) registerControlRoutes(mux *http.ServeMux) {
+	mux.Handle("/trigger-check", s.requireScope(types.ScopeTriggerChecks, s.requireWrite(http.MethodPost, s.handleTriggerCheck)))
+	s.registerVaultRoutes(mux)
+}
+
+// requireWrite rejects methods other than method, answers 403 in read-only mode, and answers 503
+// until the bot is connected
+func (s *Server) requireWrite(method string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != method {
+			s.writeError(w, http.StatusMethodNotAllowed, "use "+method)
+			return
+		}
+		if s.config.Discord.ReadOnly {
+			s.writeError(w, http.StatusForbidden, "this instance is read-only")
+			return
+		}
+		if s.controller == nil {
+			s.writeError(w, http.StatusServiceUnavailable, "the bot is not connected")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// handleTriggerCheck starts an immediate rate check, like /check. The optional vault_id and
+// tag query parameters limit it to one vault or group.
+func (s *Server) handleTriggerCheck(w http.ResponseWriter, r *http.Request) {
+	req := types.CheckRequest{
+		VaultID: r.URL.Query().Get("vault_id"),
+		Tag:     types.NormalizeTag(r.URL.Query().Get("tag")),
+	}
+	status := "triggered"
+	if !s.controller.TriggerCheck(req) {
+		status = "already_pending"
+	}
+	s.writeJSON(w, http.StatusAccepted, map[string]string{"status": status})
+}
+
+// caller identifies who made an authenticated request: the dashboard user, or the Discord user
+// who created the bearer token. The legacy api_token and open endpoints have no identity.
+func (s *Server) caller(r *http.Request) (userID, name string) {
+	if sess := s.currentSession(r); sess != nil {
+		return sess.UserID, sess.Username
+	}
+	if token := s.storage.FindAPIToken(bearerToken(r)); token != nil {
+		return token.CreatedByID, fmt.Sprintf("API token \"%s\"", token.Name)
+	}
+	return "", ""
+}
+
+// writeEnrollError answers a failed enrollment: 403 with the reason if it would exceed a quota,
+// otherwise 500
+func (s *Server) writeEnrollError(w http.ResponseWriter, err error) {
+	var quotaErr *storage.QuotaError
+	if errors.As(err, &quotaErr) {
+		s.writeError(w, http.StatusForbidden, quotaErr.Error())
+		return
+	}
+	s.logger.Errorf("Failed to enroll vault via HTTP API: %v", err)
+	s.writeError(w, http.StatusInternalServerError, "failed to enroll vault")
+}
+
+// handleCreateVault enrolls a vault, like /enroll
+func (s *Server) handleCreateVault(w http.ResponseWriter, r *http.Request) {
+	var req createVaultRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if err := types.ValidateThreshold(req.Threshold); err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.Nickname == "" || req.ChannelID == "" {
+		s.writeError(w, http.StatusBadRequest, "nickname and channel_id are required")
+		return
+	}
+
+	urlInfo, err := morpho.ParseVaultURL(req.URL)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid Summer.fi URL: %v", err))
+		return
+	}
+
+	vaultID := types.VaultKey(urlInfo.Network, urlInfo.Protocol, urlInfo.VaultID)
+	existing, err := s.storage.GetVault(vaultID)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, "failed to check vault")
+		return
+	}
+	if existing != nil {
+		s.writeError(w, http.StatusConflict, fmt.Sprintf("vault %s is already enrolled", vaultID))
+		return
+	}
+
+	// The vault belongs to the channel's server, like one enrolled there with /enroll
+	guildID, err := s.controller.ChannelGuild(req.ChannelID)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, fmt.Sprintf("failed to look up channel: %v", err))
+		return
+	}
+
+	// Checked before the webhook is created, so a rejected enrollment leaves nothing behind
+	vault := &types.VaultConfig{
+		VaultID:          vaultID,
+		Nickname:         req.Nickname,
+		ThresholdPercent: req.Threshold,
+		ChannelID:        req.ChannelID,
+		GuildID:          guildID,
+		MarketPair:       urlInfo.MarketPair,
+		EnrollSource:     types.EnrollSourceAPI,
+	}
+	vault.EnrolledBy, vault.EnrolledByName = s.caller(r)
+	quota := storage.NewQuota(s.config)
+	if err := quota.Check(s.storage, vault); err != nil {
+		s.writeEnrollError(w, err)
+		return
+	}
+
+	webhookURL, err := s.controller.CreateWebhook(req.ChannelID)
+	if err != nil {
+		s.writeError(w, http.StatusBadGateway, fmt.Sprintf("failed to create webhook for channel: %v", err))
+		return
+	}
+
+	vault.WebhookURL = webhookURL
+	if err := storage.EnrollVault(s.storage, vault, quota); err != nil {
+		// Clean up webhook if storage fails
+		if deleteErr := s.controller.DeleteWebhook(webhookURL); deleteErr != nil {
+			s.logger.Errorf("Failed to delete webhook for %s after enrollment failed: %v", vaultID, deleteErr)
+		}
+		s.writeEnrollError(w, err)
+		return
+	}
+
+	s.logger.Infof("Enrolled vault %s via HTTP API", vault.VaultID)
+	// Webhook URLs are left out of the response, as in GET /vaults
+	s.writeJSON(w, http.StatusCreated, newVaultResponse(vault))
+}
@@ -0,0 +1,91 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/morrisonbrett/SummerRateChecker/internal/config"
+	"github.com/morrisonbrett/SummerRateChecker/internal/storage"
+	"github.com/morrisonbrett/SummerRateChecker/internal/types"
+	"go.uber.org/zap"
+)
+
+// fakeController creates webhooks without Discord, in a channel of server guildID
+type fakeController struct {
+	guildID  string
+	webhooks int
+}
+
+func (c *fakeController) TriggerCheck(req types.CheckRequest) bool { return true }
+
+func (c *fakeController) CreateWebhook(channelID string) (string, error) {
+	c.webhooks++
+	return "https://discord.com/api/webhooks/1/secret", nil
+}
+
+func (c *fakeController) DeleteWebhook(webhookURL string) error {
+	c.webhooks--
+	return nil
+}
+
+func (c *fakeController) ChannelGuild(channelID string) (string, error) {
+	return c.guildID, nil
+}
+
+func createVault(s *Server) *httptest.ResponseRecorder {
+	body := `{"url": "https://pro.summer.fi/ethereum/morphoblue/borrow/WBTC-USDC/5678", "nickname": "New", "threshold": 0.5, "channel_id": "channel"}`
+	w := httptest.NewRecorder()
+	s.handleCreateVault(w, httptest.NewRequest(http.MethodPost, "/vaults", strings.NewReader(body)))
+	return w
+}
+
+func TestCreateVault(t *testing.T) {
+	controller := &fakeController{guildID: "guild"}
+	store := storage.NewInMemoryStorage()
+	s := &Server{config: &config.Config{}, storage: store, logger: zap.NewNop().Sugar(), controller: controller, sessions: newSessionStore()}
+
+	w := createVault(s)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("POST /vaults answered %d: %s", w.Code, w.Body)
+	}
+	var response map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := response["webhook_url"]; ok || strings.Contains(w.Body.String(), "secret") {
+		t.Errorf("response exposes the webhook URL: %s", w.Body)
+	}
+	if response["guild_id"] != "guild" {
+		t.Errorf("guild_id = %v, want the channel's server", response["guild_id"])
+	}
+
+	vault, _ := store.GetVault("5678")
+	if vault == nil || vault.GuildID != "guild" || vault.WebhookURL == "" {
+		t.Errorf("stored vault = %+v, want it in the channel's server with its webhook", vault)
+	}
+}
+
+func TestCreateVaultRespectsQuota(t *testing.T) {
+	controller := &fakeController{guildID: "guild"}
+	cfg := &config.Config{}
+	cfg.Limits.MaxVaultsPerGuild = 1
+	store := storage.NewInMemoryStorage()
+	if err := store.AddVault(&types.VaultConfig{VaultID: "1234", Nickname: "Existing", GuildID: "guild"}); err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{config: cfg, storage: store, logger: zap.NewNop().Sugar(), controller: controller, sessions: newSessionStore()}
+
+	w := createVault(s)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("POST /vaults past the server's limit answered %d, want 403: %s", w.Code, w.Body)
+	}
+	if vault, _ := store.GetVault("5678"); vault != nil {
+		t.Error("vault was enrolled past the server's limit")
+	}
+	if controller.webhooks != 0 {
+		t.Errorf("%d webhook(s) left behind by the rejected enrollment", controller.webhooks)
+	}
+}
@@ -0,0 +1,110 @@
+package httpapi
+
+import (
+	"html/template"
+	"net/http"
+	"time"
+)
+
+var dashboardTemplate = template.Must(template.New("dashboard").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>SummerRateChecker</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+th, td { padding: 0.4em 0.8em; border-bottom: 1px solid #ddd; text-align: left; }
+.stale { color: #b00; }
+</style>
+</head>
+<body>
+<p>Logged in as <b>{{.Username}}</b> ({{if .Admin}}admin{{else}}viewer{{end}}) · <a href="/auth/logout">Log out</a></p>
+<h1>Vaults</h1>
+<table>
+<tr><th>Vault</th><th>Market</th><th>Borrow APY</th><th>Threshold</th><th>Last checked</th></tr>
+{{range .Vaults}}<tr>
+<td>{{.Nickname}}<br><small>{{.VaultID}}</small></td>
+<td>{{.MarketPair}}</td>
+<td>{{if .Rate}}{{.Rate}}{{else}}n/a{{end}}</td>
+<td>{{.Threshold}}</td>
+<td{{if .Stale}} class="stale"{{end}}>{{.LastChecked}}{{if .Disabled}} (disabled){{end}}</td>
+</tr>{{else}}<tr><td colspan="5">No vaults enrolled</td></tr>{{end}}
+</table>
+{{if .Admin}}<p><button onclick="fetch('/trigger-check', {method: 'POST'}).then(r => r.json()).then(j => alert(j.status || j.error))">Check now</button></p>{{end}}
+</body>
+</html>
+`))
+
+type dashboardVault struct {
+	VaultID     string
+	Nickname    string
+	MarketPair  string
+	Rate        string // Empty until the vault's first check
+	Threshold   string
+	LastChecked string
+	Stale       bool
+	Disabled    bool
+}
+
+// handleDashboard renders the vault overview for logged-in guild members, showing the vaults of the
+// servers they belong to
+func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
+	if !s.oauthEnabled() {
+		s.writeError(w, http.StatusNotFound, "the dashboard requires Discord login; configure [http.oauth]")
+		return
+	}
+
+	sess := s.currentSession(r)
+	if sess == nil {
+		http.Redirect(w, r, "/auth/login", http.StatusFound)
+		return
+	}
+
+	vaults, err := s.storage.GetAllVaults()
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, "failed to get vaults")
+		return
+	}
+
+	member := make(map[string]bool, len(sess.GuildIDs))
+	for _, id := range sess.GuildIDs {
+		member[id] = true
+	}
+
+	settings := s.storage.GetSettings()
+	now := time.Now()
+	rows := make([]dashboardVault, 0, len(vaults))
+	for _, vault := range vaults {
+		if !member[vault.Guild(s.config.Discord.GuildID)] {
+			continue
+		}
+		maxAge := 2 * settings.CheckInterval(vault.Guild(s.config.Discord.GuildID), s.config.Monitor.CheckIntervalMinutes)
+		row := dashboardVault{
+			VaultID:     vault.VaultID,
+			Nickname:    vault.Nickname,
+			MarketPair:  vault.MarketPair,
+			Threshold:   vault.DescribeThreshold(),
+			LastChecked: "never",
+			Stale:       vault.IsStale(now, maxAge),
+			Disabled:    vault.Disabled,
+		}
+		if rate, ok := s.storage.GetLastRate(vault.VaultID); ok {
+			row.Rate = vault.RateFormat(s.config.Monitor.RateFormat()).Rate(rate)
+		}
+		if !vault.LastCheckedAt.IsZero() {
+			row.LastChecked = vault.LastCheckedAt.Format("2006-01-02 15:04 MST")
+		}
+		rows = append(rows, row)
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	err = dashboardTemplate.Execute(w, struct {
+		Username string
+		Admin    bool
+		Vaults   []dashboardVault
+	}{sess.Username, sess.Admin, rows})
+	if err != nil {
+		s.logger.Errorf("Failed to render dashboard: %v", err)
+	}
+}
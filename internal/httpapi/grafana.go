@@ -0,0 +1,143 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The Grafana JSON datasource plugin (simpod-json-datasource) talks to these
+// routes. Point the datasource at http://<listen_addr>/grafana.
+//
+// Targets are named "<vault_id>:borrow" or "<vault_id>:supply".
+
+const (
+	grafanaBorrowSuffix = ":borrow"
+	grafanaSupplySuffix = ":supply"
+)
+
+type grafanaQueryRequest struct {
+	Range struct {
+		From time.Time `json:"from"`
+		To   time.Time `json:"to"`
+	} `json:"range"`
+	Targets []struct {
+		Target string `json:"target"`
+	} `json:"targets"`
+	MaxDataPoints int `json:"maxDataPoints"`
+}
+
+type grafanaTimeseries struct {
+	Target     string       `json:"target"`
+	Datapoints [][2]float64 `json:"datapoints"` // [value, unix milliseconds]
+}
+
+func (s *Server) registerGrafanaRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/grafana/", s.handleGrafanaHealth)
+	mux.HandleFunc("/grafana/search", s.handleGrafanaSearch)
+	mux.HandleFunc("/grafana/metrics", s.handleGrafanaSearch)
+	mux.HandleFunc("/grafana/query", s.handleGrafanaQuery)
+}
+
+// handleGrafanaHealth answers the datasource "Save & test" probe
+func (s *Server) handleGrafanaHealth(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/grafana/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleGrafanaSearch lists the available targets
+func (s *Server) handleGrafanaSearch(w http.ResponseWriter, r *http.Request) {
+	vaults, err := s.storage.GetAllVaults()
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, "failed to get vaults")
+		return
+	}
+
+	targets := make([]string, 0, len(vaults)*2)
+	for _, vault := range vaults {
+		targets = append(targets, vault.VaultID+grafanaBorrowSuffix, vault.VaultID+grafanaSupplySuffix)
+	}
+	sort.Strings(targets)
+
+	if strings.HasSuffix(r.URL.Path, "/metrics") {
+		// The newer plugin API expects label/value objects
+		metrics := make([]map[string]string, 0, len(targets))
+		for _, target := range targets {
+			metrics = append(metrics, map[string]string{"label": target, "value": target})
+		}
+		s.writeJSON(w, http.StatusOK, metrics)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, targets)
+}
+
+// handleGrafanaQuery returns timeseries for the requested targets and range
+func (s *Server) handleGrafanaQuery(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "use POST")
+		return
+	}
+
+	var req grafanaQueryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid query body")
+		return
+	}
+
+	to := req.Range.To
+	if to.IsZero() {
+		to = time.Now()
+	}
+
+	results := make([]grafanaTimeseries, 0, len(req.Targets))
+	for _, t := range req.Targets {
+		var vaultID string
+		supply := false
+		switch {
+		case strings.HasSuffix(t.Target, grafanaBorrowSuffix):
+			vaultID = strings.TrimSuffix(t.Target, grafanaBorrowSuffix)
+		case strings.HasSuffix(t.Target, grafanaSupplySuffix):
+			vaultID = strings.TrimSuffix(t.Target, grafanaSupplySuffix)
+			supply = true
+		default:
+			continue
+		}
+
+		series := grafanaTimeseries{Target: t.Target, Datapoints: [][2]float64{}}
+		for _, sample := range s.storage.GetRateHistory(vaultID, req.Range.From) {
+			if sample.Timestamp.After(to) {
+				break
+			}
+			value := sample.BorrowRate
+			if supply {
+				value = sample.SupplyRate
+			}
+			series.Datapoints = append(series.Datapoints, [2]float64{value, float64(sample.Timestamp.UnixMilli())})
+		}
+		series.Datapoints = thinDatapoints(series.Datapoints, req.MaxDataPoints)
+
+		results = append(results, series)
+	}
+
+	s.writeJSON(w, http.StatusOK, results)
+}
+
+// thinDatapoints keeps every nth point so the response respects Grafana's maxDataPoints
+func thinDatapoints(points [][2]float64, max int) [][2]float64 {
+	if max <= 0 || len(points) <= max {
+		return points
+	}
+
+	step := (len(points) + max - 1) / max
+	thinned := make([][2]float64, 0, max)
+	for i := 0; i < len(points); i += step {
+		thinned = append(thinned, points[i])
+	}
+	return thinned
+}
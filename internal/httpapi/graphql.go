@@ -0,0 +1,179 @@
+package httpapi
+
+import (
+	"fmt"
+	"time"
+
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/relay"
+	"github.com/morrisonbrett/SummerRateChecker/internal/storage"
+	"github.com/morrisonbrett/SummerRateChecker/internal/types"
+)
+
+// graphqlSchema mirrors the camelCase style of the upstream Morpho API
+const graphqlSchema = `
+	scalar Time
+
+	schema {
+		query: Query
+	}
+
+	type Query {
+		vaults: [Vault!]!
+		vault(id: String!): Vault
+		alerts(vaultId: String, limit: Int = 50): [Alert!]!
+	}
+
+	type Vault {
+		id: String!
+		nickname: String!
+		marketPair: String!
+		morphoMarketKey: String!
+		thresholdPercent: Float!
+		channelId: String!
+		lastAlertRate: Float!
+		createdAt: Time!
+		enrolledBy: String!
+		enrolledByName: String!
+		enrollSource: String!
+		currentRate: Float
+		history(hours: Int = 24): [RateSample!]!
+		alerts(limit: Int = 10): [Alert!]!
+	}
+
+	type RateSample {
+		timestamp: Time!
+		borrowRate: Float!
+		supplyRate: Float!
+	}
+
+	type Alert {
+		vaultId: String!
+		nickname: String!
+		marketPair: String!
+		previousRate: Float!
+		currentRate: Float!
+		changePercent: Float!
+		timestamp: Time!
+	}
+`
+
+// newGraphQLHandler builds the /graphql endpoint over the bot's storage
+func newGraphQLHandler(store storage.Storage) (*relay.Handler, error) {
+	schema, err := graphql.ParseSchema(graphqlSchema, &queryResolver{storage: store})
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse GraphQL schema: %w", err)
+	}
+	return &relay.Handler{Schema: schema}, nil
+}
+
+type queryResolver struct {
+	storage storage.Storage
+}
+
+func (q *queryResolver) Vaults() ([]*vaultResolver, error) {
+	vaults, err := q.storage.GetAllVaults()
+	if err != nil {
+		return nil, err
+	}
+
+	resolvers := make([]*vaultResolver, 0, len(vaults))
+	for _, vault := range vaults {
+		resolvers = append(resolvers, &vaultResolver{storage: q.storage, vault: vault})
+	}
+	return resolvers, nil
+}
+
+func (q *queryResolver) Vault(args struct{ ID string }) (*vaultResolver, error) {
+	vault, err := q.storage.GetVault(args.ID)
+	if err != nil || vault == nil {
+		return nil, err
+	}
+	return &vaultResolver{storage: q.storage, vault: vault}, nil
+}
+
+func (q *queryResolver) Alerts(args struct {
+	VaultID *string
+	Limit   int32
+}) []*alertResolver {
+	var vaultID string
+	if args.VaultID != nil {
+		vaultID = *args.VaultID
+	}
+	return alertResolvers(q.storage, vaultID, int(args.Limit))
+}
+
+type vaultResolver struct {
+	storage storage.Storage
+	vault   *types.VaultConfig
+}
+
+func (v *vaultResolver) ID() string                { return v.vault.VaultID }
+func (v *vaultResolver) Nickname() string          { return v.vault.Nickname }
+func (v *vaultResolver) MarketPair() string        { return v.vault.MarketPair }
+func (v *vaultResolver) MorphoMarketKey() string   { return v.vault.MorphoMarketKey }
+func (v *vaultResolver) ThresholdPercent() float64 { return v.vault.ThresholdPercent }
+func (v *vaultResolver) ChannelID() string         { return v.vault.ChannelID }
+func (v *vaultResolver) LastAlertRate() float64    { return v.vault.LastAlertRate }
+func (v *vaultResolver) CreatedAt() graphql.Time   { return graphql.Time{Time: v.vault.CreatedAt} }
+func (v *vaultResolver) EnrolledBy() string        { return v.vault.EnrolledBy }
+func (v *vaultResolver) EnrolledByName() string    { return v.vault.EnrolledByName }
+func (v *vaultResolver) EnrollSource() string      { return string(v.vault.EnrollSource) }
+
+func (v *vaultResolver) CurrentRate() *float64 {
+	rate, exists := v.storage.GetLastRate(v.vault.VaultID)
+	if !exists {
+		return nil
+	}
+	return &rate
+}
+
+func (v *vaultResolver) History(args struct{ Hours int32 }) []*rateSampleResolver {
+	since := time.Now().Add(-time.Duration(args.Hours) * time.Hour)
+	history := v.storage.GetRateHistory(v.vault.VaultID, since)
+
+	resolvers := make([]*rateSampleResolver, 0, len(history))
+	for _, sample := range history {
+		resolvers = append(resolvers, &rateSampleResolver{sample: sample})
+	}
+	return resolvers
+}
+
+func (v *vaultResolver) Alerts(args struct{ Limit int32 }) []*alertResolver {
+	return alertResolvers(v.storage, v.vault.VaultID, int(args.Limit))
+}
+
+type rateSampleResolver struct {
+	sample types.RateSample
+}
+
+func (r *rateSampleResolver) Timestamp() graphql.Time { return graphql.Time{Time: r.sample.Timestamp} }
+func (r *rateSampleResolver) BorrowRate() float64     { return r.sample.BorrowRate }
+func (r *rateSampleResolver) SupplyRate() float64     { return r.sample.SupplyRate }
+
+type alertResolver struct {
+	alert *types.RateChangeAlert
+}
+
+func (a *alertResolver) VaultID() string         { return a.alert.VaultID }
+func (a *alertResolver) Nickname() string        { return a.alert.Nickname }
+func (a *alertResolver) MarketPair() string      { return a.alert.MarketPair }
+func (a *alertResolver) PreviousRate() float64   { return a.alert.PreviousRate }
+func (a *alertResolver) CurrentRate() float64    { return a.alert.CurrentRate }
+func (a *alertResolver) ChangePercent() float64  { return a.alert.ChangePercent }
+func (a *alertResolver) Timestamp() graphql.Time { return graphql.Time{Time: a.alert.Timestamp} }
+
+// alertResolvers returns up to limit recent alerts, optionally restricted to one vault
+func alertResolvers(store storage.Storage, vaultID string, limit int) []*alertResolver {
+	var resolvers []*alertResolver
+	for _, alert := range store.GetRecentAlerts(0) {
+		if vaultID != "" && alert.VaultID != vaultID {
+			continue
+		}
+		resolvers = append(resolvers, &alertResolver{alert: alert})
+		if limit > 0 && len(resolvers) >= limit {
+			break
+		}
+	}
+	return resolvers
+}
@@ -0,0 +1,314 @@
+package httpapi
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Discord's OAuth2 endpoints. The "guilds" scope returns the user's computed permissions in
+// each guild, which is what slash command permissions are based on.
+const (
+	discordAuthorizeURL = "https://discord.com/oauth2/authorize"
+	discordTokenURL     = "https://discord.com/api/oauth2/token"
+	discordAPIURL       = "https://discord.com/api"
+
+	sessionCookie = "src_session"
+	stateCookie   = "src_oauth_state"
+	sessionTTL    = 7 * 24 * time.Hour
+
+	// Matches the Administrator permission required by admin slash commands
+	discordAdministrator = 0x8
+)
+
+// session is a logged-in dashboard user. Sessions live in memory, so a restart logs everyone out.
+type session struct {
+	UserID    string
+	Username  string
+	Admin     bool     // An administrator in at least one of the bot's servers
+	GuildIDs  []string // The bot's servers the user belongs to
+	ExpiresAt time.Time
+}
+
+type sessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]*session
+}
+
+func newSessionStore() *sessionStore {
+	return &sessionStore{sessions: make(map[string]*session)}
+}
+
+func (st *sessionStore) create(sess *session) (string, error) {
+	id, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	// Drop expired sessions while we're here
+	now := time.Now()
+	for key, existing := range st.sessions {
+		if now.After(existing.ExpiresAt) {
+			delete(st.sessions, key)
+		}
+	}
+	st.sessions[id] = sess
+	return id, nil
+}
+
+func (st *sessionStore) get(id string) *session {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	sess, ok := st.sessions[id]
+	if !ok || time.Now().After(sess.ExpiresAt) {
+		delete(st.sessions, id)
+		return nil
+	}
+	return sess
+}
+
+func (st *sessionStore) delete(id string) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	delete(st.sessions, id)
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func (s *Server) oauthEnabled() bool {
+	oauth := s.config.HTTP.OAuth
+	return oauth.ClientID != "" && oauth.ClientSecret != "" && oauth.RedirectURL != "" && len(s.config.Discord.Guilds()) > 0
+}
+
+func (s *Server) registerOAuthRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/auth/login", s.handleLogin)
+	mux.HandleFunc("/auth/callback", s.handleCallback)
+	mux.HandleFunc("/auth/logout", s.handleLogout)
+}
+
+// currentSession returns the logged-in user for r, or nil
+func (s *Server) currentSession(r *http.Request) *session {
+	cookie, err := r.Cookie(sessionCookie)
+	if err != nil {
+		return nil
+	}
+	return s.sessions.get(cookie.Value)
+}
+
+func (s *Server) secureCookies() bool {
+	return strings.HasPrefix(s.config.HTTP.OAuth.RedirectURL, "https://")
+}
+
+// handleLogin redirects to Discord's consent screen
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	if !s.oauthEnabled() {
+		s.writeError(w, http.StatusNotFound, "Discord login is not configured")
+		return
+	}
+
+	state, err := randomToken()
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, "failed to start login")
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     stateCookie,
+		Value:    state,
+		Path:     "/auth/",
+		MaxAge:   600,
+		HttpOnly: true,
+		Secure:   s.secureCookies(),
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	query := url.Values{
+		"client_id":     {s.config.HTTP.OAuth.ClientID},
+		"redirect_uri":  {s.config.HTTP.OAuth.RedirectURL},
+		"response_type": {"code"},
+		"scope":         {"identify guilds"},
+		"state":         {state},
+		"prompt":        {"none"},
+	}
+	http.Redirect(w, r, discordAuthorizeURL+"?"+query.Encode(), http.StatusFound)
+}
+
+// handleCallback exchanges the authorization code and starts a session for guild members
+func (s *Server) handleCallback(w http.ResponseWriter, r *http.Request) {
+	if !s.oauthEnabled() {
+		s.writeError(w, http.StatusNotFound, "Discord login is not configured")
+		return
+	}
+
+	state, err := r.Cookie(stateCookie)
+	if err != nil || state.Value == "" || state.Value != r.URL.Query().Get("state") {
+		s.writeError(w, http.StatusBadRequest, "login expired, please try again")
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: stateCookie, Path: "/auth/", MaxAge: -1})
+
+	code := r.URL.Query().Get("code")
+	if code == "" {
+		s.writeError(w, http.StatusBadRequest, "login was cancelled")
+		return
+	}
+
+	accessToken, err := s.exchangeCode(r.Context(), code)
+	if err != nil {
+		s.logger.Warnf("Discord OAuth code exchange failed: %v", err)
+		s.writeError(w, http.StatusBadGateway, "failed to log in with Discord")
+		return
+	}
+
+	sess, err := s.lookupMember(r.Context(), accessToken)
+	if err != nil {
+		s.logger.Warnf("Discord OAuth member lookup failed: %v", err)
+		s.writeError(w, http.StatusBadGateway, "failed to log in with Discord")
+		return
+	}
+	if sess == nil {
+		s.writeError(w, http.StatusForbidden, "you must be a member of one of the bot's Discord servers")
+		return
+	}
+
+	id, err := s.sessions.create(sess)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, "failed to start session")
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    id,
+		Path:     "/",
+		MaxAge:   int(sessionTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   s.secureCookies(),
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	role := "viewer"
+	if sess.Admin {
+		role = "admin"
+	}
+	s.logger.Infof("Dashboard login by %s (%s) as %s", sess.Username, sess.UserID, role)
+	http.Redirect(w, r, "/dashboard", http.StatusFound)
+}
+
+func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(sessionCookie); err == nil {
+		s.sessions.delete(cookie.Value)
+	}
+	http.SetCookie(w, &http.Cookie{Name: sessionCookie, Path: "/", MaxAge: -1})
+	http.Redirect(w, r, "/dashboard", http.StatusFound)
+}
+
+// exchangeCode trades an authorization code for a user access token
+func (s *Server) exchangeCode(ctx context.Context, code string) (string, error) {
+	form := url.Values{
+		"client_id":     {s.config.HTTP.OAuth.ClientID},
+		"client_secret": {s.config.HTTP.OAuth.ClientSecret},
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {s.config.HTTP.OAuth.RedirectURL},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, discordTokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := s.doDiscordRequest(req, &token); err != nil {
+		return "", err
+	}
+	if token.AccessToken == "" {
+		return "", fmt.Errorf("no access token in response")
+	}
+	return token.AccessToken, nil
+}
+
+// lookupMember returns a session for the user if they belong to any of the bot's servers, or nil if
+// they don't. The user is an admin if they are one in any of those servers.
+func (s *Server) lookupMember(ctx context.Context, accessToken string) (*session, error) {
+	var user struct {
+		ID       string `json:"id"`
+		Username string `json:"username"`
+	}
+	if err := s.discordGet(ctx, accessToken, "/users/@me", &user); err != nil {
+		return nil, err
+	}
+
+	var guilds []struct {
+		ID          string `json:"id"`
+		Owner       bool   `json:"owner"`
+		Permissions string `json:"permissions"`
+	}
+	if err := s.discordGet(ctx, accessToken, "/users/@me/guilds", &guilds); err != nil {
+		return nil, err
+	}
+
+	configured := make(map[string]bool)
+	for _, id := range s.config.Discord.Guilds() {
+		configured[id] = true
+	}
+
+	sess := &session{
+		UserID:    user.ID,
+		Username:  user.Username,
+		ExpiresAt: time.Now().Add(sessionTTL),
+	}
+	for _, guild := range guilds {
+		if !configured[guild.ID] {
+			continue
+		}
+		permissions, _ := strconv.ParseInt(guild.Permissions, 10, 64)
+		sess.GuildIDs = append(sess.GuildIDs, guild.ID)
+		sess.Admin = sess.Admin || guild.Owner || permissions&discordAdministrator != 0
+	}
+	if len(sess.GuildIDs) == 0 {
+		return nil, nil
+	}
+	return sess, nil
+}
+
+func (s *Server) discordGet(ctx context.Context, accessToken, path string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, discordAPIURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	return s.doDiscordRequest(req, v)
+}
+
+func (s *Server) doDiscordRequest(req *http.Request, v interface{}) error {
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s returned status %d", req.URL.Path, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
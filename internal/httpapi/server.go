@@ -0,0 +1,137 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/morrisonbrett/SummerRateChecker/internal/config"
+	"github.com/morrisonbrett/SummerRateChecker/internal/events"
+	"github.com/morrisonbrett/SummerRateChecker/internal/metrics"
+	"github.com/morrisonbrett/SummerRateChecker/internal/storage"
+	"github.com/morrisonbrett/SummerRateChecker/internal/types"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+)
+
+// Server exposes the bot's data over HTTP
+type Server struct {
+	config  *config.Config
+	storage storage.Storage
+	broker  *events.Broker
+	logger  *zap.SugaredLogger
+	server  *http.Server
+
+	controller Controller
+	sessions   *sessionStore
+	httpClient *http.Client
+}
+
+func New(cfg *config.Config, store storage.Storage, broker *events.Broker, logger *zap.SugaredLogger) (*Server, error) {
+	s := &Server{
+		config:  cfg,
+		storage: store,
+		broker:  broker,
+		logger:  logger,
+
+		sessions:   newSessionStore(),
+		httpClient: &http.Client{Timeout: 15 * time.Second},
+	}
+
+	// Everything except the write endpoints needs the read scope
+	readMux := http.NewServeMux()
+	s.registerGrafanaRoutes(readMux)
+	readMux.HandleFunc("/events", s.handleEvents)
+	readMux.Handle("/metrics", promhttp.Handler())
+	readMux.HandleFunc("/metrics/rules", s.handleAlertingRules)
+	readMux.HandleFunc("/schema/alert/v1", s.handleAlertSchema)
+	readMux.HandleFunc("/rates", s.handleRates)
+
+	graphqlHandler, err := newGraphQLHandler(store)
+	if err != nil {
+		return nil, err
+	}
+	readMux.Handle("/graphql", graphqlHandler)
+
+	mux := http.NewServeMux()
+	s.registerControlRoutes(mux)
+	s.registerOAuthRoutes(mux)
+	mux.HandleFunc("/dashboard", s.handleDashboard)
+	mux.Handle("/", s.requireScope(types.ScopeRead, readMux))
+
+	s.server = &http.Server{
+		Addr:              cfg.HTTP.ListenAddr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	return s, nil
+}
+
+// Start begins serving in the background
+func (s *Server) Start() error {
+	if s.config.HTTP.ListenAddr == "" {
+		return fmt.Errorf("http.listen_addr is not configured")
+	}
+
+	go func() {
+		s.logger.Infof("HTTP API listening on %s", s.config.HTTP.ListenAddr)
+		if err := s.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Errorf("HTTP API stopped: %v", err)
+		}
+	}()
+
+	return nil
+}
+
+func (s *Server) Stop() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return s.server.Shutdown(ctx)
+}
+
+// handleAlertingRules serves a Prometheus rule file mirroring the enrolled vaults' alerts
+func (s *Server) handleAlertingRules(w http.ResponseWriter, r *http.Request) {
+	vaults, err := s.storage.GetAllVaults()
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, "failed to get vaults")
+		return
+	}
+
+	// Disabled vaults aren't checked, so their gauges would only hold stale values
+	var active []*types.VaultConfig
+	for _, vault := range vaults {
+		if !vault.Disabled {
+			active = append(active, vault)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Header().Set("Content-Disposition", `attachment; filename="summer-rate-checker.rules.yml"`)
+	if _, err := w.Write([]byte(metrics.AlertingRules(active))); err != nil {
+		s.logger.Errorf("Failed to write alerting rules: %v", err)
+	}
+}
+
+// handleAlertSchema serves the JSON Schema for the alert payload sent by the webhook sink
+func (s *Server) handleAlertSchema(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/schema+json")
+	if _, err := w.Write([]byte(types.AlertJSONSchema)); err != nil {
+		s.logger.Errorf("Failed to write alert schema: %v", err)
+	}
+}
+
+func (s *Server) writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		s.logger.Errorf("Failed to encode HTTP response: %v", err)
+	}
+}
+
+func (s *Server) writeError(w http.ResponseWriter, status int, message string) {
+	s.writeJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,202 @@
+package httpapi
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/morrisonbrett/SummerRateChecker/internal/types"
+)
+
+// vaultResponse is a vault as GET /vaults lists it. Webhook URLs are left out, since anyone who
+// has one can post to the channel.
+type vaultResponse struct {
+	ID              string              `json:"id"`
+	Nickname        string              `json:"nickname"`
+	MarketPair      string              `json:"market_pair,omitempty"`
+	MorphoMarketKey string              `json:"morpho_market_key,omitempty"`
+	Threshold       float64             `json:"threshold"`
+	ThresholdMode   types.ThresholdMode `json:"threshold_mode,omitempty"`
+	RateSource      types.RateSource    `json:"rate_source,omitempty"`
+	CriticalRate    float64             `json:"critical_rate,omitempty"`
+	ChannelID       string              `json:"channel_id"`
+	GuildID         string              `json:"guild_id,omitempty"`
+	Tags            []string            `json:"tags,omitempty"`
+	Notes           string              `json:"notes,omitempty"`
+	Paused          bool                `json:"paused,omitempty"`
+	Disabled        bool                `json:"disabled,omitempty"`
+	CreatedAt       time.Time           `json:"created_at"`
+	EnrolledBy      string              `json:"enrolled_by,omitempty"`
+	EnrolledByName  string              `json:"enrolled_by_name,omitempty"`
+	EnrollSource    types.EnrollSource  `json:"enroll_source,omitempty"`
+}
+
+// rateResponse is a vault's latest borrow rate as GET /rates lists it
+type rateResponse struct {
+	VaultID    string     `json:"vault_id"`
+	Nickname   string     `json:"nickname"`
+	MarketPair string     `json:"market_pair,omitempty"`
+	BorrowRate *float64   `json:"borrow_rate"`          // Null until the vault's first check
+	CheckedAt  *time.Time `json:"checked_at,omitempty"` // When market data was last fetched
+}
+
+func newVaultResponse(vault *types.VaultConfig) vaultResponse {
+	return vaultResponse{
+		ID:              vault.VaultID,
+		Nickname:        vault.Nickname,
+		MarketPair:      vault.MarketPair,
+		MorphoMarketKey: vault.MorphoMarketKey,
+		Threshold:       vault.ThresholdPercent,
+		ThresholdMode:   vault.ThresholdMode,
+		RateSource:      vault.RateSource,
+		CriticalRate:    vault.CriticalRate,
+		ChannelID:       vault.ChannelID,
+		GuildID:         vault.GuildID,
+		Tags:            vault.Tags,
+		Notes:           vault.Notes,
+		Paused:          vault.Paused,
+		Disabled:        vault.Disabled,
+		CreatedAt:       vault.CreatedAt,
+		EnrolledBy:      vault.EnrolledBy,
+		EnrolledByName:  vault.EnrolledByName,
+		EnrollSource:    vault.EnrollSource,
+	}
+}
+
+func (s *Server) registerVaultRoutes(mux *http.ServeMux) {
+	mux.Handle("/vaults", s.byMethod(map[string]http.Handler{
+		http.MethodGet:  s.requireScope(types.ScopeRead, http.HandlerFunc(s.handleListVaults)),
+		http.MethodPost: s.requireScope(types.ScopeManageVaults, s.requireWrite(http.MethodPost, s.handleCreateVault)),
+	}))
+	mux.Handle("/vaults/", s.byMethod(map[string]http.Handler{
+		http.MethodDelete: s.requireScope(types.ScopeManageVaults, s.requireWrite(http.MethodDelete, s.handleDeleteVault)),
+	}))
+}
+
+// byMethod routes a path to a handler per method, so reading and writing one path can need
+// different scopes
+func (s *Server) byMethod(handlers map[string]http.Handler) http.Handler {
+	methods := make([]string, 0, len(handlers))
+	for method := range handlers {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler, ok := handlers[r.Method]
+		if !ok {
+			w.Header().Set("Allow", strings.Join(methods, ", "))
+			s.writeError(w, http.StatusMethodNotAllowed, "use "+strings.Join(methods, " or "))
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// sortedVaults returns every enrolled vault ordered by nickname
+func (s *Server) sortedVaults() ([]*types.VaultConfig, error) {
+	vaults, err := s.storage.GetAllVaults()
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(vaults, func(i, j int) bool {
+		return strings.ToLower(vaults[i].Nickname) < strings.ToLower(vaults[j].Nickname)
+	})
+	return vaults, nil
+}
+
+// handleListVaults lists the enrolled vaults, like /list. The optional tag query parameter limits
+// it to one group.
+func (s *Server) handleListVaults(w http.ResponseWriter, r *http.Request) {
+	vaults, err := s.sortedVaults()
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, "failed to get vaults")
+		return
+	}
+
+	tag := types.NormalizeTag(r.URL.Query().Get("tag"))
+	response := make([]vaultResponse, 0, len(vaults))
+	for _, vault := range vaults {
+		if tag != "" && !vault.HasTag(tag) {
+			continue
+		}
+		response = append(response, newVaultResponse(vault))
+	}
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{"vaults": response})
+}
+
+// handleDeleteVault unenrolls a vault, like /unenroll. It stays in the trash for
+// monitor.unenroll_grace_hours, so /restore can bring it back.
+func (s *Server) handleDeleteVault(w http.ResponseWriter, r *http.Request) {
+	vaultID := strings.TrimPrefix(r.URL.Path, "/vaults/")
+	if vaultID == "" || strings.Contains(vaultID, "/") {
+		s.writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	vault, err := s.storage.GetVault(vaultID)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, "failed to check vault")
+		return
+	}
+	if vault == nil {
+		s.writeError(w, http.StatusNotFound, fmt.Sprintf("vault %s is not enrolled", vaultID))
+		return
+	}
+
+	if err := s.storage.TrashVault(vaultID); err != nil {
+		s.writeError(w, http.StatusInternalServerError, "failed to unenroll vault")
+		return
+	}
+	userID, _ := s.caller(r)
+	entry := types.NewAuditEntry(userID, types.AuditUnenroll, vault)
+	if err := s.storage.RecordAudit(entry); err != nil {
+		s.logger.Errorf("Failed to record audit entry for %s: %v", entry.Describe(), err)
+	}
+
+	s.logger.Infof("Unenrolled vault %s via HTTP API", vaultID)
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status":        "unenrolled",
+		"vault_id":      vaultID,
+		"restore_hours": s.config.Monitor.UnenrollGraceHours,
+	})
+}
+
+// handleRates lists each vault's latest borrow rate. The optional vault_id and tag query
+// parameters limit it to one vault or group.
+func (s *Server) handleRates(w http.ResponseWriter, r *http.Request) {
+	vaults, err := s.sortedVaults()
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, "failed to get vaults")
+		return
+	}
+
+	vaultID := r.URL.Query().Get("vault_id")
+	tag := types.NormalizeTag(r.URL.Query().Get("tag"))
+	rates := make([]rateResponse, 0, len(vaults))
+	for _, vault := range vaults {
+		if (vaultID != "" && vault.VaultID != vaultID) || (tag != "" && !vault.HasTag(tag)) {
+			continue
+		}
+		rate := rateResponse{
+			VaultID:    vault.VaultID,
+			Nickname:   vault.Nickname,
+			MarketPair: vault.MarketPair,
+		}
+		if borrow, ok := s.storage.GetLastRate(vault.VaultID); ok {
+			rate.BorrowRate = &borrow
+		}
+		if !vault.LastCheckedAt.IsZero() {
+			checkedAt := vault.LastCheckedAt.UTC()
+			rate.CheckedAt = &checkedAt
+		}
+		rates = append(rates, rate)
+	}
+	if vaultID != "" && len(rates) == 0 {
+		s.writeError(w, http.StatusNotFound, fmt.Sprintf("vault %s is not enrolled", vaultID))
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{"rates": rates})
+}
@@ -0,0 +1,136 @@
+package integrity
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/morrisonbrett/SummerRateChecker/internal/storage"
+	"github.com/morrisonbrett/SummerRateChecker/internal/types"
+)
+
+// IssueKind classifies an inconsistency in stored data
+type IssueKind string
+
+const (
+	OrphanedRate        IssueKind = "orphaned_rate"         // A last rate is stored for a vault that no longer exists
+	OrphanedHistory     IssueKind = "orphaned_history"      // Rate history is stored for a vault that no longer exists
+	MissingWebhook      IssueKind = "missing_webhook"       // A vault has no webhook, so its alerts go nowhere
+	UnresolvedMarketKey IssueKind = "unresolved_market_key" // A vault's Morpho market key was never discovered
+)
+
+// Issue is one problem found by Check
+type Issue struct {
+	Kind    IssueKind
+	VaultID string
+	Detail  string
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%s %s: %s", i.Kind, i.VaultID, i.Detail)
+}
+
+// MarketKeyResolver finds a vault's Morpho market. morpho.Client implements it.
+type MarketKeyResolver interface {
+	GetMarketDataByVaultID(ctx context.Context, vaultID string, morphoMarketKey string, marketPair string) (*types.MarketData, error)
+}
+
+// WebhookCreator creates an alert webhook in a Discord channel and returns its URL
+type WebhookCreator interface {
+	CreateWebhook(channelID string) (string, error)
+}
+
+// Check cross-checks vault configs against the rest of storage
+func Check(store storage.Storage) ([]Issue, error) {
+	vaults, err := store.GetAllVaults()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vaults: %w", err)
+	}
+
+	known := make(map[string]bool, len(vaults))
+	var issues []Issue
+	for _, vault := range vaults {
+		known[vault.VaultID] = true
+
+		if vault.WebhookURL == "" {
+			issues = append(issues, Issue{MissingWebhook, vault.VaultID, fmt.Sprintf("%s has no alert webhook", vault.Nickname)})
+		}
+		if vault.MorphoMarketKey == "" {
+			issues = append(issues, Issue{UnresolvedMarketKey, vault.VaultID, fmt.Sprintf("%s has no Morpho market key", vault.Nickname)})
+		}
+	}
+
+	// Trashed vaults keep their data until they're purged
+	for _, vault := range store.GetTrashedVaults() {
+		known[vault.VaultID] = true
+	}
+
+	for vaultID := range store.GetAllLastRates() {
+		if !known[vaultID] {
+			issues = append(issues, Issue{OrphanedRate, vaultID, "last rate stored for an unknown vault"})
+		}
+	}
+	for _, vaultID := range store.GetHistoryVaultIDs() {
+		if !known[vaultID] {
+			issues = append(issues, Issue{OrphanedHistory, vaultID, "rate history stored for an unknown vault"})
+		}
+	}
+
+	return issues, nil
+}
+
+// Repair fixes what it can and returns the issues it couldn't. Orphaned data is pruned,
+// market keys are looked up with resolver, and webhooks are recreated with webhooks if it's non-nil.
+func Repair(ctx context.Context, store storage.Storage, issues []Issue, resolver MarketKeyResolver, webhooks WebhookCreator) ([]Issue, error) {
+	var remaining []Issue
+	for _, issue := range issues {
+		fixed, err := repair(ctx, store, issue, resolver, webhooks)
+		if err != nil {
+			return nil, fmt.Errorf("failed to repair %s: %w", issue, err)
+		}
+		if !fixed {
+			remaining = append(remaining, issue)
+		}
+	}
+	return remaining, nil
+}
+
+func repair(ctx context.Context, store storage.Storage, issue Issue, resolver MarketKeyResolver, webhooks WebhookCreator) (bool, error) {
+	switch issue.Kind {
+	case OrphanedRate, OrphanedHistory:
+		// Removing the (already missing) vault clears every piece of data keyed by its ID
+		return true, store.RemoveVault(issue.VaultID)
+
+	case UnresolvedMarketKey:
+		vault, err := store.GetVault(issue.VaultID)
+		if err != nil || vault == nil {
+			return false, err
+		}
+		data, err := resolver.GetMarketDataByVaultID(ctx, vault.VaultID, "", vault.MarketPair)
+		if err != nil || data.MorphoMarketKey == "" {
+			return false, nil
+		}
+		return true, store.UpdateVault(vault.VaultID, func(stored *types.VaultConfig) error {
+			stored.MorphoMarketKey = data.MorphoMarketKey
+			return nil
+		})
+
+	case MissingWebhook:
+		if webhooks == nil {
+			return false, nil
+		}
+		vault, err := store.GetVault(issue.VaultID)
+		if err != nil || vault == nil || vault.ChannelID == "" {
+			return false, err
+		}
+		webhookURL, err := webhooks.CreateWebhook(vault.ChannelID)
+		if err != nil {
+			return false, nil
+		}
+		return true, store.UpdateVault(vault.VaultID, func(stored *types.VaultConfig) error {
+			stored.WebhookURL = webhookURL
+			return nil
+		})
+	}
+
+	return false, nil
+}
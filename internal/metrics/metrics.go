@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Collectors are registered with the default Prometheus registry and served at /metrics
+var (
+	AlertDeliveries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "summer_alert_deliveries_total",
+		Help: "Alert delivery attempts by vault, sink, and outcome.",
+	}, []string{"vault_id", "sink", "outcome"})
+
+	AlertDeliveryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "summer_alert_delivery_duration_seconds",
+		Help:    "Time taken to deliver an alert to a sink.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"sink"})
+
+	StorageReads = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "summer_storage_reads_total",
+		Help: "Data file reads by file.",
+	}, []string{"file"})
+
+	StorageWrites = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "summer_storage_writes_total",
+		Help: "Data file writes by file.",
+	}, []string{"file"})
+
+	StorageErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "summer_storage_errors_total",
+		Help: "Failed data file reads and writes by file and operation.",
+	}, []string{"file", "op"})
+
+	StorageWriteDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "summer_storage_write_duration_seconds",
+		Help:    "Time taken to marshal and write a data file.",
+		Buckets: prometheus.ExponentialBuckets(0.0005, 2, 14),
+	}, []string{"file"})
+
+	StorageFileBytes = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "summer_storage_file_bytes",
+		Help: "Size of each data file as last read or written.",
+	}, []string{"file"})
+
+	VaultBorrowRate = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "summer_vault_borrow_rate_percent",
+		Help: "Spot borrow APY of each vault's market as of the last check.",
+	}, []string{"vault_id"})
+
+	VaultAlertRate = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "summer_vault_alert_rate_percent",
+		Help: "Rate each vault's threshold is checked against: the spot APY or the average it was set to.",
+	}, []string{"vault_id"})
+
+	VaultAlertBaseline = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "summer_vault_alert_baseline_percent",
+		Help: "Rate each vault's alert rate is compared with to decide whether it moved past its threshold.",
+	}, []string{"vault_id"})
+
+	VaultLTVHeadroom = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "summer_vault_ltv_headroom_points",
+		Help: "Percentage points between each Summer.fi position's LTV and its market's LLTV as of the last check.",
+	}, []string{"vault_id"})
+)
+
+// ForgetVault drops a purged vault's rate series so they stop being scraped
+func ForgetVault(vaultID string) {
+	VaultBorrowRate.DeleteLabelValues(vaultID)
+	VaultAlertRate.DeleteLabelValues(vaultID)
+	VaultAlertBaseline.DeleteLabelValues(vaultID)
+	VaultLTVHeadroom.DeleteLabelValues(vaultID)
+}
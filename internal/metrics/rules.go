@@ -0,0 +1,76 @@
+package metrics
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/morrisonbrett/SummerRateChecker/internal/types"
+)
+
+// RuleGroupName names the group AlertingRules generates
+const RuleGroupName = "summer-rate-checker"
+
+// AlertingRules renders a Prometheus rule file that mirrors the bot's alerts for each vault, built
+// on the summer_vault_* gauges:
+//
+//   - SummerRateThresholdExceeded when the alert rate has moved the vault's threshold away from its
+//     baseline, in points or, for relative thresholds, percent of the baseline
+//   - SummerRateCritical while the borrow rate is at or above the vault's critical level
+//
+// The rules are rebuilt from the vaults on every call, so they're only as current as the last
+// time they were fetched.
+func AlertingRules(vaults []*types.VaultConfig) string {
+	vaults = append([]*types.VaultConfig(nil), vaults...)
+	sort.Slice(vaults, func(a, b int) bool { return vaults[a].VaultID < vaults[b].VaultID })
+
+	var b strings.Builder
+	b.WriteString("# Generated by SummerRateChecker from the enrolled vaults' thresholds.\n")
+	b.WriteString("# Fetch it again after changing a threshold or enrolling a vault.\n")
+	b.WriteString("groups:\n")
+	fmt.Fprintf(&b, "  - name: %s\n", RuleGroupName)
+	if len(vaults) == 0 {
+		b.WriteString("    rules: []\n")
+		return b.String()
+	}
+	b.WriteString("    rules:\n")
+
+	for _, vault := range vaults {
+		selector := fmt.Sprintf("{vault_id=%s}", strconv.Quote(vault.VaultID))
+		move := fmt.Sprintf("abs(summer_vault_alert_rate_percent%s - summer_vault_alert_baseline_percent%s)", selector, selector)
+		summary := fmt.Sprintf("%s borrow rate moved {{ $value | printf \"%%.2f\" }} points from its baseline", vault.Nickname)
+		if vault.ThresholdMode == types.ThresholdRelative {
+			move = fmt.Sprintf("%s / summer_vault_alert_baseline_percent%s * 100", move, selector)
+			summary = fmt.Sprintf("%s borrow rate moved {{ $value | printf \"%%.1f\" }}%% from its baseline", vault.Nickname)
+		}
+		writeRule(&b, "SummerRateThresholdExceeded", fmt.Sprintf("%s >= %s", move, types.FormatThreshold(vault.ThresholdPercent)),
+			"warning", vault, summary, "Threshold: "+vault.DescribeThreshold())
+
+		if vault.CriticalRate > 0 {
+			writeRule(&b, "SummerRateCritical",
+				fmt.Sprintf("summer_vault_borrow_rate_percent%s >= %s", selector, strconv.FormatFloat(vault.CriticalRate, 'f', -1, 64)),
+				"critical", vault,
+				fmt.Sprintf("%s borrow rate is {{ $value | printf \"%%.2f\" }}%%", vault.Nickname),
+				fmt.Sprintf("Critical level: %s%%", strconv.FormatFloat(vault.CriticalRate, 'f', -1, 64)))
+		}
+	}
+	return b.String()
+}
+
+func writeRule(b *strings.Builder, name, expr, severity string, vault *types.VaultConfig, summary, description string) {
+	fmt.Fprintf(b, "      - alert: %s\n", name)
+	fmt.Fprintf(b, "        expr: %s\n", yamlQuote(expr))
+	b.WriteString("        labels:\n")
+	fmt.Fprintf(b, "          severity: %s\n", severity)
+	fmt.Fprintf(b, "          vault_id: %s\n", yamlQuote(vault.VaultID))
+	fmt.Fprintf(b, "          market_pair: %s\n", yamlQuote(vault.MarketPair))
+	b.WriteString("        annotations:\n")
+	fmt.Fprintf(b, "          summary: %s\n", yamlQuote(summary))
+	fmt.Fprintf(b, "          description: %s\n", yamlQuote(description))
+}
+
+// yamlQuote single-quotes s for YAML, where the only escape is a doubled quote
+func yamlQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
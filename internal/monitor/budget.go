@@ -0,0 +1,78 @@
+package monitor
+
+import (
+	"sort"
+	"time"
+
+	"github.com/morrisonbrett/SummerRateChecker/internal/types"
+)
+
+// pressureFailureRatio is the share of a cycle's fetches that must fail for the next cycle to run
+// under API pressure. Fewer failures are more likely dead markets than an overloaded API.
+const pressureFailureRatio = 0.5
+
+// applyBudget orders the vaults due for a scheduled check by priority, most overdue first within
+// each, and defers those beyond Monitor.CallBudget to a later cycle. Under API pressure the budget
+// is halved and low-priority vaults are only checked every LowPriorityBackoff intervals, so the
+// calls that do go through are spent on the vaults that matter most.
+func (m *Monitor) applyBudget(vaults []*types.VaultConfig, settings types.Settings, now time.Time) (kept []*types.VaultConfig, deferred int) {
+	sort.SliceStable(vaults, func(a, b int) bool {
+		if vaults[a].Priority != vaults[b].Priority {
+			return vaults[a].Priority.Outranks(vaults[b].Priority)
+		}
+		return vaults[a].LastCheckedAt.Before(vaults[b].LastCheckedAt)
+	})
+
+	budget := m.config.Monitor.CallBudget
+	backoff := m.config.Monitor.LowPriorityBackoff
+	if m.underPressure && budget > 1 {
+		budget /= 2
+	}
+
+	// Vaults sharing a market are fetched with one call, so the budget counts markets
+	calls := make(map[string]bool)
+	for _, vault := range vaults {
+		if m.underPressure && backoff > 1 && vault.Priority == types.PriorityLow && !vault.LastCheckedAt.IsZero() {
+			interval := settings.CheckInterval(vault.Guild(m.config.Discord.GuildID), m.config.Monitor.CheckIntervalMinutes)
+			slack := time.Duration(m.config.Monitor.CheckIntervalMinutes) * time.Minute / 2
+			if now.Sub(vault.LastCheckedAt) < interval*time.Duration(backoff)-slack {
+				deferred++
+				continue
+			}
+		}
+
+		key := vault.MorphoMarketKey
+		if key == "" {
+			key = "vault:" + vault.VaultID
+		}
+		if budget > 0 && !calls[key] && len(calls) >= budget {
+			deferred++
+			continue
+		}
+		calls[key] = true
+		kept = append(kept, vault)
+	}
+
+	if deferred > 0 {
+		m.logger.Warnf("Deferred %d vaults to a later cycle (budget %d calls, under pressure: %v)", deferred, budget, m.underPressure)
+	}
+	return kept, deferred
+}
+
+// trackPressure records whether the API struggled with this cycle's fetches, which puts the next
+// scheduled cycle under pressure. A cycle that mostly succeeds clears it.
+func (m *Monitor) trackPressure(attempted, fetched int, fetchErr error) {
+	if attempted == 0 {
+		return
+	}
+	failed := attempted - fetched
+	pressure := fetchErr != nil || float64(failed)/float64(attempted) >= pressureFailureRatio
+	if pressure != m.underPressure {
+		if pressure {
+			m.logger.Warnf("%d of %d market fetches failed; checking high-priority vaults first until the API recovers", failed, attempted)
+		} else {
+			m.logger.Info("Market API recovered; checking vaults normally")
+		}
+	}
+	m.underPressure = pressure
+}
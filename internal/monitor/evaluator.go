@@ -0,0 +1,84 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/morrisonbrett/SummerRateChecker/internal/types"
+)
+
+// Evaluator is an extension point for alert logic the core doesn't ship. Evaluators are
+// compiled in and registered with Monitor.RegisterEvaluator; each one sees every vault's
+// market data on every check.
+type Evaluator interface {
+	Name() string
+	// Evaluate returns a decision to alert, or nil to stay quiet. Evaluators keep any
+	// state they need themselves; vault must not be modified.
+	Evaluate(ctx context.Context, vault *types.VaultConfig, data *types.MarketData) (*Decision, error)
+}
+
+// Decision is an evaluator's request to alert a vault's channel
+type Decision struct {
+	Title    string
+	Message  string
+	Severity types.Severity // Defaults to warning
+}
+
+// RegisterEvaluator adds a custom evaluator that runs after the built-in checks
+func (m *Monitor) RegisterEvaluator(evaluator Evaluator) {
+	m.evaluators = append(m.evaluators, evaluator)
+	m.logger.Infof("Registered %s evaluator", evaluator.Name())
+}
+
+// runEvaluators passes the vault's market data to every registered evaluator and sends their alerts
+func (m *Monitor) runEvaluators(ctx context.Context, vault *types.VaultConfig, data *types.MarketData) {
+	for _, evaluator := range m.evaluators {
+		decision, err := m.evaluate(ctx, evaluator, vault, data)
+		if err != nil {
+			m.logger.Errorf("Evaluator %s failed for %s: %v", evaluator.Name(), vault.VaultID, err)
+			continue
+		}
+		if decision == nil {
+			continue
+		}
+
+		m.logger.Infof("Evaluator %s alerted for %s: %s", evaluator.Name(), vault.VaultID, decision.Title)
+		if m.silenced(vault) || vault.WebhookURL == "" {
+			continue
+		}
+
+		color := 0xffa500 // Orange for warnings
+		if decision.Severity == types.SeverityCritical {
+			color = 0xff0000
+		}
+		title := decision.Title
+		if title == "" {
+			title = evaluator.Name()
+		}
+		embed := types.DiscordEmbed{
+			Title:       fmt.Sprintf("🧩 %s: %s", title, vault.Nickname),
+			Description: decision.Message,
+			Color:       color,
+			Timestamp:   time.Now().Format(time.RFC3339),
+			Footer: &types.DiscordEmbedFooter{
+				Text: fmt.Sprintf("%s • %s", types.DefaultFooter, evaluator.Name()),
+			},
+		}
+		if err := m.postWebhook(vault.WebhookURL, types.DiscordWebhookPayload{Embeds: m.brand(vault, []types.DiscordEmbed{embed})}); err != nil {
+			m.logger.Errorf("Failed to send %s alert for %s: %v", evaluator.Name(), vault.VaultID, err)
+		}
+	}
+}
+
+// evaluate calls evaluator, turning a panic into an error so one buggy evaluator can't stop the monitor
+func (m *Monitor) evaluate(ctx context.Context, evaluator Evaluator, vault *types.VaultConfig, data *types.MarketData) (decision *Decision, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	// Hand out a copy so evaluators can't change stored vault state
+	return evaluator.Evaluate(ctx, vault.Clone(), data)
+}
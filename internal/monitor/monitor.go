@@ -1,346 +1,2018 @@
-package monitor
-
-import (
-	"bytes"
-	"context"
-	"encoding/json"
-	"fmt"
-	"math"
-	"net/http"
-	"time"
-
-	"github.com/morrisonbrett/SummerRateChecker/internal/config"
-	"github.com/morrisonbrett/SummerRateChecker/internal/morpho"
-	"github.com/morrisonbrett/SummerRateChecker/internal/storage"
-	"github.com/morrisonbrett/SummerRateChecker/internal/types"
-	"go.uber.org/zap"
-)
-
-type Monitor struct {
-	config       *config.Config
-	storage      storage.Storage
-	morphoClient *morpho.Client
-	httpClient   *http.Client
-	logger       *zap.SugaredLogger
-	checkTrigger <-chan bool
-}
-
-func New(cfg *config.Config, store storage.Storage, logger *zap.SugaredLogger) *Monitor {
-	return &Monitor{
-		config:       cfg,
-		storage:      store,
-		morphoClient: morpho.NewClient(cfg.Morpho.APIURL, logger),
-		httpClient:   &http.Client{Timeout: 30 * time.Second},
-		logger:       logger,
-	}
-}
-
-func (m *Monitor) SetCheckTrigger(trigger <-chan bool) {
-	m.checkTrigger = trigger
-}
-
-func (m *Monitor) CheckOnce() {
-	m.checkAllVaults()
-}
-
-func (m *Monitor) Start() {
-	ticker := time.NewTicker(time.Duration(m.config.Monitor.CheckIntervalMinutes) * time.Minute)
-	defer ticker.Stop()
-
-	m.logger.Infof("Starting rate monitor with %d minute intervals", m.config.Monitor.CheckIntervalMinutes)
-
-	// Run initial check
-	m.checkAllVaults()
-
-	// Run periodic checks and listen for manual triggers
-	for {
-		select {
-		case <-ticker.C:
-			m.checkAllVaults()
-		case <-m.checkTrigger:
-			m.logger.Info("Manual check triggered")
-			m.checkAllVaults()
-		}
-	}
-}
-
-func (m *Monitor) checkAllVaults() {
-	m.checkRates(context.Background())
-}
-
-func (m *Monitor) checkRates(ctx context.Context) error {
-	m.logger.Info("Checking rates for all vaults")
-
-	// Get all vaults
-	vaults, err := m.storage.GetAllVaults()
-	if err != nil {
-		return fmt.Errorf("failed to get vaults: %w", err)
-	}
-
-	if len(vaults) == 0 {
-		m.logger.Info("No vaults to check")
-		return nil
-	}
-
-	m.logger.Infof("Checking %d vaults", len(vaults))
-
-	// Get current rates for all vaults
-	marketData, err := m.morphoClient.GetMultipleMarkets(ctx, vaults)
-	if err != nil {
-		return fmt.Errorf("failed to get market data: %w", err)
-	}
-
-	// Process each vault's rate and build embeds
-	var embeds []types.DiscordEmbed
-	for _, data := range marketData {
-		// Find the vault config using the vault ID
-		var vaultConfig *types.VaultConfig
-		for _, v := range vaults {
-			if v.VaultID == data.VaultID {
-				vaultConfig = v
-				break
-			}
-		}
-
-		if vaultConfig == nil {
-			m.logger.Warnf("No vault config found for vault ID %s", data.VaultID)
-			continue
-		}
-
-		// Get the last known rate
-		lastRate, exists := m.storage.GetLastRate(vaultConfig.VaultID)
-		if !exists {
-			m.logger.Infof("First rate check for vault %s: %.4f%%", vaultConfig.Nickname, data.BorrowRate)
-			if err := m.storage.UpdateLastRate(vaultConfig.VaultID, data.BorrowRate); err != nil {
-				m.logger.Errorf("Failed to update last rate for %s: %v", vaultConfig.VaultID, err)
-			}
-			// Also set this as the last alert rate
-			vaultConfig.LastAlertRate = data.BorrowRate
-			if err := m.storage.AddVault(vaultConfig); err != nil {
-				m.logger.Errorf("Failed to update last alert rate for %s: %v", vaultConfig.VaultID, err)
-			}
-			// Create embed for first check
-			embed := types.DiscordEmbed{
-				Title:       fmt.Sprintf("Rate Status: %s", vaultConfig.Nickname),
-				Description: fmt.Sprintf("First rate check for %s", vaultConfig.Nickname),
-				Color:       0x808080, // Gray for first check
-				Fields: []types.DiscordEmbedField{
-					{
-						Name:   fmt.Sprintf("**Current Rate:** %.2f%%", data.BorrowRate),
-						Value:  " ",
-						Inline: false,
-					},
-					{
-						Name:   "Market Pair",
-						Value:  vaultConfig.MarketPair,
-						Inline: true,
-					},
-				},
-				Timestamp: time.Now().Format(time.RFC3339),
-				Footer: &types.DiscordEmbedFooter{
-					Text: "SummerRateChecker",
-				},
-			}
-			embeds = append(embeds, embed)
-			continue
-		}
-
-		// Calculate rate change in percentage points from the last alert rate
-		// If LastAlertRate is not set (0), use the last check rate
-		compareRate := vaultConfig.LastAlertRate
-		if compareRate == 0 {
-			compareRate = lastRate
-		}
-		rateChange := data.BorrowRate - compareRate
-		rateChangePoints := math.Abs(rateChange) // This is now in percentage points
-
-		// Only send messages if there's an actual change that exceeds the threshold
-		if rateChangePoints >= vaultConfig.ThresholdPercent {
-			// Create alert using the existing alert format
-			alert := types.NewRateChangeAlert(
-				vaultConfig.VaultID,
-				vaultConfig.Nickname,
-				vaultConfig.MarketPair,
-				compareRate, // Use the comparison rate (last alert or last check)
-				data.BorrowRate,
-			)
-
-			// Send alert
-			if err := m.sendDiscordAlert(alert, vaultConfig.ChannelID); err != nil {
-				m.logger.Errorf("Failed to send Discord alert: %v", err)
-			}
-
-			// Update the last alert rate
-			vaultConfig.LastAlertRate = data.BorrowRate
-			if err := m.storage.AddVault(vaultConfig); err != nil {
-				m.logger.Errorf("Failed to update last alert rate for %s: %v", vaultConfig.VaultID, err)
-			}
-		}
-
-		// Update last rate regardless of whether we sent an alert
-		if err := m.storage.UpdateLastRate(vaultConfig.VaultID, data.BorrowRate); err != nil {
-			m.logger.Errorf("Failed to update last rate for %s: %v", vaultConfig.VaultID, err)
-		}
-	}
-
-	// Only send status embeds if we have any to send
-	if len(embeds) > 0 {
-		// Send status embeds to all unique channels
-		channelMap := make(map[string]bool)
-		for _, vault := range vaults {
-			if !channelMap[vault.ChannelID] && vault.WebhookURL != "" {
-				payload := types.DiscordWebhookPayload{
-					Embeds: embeds,
-				}
-				jsonData, err := json.Marshal(payload)
-				if err != nil {
-					m.logger.Errorf("Failed to marshal webhook payload: %v", err)
-					continue
-				}
-
-				resp, err := m.httpClient.Post(vault.WebhookURL, "application/json", bytes.NewBuffer(jsonData))
-				if err != nil {
-					m.logger.Errorf("Failed to send webhook: %v", err)
-					continue
-				}
-				resp.Body.Close()
-
-				channelMap[vault.ChannelID] = true
-			}
-		}
-	}
-
-	return nil
-}
-
-func (m *Monitor) processMarketData(marketData *types.MarketData) error {
-	vault, err := m.storage.GetVault(marketData.VaultID)
-	if err != nil {
-		return fmt.Errorf("failed to get vault config: %w", err)
-	}
-
-	if vault == nil {
-		m.logger.Warnf("Received data for unknown vault: %s", marketData.VaultID)
-		return nil
-	}
-
-	currentRate := marketData.BorrowRate
-	previousRate, hasPreviousRate := m.storage.GetLastRate(marketData.VaultID)
-
-	// Update the last rate
-	if err := m.storage.UpdateLastRate(marketData.VaultID, currentRate); err != nil {
-		m.logger.Errorf("Failed to update last rate for vault %s: %v", marketData.VaultID, err)
-	}
-
-	// Check if we should send an alert
-	if hasPreviousRate {
-		changePoints := math.Abs(currentRate - previousRate) // This is now in percentage points
-
-		// Alert on both increases and decreases that exceed threshold
-		if changePoints >= vault.ThresholdPercent {
-			alert := types.NewRateChangeAlert(
-				vault.VaultID,
-				vault.Nickname,
-				vault.MarketPair,
-				previousRate,
-				currentRate,
-			)
-
-			m.logger.Infof(
-				"Rate change alert for %s: %.2f%% → %.2f%% (%+.2f%%)",
-				vault.Nickname, previousRate, currentRate, alert.ChangePercent,
-			)
-
-			if err := m.sendDiscordAlert(alert, vault.ChannelID); err != nil {
-				m.logger.Errorf("Failed to send Discord alert: %v", err)
-			}
-		}
-	} else {
-		m.logger.Infof("First check for vault %s (%s): %.2f%%", vault.VaultID, vault.Nickname, currentRate)
-	}
-
-	return nil
-}
-
-func (m *Monitor) sendDiscordAlert(alert *types.RateChangeAlert, channelID string) error {
-	vault, err := m.storage.GetVault(alert.VaultID)
-	if err != nil {
-		return fmt.Errorf("failed to get vault config: %w", err)
-	}
-
-	if vault == nil {
-		return fmt.Errorf("vault %s not found", alert.VaultID)
-	}
-
-	if vault.WebhookURL == "" {
-		m.logger.Warnf("No webhook URL configured for vault %s, skipping alert", alert.VaultID)
-		return nil
-	}
-
-	payload := alert.ToDiscordEmbed()
-
-	jsonData, err := json.Marshal(payload)
-	if err != nil {
-		return fmt.Errorf("failed to marshal webhook payload: %w", err)
-	}
-
-	resp, err := m.httpClient.Post(
-		vault.WebhookURL,
-		"application/json",
-		bytes.NewBuffer(jsonData),
-	)
-	if err != nil {
-		return fmt.Errorf("failed to send webhook: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
-	}
-
-	return nil
-}
-
-func (m *Monitor) sendAlert(channelID, message string) {
-	vaults, err := m.storage.GetAllVaults()
-	if err != nil {
-		m.logger.Errorf("Failed to get vaults: %v", err)
-		return
-	}
-
-	// Find vaults that use this channel
-	for _, vault := range vaults {
-		if vault.ChannelID == channelID && vault.WebhookURL != "" {
-			payload := map[string]interface{}{
-				"content": message,
-			}
-
-			jsonData, err := json.Marshal(payload)
-			if err != nil {
-				m.logger.Errorf("Failed to marshal webhook payload: %v", err)
-				continue
-			}
-
-			resp, err := m.httpClient.Post(vault.WebhookURL, "application/json", bytes.NewBuffer(jsonData))
-			if err != nil {
-				m.logger.Errorf("Failed to send webhook: %v", err)
-				continue
-			}
-			defer resp.Body.Close()
-
-			if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-				m.logger.Errorf("Webhook request failed with status %d", resp.StatusCode)
-			}
-		}
-	}
-}
-
-func rateChangeVerb(rateChange float64) string {
-	if rateChange > 0 {
-		return "Increased"
-	} else if rateChange < 0 {
-		return "Decreased"
-	} else {
-		return "Stable"
-	}
-}
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/morrisonbrett/SummerRateChecker/internal/config"
+	"github.com/morrisonbrett/SummerRateChecker/internal/events"
+	"github.com/morrisonbrett/SummerRateChecker/internal/metrics"
+	"github.com/morrisonbrett/SummerRateChecker/internal/morpho"
+	"github.com/morrisonbrett/SummerRateChecker/internal/notify"
+	"github.com/morrisonbrett/SummerRateChecker/internal/protocols"
+	"github.com/morrisonbrett/SummerRateChecker/internal/rules"
+	"github.com/morrisonbrett/SummerRateChecker/internal/stats"
+	"github.com/morrisonbrett/SummerRateChecker/internal/storage"
+	"github.com/morrisonbrett/SummerRateChecker/internal/summerfi"
+	"github.com/morrisonbrett/SummerRateChecker/internal/types"
+	"go.uber.org/zap"
+)
+
+const (
+	// webhookAttempts is how many times an alert webhook is tried before falling back
+	webhookAttempts = 3
+	// webhookRetryDelay is the base delay between webhook attempts, doubled after each failure
+	webhookRetryDelay = time.Second
+	// maxWebhookRetryDelay caps how long a check waits to retry a webhook. If Discord's Retry-After
+	// asks for longer, the alert falls back instead.
+	maxWebhookRetryDelay = 10 * time.Second
+
+	// percentileWindowDays is how much history alerts are ranked against
+	percentileWindowDays = 30
+	// minPercentileSamples avoids reporting a percentile from a handful of checks
+	minPercentileSamples = 10
+
+	// alertDedupWindow is how long an identical alert for the same vault is suppressed
+	alertDedupWindow = 10 * time.Minute
+)
+
+// DirectMessenger delivers a message straight to a Discord user, used as the fallback
+// when a vault's webhook can't be reached
+type DirectMessenger interface {
+	SendDirectMessage(userID string, payload *types.DiscordWebhookPayload) error
+}
+
+type Monitor struct {
+	config       *config.Config
+	storage      storage.Storage
+	morphoClient *morpho.Client
+	summerfi     *summerfi.Client  // Nil unless summerfi.api_url is set
+	protocols    *protocols.Client // Nil unless compare.api_url is set
+	notifier     *notify.Notifier
+	httpClient   *http.Client
+	logger       *zap.SugaredLogger
+	commands     <-chan types.MonitorCommand
+	broker       *events.Broker
+	dm           DirectMessenger
+	evaluators   []Evaluator
+
+	// cycleMu serializes check cycles, so a manual check can't run while a scheduled one is
+	// mid-cycle and alert on the same breach. It guards the per-cycle state below.
+	cycleMu sync.Mutex
+	// manualPending is set while a manual check is waiting for or running its cycle
+	manualPending atomic.Bool
+
+	// lastReping tracks when each unacknowledged critical alert was last re-pinged
+	lastReping map[string]time.Time
+
+	// recentAlerts records when each alert was last dispatched, keyed by RateChangeAlert.DedupKey
+	recentAlerts map[string]time.Time
+
+	// cycleAlerts counts alerts dispatched during the current check, for its summary
+	cycleAlerts int
+
+	// checkStart holds each vault as it was last read or saved during the current check, so
+	// saveVaultState only writes back the fields the check changed
+	checkStart map[string]*types.VaultConfig
+	// pendingState holds the vaults saved during the current check, which flushVaultState writes
+	// together once it ends rather than rewriting the vaults file for each one
+	pendingState map[string]*types.VaultConfig
+
+	// pausedUntil skips scheduled checks until this time, set by a pause-all command
+	pausedUntil time.Time
+
+	// underPressure is set when most of the last cycle's market fetches failed, so the next one
+	// checks fewer vaults, high priority first. Only check cycles touch it, and cycleMu serializes those.
+	underPressure bool
+}
+
+func New(cfg *config.Config, store storage.Storage, logger *zap.SugaredLogger) *Monitor {
+	notifier := notify.FromConfig(&cfg.Notify, logger)
+	notifier.SetRecorder(store)
+
+	m := &Monitor{
+		config:       cfg,
+		storage:      store,
+		morphoClient: morpho.NewClient(cfg.Morpho.APIURL, logger),
+		notifier:     notifier,
+		httpClient:   &http.Client{Timeout: 30 * time.Second},
+		logger:       logger,
+		lastReping:   make(map[string]time.Time),
+		recentAlerts: make(map[string]time.Time),
+	}
+	if cfg.SummerFi.APIURL != "" {
+		m.summerfi = summerfi.NewClient(cfg.SummerFi.APIURL, logger)
+	}
+	if cfg.Compare.APIURL != "" {
+		m.protocols = protocols.NewClient(cfg.Compare.APIURL, cfg.Compare.Protocols, logger)
+	}
+	return m
+}
+
+// SetCommandBus makes the monitor handle commands sent by the bot, e.g. manual checks
+func (m *Monitor) SetCommandBus(commands <-chan types.MonitorCommand) {
+	m.commands = commands
+}
+
+// SetEventBroker publishes rate updates and alerts to broker as they happen
+func (m *Monitor) SetEventBroker(broker *events.Broker) {
+	m.broker = broker
+}
+
+// SetDirectMessenger enables DM fallback delivery for vaults with a fallback user
+func (m *Monitor) SetDirectMessenger(dm DirectMessenger) {
+	m.dm = dm
+}
+
+func (m *Monitor) CheckOnce() {
+	m.checkAllVaults()
+}
+
+func (m *Monitor) Start() {
+	ticker := time.NewTicker(time.Duration(m.config.Monitor.CheckIntervalMinutes) * time.Minute)
+	defer ticker.Stop()
+
+	m.logger.Infof("Starting rate monitor with %d minute intervals", m.config.Monitor.CheckIntervalMinutes)
+
+	// Cycles run in the background so a slow one doesn't hold up the ticker or the command bus
+	m.startScheduledCycle()
+
+	// Run periodic checks and listen for commands from the bot
+	for {
+		select {
+		case <-ticker.C:
+			if time.Now().Before(m.pausedUntil) {
+				m.logger.Infof("Scheduled checks paused until %s, skipping", m.pausedUntil.Format(time.RFC3339))
+				continue
+			}
+			m.startScheduledCycle()
+		case cmd := <-m.commands:
+			m.handleCommand(cmd)
+		}
+	}
+}
+
+// startScheduledCycle runs a full check in a new goroutine, unless a cycle is still running,
+// in which case this tick is skipped rather than queued behind it
+func (m *Monitor) startScheduledCycle() {
+	if !m.cycleMu.TryLock() {
+		m.logger.Warn("Previous check cycle is still running, skipping this scheduled check")
+		return
+	}
+	go func() {
+		defer m.cycleMu.Unlock()
+		m.runFullCycle()
+	}()
+}
+
+// handleCommand performs a command from the bus and sends its result to the requester. Checks
+// wait for the current cycle, so they run in the background; a manual check runs even while
+// scheduled checks are paused, since someone asked for it.
+func (m *Monitor) handleCommand(cmd types.MonitorCommand) {
+	m.logger.Infof("Handling command %s", cmd.Describe())
+
+	switch cmd.Kind {
+	case types.CommandCheckAll, types.CommandCheckVault:
+		if !m.manualPending.CompareAndSwap(false, true) {
+			m.respond(cmd, types.CommandResult{Err: fmt.Errorf("a manual check is already queued or running")})
+			return
+		}
+		go func() {
+			defer m.manualPending.Store(false)
+			var result types.CommandResult
+			if cmd.Kind == types.CommandCheckAll {
+				result.Check = m.checkAllVaults()
+			} else {
+				result.Check = m.checkVaults(cmd.Check)
+			}
+			result.Check.Request = cmd.Check
+			m.respond(cmd, result)
+		}()
+	case types.CommandPauseAll:
+		// pausedUntil is only touched by the scheduler goroutine, so this needn't wait for a cycle
+		var message string
+		if cmd.PauseFor <= 0 {
+			m.pausedUntil = time.Time{}
+			message = "scheduled checks resumed"
+		} else {
+			m.pausedUntil = time.Now().Add(cmd.PauseFor)
+			message = fmt.Sprintf("scheduled checks paused until %s", m.pausedUntil.UTC().Format("Jan 2 15:04 UTC"))
+		}
+		m.respond(cmd, types.CommandResult{Message: message})
+	case types.CommandResolveCritical:
+		go func() {
+			m.cycleMu.Lock()
+			defer m.cycleMu.Unlock()
+			m.respond(cmd, types.CommandResult{Err: m.resolveDisabledCritical(cmd.VaultID)})
+		}()
+	default:
+		m.respond(cmd, types.CommandResult{Err: fmt.Errorf("unknown command %q", cmd.Kind)})
+	}
+}
+
+// respond logs a failed command and sends its result to the requester
+func (m *Monitor) respond(cmd types.MonitorCommand, result types.CommandResult) {
+	if result.Err != nil {
+		m.logger.Errorf("Command %s failed: %v", cmd.Describe(), result.Err)
+	}
+	cmd.Respond(result)
+}
+
+// checkAllVaults runs a full check cycle, waiting for any cycle in progress to finish first
+func (m *Monitor) checkAllVaults() types.CheckSummary {
+	m.cycleMu.Lock()
+	defer m.cycleMu.Unlock()
+	return m.runFullCycle()
+}
+
+// runFullCycle purges the trash, scans market listings, checks every due vault and posts the
+// weekly report when it's due. The caller must hold cycleMu.
+func (m *Monitor) runFullCycle() types.CheckSummary {
+	ctx := context.Background()
+	m.purgeTrash()
+	m.checkMarketListings(ctx)
+	summary := m.checkRates(ctx, types.CheckRequest{})
+	if summary.Err != nil {
+		m.logger.Errorf("Rate check failed: %v", summary.Err)
+	}
+	m.postWeeklyReportIfDue(time.Now())
+
+	// Rate and history updates are buffered, so persist the whole cycle in one write
+	if err := m.storage.Flush(); err != nil {
+		m.logger.Errorf("Failed to persist rates: %v", err)
+	}
+	return summary
+}
+
+// checkVaults re-fetches only the vaults a scoped manual check asked for, skipping the
+// trash purge and market listings a full cycle does
+func (m *Monitor) checkVaults(req types.CheckRequest) types.CheckSummary {
+	m.cycleMu.Lock()
+	defer m.cycleMu.Unlock()
+
+	summary := m.checkRates(context.Background(), req)
+	if summary.Err != nil {
+		m.logger.Errorf("Manual check for %s failed: %v", req.Describe(), summary.Err)
+	}
+
+	if err := m.storage.Flush(); err != nil {
+		m.logger.Errorf("Failed to persist rates: %v", err)
+	}
+	return summary
+}
+
+// dueForCheck reports whether the vault's server interval has elapsed since its last check
+func (m *Monitor) dueForCheck(vault *types.VaultConfig, settings types.Settings, now time.Time) bool {
+	if vault.LastCheckedAt.IsZero() {
+		return true
+	}
+	interval := settings.CheckInterval(vault.Guild(m.config.Discord.GuildID), m.config.Monitor.CheckIntervalMinutes)
+	// Leave half a global interval of slack so ticker jitter doesn't push a check back a whole cycle
+	slack := time.Duration(m.config.Monitor.CheckIntervalMinutes) * time.Minute / 2
+	return now.Sub(vault.LastCheckedAt) >= interval-slack
+}
+
+// purgeTrash permanently deletes vaults unenrolled more than Monitor.UnenrollGraceHours ago,
+// along with their webhooks once nothing else posts through them
+func (m *Monitor) purgeTrash() {
+	cutoff := time.Now().Add(-time.Duration(m.config.Monitor.UnenrollGraceHours) * time.Hour)
+	purged, err := m.storage.PurgeTrash(cutoff)
+	if err != nil {
+		m.logger.Errorf("Failed to purge unenrolled vaults: %v", err)
+	}
+
+	for _, vault := range purged {
+		m.logger.Infof("Purged vault %s (%s), unenrolled at %s", vault.VaultID, vault.Nickname, vault.DeletedAt.Format(time.RFC3339))
+		metrics.ForgetVault(vault.VaultID)
+		for _, webhookURL := range vault.Webhooks() {
+			if storage.WebhookInUse(m.storage, webhookURL) {
+				continue
+			}
+			if err := m.deleteWebhook(webhookURL); err != nil {
+				m.logger.Warnf("Failed to delete webhook for purged vault %s: %v", vault.VaultID, err)
+			}
+		}
+	}
+}
+
+// checkMarketListings fetches the markets list once per cycle for auto-enroll rules and new-market watches
+func (m *Monitor) checkMarketListings(ctx context.Context) {
+	rules := m.storage.GetEnrollRules()
+	watches := m.storage.GetMarketWatches()
+	if len(rules) == 0 && len(watches) == 0 {
+		return
+	}
+
+	markets, err := m.morphoClient.ListMarkets(ctx, types.EthereumChainID)
+	if err != nil {
+		m.logger.Errorf("Failed to list markets: %v", err)
+		return
+	}
+
+	m.applyEnrollRules(rules, markets)
+	m.checkMarketWatches(watches, markets)
+}
+
+// applyEnrollRules enrolls every market matching a rule that isn't already monitored
+func (m *Monitor) applyEnrollRules(rules []*types.EnrollRule, markets []*types.MarketInfo) {
+	if len(rules) == 0 {
+		return
+	}
+
+	vaults, err := m.storage.GetAllVaults()
+	if err != nil {
+		m.logger.Errorf("Failed to get vaults for auto-enroll: %v", err)
+		return
+	}
+	// Trashed vaults count as monitored so a rule doesn't re-enroll a market the user just unenrolled
+	monitored := make(map[string]bool, len(vaults))
+	for _, vault := range append(vaults, m.storage.GetTrashedVaults()...) {
+		monitored[vault.VaultID] = true
+		if vault.MorphoMarketKey != "" {
+			monitored[vault.MorphoMarketKey] = true
+		}
+	}
+
+	for _, rule := range rules {
+		for _, market := range markets {
+			if !rule.Matches(market) || monitored[market.UniqueKey] {
+				continue
+			}
+
+			vault := rule.NewVault(market)
+			if err := storage.EnrollVault(m.storage, vault, storage.NewQuota(m.config)); err != nil {
+				m.logger.Errorf("Failed to auto-enroll market %s via rule %s: %v", market.UniqueKey, rule.ID, err)
+				continue
+			}
+			monitored[market.UniqueKey] = true
+			m.logger.Infof("Auto-enrolled market %s (%s) via rule %s", market.UniqueKey, market.MarketPair(), rule.ID)
+
+			message := fmt.Sprintf(
+				"🤖 Auto-enrolled **%s** via rule `%s` with a %s threshold (borrow APY %s)",
+				vault.Nickname, rule.ID, vault.DescribeThreshold(), m.formatRate(vault, market.BorrowRate),
+			)
+			if err := m.postWebhook(rule.WebhookURL, map[string]interface{}{"content": message}); err != nil {
+				m.logger.Errorf("Failed to announce auto-enrollment of %s: %v", market.UniqueKey, err)
+			}
+		}
+	}
+}
+
+// checkMarketWatches alerts each watch's channel about markets for its pair that it hasn't seen before
+func (m *Monitor) checkMarketWatches(watches []*types.MarketWatch, markets []*types.MarketInfo) {
+	for _, watch := range watches {
+		var discovered []*types.MarketInfo
+		for _, market := range markets {
+			if watch.Matches(market) && !watch.Knows(market.UniqueKey) {
+				discovered = append(discovered, market)
+			}
+		}
+		if len(discovered) == 0 {
+			continue
+		}
+
+		if !m.inMaintenance() {
+			for _, market := range discovered {
+				embed := types.DiscordEmbed{
+					Title:       fmt.Sprintf("🆕 New Market: %s", market.MarketPair()),
+					Description: fmt.Sprintf("A new Morpho market matching watch `%s` has appeared.\n`%s`", watch.ID, market.UniqueKey),
+					Color:       0x9b59b6, // Purple for discoveries
+					Fields: []types.DiscordEmbedField{
+						{Name: "Borrow APY", Value: m.config.Monitor.RateFormat().Rate(market.BorrowRate), Inline: true},
+						{Name: "Supply APY", Value: m.config.Monitor.RateFormat().Rate(market.SupplyRate), Inline: true},
+						{Name: "LLTV", Value: fmt.Sprintf("%.1f%%", market.LLTV), Inline: true},
+					},
+					Timestamp: time.Now().Format(time.RFC3339),
+					Footer: &types.DiscordEmbedFooter{
+						Text: types.DefaultFooter,
+					},
+				}
+				if err := m.postWebhook(watch.WebhookURL, types.DiscordWebhookPayload{Embeds: []types.DiscordEmbed{embed}}); err != nil {
+					m.logger.Errorf("Failed to send new market alert for watch %s: %v", watch.ID, err)
+				}
+			}
+		}
+
+		err := m.storage.UpdateMarketWatch(watch.ID, func(watch *types.MarketWatch) {
+			for _, market := range discovered {
+				if !watch.Knows(market.UniqueKey) {
+					watch.KnownMarkets = append(watch.KnownMarkets, market.UniqueKey)
+				}
+			}
+		})
+		if err != nil {
+			m.logger.Errorf("Failed to update watch %s: %v", watch.ID, err)
+		}
+	}
+}
+
+func (m *Monitor) checkRates(ctx context.Context, req types.CheckRequest) (summary types.CheckSummary) {
+	m.logger.Infof("Checking rates for %s", req.Describe())
+	start := time.Now()
+	m.cycleAlerts = 0
+	defer func() {
+		summary.Alerts = m.cycleAlerts
+		summary.Duration = time.Since(start)
+	}()
+
+	// Get all vaults
+	allVaults, err := m.storage.GetAllVaults()
+	if err != nil {
+		summary.Err = fmt.Errorf("failed to get vaults: %w", err)
+		return summary
+	}
+
+	// Skip vaults that were disabled after repeated failures, and vaults whose server chose a
+	// slower interval with /setup and isn't due yet. A scoped check was asked for explicitly, so
+	// it runs whether or not its vaults are due.
+	settings := m.storage.GetSettings()
+	now := time.Now()
+	var vaults []*types.VaultConfig
+	for _, vault := range allVaults {
+		if vault.Disabled || !req.Matches(vault) {
+			continue
+		}
+		if req.Scoped() || m.dueForCheck(vault, settings, now) {
+			vaults = append(vaults, vault)
+		}
+	}
+
+	if !req.Scoped() {
+		vaults, summary.Deferred = m.applyBudget(vaults, settings, now)
+	}
+
+	if len(vaults) == 0 {
+		m.logger.Info("No vaults to check")
+		return summary
+	}
+
+	m.checkStart = make(map[string]*types.VaultConfig, len(vaults))
+	for _, vault := range vaults {
+		m.checkStart[vault.VaultID] = vault.Clone()
+	}
+	m.pendingState = make(map[string]*types.VaultConfig)
+	defer m.flushVaultState()
+
+	m.logger.Infof("Checking %d vaults", len(vaults))
+
+	// Get current rates for all vaults
+	marketData, err := m.morphoClient.GetMultipleMarkets(ctx, vaults)
+	m.trackFailures(vaults, marketData, err)
+	m.trackPressure(len(vaults), len(marketData), err)
+	summary.Checked = len(marketData)
+	summary.Failed = len(vaults) - len(marketData)
+	if err != nil {
+		summary.Err = fmt.Errorf("failed to get market data: %w", err)
+		return summary
+	}
+
+	// Process each vault's rate and build embeds
+	var embeds []types.DiscordEmbed
+	for _, data := range marketData {
+		// Find the vault config using the vault ID
+		var vaultConfig *types.VaultConfig
+		for _, v := range vaults {
+			if v.VaultID == data.VaultID {
+				vaultConfig = v
+				break
+			}
+		}
+
+		if vaultConfig == nil {
+			m.logger.Warnf("No vault config found for vault ID %s", data.VaultID)
+			continue
+		}
+
+		vaultConfig.LastCheckedAt = data.Timestamp
+		vaultConfig.BorrowAverages = data.BorrowAverages
+		if data.LLTV > 0 {
+			vaultConfig.LLTV = data.LLTV
+		}
+		if err := m.saveVaultState(vaultConfig); err != nil {
+			m.logger.Errorf("Failed to update last checked time for %s: %v", vaultConfig.VaultID, err)
+		}
+
+		// Record the sample in the vault's rate history
+		sample := types.RateSample{
+			Timestamp:  data.Timestamp,
+			BorrowRate: data.BorrowRate,
+			SupplyRate: data.SupplyRate,
+			SupplyUSD:  data.SupplyUSD,
+			BorrowUSD:  data.BorrowUSD,
+		}
+		m.refreshPosition(ctx, vaultConfig)
+		m.checkLiquiditySwing(vaultConfig, data)
+		m.checkRiskEvents(vaultConfig, data)
+		m.checkKinkProximity(vaultConfig, data)
+		m.checkHeadroom(vaultConfig, data)
+		m.evaluateRules(vaultConfig, data)
+		m.runEvaluators(ctx, vaultConfig, data)
+		if err := m.storage.AppendRateHistory(vaultConfig.VaultID, sample); err != nil {
+			m.logger.Errorf("Failed to record rate history for %s: %v", vaultConfig.VaultID, err)
+		}
+		m.broker.Publish(events.TypeRateUpdate, events.RateUpdate{
+			VaultID:    vaultConfig.VaultID,
+			Nickname:   vaultConfig.Nickname,
+			MarketPair: vaultConfig.MarketPair,
+			BorrowRate: data.BorrowRate,
+			SupplyRate: data.SupplyRate,
+		})
+
+		// Deliver anything held back while the vault's alert window was closed
+		m.releaseHeldAlerts(vaultConfig)
+
+		// The rate the threshold is checked against: the spot APY or, if the vault asks for it, a
+		// daily or trailing average
+		rate := AlertRate(m.storage, vaultConfig, data)
+		metrics.VaultBorrowRate.WithLabelValues(vaultConfig.VaultID).Set(data.BorrowRate)
+		metrics.VaultAlertRate.WithLabelValues(vaultConfig.VaultID).Set(rate)
+
+		// Get the last known rate
+		lastRate, exists := m.storage.GetLastRate(vaultConfig.VaultID)
+
+		// Critical level crossings are tracked independently of the change threshold
+		previousRate := rate
+		if exists {
+			previousRate = lastRate
+		}
+		m.checkCriticalLevel(ctx, vaultConfig, previousRate, rate)
+		m.repingUnacknowledged(vaultConfig, rate)
+
+		if !exists {
+			m.logger.Infof("First rate check for vault %s: %.4f%%", vaultConfig.Nickname, rate)
+			metrics.VaultAlertBaseline.WithLabelValues(vaultConfig.VaultID).Set(rate)
+			if err := m.storage.UpdateLastRate(vaultConfig.VaultID, rate); err != nil {
+				m.logger.Errorf("Failed to update last rate for %s: %v", vaultConfig.VaultID, err)
+			}
+			// Also set this as the last alert rate
+			vaultConfig.LastAlertRate = rate
+			if err := m.saveVaultState(vaultConfig); err != nil {
+				m.logger.Errorf("Failed to update last alert rate for %s: %v", vaultConfig.VaultID, err)
+			}
+			// Create embed for first check
+			embed := types.DiscordEmbed{
+				Title:       fmt.Sprintf("Rate Status: %s", vaultConfig.Nickname),
+				Description: fmt.Sprintf("First rate check for %s", vaultConfig.Nickname),
+				Color:       0x808080, // Gray for first check
+				Fields: []types.DiscordEmbedField{
+					{
+						Name:   fmt.Sprintf("**Current Rate:** %s", m.formatRate(vaultConfig, rate)),
+						Value:  " ",
+						Inline: false,
+					},
+					{
+						Name:   "Market Pair",
+						Value:  vaultConfig.MarketPair,
+						Inline: true,
+					},
+				},
+				Timestamp: time.Now().Format(time.RFC3339),
+				Footer: &types.DiscordEmbedFooter{
+					Text: types.DefaultFooter,
+				},
+			}
+			embeds = append(embeds, embed)
+			continue
+		}
+
+		// Compare the rate against the vault's baseline
+		compareRate := ComparisonBaseline(m.storage, vaultConfig, lastRate)
+		metrics.VaultAlertBaseline.WithLabelValues(vaultConfig.VaultID).Set(compareRate)
+
+		// Only send messages if there's an actual change that exceeds the threshold
+		alerted := vaultConfig.ExceedsThreshold(compareRate, rate)
+		if alerted && vaultConfig.BaselineStrategy == types.BaselineDailyOpen &&
+			vaultConfig.ExceedsThreshold(compareRate, lastRate) {
+			// Today's move already crossed the threshold on an earlier check
+			alerted = false
+		}
+		if alerted && vaultConfig.InCooldown(now) {
+			// Leave the baseline alone, so a move that outlasts the cooldown alerts once it ends
+			m.logger.Infof("Skipping alert for %s: in cooldown until %s", vaultConfig.Nickname, vaultConfig.CooldownEnds().UTC().Format("15:04 UTC"))
+			alerted = false
+		}
+		if alerted {
+			// Create alert using the existing alert format
+			alert := types.NewRateChangeAlert(
+				vaultConfig.VaultID,
+				vaultConfig.Nickname,
+				vaultConfig.MarketPair,
+				compareRate, // Use the comparison rate (last alert or last check)
+				rate,
+			)
+			m.addPercentileContext(alert)
+			m.addBandContext(alert, vaultConfig)
+			m.addAutomationContext(alert, vaultConfig)
+			m.add24hContext(alert)
+			m.addProjection(ctx, alert, vaultConfig)
+			m.addAlternatives(ctx, alert, vaultConfig)
+
+			// Send alert
+			m.dispatchAlert(ctx, alert, vaultConfig)
+
+			// Update the last alert rate
+			vaultConfig.LastAlertRate = rate
+			vaultConfig.LastAlertAt = now
+			if err := m.saveVaultState(vaultConfig); err != nil {
+				m.logger.Errorf("Failed to update last alert rate for %s: %v", vaultConfig.VaultID, err)
+			}
+		}
+
+		m.trackSustainedBreach(ctx, vaultConfig, compareRate, rate, alerted)
+
+		// Update last rate regardless of whether we sent an alert
+		if err := m.storage.UpdateLastRate(vaultConfig.VaultID, rate); err != nil {
+			m.logger.Errorf("Failed to update last rate for %s: %v", vaultConfig.VaultID, err)
+		}
+	}
+
+	// Only send status embeds if we have any to send
+	if len(embeds) > 0 {
+		// Send status embeds to all unique channels
+		channelMap := make(map[string]bool)
+		for _, vault := range vaults {
+			if !channelMap[vault.ChannelID] && vault.WebhookURL != "" {
+				payload := types.DiscordWebhookPayload{
+					Embeds: m.brand(vault, embeds),
+				}
+				jsonData, err := json.Marshal(payload)
+				if err != nil {
+					m.logger.Errorf("Failed to marshal webhook payload: %v", err)
+					continue
+				}
+
+				resp, err := m.httpClient.Post(vault.WebhookURL, "application/json", bytes.NewBuffer(jsonData))
+				if err != nil {
+					m.logger.Errorf("Failed to send webhook: %v", err)
+					continue
+				}
+				resp.Body.Close()
+
+				channelMap[vault.ChannelID] = true
+			}
+		}
+	}
+
+	return summary
+}
+
+// checkLiquiditySwing alerts when the market's total supply or borrow moved sharply within the
+// configured window, since large liquidity moves often precede rate spikes
+func (m *Monitor) checkLiquiditySwing(vault *types.VaultConfig, data *types.MarketData) {
+	limit := m.config.Monitor.LiquidityAlertPercent
+	window := time.Duration(m.config.Monitor.LiquidityWindowMin) * time.Minute
+	if limit <= 0 || window <= 0 || data.SupplyUSD <= 0 || vault.WebhookURL == "" {
+		return
+	}
+	// One alert per window, otherwise every check during a swing would repeat it
+	if data.Timestamp.Sub(vault.LiquidityAlertAt) < window {
+		return
+	}
+
+	// Compare against the newest sample with liquidity data from at least a window ago. The oldest
+	// sample inside the window would sit right on its edge when the window matches the check
+	// interval, so whether a swing was caught would depend on check timing. Samples more than two
+	// windows old are too stale to compare against.
+	var reference *types.RateSample
+	cutoff := data.Timestamp.Add(-window)
+	history := m.storage.GetRateHistory(vault.VaultID, cutoff.Add(-window))
+	for idx := range history {
+		if history[idx].Timestamp.After(cutoff) {
+			break
+		}
+		if history[idx].SupplyUSD > 0 {
+			reference = &history[idx]
+		}
+	}
+	if reference == nil {
+		return
+	}
+
+	supplyChange := percentChange(reference.SupplyUSD, data.SupplyUSD)
+	borrowChange := percentChange(reference.BorrowUSD, data.BorrowUSD)
+	if math.Abs(supplyChange) < limit && math.Abs(borrowChange) < limit {
+		return
+	}
+	if m.silenced(vault) {
+		return
+	}
+
+	elapsed := data.Timestamp.Sub(reference.Timestamp).Round(time.Minute)
+	embed := types.DiscordEmbed{
+		Title:       fmt.Sprintf("💧 Liquidity Swing: %s", vault.Nickname),
+		Description: fmt.Sprintf("Total supply or borrow moved more than %.0f%% in the last %s, which often precedes a rate move.", limit, elapsed),
+		Color:       0x3498db, // Blue for liquidity
+		Fields: []types.DiscordEmbedField{
+			{
+				Name:   "Total Supply",
+				Value:  fmt.Sprintf("%s → %s (%+.1f%%)", types.FormatUSD(reference.SupplyUSD), types.FormatUSD(data.SupplyUSD), supplyChange),
+				Inline: true,
+			},
+			{
+				Name:   "Total Borrow",
+				Value:  fmt.Sprintf("%s → %s (%+.1f%%)", types.FormatUSD(reference.BorrowUSD), types.FormatUSD(data.BorrowUSD), borrowChange),
+				Inline: true,
+			},
+			{
+				Name:   "Borrow APY",
+				Value:  m.formatRate(vault, data.BorrowRate),
+				Inline: true,
+			},
+		},
+		Timestamp: time.Now().Format(time.RFC3339),
+		Footer: &types.DiscordEmbedFooter{
+			Text: types.DefaultFooter,
+		},
+	}
+	if err := m.postWebhook(vault.WebhookURL, types.DiscordWebhookPayload{Embeds: m.brand(vault, []types.DiscordEmbed{embed})}); err != nil {
+		m.logger.Errorf("Failed to send liquidity alert for %s: %v", vault.VaultID, err)
+		return
+	}
+	m.logger.Infof("Liquidity swing for %s: supply %+.1f%%, borrow %+.1f%%", vault.VaultID, supplyChange, borrowChange)
+
+	vault.LiquidityAlertAt = data.Timestamp
+	if err := m.saveVaultState(vault); err != nil {
+		m.logger.Errorf("Failed to record liquidity alert for %s: %v", vault.VaultID, err)
+	}
+}
+
+// checkRiskEvents alerts when the market accrues bad debt or the API flags it with a new warning.
+// These matter far more to lenders than a rate move, so they aren't subject to the threshold.
+func (m *Monitor) checkRiskEvents(vault *types.VaultConfig, data *types.MarketData) {
+	current := make([]string, 0, len(data.Warnings))
+	for _, warning := range data.Warnings {
+		current = append(current, warning.Type)
+	}
+
+	// The first check only records the market's existing warnings and bad debt, so enrolling a
+	// vault in a market that already has them doesn't raise a burst of alerts
+	if !vault.RiskObserved {
+		vault.RiskObserved = true
+		vault.KnownWarnings = current
+		vault.BadDebtUSD = data.BadDebtUSD
+		if err := m.saveVaultState(vault); err != nil {
+			m.logger.Errorf("Failed to update risk state for %s: %v", vault.VaultID, err)
+		}
+		return
+	}
+
+	known := make(map[string]bool, len(vault.KnownWarnings))
+	for _, warning := range vault.KnownWarnings {
+		known[warning] = true
+	}
+
+	var fields []types.DiscordEmbedField
+	for _, warning := range data.Warnings {
+		if known[warning.Type] {
+			continue
+		}
+		fields = append(fields, types.DiscordEmbedField{
+			Name:   "Market Warning",
+			Value:  fmt.Sprintf("`%s` (%s)", warning.Type, strings.ToLower(warning.Level)),
+			Inline: true,
+		})
+	}
+
+	// Ignore sub-dollar changes from price fluctuations in the USD valuation
+	if data.BadDebtUSD > vault.BadDebtUSD+1 {
+		fields = append(fields, types.DiscordEmbedField{
+			Name:   "Bad Debt",
+			Value:  fmt.Sprintf("%s → %s", types.FormatUSD(vault.BadDebtUSD), types.FormatUSD(data.BadDebtUSD)),
+			Inline: true,
+		})
+	}
+
+	// Cleared warnings are forgotten so they alert again if the API re-flags the market
+	changed := len(fields) > 0 || len(current) != len(vault.KnownWarnings) || data.BadDebtUSD != vault.BadDebtUSD
+	vault.KnownWarnings = current
+	vault.BadDebtUSD = data.BadDebtUSD
+	if changed {
+		if err := m.saveVaultState(vault); err != nil {
+			m.logger.Errorf("Failed to update risk state for %s: %v", vault.VaultID, err)
+		}
+	}
+	if len(fields) == 0 {
+		return
+	}
+
+	m.logger.Warnf("Risk event on %s: %d new warning(s)/bad debt changes", vault.VaultID, len(fields))
+	if m.silenced(vault) || vault.WebhookURL == "" {
+		return
+	}
+
+	embed := types.DiscordEmbed{
+		Title:       fmt.Sprintf("☠️ Market Risk Event: %s", vault.Nickname),
+		Description: fmt.Sprintf("The %s market backing this vault has new risk flags. Review your position.", vault.MarketPair),
+		Color:       0x8b0000, // Dark red for risk events
+		Fields:      fields,
+		Timestamp:   time.Now().Format(time.RFC3339),
+		Footer: &types.DiscordEmbedFooter{
+			Text: types.DefaultFooter,
+		},
+	}
+	if err := m.postWebhook(vault.WebhookURL, types.DiscordWebhookPayload{Embeds: m.brand(vault, []types.DiscordEmbed{embed})}); err != nil {
+		m.logger.Errorf("Failed to send risk alert for %s: %v", vault.VaultID, err)
+	}
+}
+
+// kinkHysteresis is how far, in utilization points, a market must fall below the kink warning level
+// before it's considered clear, so utilization hovering at the level doesn't alert every check
+const kinkHysteresis = 1.0
+
+// checkKinkProximity warns when the market's utilization moves past the IRM's target, into the
+// steep part of the rate curve, and notes when it drops back. Rates spike quickly above the kink,
+// so this is the earliest sign of one.
+func (m *Monitor) checkKinkProximity(vault *types.VaultConfig, data *types.MarketData) {
+	if !m.config.Monitor.KinkAlerts {
+		return
+	}
+	target, ok := morpho.TargetUtilization(data.IRMAddress)
+	if !ok || data.Utilization <= 0 {
+		return
+	}
+
+	level := target - m.config.Monitor.KinkWarningMargin
+	var near bool
+	switch {
+	case !vault.NearKink && data.Utilization >= level:
+		near = true
+	case vault.NearKink && data.Utilization < level-kinkHysteresis:
+		near = false
+	default:
+		return
+	}
+
+	vault.NearKink = near
+	if err := m.saveVaultState(vault); err != nil {
+		m.logger.Errorf("Failed to update kink state for %s: %v", vault.VaultID, err)
+	}
+	m.logger.Infof("Utilization of %s is %.2f%% (kink at %.0f%%, near kink: %v)", vault.VaultID, data.Utilization, target, near)
+	if m.silenced(vault) || vault.WebhookURL == "" {
+		return
+	}
+
+	embed := types.DiscordEmbed{
+		Title:       fmt.Sprintf("📈 Utilization Near Kink: %s", vault.Nickname),
+		Description: fmt.Sprintf("Utilization of the %s market is past %.0f%%. Above the IRM's %.0f%% target the borrow rate rises steeply, so a spike may follow.", vault.MarketPair, level, target),
+		Color:       0xe67e22, // Orange for a leading indicator
+		Fields: []types.DiscordEmbedField{
+			{Name: "Utilization", Value: fmt.Sprintf("%.2f%%", data.Utilization), Inline: true},
+			{Name: "Borrow APY", Value: m.formatRate(vault, data.BorrowRate), Inline: true},
+		},
+		Timestamp: time.Now().Format(time.RFC3339),
+		Footer: &types.DiscordEmbedFooter{
+			Text: types.DefaultFooter,
+		},
+	}
+	if near {
+		if projected, ok := morpho.ProjectBorrowRate(data.BorrowRate, data.Utilization, 100); ok {
+			embed.Fields = append(embed.Fields, types.DiscordEmbedField{
+				Name: "At 100% Utilization", Value: "~" + m.formatRate(vault, projected), Inline: true,
+			})
+		}
+	} else {
+		embed.Title = fmt.Sprintf("📉 Utilization Below Kink: %s", vault.Nickname)
+		embed.Description = fmt.Sprintf("Utilization of the %s market is back below %.0f%%, on the flatter part of the rate curve.", vault.MarketPair, level)
+		embed.Color = 0x00ff00 // Green for recovery
+	}
+	if err := m.postWebhook(vault.WebhookURL, types.DiscordWebhookPayload{Embeds: m.brand(vault, []types.DiscordEmbed{embed})}); err != nil {
+		m.logger.Errorf("Failed to send kink alert for %s: %v", vault.VaultID, err)
+	}
+}
+
+// headroomHysteresis is how far, in LTV points, headroom must recover past the margin before the
+// position is considered clear, so an LTV hovering at the margin doesn't alert every check
+const headroomHysteresis = 1.0
+
+// checkHeadroom tracks how far the vault's Summer.fi position is from its market's LLTV, and warns
+// when that headroom falls below monitor.headroom_margin and again once it recovers. Headroom
+// measures liquidation risk the same way on markets with different LLTVs.
+func (m *Monitor) checkHeadroom(vault *types.VaultConfig, data *types.MarketData) {
+	position := vault.Position
+	if position == nil || position.CollateralUSD <= 0 || data.LLTV <= 0 {
+		return
+	}
+	headroom := position.Headroom(data.LLTV)
+	metrics.VaultLTVHeadroom.WithLabelValues(vault.VaultID).Set(headroom)
+
+	margin := m.config.Monitor.HeadroomMargin
+	if margin <= 0 {
+		return
+	}
+	var low bool
+	switch {
+	case !vault.LowHeadroom && headroom < margin:
+		low = true
+	case vault.LowHeadroom && headroom >= margin+headroomHysteresis:
+		low = false
+	default:
+		return
+	}
+
+	vault.LowHeadroom = low
+	if err := m.saveVaultState(vault); err != nil {
+		m.logger.Errorf("Failed to update headroom state for %s: %v", vault.VaultID, err)
+	}
+	m.logger.Infof("LTV of %s is %.2f%% (LLTV %.1f%%, headroom %.2f points, low: %v)", vault.VaultID, position.LTV(), data.LLTV, headroom, low)
+	if m.silenced(vault) || vault.WebhookURL == "" {
+		return
+	}
+
+	embed := types.DiscordEmbed{
+		Title: fmt.Sprintf("🧯 Low LTV Headroom: %s", vault.Nickname),
+		Description: fmt.Sprintf("The position's LTV is within %.0f points of the %s market's %.1f%% LLTV. A %.1f%% fall in the %s price would make it liquidatable.",
+			margin, vault.MarketPair, data.LLTV, position.PriceBuffer(data.LLTV), position.CollateralSymbol),
+		Color: 0xe74c3c, // Red for liquidation risk
+		Fields: []types.DiscordEmbedField{
+			{Name: "LTV", Value: fmt.Sprintf("%.2f%%", position.LTV()), Inline: true},
+			{Name: "LLTV", Value: fmt.Sprintf("%.1f%%", data.LLTV), Inline: true},
+			{Name: "Headroom", Value: fmt.Sprintf("%.2f points", headroom), Inline: true},
+		},
+		Timestamp: time.Now().Format(time.RFC3339),
+		Footer: &types.DiscordEmbedFooter{
+			Text: types.DefaultFooter,
+		},
+	}
+	if position.LiquidationPrice > 0 {
+		embed.Fields = append(embed.Fields, types.DiscordEmbedField{
+			Name: "Liquidation Price", Value: fmt.Sprintf("$%.2f", position.LiquidationPrice), Inline: true,
+		})
+	}
+	if !low {
+		embed.Title = fmt.Sprintf("✅ LTV Headroom Restored: %s", vault.Nickname)
+		embed.Description = fmt.Sprintf("The position's LTV is back more than %.0f points below the %s market's %.1f%% LLTV.", margin+headroomHysteresis, vault.MarketPair, data.LLTV)
+		embed.Color = 0x00ff00 // Green for recovery
+	}
+	if err := m.postWebhook(vault.WebhookURL, types.DiscordWebhookPayload{Embeds: m.brand(vault, []types.DiscordEmbed{embed})}); err != nil {
+		m.logger.Errorf("Failed to send headroom alert for %s: %v", vault.VaultID, err)
+	}
+}
+
+// refreshPosition updates the vault's Summer.fi position metadata. If the API can't be reached,
+// the previous position is kept. Vaults monitoring a market rather than a position are skipped.
+func (m *Monitor) refreshPosition(ctx context.Context, vault *types.VaultConfig) {
+	if m.summerfi == nil || !vault.HasPosition() {
+		return
+	}
+	position, err := m.summerfi.GetPosition(ctx, vault.VaultID)
+	if err != nil {
+		m.logger.Warnf("Failed to fetch Summer.fi position for %s: %v", vault.VaultID, err)
+		return
+	}
+	vault.Position = position
+	if err := m.saveVaultState(vault); err != nil {
+		m.logger.Errorf("Failed to update position for %s: %v", vault.VaultID, err)
+	}
+}
+
+// RuleEnv returns the values a vault's rule expressions are evaluated against. Variables that
+// can't be computed yet, like change24h before any history exists or debtUsd without Summer.fi
+// position data, are left out.
+func RuleEnv(store storage.Storage, vault *types.VaultConfig, data *types.MarketData) rules.Env {
+	vaultID := vault.VaultID
+	env := rules.Env{
+		"borrowApy":  data.BorrowRate,
+		"supplyApy":  data.SupplyRate,
+		"supplyUsd":  data.SupplyUSD,
+		"borrowUsd":  data.BorrowUSD,
+		"badDebtUsd": data.BadDebtUSD,
+	}
+	if data.SupplyUSD > 0 {
+		env["utilization"] = data.BorrowUSD / data.SupplyUSD
+	}
+	if history := store.GetRateHistory(vaultID, data.Timestamp.Add(-24*time.Hour)); len(history) > 0 {
+		env["change24h"] = data.BorrowRate - history[0].BorrowRate
+	}
+	if index, ok := stats.VolatilityIndex(store.GetRateHistory(vaultID, data.Timestamp.Add(-stats.VolatilityWindow))); ok {
+		env["volatility"] = index
+	}
+	if data.LLTV > 0 {
+		env["lltv"] = data.LLTV
+	}
+	if vault.Position != nil {
+		env["debtUsd"] = vault.Position.DebtUSD
+		if vault.Position.CollateralUSD > 0 {
+			env["ltv"] = vault.Position.LTV()
+			if data.LLTV > 0 {
+				env["headroom"] = vault.Position.Headroom(data.LLTV)
+			}
+		}
+	}
+	return env
+}
+
+// evaluateRules alerts for each of the vault's rules that became true on this check
+func (m *Monitor) evaluateRules(vault *types.VaultConfig, data *types.MarketData) {
+	if len(vault.Rules) == 0 {
+		return
+	}
+
+	env := RuleEnv(m.storage, vault, data)
+	changed := false
+	for _, rule := range vault.Rules {
+		expr, err := rules.Parse(rule.Expression)
+		if err != nil {
+			m.logger.Errorf("Invalid rule %s on %s: %v", rule.ID, vault.VaultID, err)
+			continue
+		}
+		holds, err := expr.Eval(env)
+		if err != nil {
+			m.logger.Warnf("Skipping rule %s on %s: %v", rule.ID, vault.VaultID, err)
+			continue
+		}
+		if holds == rule.Active {
+			continue
+		}
+
+		rule.Active = holds
+		changed = true
+		if !holds {
+			m.logger.Infof("Rule %s on %s cleared", rule.ID, vault.VaultID)
+			continue
+		}
+
+		m.logger.Infof("Rule %s on %s triggered: %s", rule.ID, vault.VaultID, rule.Expression)
+		if m.silenced(vault) || vault.WebhookURL == "" {
+			continue
+		}
+
+		values := make([]string, 0, len(env))
+		for _, name := range rules.VariableNames() {
+			if value, ok := env[name]; ok {
+				values = append(values, fmt.Sprintf("%s = %.4g", name, value))
+			}
+		}
+		embed := types.DiscordEmbed{
+			Title:       fmt.Sprintf("📐 Rule Triggered: %s", vault.Nickname),
+			Description: fmt.Sprintf("`%s`", rule.Expression),
+			Color:       0x9b59b6, // Purple for rules
+			Fields: []types.DiscordEmbedField{
+				{
+					Name:   "Values",
+					Value:  "```\n" + strings.Join(values, "\n") + "\n```",
+					Inline: false,
+				},
+			},
+			Timestamp: time.Now().Format(time.RFC3339),
+			Footer: &types.DiscordEmbedFooter{
+				Text: fmt.Sprintf("%s • Rule %s", types.DefaultFooter, rule.ID),
+			},
+		}
+		if err := m.postWebhook(vault.WebhookURL, types.DiscordWebhookPayload{Embeds: m.brand(vault, []types.DiscordEmbed{embed})}); err != nil {
+			m.logger.Errorf("Failed to send rule alert for %s: %v", vault.VaultID, err)
+		}
+	}
+
+	if changed {
+		if err := m.saveVaultState(vault); err != nil {
+			m.logger.Errorf("Failed to update rule state for %s: %v", vault.VaultID, err)
+		}
+	}
+}
+
+// percentChange returns the change from old to current in percent, or 0 if old is zero
+func percentChange(old, current float64) float64 {
+	if old == 0 {
+		return 0
+	}
+	return (current - old) / old * 100
+}
+
+// addPercentileContext ranks the alert's current rate against the vault's recent history
+func (m *Monitor) addPercentileContext(alert *types.RateChangeAlert) {
+	since := time.Now().AddDate(0, 0, -percentileWindowDays)
+	history := m.storage.GetRateHistory(alert.VaultID, since)
+	if len(history) < minPercentileSamples {
+		return
+	}
+	alert.SetPercentile(stats.Percentile(history, alert.CurrentRate), percentileWindowDays)
+}
+
+// addBandContext attaches the vault's normal rate range, which grades the alert's color: the band
+// set with /band, or else the recent average ± 2 standard deviations
+func (m *Monitor) addBandContext(alert *types.RateChangeAlert, vault *types.VaultConfig) {
+	if vault.Band != nil {
+		band := *vault.Band
+		band.Source = "configured"
+		alert.Band = &band
+		return
+	}
+
+	since := time.Now().AddDate(0, 0, -percentileWindowDays)
+	history := m.storage.GetRateHistory(alert.VaultID, since)
+	if len(history) < minPercentileSamples {
+		return
+	}
+	mean, stddev, _ := stats.MeanStdDev(history)
+	if stddev == 0 {
+		return
+	}
+	alert.Band = &types.RateBand{
+		Low:    math.Max(0, mean-2*stddev),
+		High:   mean + 2*stddev,
+		Source: fmt.Sprintf("%d-day average ± 2σ", percentileWindowDays),
+	}
+}
+
+// addAutomationContext notes any Summer.fi automation on the vault's position, which may already
+// act on what the alert reports
+func (m *Monitor) addAutomationContext(alert *types.RateChangeAlert, vault *types.VaultConfig) {
+	if vault.Position == nil || len(vault.Position.Automations) == 0 {
+		return
+	}
+	alert.Automations = vault.Position.DescribeAutomations()
+}
+
+// addAlternatives attaches the vault's loan asset's borrow rate on other protocols
+func (m *Monitor) addAlternatives(ctx context.Context, alert *types.RateChangeAlert, vault *types.VaultConfig) {
+	symbol := vault.LoanSymbol()
+	if m.protocols == nil || symbol == "" {
+		return
+	}
+	rates, err := m.protocols.GetBorrowRates(ctx, symbol)
+	if err != nil {
+		m.logger.Warnf("Failed to fetch %s rates on other protocols for %s: %v", symbol, vault.VaultID, err)
+		return
+	}
+	alert.Alternatives = rates
+}
+
+// add24hContext attaches the intraday high, low, and net change so the alert isn't just a bare previous/current pair
+func (m *Monitor) add24hContext(alert *types.RateChangeAlert) {
+	history := m.storage.GetRateHistory(alert.VaultID, time.Now().Add(-24*time.Hour))
+	if summary, ok := stats.Summarize(history); ok {
+		alert.Set24hRange(summary.High, summary.Low, summary.NetChange)
+	}
+}
+
+// addProjection attaches the borrow rate the market's IRM would charge at the configured utilization
+func (m *Monitor) addProjection(ctx context.Context, alert *types.RateChangeAlert, vault *types.VaultConfig) {
+	target := m.config.Monitor.ProjectionUtilization
+	chainID, ok := vault.ChainID()
+	if target <= 0 || vault.MorphoMarketKey == "" || !ok {
+		return
+	}
+
+	info, err := m.morphoClient.GetMarketInfo(ctx, vault.MorphoMarketKey, chainID)
+	if err != nil {
+		m.logger.Warnf("Failed to fetch market info for projection on %s: %v", vault.VaultID, err)
+		return
+	}
+	if !morpho.IsAdaptiveCurveIRM(info.IRMAddress) || info.Utilization >= target {
+		return
+	}
+
+	if projected, ok := morpho.ProjectBorrowRate(info.BorrowRate, info.Utilization, target); ok {
+		alert.SetProjection(info.Utilization, target, projected)
+	}
+}
+
+// resolveDisabledCritical resolves the open critical alert of a vault whose critical level was
+// turned off by /critical. The caller must hold cycleMu.
+func (m *Monitor) resolveDisabledCritical(vaultID string) error {
+	vault, err := m.storage.GetVault(vaultID)
+	if err != nil {
+		return fmt.Errorf("failed to get vault %s: %w", vaultID, err)
+	}
+	if vault == nil || !vault.CriticalActive {
+		return nil
+	}
+
+	m.checkStart = map[string]*types.VaultConfig{vaultID: vault.Clone()}
+	rate, _ := m.storage.GetLastRate(vaultID)
+	m.checkCriticalLevel(context.Background(), vault, rate, rate)
+	return nil
+}
+
+// checkCriticalLevel raises a critical alert when the rate reaches the vault's critical level
+// and resolves it once the rate falls back below, or once the level is turned off
+func (m *Monitor) checkCriticalLevel(ctx context.Context, vault *types.VaultConfig, previousRate, currentRate float64) {
+	if vault.CriticalRate <= 0 && !vault.CriticalActive {
+		return
+	}
+
+	above := vault.CriticalRate > 0 && currentRate >= vault.CriticalRate
+	if above == vault.CriticalActive {
+		return
+	}
+
+	alert := types.NewCriticalAlert(
+		vault.VaultID,
+		vault.Nickname,
+		vault.MarketPair,
+		previousRate,
+		currentRate,
+		vault.CriticalRate,
+	)
+	alert.Format = m.rateFormat(vault) // Also used by the resolve below
+
+	if above {
+		m.logger.Warnf("Vault %s reached critical level: %.2f%% >= %.2f%%", vault.Nickname, currentRate, vault.CriticalRate)
+		m.addPercentileContext(alert)
+		m.addBandContext(alert, vault)
+		m.addAutomationContext(alert, vault)
+		m.add24hContext(alert)
+		m.addAlternatives(ctx, alert, vault)
+		m.dispatchAlert(ctx, alert, vault)
+	} else {
+		var message string
+		if vault.CriticalRate <= 0 {
+			m.logger.Infof("Vault %s critical level turned off while active, resolving", vault.Nickname)
+			message = fmt.Sprintf(
+				"✅ **Resolved: %s**\nCritical alerts were turned off (borrow rate now %s)",
+				vault.Nickname, m.formatRate(vault, currentRate),
+			)
+		} else {
+			m.logger.Infof("Vault %s recovered below critical level: %.2f%% < %.2f%%", vault.Nickname, currentRate, vault.CriticalRate)
+			message = fmt.Sprintf(
+				"✅ **Recovered: %s**\nBorrow rate is back below the critical level of %s (now %s)",
+				vault.Nickname, m.formatRate(vault, vault.CriticalRate), m.formatRate(vault, currentRate),
+			)
+		}
+		if !m.silenced(vault) {
+			if err := m.postWebhook(vault.WebhookFor(types.SeverityCritical), map[string]interface{}{"content": message}); err != nil {
+				m.logger.Errorf("Failed to send recovery message for %s: %v", vault.VaultID, err)
+			}
+		}
+		if vault.CriticalMessageID != "" {
+			m.resolveMessages(vault, types.SeverityCritical, []string{vault.CriticalMessageID}, currentRate)
+			vault.CriticalMessageID = ""
+		}
+		delete(m.lastReping, vault.CriticalAlertID)
+		vault.CriticalAlertID = ""
+		m.notifier.Resolve(ctx, alert, vault)
+	}
+
+	vault.CriticalActive = above
+	if err := m.saveVaultState(vault); err != nil {
+		m.logger.Errorf("Failed to update critical state for %s: %v", vault.VaultID, err)
+	}
+}
+
+// trackFailures counts consecutive fetch failures per vault, warning the channel and eventually
+// disabling vaults whose market can no longer be fetched. Only vaults missing from an otherwise
+// successful fetch count: when the whole fetch fails the API is down, not the vaults' markets, and
+// counting it would disable every vault after one outage.
+func (m *Monitor) trackFailures(vaults []*types.VaultConfig, marketData []*types.MarketData, fetchErr error) {
+	if fetchErr != nil {
+		m.logger.Warnf("Market data fetch failed, not counting it against %d vaults: %v", len(vaults), fetchErr)
+		return
+	}
+	if len(marketData) == 0 {
+		m.logger.Warnf("Market data fetch returned no markets, not counting it against %d vaults", len(vaults))
+		return
+	}
+
+	fetched := make(map[string]bool, len(marketData))
+	for _, data := range marketData {
+		fetched[data.VaultID] = true
+	}
+
+	alertAfter := m.config.Monitor.FailureAlertAfter
+	disableAfter := m.config.Monitor.DisableAfterFailures
+
+	for _, vault := range vaults {
+		if fetched[vault.VaultID] {
+			if vault.FailureCount > 0 {
+				m.logger.Infof("Vault %s recovered after %d failed checks", vault.Nickname, vault.FailureCount)
+				vault.FailureCount = 0
+				if err := m.saveVaultState(vault); err != nil {
+					m.logger.Errorf("Failed to reset failure count for %s: %v", vault.VaultID, err)
+				}
+			}
+			continue
+		}
+
+		vault.FailureCount++
+		m.logger.Warnf("No market data for vault %s (%d consecutive failures)", vault.Nickname, vault.FailureCount)
+
+		var message string
+		if disableAfter > 0 && vault.FailureCount >= disableAfter {
+			vault.Disabled = true
+			message = fmt.Sprintf(
+				"⏸️ **%s** (`%s`) has been disabled after %d consecutive failed checks. Fix the market or run `/enable %s` to try again.",
+				vault.Nickname, vault.VaultID, vault.FailureCount, vault.VaultID,
+			)
+		} else if alertAfter > 0 && vault.FailureCount == alertAfter {
+			message = fmt.Sprintf(
+				"⚠️ I couldn't fetch market data for **%s** (`%s`) on the last %d checks, the market may no longer exist. I'll keep trying.",
+				vault.Nickname, vault.VaultID, vault.FailureCount,
+			)
+		}
+
+		if message != "" && !m.inMaintenance() {
+			if err := m.postWebhook(vault.WebhookURL, map[string]interface{}{"content": message}); err != nil {
+				m.logger.Errorf("Failed to send failure notice for %s: %v", vault.VaultID, err)
+			}
+		}
+
+		if err := m.saveVaultState(vault); err != nil {
+			m.logger.Errorf("Failed to update failure count for %s: %v", vault.VaultID, err)
+		}
+	}
+}
+
+// ComparisonBaseline returns the rate a vault's current rate is compared against, per its baseline strategy
+func ComparisonBaseline(store storage.Storage, vault *types.VaultConfig, lastRate float64) float64 {
+	switch vault.BaselineStrategy {
+	case types.BaselinePreviousCheck:
+		return lastRate
+	case types.BaselineDailyOpen:
+		now := time.Now().UTC()
+		midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		if today := store.GetRateHistory(vault.VaultID, midnight); len(today) > 0 {
+			return today[0].BorrowRate
+		}
+		return lastRate
+	default:
+		// If LastAlertRate is not set (0), use the last check rate
+		if vault.LastAlertRate == 0 {
+			return lastRate
+		}
+		return vault.LastAlertRate
+	}
+}
+
+// AlertRate returns the borrow rate a vault's threshold is checked against, per its rate source.
+// A trailing average is the mean of the samples recorded within the vault's window, including the
+// one just taken; until the window holds two samples it falls back to the API's daily average.
+func AlertRate(store storage.Storage, vault *types.VaultConfig, data *types.MarketData) float64 {
+	if vault.RateSource != types.RateTrailingAverage {
+		return vault.AlertRate(data)
+	}
+	samples := store.GetRateHistory(vault.VaultID, data.Timestamp.Add(-vault.AverageWindow()))
+	if mean, _, ok := stats.MeanStdDev(samples); ok && len(samples) >= 2 {
+		return mean
+	}
+	return vault.AlertRate(data)
+}
+
+// saveVaultState persists the monitor's changes to a vault. The monitor works on a copy read at the
+// start of the check, so only the fields it maintains and changed since are written back, under the
+// storage lock; settings and state changed by commands in the meantime are kept, and a vault
+// removed mid-check isn't re-created. During a check the write is left to flushVaultState.
+func (m *Monitor) saveVaultState(vault *types.VaultConfig) error {
+	if m.pendingState != nil {
+		m.pendingState[vault.VaultID] = vault.Clone()
+		return nil
+	}
+
+	base := m.checkStart[vault.VaultID]
+	err := m.storage.UpdateVault(vault.VaultID, func(stored *types.VaultConfig) error {
+		if base == nil {
+			// Not read by this check, so write back whatever differs
+			base = stored.Clone()
+		}
+		stored.CopyMonitorState(vault, base)
+		return nil
+	})
+	if err == nil && m.checkStart != nil {
+		m.checkStart[vault.VaultID] = vault.Clone()
+	}
+	return err
+}
+
+// flushVaultState writes the vaults saved during the check in one storage update and ends the
+// buffering
+func (m *Monitor) flushVaultState() {
+	pending := m.pendingState
+	m.pendingState = nil
+	if len(pending) == 0 {
+		return
+	}
+
+	updates := make(map[string]func(stored *types.VaultConfig) error, len(pending))
+	for vaultID, vault := range pending {
+		base := m.checkStart[vaultID]
+		updates[vaultID] = func(stored *types.VaultConfig) error {
+			if base == nil {
+				base = stored.Clone()
+			}
+			stored.CopyMonitorState(vault, base)
+			return nil
+		}
+	}
+	if err := m.storage.UpdateVaults(updates); err != nil {
+		m.logger.Errorf("Failed to save state of %d vaults: %v", len(pending), err)
+		return
+	}
+	for vaultID, vault := range pending {
+		m.checkStart[vaultID] = vault
+	}
+}
+
+// inMaintenance reports whether alert delivery is currently silenced by /maintenance. A read-only
+// mirror is always silenced, since the instance it mirrors already delivers to the same webhooks.
+func (m *Monitor) inMaintenance() bool {
+	return m.config.Discord.ReadOnly || m.storage.GetSettings().InMaintenance(time.Now())
+}
+
+// silenced reports whether the vault's alerts are currently held back, by /maintenance or by /pause
+func (m *Monitor) silenced(vault *types.VaultConfig) bool {
+	return m.inMaintenance() || vault.AlertsPaused(time.Now())
+}
+
+// repingUnacknowledged reminds the channel about a critical alert nobody has acknowledged yet
+func (m *Monitor) repingUnacknowledged(vault *types.VaultConfig, currentRate float64) {
+	delay := time.Duration(m.config.Monitor.CriticalRepingMinutes) * time.Minute
+	if delay <= 0 || !vault.CriticalActive || vault.CriticalAlertID == "" || m.silenced(vault) {
+		return
+	}
+
+	alert := m.storage.GetAlert(vault.CriticalAlertID)
+	if alert == nil || alert.Acknowledged() {
+		return
+	}
+
+	last, pinged := m.lastReping[alert.ID]
+	if !pinged {
+		last = alert.Timestamp
+	}
+	if time.Since(last) < delay {
+		return
+	}
+
+	payload := types.DiscordWebhookPayload{
+		Content: fmt.Sprintf(
+			"🔔 Critical alert `%s` for **%s** is still unacknowledged (borrow rate now %s). Use `/ack %s` or the Ack button.",
+			alert.ID, vault.Nickname, m.formatRate(vault, currentRate), alert.ID,
+		),
+		Embeds: []types.DiscordEmbed{},
+	}
+	if vault.EscalationRoleID != "" {
+		payload.Content = fmt.Sprintf("<@&%s> %s", vault.EscalationRoleID, payload.Content)
+		payload.AllowedMentions = &types.DiscordAllowedMentions{Roles: []string{vault.EscalationRoleID}}
+	}
+
+	if err := m.postWebhook(vault.WebhookFor(types.SeverityCritical), payload); err != nil {
+		m.logger.Errorf("Failed to re-ping critical alert %s for %s: %v", alert.ID, vault.VaultID, err)
+		return
+	}
+	m.lastReping[alert.ID] = time.Now()
+}
+
+// trackSustainedBreach counts consecutive checks the rate stays beyond the threshold from where it
+// was before the breach began, and escalates once the breach lasts Monitor.EscalateAfterChecks checks
+func (m *Monitor) trackSustainedBreach(ctx context.Context, vault *types.VaultConfig, compareRate, currentRate float64, alerted bool) {
+	if vault.BreachBaseline == 0 {
+		if !alerted {
+			return
+		}
+		// A new breach starts counting from the rate the alert compared against
+		vault.BreachBaseline = compareRate
+		vault.BreachChecks = 1
+	} else if vault.ExceedsThreshold(vault.BreachBaseline, currentRate) {
+		vault.BreachChecks++
+	} else {
+		m.logger.Infof("Breach cleared for %s after %d checks", vault.Nickname, vault.BreachChecks)
+		m.resolveMessages(vault, types.SeverityWarning, vault.OpenAlertMessages, currentRate)
+		vault.OpenAlertMessages = nil
+		vault.BreachBaseline = 0
+		vault.BreachChecks = 0
+		vault.BreachEscalated = false
+	}
+
+	limit := m.config.Monitor.EscalateAfterChecks
+	if vault.BreachBaseline != 0 && limit > 0 && vault.BreachChecks > limit && !vault.BreachEscalated {
+		alert := types.NewEscalationAlert(
+			vault.VaultID,
+			vault.Nickname,
+			vault.MarketPair,
+			vault.BreachBaseline,
+			currentRate,
+			vault.BreachChecks,
+		)
+		m.addPercentileContext(alert)
+		m.addBandContext(alert, vault)
+		m.addAutomationContext(alert, vault)
+		m.add24hContext(alert)
+
+		m.logger.Warnf("Escalating sustained breach for %s after %d checks", vault.Nickname, vault.BreachChecks)
+		m.dispatchAlert(ctx, alert, vault)
+		m.sendEscalationDM(vault, alert)
+		vault.BreachEscalated = true
+	}
+
+	if err := m.saveVaultState(vault); err != nil {
+		m.logger.Errorf("Failed to update breach state for %s: %v", vault.VaultID, err)
+	}
+}
+
+// sendEscalationDM notifies the vault's escalation user directly, if one is set
+func (m *Monitor) sendEscalationDM(vault *types.VaultConfig, alert *types.RateChangeAlert) {
+	if vault.EscalationUserID == "" || m.dm == nil {
+		return
+	}
+
+	payload := alert.ToDiscordEmbed()
+	payload.Embeds = m.brand(vault, payload.Embeds)
+	payload.Content = "⏫ A rate breach has persisted and was escalated to you:"
+	start := time.Now()
+	err := m.dm.SendDirectMessage(vault.EscalationUserID, payload)
+	notify.RecordOutcome(m.storage, m.logger, alert.VaultID, "discord_dm", start, err)
+	if err != nil {
+		m.logger.Errorf("Failed to DM escalation for %s: %v", vault.VaultID, err)
+	}
+}
+
+// dispatchAlert delivers an alert to the vault's Discord channel and every eligible sink, then records it.
+// Warnings raised outside the vault's alert schedule are added to vault.HeldAlerts instead of being
+// delivered; callers persist the vault afterwards.
+func (m *Monitor) dispatchAlert(ctx context.Context, alert *types.RateChangeAlert, vault *types.VaultConfig) {
+	alert.Format = m.rateFormat(vault)
+	if alert.Severity != types.SeverityCritical {
+		alert.Threshold = vault.AlertThreshold()
+	}
+	if m.recentlyDispatched(alert) {
+		m.logger.Infof("Suppressing duplicate %s alert for %s raised within %s", alert.Severity, vault.Nickname, alertDedupWindow)
+		return
+	}
+	m.cycleAlerts++
+	if m.silenced(vault) {
+		m.logger.Infof("Maintenance mode active or vault paused, not delivering alert for %s", vault.Nickname)
+		if err := m.storage.RecordAlert(alert); err != nil {
+			m.logger.Errorf("Failed to record alert for %s: %v", alert.VaultID, err)
+		}
+		return
+	}
+
+	if vault.AlertSchedule != nil && alert.Severity != types.SeverityCritical && !vault.AlertSchedule.Allows(time.Now()) {
+		m.logger.Infof("Holding alert for %s until its alert window opens (%s)", vault.Nickname, vault.AlertSchedule)
+		vault.HeldAlerts = append(vault.HeldAlerts, alert)
+		if err := m.storage.RecordAlert(alert); err != nil {
+			m.logger.Errorf("Failed to record alert for %s: %v", alert.VaultID, err)
+		}
+		return
+	}
+
+	if err := m.sendDiscordAlert(ctx, alert); err != nil {
+		m.logger.Errorf("Failed to send Discord alert: %v", err)
+	}
+	if alert.Severity == types.SeverityCritical {
+		vault.CriticalAlertID = alert.ID
+	}
+	if alert.MessageID != "" {
+		if alert.Severity == types.SeverityCritical {
+			vault.CriticalMessageID = alert.MessageID
+		} else {
+			vault.OpenAlertMessages = append(vault.OpenAlertMessages, alert.MessageID)
+		}
+	}
+	m.notifier.Notify(ctx, alert, vault)
+
+	if err := m.storage.RecordAlert(alert); err != nil {
+		m.logger.Errorf("Failed to record alert for %s: %v", alert.VaultID, err)
+	}
+	m.broker.Publish(events.TypeAlert, alert)
+}
+
+// recentlyDispatched reports whether an identical alert was dispatched within alertDedupWindow,
+// and otherwise remembers this one. Expired entries are dropped as it goes.
+func (m *Monitor) recentlyDispatched(alert *types.RateChangeAlert) bool {
+	now := time.Now()
+	for key, at := range m.recentAlerts {
+		if now.Sub(at) >= alertDedupWindow {
+			delete(m.recentAlerts, key)
+		}
+	}
+
+	key := alert.DedupKey()
+	if _, seen := m.recentAlerts[key]; seen {
+		return true
+	}
+	m.recentAlerts[key] = now
+	return false
+}
+
+// releaseHeldAlerts posts a summary of alerts held outside the vault's schedule once its window opens
+func (m *Monitor) releaseHeldAlerts(vault *types.VaultConfig) {
+	if len(vault.HeldAlerts) == 0 || m.silenced(vault) {
+		return
+	}
+	if vault.AlertSchedule != nil && !vault.AlertSchedule.Allows(time.Now()) {
+		return
+	}
+
+	var lines strings.Builder
+	for _, held := range vault.HeldAlerts {
+		lines.WriteString(fmt.Sprintf("• <t:%d:f> %s → %s (%s)\n",
+			held.Timestamp.Unix(), held.FormatRate(held.PreviousRate), held.FormatRate(held.CurrentRate), held.FormatPoints(held.ChangePercent)))
+	}
+
+	embed := types.DiscordEmbed{
+		Title:       fmt.Sprintf("Held Alerts: %s", vault.Nickname),
+		Description: fmt.Sprintf("%d alert(s) were raised outside this vault's alert window:\n\n%s", len(vault.HeldAlerts), lines.String()),
+		Color:       0x3498db, // Blue for summaries
+		Timestamp:   time.Now().Format(time.RFC3339),
+		Footer: &types.DiscordEmbedFooter{
+			Text: types.DefaultFooter,
+		},
+	}
+	if err := m.postWebhook(vault.WebhookURL, types.DiscordWebhookPayload{Embeds: m.brand(vault, []types.DiscordEmbed{embed})}); err != nil {
+		m.logger.Errorf("Failed to send held alert summary for %s: %v", vault.VaultID, err)
+		return
+	}
+
+	vault.HeldAlerts = nil
+	if err := m.saveVaultState(vault); err != nil {
+		m.logger.Errorf("Failed to clear held alerts for %s: %v", vault.VaultID, err)
+	}
+}
+
+func (m *Monitor) processMarketData(marketData *types.MarketData) error {
+	vault, err := m.storage.GetVault(marketData.VaultID)
+	if err != nil {
+		return fmt.Errorf("failed to get vault config: %w", err)
+	}
+
+	if vault == nil {
+		m.logger.Warnf("Received data for unknown vault: %s", marketData.VaultID)
+		return nil
+	}
+
+	currentRate := AlertRate(m.storage, vault, marketData)
+	previousRate, hasPreviousRate := m.storage.GetLastRate(marketData.VaultID)
+
+	// Update the last rate
+	if err := m.storage.UpdateLastRate(marketData.VaultID, currentRate); err != nil {
+		m.logger.Errorf("Failed to update last rate for vault %s: %v", marketData.VaultID, err)
+	}
+
+	// Check if we should send an alert
+	if hasPreviousRate {
+		// Alert on both increases and decreases that exceed threshold
+		if vault.ExceedsThreshold(previousRate, currentRate) {
+			alert := types.NewRateChangeAlert(
+				vault.VaultID,
+				vault.Nickname,
+				vault.MarketPair,
+				previousRate,
+				currentRate,
+			)
+			alert.Format = m.rateFormat(vault)
+			alert.Threshold = vault.AlertThreshold()
+
+			m.logger.Infof(
+				"Rate change alert for %s: %.2f%% → %.2f%% (%+.2f%%)",
+				vault.Nickname, previousRate, currentRate, alert.ChangePercent,
+			)
+
+			if err := m.sendDiscordAlert(context.Background(), alert); err != nil {
+				m.logger.Errorf("Failed to send Discord alert: %v", err)
+			}
+		}
+	} else {
+		m.logger.Infof("First check for vault %s (%s): %.2f%%", vault.VaultID, vault.Nickname, currentRate)
+	}
+
+	return nil
+}
+
+// sendDiscordAlert posts alert to the vault's webhook. Network errors, rate limits and server
+// errors are retried a few times, as long as ctx allows; if the webhook still fails, the alert
+// falls back to a DM.
+func (m *Monitor) sendDiscordAlert(ctx context.Context, alert *types.RateChangeAlert) error {
+	vault, err := m.storage.GetVault(alert.VaultID)
+	if err != nil {
+		return fmt.Errorf("failed to get vault config: %w", err)
+	}
+
+	if vault == nil {
+		return fmt.Errorf("vault %s not found", alert.VaultID)
+	}
+
+	// Alerts go to the channel routed for their severity, if any
+	webhookURL := vault.WebhookFor(alert.Severity)
+	if webhookURL == "" {
+		m.logger.Warnf("No webhook URL configured for vault %s, skipping alert", alert.VaultID)
+		return nil
+	}
+
+	payload := m.alertPayload(vault, alert)
+
+	// Retry the primary webhook before giving up on it
+	var lastErr error
+	delay := webhookRetryDelay
+retry:
+	for attempt := 1; attempt <= webhookAttempts; attempt++ {
+		start := time.Now()
+		alert.MessageID, lastErr = m.postWebhookMessage(ctx, webhookURL, payload)
+		notify.RecordOutcome(m.storage, m.logger, alert.VaultID, "discord_webhook", start, lastErr)
+		if lastErr == nil {
+			return nil
+		}
+		m.logger.Warnf("Webhook delivery attempt %d/%d failed for vault %s: %v", attempt, webhookAttempts, alert.VaultID, lastErr)
+		if attempt == webhookAttempts {
+			break
+		}
+
+		wait := delay
+		var statusErr *notify.StatusError
+		if errors.As(lastErr, &statusErr) {
+			if !statusErr.Retryable() {
+				break
+			}
+			if statusErr.RetryAfter > 0 {
+				wait = statusErr.RetryAfter
+			}
+		}
+		if wait > maxWebhookRetryDelay {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			break retry
+		case <-time.After(wait):
+		}
+		delay *= 2
+	}
+
+	// Fall back to a DM so a broken webhook degrades gracefully instead of dropping the alert
+	if vault.FallbackUserID == "" || m.dm == nil {
+		return lastErr
+	}
+	dmPayload := alert.ToDiscordEmbed()
+	dmPayload.Embeds = m.brand(vault, dmPayload.Embeds)
+	start := time.Now()
+	err = m.dm.SendDirectMessage(vault.FallbackUserID, dmPayload)
+	notify.RecordOutcome(m.storage, m.logger, alert.VaultID, "discord_dm", start, err)
+	if err != nil {
+		return fmt.Errorf("webhook failed (%v) and fallback DM failed: %w", lastErr, err)
+	}
+	m.logger.Infof("Delivered alert for vault %s to fallback user %s after webhook failures", alert.VaultID, vault.FallbackUserID)
+	return nil
+}
+
+// postWebhook sends a JSON payload to a Discord webhook
+func (m *Monitor) postWebhook(webhookURL string, payload interface{}) error {
+	_, err := m.postWebhookMessage(context.Background(), webhookURL, payload)
+	return err
+}
+
+// postWebhookMessage posts payload and returns the ID of the message Discord created,
+// so it can be edited later
+func (m *Monitor) postWebhookMessage(ctx context.Context, webhookURL string, payload interface{}) (string, error) {
+	if webhookURL == "" {
+		return "", fmt.Errorf("no webhook URL configured")
+	}
+
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal webhook payload: %w", err)
+	}
+
+	endpoint, err := webhookEndpoint(webhookURL, "")
+	if err != nil {
+		return "", err
+	}
+	query := endpoint.Query()
+	query.Set("wait", "true")
+	endpoint.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.String(), bytes.NewBuffer(jsonData))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", &notify.StatusError{StatusCode: resp.StatusCode, RetryAfter: notify.ParseRetryAfter(resp.Header.Get("Retry-After"))}
+	}
+
+	var message struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&message); err != nil {
+		m.logger.Warnf("Failed to decode webhook message: %v", err)
+	}
+	return message.ID, nil
+}
+
+// alertPayload builds the webhook message for alert: the branded embed plus any mention or
+// text-to-speech the vault asks for
+func (m *Monitor) alertPayload(vault *types.VaultConfig, alert *types.RateChangeAlert) *types.DiscordWebhookPayload {
+	payload := alert.ToDiscordEmbed()
+	payload.Embeds = m.brand(vault, payload.Embeds)
+	if alert.SustainedChecks > 0 && vault.EscalationRoleID != "" {
+		payload.Content = fmt.Sprintf("<@&%s>", vault.EscalationRoleID)
+		payload.AllowedMentions = &types.DiscordAllowedMentions{Roles: []string{vault.EscalationRoleID}}
+	}
+	if alert.Severity == types.SeverityCritical && (vault.CriticalMention != types.MentionNone || vault.CriticalTTS) {
+		// TTS reads the content rather than the embed, so spell the alert out
+		payload.Content = strings.TrimSpace(fmt.Sprintf("%s 🚨 Critical: %s borrow rate is at %s",
+			vault.CriticalMention.Tag(), vault.Nickname, alert.FormatRate(alert.CurrentRate)))
+		payload.TTS = vault.CriticalTTS
+		if vault.CriticalMention != types.MentionNone {
+			payload.AllowedMentions = &types.DiscordAllowedMentions{Parse: []string{"everyone"}}
+		}
+	}
+	return payload
+}
+
+// brand applies the /branding of the vault's server to embeds
+func (m *Monitor) brand(vault *types.VaultConfig, embeds []types.DiscordEmbed) []types.DiscordEmbed {
+	return m.storage.GetSettings().Guild(vault.Guild(m.config.Discord.GuildID)).Brand(embeds)
+}
+
+// rateFormat returns the format the vault's rates are shown in
+func (m *Monitor) rateFormat(vault *types.VaultConfig) types.RateFormat {
+	return vault.RateFormat(m.config.Monitor.RateFormat())
+}
+
+// formatRate formats a rate in the vault's format
+func (m *Monitor) formatRate(vault *types.VaultConfig, rate float64) string {
+	return m.rateFormat(vault).Rate(rate)
+}
+
+// resolvedStatusField names the embed field resolveMessages adds to a resolved alert
+const resolvedStatusField = "Status"
+
+// resolveMessages edits alert messages of severity to mark them resolved, through the webhook
+// they were posted with. Only the embed changes; the message's content, e.g. a mention, is kept.
+func (m *Monitor) resolveMessages(vault *types.VaultConfig, severity types.Severity, messageIDs []string, currentRate float64) {
+	status := fmt.Sprintf("✅ Resolved at <t:%d:f> (borrow rate now %s)", time.Now().Unix(), m.formatRate(vault, currentRate))
+	webhookURL := vault.WebhookFor(severity)
+	for _, messageID := range messageIDs {
+		if err := m.markMessageResolved(webhookURL, messageID, status); err != nil {
+			m.logger.Errorf("Failed to mark alert message %s resolved for %s: %v", messageID, vault.VaultID, err)
+		}
+	}
+}
+
+// markMessageResolved sets status as the Status field of the message's first embed and turns it
+// green. The embeds are edited as Discord returns them, so fields this package doesn't model survive.
+func (m *Monitor) markMessageResolved(webhookURL, messageID, status string) error {
+	var message struct {
+		Embeds []map[string]interface{} `json:"embeds"`
+	}
+	if err := m.webhookMessageRequest(http.MethodGet, webhookURL, messageID, nil, &message); err != nil {
+		return err
+	}
+	if len(message.Embeds) == 0 {
+		return fmt.Errorf("message has no embed to mark")
+	}
+
+	embed := message.Embeds[0]
+	field := map[string]interface{}{"name": resolvedStatusField, "value": status, "inline": false}
+	fields, _ := embed["fields"].([]interface{})
+	replaced := false
+	for n, existing := range fields {
+		if f, ok := existing.(map[string]interface{}); ok && f["name"] == resolvedStatusField {
+			fields[n] = field
+			replaced = true
+		}
+	}
+	if !replaced {
+		fields = append(fields, field)
+	}
+	embed["fields"] = fields
+	embed["color"] = 0x00ff00 // Green for recovery
+
+	return m.webhookMessageRequest(http.MethodPatch, webhookURL, messageID, map[string]interface{}{"embeds": message.Embeds}, nil)
+}
+
+// webhookMessageRequest sends a request about one of the webhook's messages, with payload as its
+// JSON body if set, and decodes the response into result if set
+func (m *Monitor) webhookMessageRequest(method, webhookURL, messageID string, payload, result interface{}) error {
+	if webhookURL == "" {
+		return fmt.Errorf("no webhook URL configured")
+	}
+	endpoint, err := webhookEndpoint(webhookURL, messageID)
+	if err != nil {
+		return err
+	}
+
+	var body io.Reader
+	if payload != nil {
+		jsonData, err := json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("failed to marshal webhook payload: %w", err)
+		}
+		body = bytes.NewReader(jsonData)
+	}
+
+	req, err := http.NewRequest(method, endpoint.String(), body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach webhook message: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &notify.StatusError{StatusCode: resp.StatusCode}
+	}
+	if result != nil {
+		if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+			return fmt.Errorf("failed to decode webhook message: %w", err)
+		}
+	}
+	return nil
+}
+
+// webhookEndpoint parses webhookURL, appending /messages/messageID if set. Its query, e.g. a
+// thread_id, is kept so requests reach the same thread.
+func webhookEndpoint(webhookURL, messageID string) (*url.URL, error) {
+	endpoint, err := url.Parse(webhookURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhook URL: %w", err)
+	}
+	if messageID != "" {
+		endpoint = endpoint.JoinPath("messages", messageID)
+	}
+	return endpoint, nil
+}
+
+// deleteWebhook deletes a webhook using the token in its URL, so it doesn't need the bot session
+func (m *Monitor) deleteWebhook(webhookURL string) error {
+	if webhookURL == "" {
+		return nil
+	}
+
+	req, err := http.NewRequest(http.MethodDelete, webhookURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Already deleted, e.g. by removing the channel
+	if resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &notify.StatusError{StatusCode: resp.StatusCode}
+	}
+	return nil
+}
+
+func (m *Monitor) sendAlert(channelID, message string) {
+	vaults, err := m.storage.GetAllVaults()
+	if err != nil {
+		m.logger.Errorf("Failed to get vaults: %v", err)
+		return
+	}
+
+	// Find vaults that use this channel
+	for _, vault := range vaults {
+		if vault.ChannelID == channelID && vault.WebhookURL != "" {
+			payload := map[string]interface{}{
+				"content": message,
+			}
+
+			jsonData, err := json.Marshal(payload)
+			if err != nil {
+				m.logger.Errorf("Failed to marshal webhook payload: %v", err)
+				continue
+			}
+
+			resp, err := m.httpClient.Post(vault.WebhookURL, "application/json", bytes.NewBuffer(jsonData))
+			if err != nil {
+				m.logger.Errorf("Failed to send webhook: %v", err)
+				continue
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+				m.logger.Errorf("Webhook request failed with status %d", resp.StatusCode)
+			}
+		}
+	}
+}
+
+func rateChangeVerb(rateChange float64) string {
+	if rateChange > 0 {
+		return "Increased"
+	} else if rateChange < 0 {
+		return "Decreased"
+	} else {
+		return "Stable"
+	}
+}
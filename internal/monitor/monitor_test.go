@@ -0,0 +1,44 @@
+package monitor
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/morrisonbrett/SummerRateChecker/internal/config"
+	"github.com/morrisonbrett/SummerRateChecker/internal/storage"
+	"github.com/morrisonbrett/SummerRateChecker/internal/types"
+	"go.uber.org/zap"
+)
+
+func TestApplyEnrollRulesRespectsQuota(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Limits.MaxVaultsPerGuild = 2
+	store := storage.NewInMemoryStorage()
+	if err := store.AddVault(&types.VaultConfig{VaultID: "1234", Nickname: "Existing", GuildID: "guild"}); err != nil {
+		t.Fatal(err)
+	}
+	m := &Monitor{config: cfg, storage: store, logger: zap.NewNop().Sugar(), httpClient: http.DefaultClient}
+
+	rule := types.NewEnrollRule("WBTC", "USDC", 0.5, "channel", "", "guild", "user")
+	markets := []*types.MarketInfo{
+		{UniqueKey: "0xaaa", CollateralSymbol: "WBTC", LoanSymbol: "USDC"},
+		{UniqueKey: "0xbbb", CollateralSymbol: "WBTC", LoanSymbol: "USDC"},
+		{UniqueKey: "0xccc", CollateralSymbol: "WETH", LoanSymbol: "USDC"},
+	}
+	m.applyEnrollRules([]*types.EnrollRule{rule}, markets)
+
+	vaults, err := store.GetAllVaults()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vaults) != 2 {
+		t.Fatalf("got %d vaults after auto-enroll, want the server's limit of 2", len(vaults))
+	}
+	enrolled, _ := store.GetVault("0xaaa")
+	if enrolled == nil {
+		enrolled, _ = store.GetVault("0xbbb")
+	}
+	if enrolled == nil || enrolled.GuildID != "guild" || enrolled.EnrolledBy != "user" {
+		t.Errorf("auto-enrolled vault = %+v, want it in the rule's server and enrolled by its creator", enrolled)
+	}
+}
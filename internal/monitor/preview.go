@@ -0,0 +1,72 @@
+package monitor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/morrisonbrett/SummerRateChecker/internal/config"
+	"github.com/morrisonbrett/SummerRateChecker/internal/storage"
+	"github.com/morrisonbrett/SummerRateChecker/internal/types"
+)
+
+// AlertPreview is what the monitor would post if a vault's rate moved to a hypothetical value
+type AlertPreview struct {
+	Alert     *types.RateChangeAlert
+	Payload   *types.DiscordWebhookPayload // The message as the vault's webhook would receive it
+	Baseline  float64                      // The rate the move is measured from
+	ChannelID string                       // Where the alert would be posted, after /route
+	Alerts    bool                         // Whether the move would raise an alert at all
+	Notes     []string                     // Why the alert wouldn't be delivered as shown, e.g. a cooldown
+}
+
+// PreviewAlert builds the alert vault would raise if its rate moved to rate, without sending or
+// recording anything. Context that needs a network call (the IRM projection and other protocols'
+// rates) is left out.
+func PreviewAlert(store storage.Storage, cfg *config.Config, vault *types.VaultConfig, rate float64) (*AlertPreview, error) {
+	lastRate, checked := store.GetLastRate(vault.VaultID)
+	if !checked {
+		return nil, fmt.Errorf("vault `%s` hasn't been checked yet, so there's no rate to measure a move from", vault.VaultID)
+	}
+
+	// The context below only reads storage and config, so a bare monitor builds it as a real check would
+	m := &Monitor{config: cfg, storage: store}
+	now := time.Now()
+	preview := &AlertPreview{Baseline: ComparisonBaseline(store, vault, lastRate)}
+	format := m.rateFormat(vault)
+
+	var alert *types.RateChangeAlert
+	if vault.CriticalRate > 0 && rate >= vault.CriticalRate {
+		alert = types.NewCriticalAlert(vault.VaultID, vault.Nickname, vault.MarketPair, lastRate, rate, vault.CriticalRate)
+		preview.Baseline = lastRate
+		preview.Alerts = !vault.CriticalActive
+		if vault.CriticalActive {
+			preview.Notes = append(preview.Notes, fmt.Sprintf("The rate is already at or above the critical level, so no new alert is sent until it drops back below %s", format.Rate(vault.CriticalRate)))
+		}
+	} else {
+		alert = types.NewRateChangeAlert(vault.VaultID, vault.Nickname, vault.MarketPair, preview.Baseline, rate)
+		alert.Threshold = vault.AlertThreshold()
+		preview.Alerts = vault.ExceedsThreshold(preview.Baseline, rate)
+		switch {
+		case !preview.Alerts:
+			preview.Notes = append(preview.Notes, fmt.Sprintf("A move from %s is within the %s threshold, so no alert would be sent", format.Rate(preview.Baseline), vault.DescribeThreshold()))
+		case vault.InCooldown(now):
+			preview.Notes = append(preview.Notes, fmt.Sprintf("In cooldown until <t:%d:t>; the alert would be sent once it ends if the move lasts", vault.CooldownEnds().Unix()))
+		case vault.AlertSchedule != nil && !vault.AlertSchedule.Allows(now):
+			preview.Notes = append(preview.Notes, fmt.Sprintf("Outside the alert schedule (%s); the alert would be held for the next window's summary", vault.AlertSchedule))
+		}
+	}
+	if m.silenced(vault) {
+		preview.Notes = append(preview.Notes, "Alerts for this vault are paused or in maintenance, so it would be recorded but not delivered")
+	}
+
+	alert.Format = format
+	m.addPercentileContext(alert)
+	m.addBandContext(alert, vault)
+	m.addAutomationContext(alert, vault)
+	m.add24hContext(alert)
+
+	preview.Alert = alert
+	preview.Payload = m.alertPayload(vault, alert)
+	preview.ChannelID = vault.ChannelFor(alert.Severity)
+	return preview, nil
+}
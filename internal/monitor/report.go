@@ -0,0 +1,307 @@
+package monitor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"mime/multipart"
+	"net/textproto"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/morrisonbrett/SummerRateChecker/internal/chart"
+	"github.com/morrisonbrett/SummerRateChecker/internal/notify"
+	"github.com/morrisonbrett/SummerRateChecker/internal/stats"
+	"github.com/morrisonbrett/SummerRateChecker/internal/types"
+)
+
+const (
+	// reportWindow is how much history the weekly report covers
+	reportWindow = 7 * 24 * time.Hour
+
+	// weeklyChartFile is the name the report's chart is attached under
+	weeklyChartFile = "weekly-rates.png"
+
+	// untaggedSeries names the line for vaults without tags when the report groups by tag
+	untaggedSeries = "untagged"
+
+	// maxEmbedFieldValue is Discord's limit on an embed field's value, in characters
+	maxEmbedFieldValue = 1024
+)
+
+// postWeeklyReportIfDue posts the weekly report to each server once report.weekday and
+// report.hour have passed since the last one. A report missed while the bot was down is posted on
+// the next cycle.
+func (m *Monitor) postWeeklyReportIfDue(now time.Time) {
+	cfg := m.config.Report
+	if !cfg.Enabled {
+		return
+	}
+	day, err := cfg.Day()
+	if err != nil {
+		m.logger.Errorf("Skipping weekly report: %v", err)
+		return
+	}
+	if !m.storage.GetSettings().WeeklyReportAt.Before(lastWeeklySlot(now, day, cfg.Hour)) {
+		return
+	}
+
+	vaults, err := m.storage.GetAllVaults()
+	if err != nil {
+		m.logger.Errorf("Failed to get vaults for weekly report: %v", err)
+		return
+	}
+
+	byGuild := make(map[string][]*types.VaultConfig)
+	for _, vault := range vaults {
+		byGuild[vault.GuildID] = append(byGuild[vault.GuildID], vault)
+	}
+	settings := m.storage.GetSettings()
+	for guildID, guildVaults := range byGuild {
+		if err := m.postWeeklyReport(guildVaults, settings.Guild(guildID).AlertChannelID, now); err != nil {
+			m.logger.Errorf("Failed to post weekly report for guild %s: %v", guildID, err)
+		}
+	}
+
+	// Record the report even if a server's failed, so one broken webhook doesn't repost the
+	// others every cycle
+	err = m.storage.UpdateSettings(func(settings *types.Settings) {
+		settings.WeeklyReportAt = now
+	})
+	if err != nil {
+		m.logger.Errorf("Failed to record weekly report time: %v", err)
+	}
+}
+
+// lastWeeklySlot returns the most recent time at or before now that falls on day at hour UTC
+func lastWeeklySlot(now time.Time, day time.Weekday, hour int) time.Time {
+	now = now.UTC()
+	slot := time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, time.UTC)
+	slot = slot.AddDate(0, 0, -int((now.Weekday()-day+7)%7))
+	if slot.After(now) {
+		slot = slot.AddDate(0, 0, -7)
+	}
+	return slot
+}
+
+// postWeeklyReport charts one server's week and posts it through the webhook of the vault
+// alerting in the server's /setup channel, or failing that its first vault by nickname
+func (m *Monitor) postWeeklyReport(vaults []*types.VaultConfig, alertChannelID string, now time.Time) error {
+	sort.Slice(vaults, func(i, j int) bool {
+		return strings.ToLower(vaults[i].Nickname) < strings.ToLower(vaults[j].Nickname)
+	})
+	webhookURL := vaults[0].WebhookURL
+	for _, vault := range vaults {
+		if vault.ChannelID == alertChannelID && vault.WebhookURL != "" {
+			webhookURL = vault.WebhookURL
+			break
+		}
+	}
+
+	var series []chart.Series
+	if m.config.Report.GroupBy == "tag" {
+		series = m.tagSeries(vaults, now.Add(-reportWindow))
+	} else {
+		series = m.vaultSeries(vaults, now.Add(-reportWindow))
+	}
+	if len(series) == 0 {
+		m.logger.Info("No rate history for the weekly report yet")
+		return nil
+	}
+
+	image, scale, err := chart.Render(series)
+	if err != nil {
+		return err
+	}
+
+	format := m.config.Monitor.RateFormat()
+	var lines []string
+	for i, s := range series {
+		if i == len(chart.Palette) {
+			var rest []string
+			for _, skipped := range series[i:] {
+				rest = append(rest, skipped.Name)
+			}
+			lines = append(lines, fmt.Sprintf("Not charted: %s", strings.Join(rest, ", ")))
+			break
+		}
+		lines = append(lines, fmt.Sprintf("%s **%s** %s", chart.Palette[i].Swatch, s.Name, describeWeek(s.Points, format)))
+	}
+
+	embed := types.DiscordEmbed{
+		Title:       "📈 Weekly Rate Report",
+		Description: strings.Join(lines, "\n"),
+		Color:       0x3498db, // Blue for reports
+		Timestamp:   now.Format(time.RFC3339),
+		Footer: &types.DiscordEmbedFooter{
+			Text: fmt.Sprintf("Borrow rates over the last 7 days · gridlines every %s from %s to %s",
+				format.Rate(scale.Step), format.Rate(scale.Min), format.Rate(scale.Max)),
+		},
+		Image: &types.DiscordEmbedImage{URL: "attachment://" + weeklyChartFile},
+	}
+	if field := m.interestField(vaults, now); field != nil {
+		embed.Fields = append(embed.Fields, *field)
+	}
+	payload := types.DiscordWebhookPayload{Embeds: m.brand(vaults[0], []types.DiscordEmbed{embed})}
+	return m.postWebhookFile(webhookURL, payload, weeklyChartFile, image)
+}
+
+// interestField estimates the interest each vault with a known debt accrued over the week and
+// since enrollment, like /portfolio, or returns nil if no vault has a debt
+func (m *Monitor) interestField(vaults []*types.VaultConfig, now time.Time) *types.DiscordEmbedField {
+	var lines []string
+	var totalWeek, totalSince float64
+	for _, vault := range vaults {
+		debt := vault.Debt()
+		if debt <= 0 {
+			continue
+		}
+		history := m.storage.GetRateHistory(vault.VaultID, vault.CreatedAt)
+		since := stats.AccruedInterest(history, debt, now)
+		week := since
+		if start := now.Add(-reportWindow); vault.CreatedAt.Before(start) {
+			week = stats.AccruedInterest(m.storage.GetRateHistory(vault.VaultID, start), debt, now)
+		}
+		totalWeek += week
+		totalSince += since
+		lines = append(lines, fmt.Sprintf("**%s** ~%s this week, ~%s since <t:%d:d> on %s debt",
+			vault.Nickname, types.FormatUSD(week), types.FormatUSD(since), vault.CreatedAt.Unix(), types.FormatUSD(debt)))
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+
+	total := fmt.Sprintf("**Total** ~%s this week, ~%s since enrollment", types.FormatUSD(totalWeek), types.FormatUSD(totalSince))
+	var value strings.Builder
+	for n, line := range lines {
+		more := fmt.Sprintf("…and %d more\n", len(lines)-n)
+		if value.Len()+len(line)+len(more)+len(total)+1 > maxEmbedFieldValue {
+			value.WriteString(more)
+			break
+		}
+		value.WriteString(line + "\n")
+	}
+	value.WriteString(total)
+	return &types.DiscordEmbedField{Name: "💸 Interest Accrued", Value: value.String()}
+}
+
+// vaultSeries returns one line per vault with history since since
+func (m *Monitor) vaultSeries(vaults []*types.VaultConfig, since time.Time) []chart.Series {
+	var series []chart.Series
+	for _, vault := range vaults {
+		var points []chart.Point
+		for _, sample := range m.storage.GetRateHistory(vault.VaultID, since) {
+			points = append(points, chart.Point{Time: sample.Timestamp, Value: sample.BorrowRate})
+		}
+		if len(points) > 0 {
+			series = append(series, chart.Series{Name: vault.Nickname, Points: points})
+		}
+	}
+	return series
+}
+
+// tagSeries returns one line per tag, averaging its vaults' rates by the hour. A vault with
+// several tags counts toward each; vaults without tags share an "untagged" line.
+func (m *Monitor) tagSeries(vaults []*types.VaultConfig, since time.Time) []chart.Series {
+	type bucket struct {
+		sum   float64
+		count int
+	}
+	groups := make(map[string]map[time.Time]*bucket)
+	for _, vault := range vaults {
+		tags := vault.Tags
+		if len(tags) == 0 {
+			tags = []string{untaggedSeries}
+		}
+		samples := m.storage.GetRateHistory(vault.VaultID, since)
+		for _, tag := range tags {
+			if groups[tag] == nil {
+				groups[tag] = make(map[time.Time]*bucket)
+			}
+			for _, sample := range samples {
+				hour := sample.Timestamp.Truncate(time.Hour)
+				if groups[tag][hour] == nil {
+					groups[tag][hour] = &bucket{}
+				}
+				groups[tag][hour].sum += sample.BorrowRate
+				groups[tag][hour].count++
+			}
+		}
+	}
+
+	names := make([]string, 0, len(groups))
+	for tag := range groups {
+		names = append(names, tag)
+	}
+	sort.Strings(names)
+
+	var series []chart.Series
+	for _, tag := range names {
+		var points []chart.Point
+		for hour, b := range groups[tag] {
+			points = append(points, chart.Point{Time: hour, Value: b.sum / float64(b.count)})
+		}
+		if len(points) == 0 {
+			continue
+		}
+		sort.Slice(points, func(i, j int) bool { return points[i].Time.Before(points[j].Time) })
+		series = append(series, chart.Series{Name: tag, Points: points})
+	}
+	return series
+}
+
+// describeWeek summarizes a line for the legend, e.g. "5.12% → 5.40% (high 5.60%, low 4.98%)"
+func describeWeek(points []chart.Point, format types.RateFormat) string {
+	high, low := math.Inf(-1), math.Inf(1)
+	for _, p := range points {
+		high = math.Max(high, p.Value)
+		low = math.Min(low, p.Value)
+	}
+	return fmt.Sprintf("%s → %s (high %s, low %s)",
+		format.Rate(points[0].Value), format.Rate(points[len(points)-1].Value), format.Rate(high), format.Rate(low))
+}
+
+// postWebhookFile posts payload with a file attached, which its embeds can show with
+// attachment://name
+func (m *Monitor) postWebhookFile(webhookURL string, payload interface{}, name string, data []byte) error {
+	if webhookURL == "" {
+		return fmt.Errorf("no webhook URL configured")
+	}
+
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook payload: %w", err)
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	if err := writer.WriteField("payload_json", string(jsonData)); err != nil {
+		return fmt.Errorf("failed to build webhook request: %w", err)
+	}
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="files[0]"; filename="%s"`, name))
+	header.Set("Content-Type", "image/png")
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		return fmt.Errorf("failed to build webhook request: %w", err)
+	}
+	if _, err := part.Write(data); err != nil {
+		return fmt.Errorf("failed to build webhook request: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to build webhook request: %w", err)
+	}
+
+	resp, err := m.httpClient.Post(webhookURL, writer.FormDataContentType(), &body)
+	if err != nil {
+		return fmt.Errorf("failed to send webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &notify.StatusError{StatusCode: resp.StatusCode}
+	}
+	return nil
+}
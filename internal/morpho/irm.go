@@ -0,0 +1,62 @@
+package morpho
+
+import (
+	"math"
+	"strings"
+)
+
+// Parameters of Morpho's AdaptiveCurveIRM
+const (
+	// AdaptiveCurveIRMAddress is the AdaptiveCurveIRM deployment on Ethereum mainnet
+	AdaptiveCurveIRMAddress = "0x870aC11D48B15DB9a138Cf899d20F13F7Ba00BC0"
+
+	targetUtilization = 0.9
+	curveSteepness    = 4.0
+	secondsPerYear    = 365 * 24 * 60 * 60
+)
+
+// curveMultiplier is the factor applied to the rate at target utilization when utilization is u (0-1)
+func curveMultiplier(u float64) float64 {
+	var errNorm float64
+	if u > targetUtilization {
+		errNorm = (u - targetUtilization) / (1 - targetUtilization)
+	} else {
+		errNorm = (u - targetUtilization) / targetUtilization
+	}
+
+	if errNorm < 0 {
+		return (1-1/curveSteepness)*errNorm + 1
+	}
+	return (curveSteepness-1)*errNorm + 1
+}
+
+// TargetUtilization returns the utilization (percent) at which irmAddress's curve kinks and
+// rates start rising steeply. ok is false for IRMs other than the AdaptiveCurveIRM.
+func TargetUtilization(irmAddress string) (utilization float64, ok bool) {
+	if !IsAdaptiveCurveIRM(irmAddress) {
+		return 0, false
+	}
+	return targetUtilization * 100, true
+}
+
+// IsAdaptiveCurveIRM reports whether irmAddress is the AdaptiveCurveIRM, the only IRM projections support
+func IsAdaptiveCurveIRM(irmAddress string) bool {
+	return strings.EqualFold(irmAddress, AdaptiveCurveIRMAddress)
+}
+
+// ProjectBorrowRate estimates the borrow APY (percent) at projectedUtilization (percent) from the current
+// borrow APY and utilization (percent). The rate at target is derived from the current point on the curve and
+// assumed to stay fixed, so this is the instantaneous jump and ignores the IRM's slower adaptation over time.
+// ok is false when there is no current rate to derive the curve from.
+func ProjectBorrowRate(borrowAPY, utilization, projectedUtilization float64) (rate float64, ok bool) {
+	if borrowAPY <= 0 {
+		return 0, false
+	}
+
+	// The IRM compounds a per-second rate continuously: APY = e^(rate × year) - 1
+	perSecond := math.Log1p(borrowAPY/100) / secondsPerYear
+	rateAtTarget := perSecond / curveMultiplier(utilization/100)
+
+	projected := rateAtTarget * curveMultiplier(math.Min(projectedUtilization, 100)/100)
+	return math.Expm1(projected*secondsPerYear) * 100, true
+}
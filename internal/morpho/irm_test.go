@@ -0,0 +1,43 @@
+package morpho
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// wstETHMarket is the wstETH/WETH (94.5% LLTV) market on Ethereum as the Morpho API returns it
+const wstETHMarket = `{
+	"uniqueKey": "0xc54d7acf14de29e0e5527cabd7a576506870346a78a11a6762e2cca66322ec41",
+	"lltv": "945000000000000000",
+	"irmAddress": "0x870aC11D48B15DB9a138Cf899d20F13F7Ba00BC0"
+}`
+
+func TestIsAdaptiveCurveIRM(t *testing.T) {
+	var market marketDataItem
+	if err := json.Unmarshal([]byte(wstETHMarket), &market); err != nil {
+		t.Fatalf("decoding market: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		address string
+		want    bool
+	}{
+		{"market irmAddress", market.IRMAddress, true},
+		{"lowercase", "0x870ac11d48b15db9a138cf899d20f13f7ba00bc0", true},
+		{"truncated", "0x870aC11D48B15DB9a138Cf899d20F13F7Ba00BC", false},
+		{"other IRM", "0x0000000000000000000000000000000000000000", false},
+		{"empty", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsAdaptiveCurveIRM(tt.address); got != tt.want {
+				t.Errorf("IsAdaptiveCurveIRM(%q) = %v, want %v", tt.address, got, tt.want)
+			}
+		})
+	}
+
+	if utilization, ok := TargetUtilization(market.IRMAddress); !ok || utilization != 90 {
+		t.Errorf("TargetUtilization(%q) = %v, %v, want 90, true", market.IRMAddress, utilization, ok)
+	}
+}
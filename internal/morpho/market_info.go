@@ -0,0 +1,180 @@
+package morpho
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/machinebox/graphql"
+	"github.com/morrisonbrett/SummerRateChecker/internal/types"
+)
+
+// wadScale is the 1e18 fixed-point scale Morpho uses for values like LLTV
+const wadScale = 1e18
+
+// marketInfoFields is the market selection shared by the key and pair lookups
+const marketInfoFields = `
+	uniqueKey
+	lltv
+	oracleAddress
+	irmAddress
+	loanAsset {
+		symbol
+	}
+	collateralAsset {
+		symbol
+	}
+	state {
+		borrowApy
+		supplyApy
+		borrowAssetsUsd
+		supplyAssetsUsd
+		utilization` + apyWindowFields + `}
+`
+
+// marketInfoItem is a market as returned with marketInfoFields
+type marketInfoItem struct {
+	UniqueKey     string      `json:"uniqueKey"`
+	LLTV          flexibleNum `json:"lltv"`
+	OracleAddress string      `json:"oracleAddress"`
+	IRMAddress    string      `json:"irmAddress"`
+	LoanAsset     struct {
+		Symbol string `json:"symbol"`
+	} `json:"loanAsset"`
+	CollateralAsset struct {
+		Symbol string `json:"symbol"`
+	} `json:"collateralAsset"`
+	State struct {
+		BorrowApy       float64 `json:"borrowApy"`
+		SupplyApy       float64 `json:"supplyApy"`
+		BorrowAssetsUsd float64 `json:"borrowAssetsUsd"`
+		SupplyAssetsUsd float64 `json:"supplyAssetsUsd"`
+		Utilization     float64 `json:"utilization"`
+		apyWindows
+	} `json:"state"`
+}
+
+// flexibleNum accepts BigInt values, which the API may encode as either strings or numbers
+type flexibleNum float64
+
+func (n *flexibleNum) UnmarshalJSON(data []byte) error {
+	raw := strings.Trim(string(data), `"`)
+	if raw == "" || raw == "null" {
+		*n = 0
+		return nil
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return fmt.Errorf("invalid number %s: %w", data, err)
+	}
+	*n = flexibleNum(value)
+	return nil
+}
+
+func (m *marketInfoItem) toMarketInfo() *types.MarketInfo {
+	return &types.MarketInfo{
+		UniqueKey:        m.UniqueKey,
+		CollateralSymbol: m.CollateralAsset.Symbol,
+		LoanSymbol:       m.LoanAsset.Symbol,
+		LLTV:             float64(m.LLTV) / wadScale * 100,
+		OracleAddress:    m.OracleAddress,
+		IRMAddress:       m.IRMAddress,
+		SupplyUSD:        m.State.SupplyAssetsUsd,
+		BorrowUSD:        m.State.BorrowAssetsUsd,
+		Utilization:      m.State.Utilization * 100,
+		BorrowRate:       m.State.BorrowApy * 100,
+		SupplyRate:       m.State.SupplyApy * 100,
+		BorrowAverages:   m.State.borrowAverages(),
+		SupplyAverages:   m.State.supplyAverages(),
+	}
+}
+
+// GetMarketInfo looks up a market on chainID by its unique key (0x...) or by pair (e.g.
+// "WBTC-USDC"). When several markets share a pair, the one with the most supply is returned.
+func (c *Client) GetMarketInfo(ctx context.Context, pairOrKey string, chainID int) (*types.MarketInfo, error) {
+	pairOrKey = strings.TrimSpace(pairOrKey)
+	if strings.HasPrefix(pairOrKey, "0x") {
+		return c.getMarketInfoByKey(ctx, pairOrKey, chainID)
+	}
+	return c.getMarketInfoByPair(ctx, pairOrKey, chainID)
+}
+
+func (c *Client) getMarketInfoByKey(ctx context.Context, uniqueKey string, chainID int) (*types.MarketInfo, error) {
+	req := graphql.NewRequest(`
+		query GetMarketInfo($uniqueKey: String!, $chainId: Int!) {
+			marketByUniqueKey(uniqueKey: $uniqueKey, chainId: $chainId) {` + marketInfoFields + `}
+		}
+	`)
+	req.Var("uniqueKey", uniqueKey)
+	req.Var("chainId", chainID)
+
+	var resp struct {
+		MarketByUniqueKey marketInfoItem `json:"marketByUniqueKey"`
+	}
+	if err := c.client.Run(ctx, req, &resp); err != nil {
+		return nil, fmt.Errorf("GraphQL API error for unique key %s: %w", uniqueKey, err)
+	}
+
+	if resp.MarketByUniqueKey.UniqueKey == "" {
+		return nil, fmt.Errorf("no market found for unique key %s", uniqueKey)
+	}
+
+	return resp.MarketByUniqueKey.toMarketInfo(), nil
+}
+
+// ListMarkets returns every market on chainID known to the API
+func (c *Client) ListMarkets(ctx context.Context, chainID int) ([]*types.MarketInfo, error) {
+	req := graphql.NewRequest(`
+		query GetMarketsInfo($chainId: Int!) {
+			markets(first: 1000, where: { chainId_in: [$chainId] }) {
+				items {` + marketInfoFields + `}
+			}
+		}
+	`)
+	req.Var("chainId", chainID)
+
+	var resp struct {
+		Markets struct {
+			Items []marketInfoItem `json:"items"`
+		} `json:"markets"`
+	}
+	if err := c.client.Run(ctx, req, &resp); err != nil {
+		return nil, fmt.Errorf("failed to fetch markets list: %w", err)
+	}
+
+	markets := make([]*types.MarketInfo, 0, len(resp.Markets.Items))
+	for idx := range resp.Markets.Items {
+		markets = append(markets, resp.Markets.Items[idx].toMarketInfo())
+	}
+	return markets, nil
+}
+
+func (c *Client) getMarketInfoByPair(ctx context.Context, marketPair string, chainID int) (*types.MarketInfo, error) {
+	parts := strings.Split(marketPair, "-")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("invalid market pair %q: should be like 'WBTC-USDC'", marketPair)
+	}
+
+	markets, err := c.ListMarkets(ctx, chainID)
+	if err != nil {
+		return nil, err
+	}
+
+	var best *types.MarketInfo
+	for _, market := range markets {
+		if !strings.EqualFold(market.CollateralSymbol, parts[0]) || !strings.EqualFold(market.LoanSymbol, parts[1]) {
+			continue
+		}
+		if best == nil || market.SupplyUSD > best.SupplyUSD {
+			best = market
+		}
+	}
+
+	if best == nil {
+		return nil, fmt.Errorf("no market found for pair %s", marketPair)
+	}
+
+	c.logger.Infof("Resolved market pair %s to %s", marketPair, best.UniqueKey)
+	return best, nil
+}
@@ -1,70 +1,157 @@
-package morpho
-
-import (
-	"fmt"
-	"net/url"
-	"strings"
-)
-
-// VaultURLInfo contains information extracted from a Summer.fi vault URL
-type VaultURLInfo struct {
-	VaultID    string // The vault ID (e.g., "1234")
-	MarketPair string // The market pair (e.g., "WBTC-USDC")
-}
-
-// ParseVaultURL extracts vault information from a Summer.fi URL
-// Example URL: https://pro.summer.fi/ethereum/morphoblue/borrow/WBTC-USDC/1234#overview
-func ParseVaultURL(urlStr string) (*VaultURLInfo, error) {
-	// Parse the URL
-	parsedURL, err := url.Parse(urlStr)
-	if err != nil {
-		return nil, fmt.Errorf("invalid URL: %w", err)
-	}
-
-	// Check if it's a Summer.fi URL
-	if !strings.Contains(parsedURL.Host, "summer.fi") {
-		return nil, fmt.Errorf("not a Summer.fi URL")
-	}
-
-	// Split the path into components
-	// Expected format: /ethereum/morphoblue/borrow/WBTC-USDC/1234
-	pathParts := strings.Split(strings.Trim(parsedURL.Path, "/"), "/")
-	if len(pathParts) < 5 {
-		return nil, fmt.Errorf("invalid URL format: expected at least 5 path components")
-	}
-
-	// Extract the market pair and vault ID
-	// The last two components should be the market pair and vault ID
-	marketPair := pathParts[len(pathParts)-2]
-	vaultID := pathParts[len(pathParts)-1]
-
-	// Validate the components
-	if marketPair == "" || vaultID == "" {
-		return nil, fmt.Errorf("invalid URL format: missing market pair or vault ID")
-	}
-
-	// Validate market pair format (should contain a hyphen)
-	if !strings.Contains(marketPair, "-") {
-		return nil, fmt.Errorf("invalid market pair format: should be like 'WBTC-USDC'")
-	}
-
-	// Validate vault ID (should be numeric)
-	if !isNumeric(vaultID) {
-		return nil, fmt.Errorf("invalid vault ID: should be numeric")
-	}
-
-	return &VaultURLInfo{
-		VaultID:    vaultID,
-		MarketPair: marketPair,
-	}, nil
-}
-
-// isNumeric checks if a string contains only digits
-func isNumeric(s string) bool {
-	for _, c := range s {
-		if c < '0' || c > '9' {
-			return false
-		}
-	}
-	return true
-}
+package morpho
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/morrisonbrett/SummerRateChecker/internal/types"
+)
+
+// VaultURLInfo contains information extracted from a Summer.fi vault URL
+type VaultURLInfo struct {
+	VaultID    string // The vault ID (e.g., "1234")
+	MarketPair string // The market pair (e.g., "WBTC-USDC")
+	Network    string // The chain (e.g., "ethereum")
+	Protocol   string // The lending protocol (e.g., "morphoblue")
+	Product    string // borrow, multiply, or earn
+}
+
+// vaultRoute is one known shape of a Summer.fi position path. Each segment is a placeholder:
+// {network}, {protocol}, {version}, {product}, {pair}, or {id}. Segments after the route's are
+// ignored, so trailing tabs like /overview still match, as do query strings and #anchors.
+type vaultRoute struct {
+	example  string // A URL of this shape, for error messages and documentation
+	segments []string
+}
+
+// vaultRoutes are tried in order and the first match wins. Add a route here when Summer.fi
+// changes its URLs, rather than changing the parsing.
+var vaultRoutes = []vaultRoute{
+	{
+		example:  "https://pro.summer.fi/ethereum/morphoblue/borrow/WBTC-USDC/1234#overview",
+		segments: []string{"{network}", "{protocol}", "{product}", "{pair}", "{id}"},
+	},
+	{
+		// Protocols with several deployments put the version in its own segment
+		example:  "https://summer.fi/ethereum/aave/v3/multiply/wstETH-ETH/1234",
+		segments: []string{"{network}", "{protocol}", "{version}", "{product}", "{pair}", "{id}"},
+	},
+	{
+		// Older links put the product first
+		example:  "https://summer.fi/borrow/ethereum/morphoblue/WBTC-USDC/1234",
+		segments: []string{"{product}", "{network}", "{protocol}", "{pair}", "{id}"},
+	},
+}
+
+// vaultProducts are the position types a Summer.fi URL can point at
+var vaultProducts = map[string]bool{
+	"borrow":   true,
+	"multiply": true,
+	"earn":     true,
+}
+
+// ParseVaultURL extracts vault information from a Summer.fi URL matching one of vaultRoutes
+// Example URL: https://pro.summer.fi/ethereum/morphoblue/borrow/WBTC-USDC/1234#overview
+func ParseVaultURL(urlStr string) (*VaultURLInfo, error) {
+	// Parse the URL
+	parsedURL, err := url.Parse(strings.TrimSpace(urlStr))
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+
+	// Check if it's a Summer.fi URL
+	if !strings.Contains(parsedURL.Host, "summer.fi") {
+		return nil, fmt.Errorf("not a Summer.fi URL")
+	}
+
+	var pathParts []string
+	for _, part := range strings.Split(parsedURL.Path, "/") {
+		if part != "" {
+			pathParts = append(pathParts, part)
+		}
+	}
+
+	// Report why the most common shape didn't match if none do
+	var firstErr error
+	for _, route := range vaultRoutes {
+		info, err := route.match(pathParts)
+		if err == nil {
+			return info, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return nil, fmt.Errorf("%w (expected a URL like %s)", firstErr, vaultRoutes[0].example)
+}
+
+// match extracts vault information from path segments of this route's shape
+func (r vaultRoute) match(pathParts []string) (*VaultURLInfo, error) {
+	if len(pathParts) < len(r.segments) {
+		return nil, fmt.Errorf("invalid URL format: expected at least %d path components", len(r.segments))
+	}
+
+	info := &VaultURLInfo{}
+	for i, segment := range r.segments {
+		value := pathParts[i]
+		switch segment {
+		case "{network}":
+			network := strings.ToLower(value)
+			if _, ok := types.ChainID(network); !ok {
+				return nil, fmt.Errorf("invalid URL format: unsupported network %q", value)
+			}
+			info.Network = network
+		case "{protocol}":
+			info.Protocol = strings.ToLower(value)
+		case "{version}":
+			info.Protocol += "-" + strings.ToLower(value)
+		case "{product}":
+			product := strings.ToLower(value)
+			if !vaultProducts[product] {
+				return nil, fmt.Errorf("invalid URL format: unknown position type %q", value)
+			}
+			info.Product = product
+		case "{pair}":
+			// Validate market pair format (should contain a hyphen)
+			if !strings.Contains(value, "-") {
+				return nil, fmt.Errorf("invalid market pair format: should be like 'WBTC-USDC'")
+			}
+			info.MarketPair = value
+		case "{id}":
+			// Validate vault ID (should be numeric)
+			if !isNumeric(value) {
+				return nil, fmt.Errorf("invalid vault ID: should be numeric")
+			}
+			info.VaultID = value
+		}
+	}
+	return info, nil
+}
+
+// IsMarketKey reports whether s is a Morpho market unique key: 0x followed by 64 hex digits
+func IsMarketKey(s string) bool {
+	s = strings.TrimSpace(s)
+	if len(s) != 66 || !strings.HasPrefix(s, "0x") {
+		return false
+	}
+	for _, c := range s[2:] {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// isNumeric checks if a string contains only digits
+func isNumeric(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
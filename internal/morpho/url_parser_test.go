@@ -0,0 +1,88 @@
+package morpho
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// parsedURL is one line of vault_urls.golden: a URL and what ParseVaultURL made of it
+type parsedURL struct {
+	URL   string        `json:"url"`
+	Info  *VaultURLInfo `json:"info,omitempty"`
+	Error string        `json:"error,omitempty"`
+}
+
+func TestParseVaultURL(t *testing.T) {
+	input, err := os.Open(filepath.Join("testdata", "vault_urls.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer input.Close()
+
+	var results []parsedURL
+	scanner := bufio.NewScanner(input)
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if trimmed := strings.TrimSpace(line); trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		result := parsedURL{URL: line}
+		info, err := ParseVaultURL(line)
+		if err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Info = info
+		}
+		results = append(results, result)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = append(got, '\n')
+
+	golden := filepath.Join("testdata", "vault_urls.golden")
+	if *update {
+		if err := os.WriteFile(golden, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(bytes.ReplaceAll(want, []byte("\r\n"), []byte("\n")), got) {
+		t.Errorf("ParseVaultURL results differ from %s; run with -update if the change is intended\n%s", golden, got)
+	}
+}
+
+func TestIsMarketKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"0xc54d7acf14de29e0e5527cabd7a576506870346a78a11a6762e2cca66322ec41", true},
+		{" 0xC54D7ACF14DE29E0E5527CABD7A576506870346A78A11A6762E2CCA66322EC41 ", true},
+		{"0xc54d7acf14de29e0e5527cabd7a576506870346a78a11a6762e2cca66322ec4", false},
+		{"0xz54d7acf14de29e0e5527cabd7a576506870346a78a11a6762e2cca66322ec41", false},
+		{"c54d7acf14de29e0e5527cabd7a576506870346a78a11a6762e2cca66322ec41", false},
+		{"WBTC-USDC", false},
+	}
+	for _, tt := range tests {
+		if got := IsMarketKey(tt.key); got != tt.want {
+			t.Errorf("IsMarketKey(%q) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+}
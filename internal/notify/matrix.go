@@ -0,0 +1,72 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/morrisonbrett/SummerRateChecker/internal/config"
+	"github.com/morrisonbrett/SummerRateChecker/internal/types"
+)
+
+// MatrixSink posts alerts into a Matrix room via the client-server API
+type MatrixSink struct {
+	homeserverURL string
+	accessToken   string
+	roomID        string
+	httpClient    *http.Client
+	txnCounter    uint64
+}
+
+type matrixMessage struct {
+	MsgType       string `json:"msgtype"`
+	Body          string `json:"body"`
+	Format        string `json:"format,omitempty"`
+	FormattedBody string `json:"formatted_body,omitempty"`
+}
+
+func NewMatrixSink(cfg config.Matrix, httpClient *http.Client) *MatrixSink {
+	return &MatrixSink{
+		homeserverURL: strings.TrimSuffix(cfg.HomeserverURL, "/"),
+		accessToken:   cfg.AccessToken,
+		roomID:        cfg.RoomID,
+		httpClient:    httpClient,
+	}
+}
+
+func (m *MatrixSink) Name() string {
+	return "matrix"
+}
+
+func (m *MatrixSink) Send(ctx context.Context, alert *types.RateChangeAlert) error {
+	text := summary(alert)
+	return m.sendMessage(ctx, matrixMessage{
+		MsgType:       "m.text",
+		Body:          text,
+		Format:        "org.matrix.custom.html",
+		FormattedBody: "<strong>" + html.EscapeString(text) + "</strong>",
+	})
+}
+
+func (m *MatrixSink) Resolve(ctx context.Context, alert *types.RateChangeAlert) error {
+	return m.sendMessage(ctx, matrixMessage{
+		MsgType: "m.notice",
+		Body:    fmt.Sprintf("Recovered: %s borrow rate is back at %s", alert.Nickname, alert.FormatRate(alert.CurrentRate)),
+	})
+}
+
+// sendMessage PUTs an event with a unique transaction ID so retries are idempotent on the homeserver
+func (m *MatrixSink) sendMessage(ctx context.Context, message matrixMessage) error {
+	txnID := fmt.Sprintf("src-%d-%d", time.Now().UnixNano(), atomic.AddUint64(&m.txnCounter, 1))
+	sendURL := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/%s",
+		m.homeserverURL, url.PathEscape(m.roomID), txnID)
+
+	return sendJSON(ctx, m.httpClient, http.MethodPut, sendURL, map[string]string{
+		"Authorization": "Bearer " + m.accessToken,
+	}, message)
+}
@@ -0,0 +1,265 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/morrisonbrett/SummerRateChecker/internal/config"
+	"github.com/morrisonbrett/SummerRateChecker/internal/metrics"
+	"github.com/morrisonbrett/SummerRateChecker/internal/types"
+	"go.uber.org/zap"
+)
+
+// Sink delivers alerts to a destination other than the vault's Discord webhook
+type Sink interface {
+	Name() string
+	Send(ctx context.Context, alert *types.RateChangeAlert) error
+}
+
+// Resolver is implemented by sinks that open incidents and can close them once the condition clears
+type Resolver interface {
+	Resolve(ctx context.Context, alert *types.RateChangeAlert) error
+}
+
+// Recorder receives the outcome of every delivery attempt
+type Recorder interface {
+	RecordDelivery(vaultID string, result types.DeliveryResult) error
+}
+
+// StatusError is returned when a sink's HTTP endpoint answers with a non-2xx status
+type StatusError struct {
+	StatusCode int
+	Body       string
+	RetryAfter time.Duration // How long the endpoint asked to wait before retrying, if it said
+}
+
+func (e *StatusError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("returned status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("returned status %d: %s", e.StatusCode, e.Body)
+}
+
+// Retryable reports whether the request may succeed if sent again: the endpoint is rate
+// limiting or failing, rather than rejecting the request itself
+func (e *StatusError) Retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// ParseRetryAfter reads a Retry-After header, given in seconds or as an HTTP date. It returns zero
+// if the header is missing or invalid.
+func ParseRetryAfter(header string) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.ParseFloat(header, 64); err == nil {
+		if seconds <= 0 {
+			return 0
+		}
+		return time.Duration(seconds * float64(time.Second))
+	}
+	if at, err := http.ParseTime(header); err == nil {
+		if wait := time.Until(at); wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
+
+type registeredSink struct {
+	sink        Sink
+	minSeverity types.Severity
+}
+
+// Notifier fans alerts out to every registered sink whose minimum severity they meet
+type Notifier struct {
+	sinks      []registeredSink
+	recorder   Recorder
+	httpClient *http.Client // For sinks built per vault
+	globalURLs map[string]bool
+	logger     *zap.SugaredLogger
+}
+
+func New(logger *zap.SugaredLogger) *Notifier {
+	return &Notifier{
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		globalURLs: make(map[string]bool),
+		logger:     logger,
+	}
+}
+
+// FromConfig builds a notifier with every sink enabled in the configuration
+func FromConfig(cfg *config.Notify, logger *zap.SugaredLogger) *Notifier {
+	n := New(logger)
+	httpClient := n.httpClient
+
+	if cfg.PagerDuty.RoutingKey != "" {
+		n.Register(NewPagerDutySink(cfg.PagerDuty, httpClient), types.SeverityCritical)
+	}
+	if cfg.Opsgenie.APIKey != "" {
+		n.Register(NewOpsgenieSink(cfg.Opsgenie, httpClient), types.SeverityCritical)
+	}
+	if cfg.Matrix.AccessToken != "" && cfg.Matrix.RoomID != "" {
+		n.Register(NewMatrixSink(cfg.Matrix, httpClient), parseSeverity(cfg.Matrix.MinSeverity))
+	}
+	if cfg.Twilio.AccountSID != "" && len(cfg.Twilio.ToNumbers) > 0 {
+		n.Register(NewTwilioSink(cfg.Twilio, httpClient), types.SeverityCritical)
+	}
+	if cfg.Webhook.URL != "" {
+		n.Register(NewWebhookSink(cfg.Webhook, httpClient), parseSeverity(cfg.Webhook.MinSeverity))
+	}
+	if cfg.Slack.WebhookURL != "" {
+		n.Register(NewSlackSink(cfg.Slack.WebhookURL, httpClient), parseSeverity(cfg.Slack.MinSeverity))
+		n.globalURLs[cfg.Slack.WebhookURL] = true
+	}
+
+	return n
+}
+
+// SetRecorder tracks the outcome of every sink delivery
+func (n *Notifier) SetRecorder(recorder Recorder) {
+	n.recorder = recorder
+}
+
+// Register adds a sink that receives alerts of minSeverity or higher
+func (n *Notifier) Register(sink Sink, minSeverity types.Severity) {
+	n.sinks = append(n.sinks, registeredSink{sink: sink, minSeverity: minSeverity})
+	n.logger.Infof("Registered %s notification sink for %s alerts", sink.Name(), minSeverity)
+}
+
+// Notify sends alert to every eligible sink, including vault's own. Failures are logged so one broken sink doesn't block the others.
+func (n *Notifier) Notify(ctx context.Context, alert *types.RateChangeAlert, vault *types.VaultConfig) {
+	for _, rs := range n.sinksFor(vault) {
+		if !alert.Severity.AtLeast(rs.minSeverity) {
+			continue
+		}
+		start := time.Now()
+		err := rs.sink.Send(ctx, alert)
+		RecordOutcome(n.recorder, n.logger, alert.VaultID, rs.sink.Name(), start, err)
+		if err != nil {
+			n.logger.Errorf("Failed to send alert for %s via %s: %v", alert.VaultID, rs.sink.Name(), err)
+		}
+	}
+}
+
+// Resolve closes any open incident for alert's vault on sinks that support it
+func (n *Notifier) Resolve(ctx context.Context, alert *types.RateChangeAlert, vault *types.VaultConfig) {
+	for _, rs := range n.sinksFor(vault) {
+		resolver, ok := rs.sink.(Resolver)
+		if !ok || !alert.Severity.AtLeast(rs.minSeverity) {
+			continue
+		}
+		if err := resolver.Resolve(ctx, alert); err != nil {
+			n.logger.Errorf("Failed to resolve alert for %s via %s: %v", alert.VaultID, rs.sink.Name(), err)
+		}
+	}
+}
+
+// sinksFor returns the registered sinks plus any vault sets for itself. A vault's Slack webhook
+// receives every severity, and is skipped if it's the global one so alerts aren't posted twice.
+func (n *Notifier) sinksFor(vault *types.VaultConfig) []registeredSink {
+	if vault == nil || vault.SlackWebhookURL == "" || n.globalURLs[vault.SlackWebhookURL] {
+		return n.sinks
+	}
+	sinks := make([]registeredSink, 0, len(n.sinks)+1)
+	sinks = append(sinks, n.sinks...)
+	return append(sinks, registeredSink{
+		sink:        NewSlackSink(vault.SlackWebhookURL, n.httpClient),
+		minSeverity: types.SeverityWarning,
+	})
+}
+
+// RecordOutcome reports a delivery attempt that started at start to metrics and, if set, recorder
+func RecordOutcome(recorder Recorder, logger *zap.SugaredLogger, vaultID, sink string, start time.Time, err error) {
+	result := types.DeliveryResult{
+		Sink:      sink,
+		Success:   err == nil,
+		Latency:   time.Since(start),
+		Timestamp: start,
+	}
+	if err != nil {
+		result.Error = err.Error()
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) {
+			result.StatusCode = statusErr.StatusCode
+		}
+	}
+
+	outcome := "success"
+	if !result.Success {
+		outcome = "failure"
+	}
+	metrics.AlertDeliveries.WithLabelValues(vaultID, sink, outcome).Inc()
+	metrics.AlertDeliveryDuration.WithLabelValues(sink).Observe(result.Latency.Seconds())
+
+	if recorder == nil {
+		return
+	}
+	if recordErr := recorder.RecordDelivery(vaultID, result); recordErr != nil {
+		logger.Errorf("Failed to record delivery for %s via %s: %v", vaultID, sink, recordErr)
+	}
+}
+
+// parseSeverity converts a configured severity name, defaulting to warning
+func parseSeverity(name string) types.Severity {
+	if types.Severity(name) == types.SeverityCritical {
+		return types.SeverityCritical
+	}
+	return types.SeverityWarning
+}
+
+// summary renders a one-line plain text description of an alert for chat-style sinks
+func summary(alert *types.RateChangeAlert) string {
+	prefix := "Rate Alert"
+	if alert.Severity == types.SeverityCritical {
+		prefix = "CRITICAL Rate Alert"
+	}
+	return fmt.Sprintf("%s: %s (%s) borrow rate %s → %s (%s)",
+		prefix, alert.Nickname, alert.MarketPair, alert.FormatRate(alert.PreviousRate), alert.FormatRate(alert.CurrentRate), alert.FormatPoints(alert.ChangePercent))
+}
+
+// incidentKey identifies a vault's open incident so triggers and resolves line up
+func incidentKey(vaultID string) string {
+	return "summer-rate-checker-" + vaultID
+}
+
+// postJSON sends payload to url and treats any non-2xx response as an error
+func postJSON(ctx context.Context, client *http.Client, url string, headers map[string]string, payload interface{}) error {
+	return sendJSON(ctx, client, http.MethodPost, url, headers, payload)
+}
+
+func sendJSON(ctx context.Context, client *http.Client, method, url string, headers map[string]string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return &StatusError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/morrisonbrett/SummerRateChecker/internal/config"
+	"github.com/morrisonbrett/SummerRateChecker/internal/types"
+)
+
+// OpsgenieSink creates and closes Opsgenie alerts, keyed by vault so a recovery closes the right one
+type OpsgenieSink struct {
+	apiKey     string
+	apiURL     string
+	httpClient *http.Client
+}
+
+type opsgenieAlert struct {
+	Message     string            `json:"message"`
+	Alias       string            `json:"alias"`
+	Description string            `json:"description,omitempty"`
+	Priority    string            `json:"priority"`
+	Source      string            `json:"source"`
+	Details     map[string]string `json:"details,omitempty"`
+}
+
+func NewOpsgenieSink(cfg config.Opsgenie, httpClient *http.Client) *OpsgenieSink {
+	return &OpsgenieSink{
+		apiKey:     cfg.APIKey,
+		apiURL:     strings.TrimSuffix(cfg.APIURL, "/"),
+		httpClient: httpClient,
+	}
+}
+
+func (o *OpsgenieSink) Name() string {
+	return "opsgenie"
+}
+
+func (o *OpsgenieSink) Send(ctx context.Context, alert *types.RateChangeAlert) error {
+	priority := "P3"
+	if alert.Severity == types.SeverityCritical {
+		priority = "P1"
+	}
+
+	return postJSON(ctx, o.httpClient, o.apiURL+"/v2/alerts", o.headers(), opsgenieAlert{
+		Message:     fmt.Sprintf("%s borrow rate at %s", alert.Nickname, alert.FormatRate(alert.CurrentRate)),
+		Alias:       incidentKey(alert.VaultID),
+		Description: fmt.Sprintf("Rate moved from %s to %s (%s)", alert.FormatRate(alert.PreviousRate), alert.FormatRate(alert.CurrentRate), alert.FormatPoints(alert.ChangePercent)),
+		Priority:    priority,
+		Source:      "SummerRateChecker",
+		Details: map[string]string{
+			"vault_id":    alert.VaultID,
+			"market_pair": alert.MarketPair,
+		},
+	})
+}
+
+func (o *OpsgenieSink) Resolve(ctx context.Context, alert *types.RateChangeAlert) error {
+	closeURL := fmt.Sprintf("%s/v2/alerts/%s/close?identifierType=alias", o.apiURL, url.PathEscape(incidentKey(alert.VaultID)))
+	return postJSON(ctx, o.httpClient, closeURL, o.headers(), map[string]string{
+		"source": "SummerRateChecker",
+		"note":   fmt.Sprintf("Rate recovered to %s", alert.FormatRate(alert.CurrentRate)),
+	})
+}
+
+func (o *OpsgenieSink) headers() map[string]string {
+	return map[string]string{"Authorization": "GenieKey " + o.apiKey}
+}
@@ -0,0 +1,76 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/morrisonbrett/SummerRateChecker/internal/config"
+	"github.com/morrisonbrett/SummerRateChecker/internal/types"
+)
+
+const pagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+// PagerDutySink opens and resolves PagerDuty incidents through the Events API v2
+type PagerDutySink struct {
+	routingKey string
+	httpClient *http.Client
+}
+
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+}
+
+type pagerDutyPayload struct {
+	Summary       string                 `json:"summary"`
+	Source        string                 `json:"source"`
+	Severity      string                 `json:"severity"`
+	CustomDetails map[string]interface{} `json:"custom_details,omitempty"`
+}
+
+func NewPagerDutySink(cfg config.PagerDuty, httpClient *http.Client) *PagerDutySink {
+	return &PagerDutySink{
+		routingKey: cfg.RoutingKey,
+		httpClient: httpClient,
+	}
+}
+
+func (p *PagerDutySink) Name() string {
+	return "pagerduty"
+}
+
+func (p *PagerDutySink) Send(ctx context.Context, alert *types.RateChangeAlert) error {
+	severity := "warning"
+	if alert.Severity == types.SeverityCritical {
+		severity = "critical"
+	}
+
+	return postJSON(ctx, p.httpClient, pagerDutyEventsURL, nil, pagerDutyEvent{
+		RoutingKey:  p.routingKey,
+		EventAction: "trigger",
+		DedupKey:    incidentKey(alert.VaultID),
+		Payload: &pagerDutyPayload{
+			Summary:  fmt.Sprintf("%s borrow rate at %s", alert.Nickname, alert.FormatRate(alert.CurrentRate)),
+			Source:   "SummerRateChecker",
+			Severity: severity,
+			CustomDetails: map[string]interface{}{
+				"vault_id":      alert.VaultID,
+				"market_pair":   alert.MarketPair,
+				"previous_rate": alert.PreviousRate,
+				"current_rate":  alert.CurrentRate,
+				"critical_rate": alert.CriticalRate,
+			},
+		},
+	})
+}
+
+func (p *PagerDutySink) Resolve(ctx context.Context, alert *types.RateChangeAlert) error {
+	return postJSON(ctx, p.httpClient, pagerDutyEventsURL, nil, pagerDutyEvent{
+		RoutingKey:  p.routingKey,
+		EventAction: "resolve",
+		DedupKey:    incidentKey(alert.VaultID),
+	})
+}
@@ -0,0 +1,108 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/morrisonbrett/SummerRateChecker/internal/types"
+)
+
+// SlackSink mirrors alerts to a Slack channel through an incoming webhook
+type SlackSink struct {
+	webhookURL string
+	httpClient *http.Client
+}
+
+// slackMessage is an incoming webhook message. Text is what notifications preview; the attachment
+// draws the alert with a colored bar, like a Discord embed.
+type slackMessage struct {
+	Text        string            `json:"text"`
+	Attachments []slackAttachment `json:"attachments,omitempty"`
+}
+
+type slackAttachment struct {
+	Color  string       `json:"color"`
+	Blocks []slackBlock `json:"blocks"`
+}
+
+type slackBlock struct {
+	Type     string      `json:"type"`
+	Text     *slackText  `json:"text,omitempty"`
+	Fields   []slackText `json:"fields,omitempty"`
+	Elements []slackText `json:"elements,omitempty"`
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// slackEscaper escapes the characters Slack treats as markup in message text
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+func mrkdwn(text string) slackText {
+	return slackText{Type: "mrkdwn", Text: text}
+}
+
+func NewSlackSink(webhookURL string, httpClient *http.Client) *SlackSink {
+	return &SlackSink{
+		webhookURL: webhookURL,
+		httpClient: httpClient,
+	}
+}
+
+func (s *SlackSink) Name() string {
+	return "slack"
+}
+
+func (s *SlackSink) Send(ctx context.Context, alert *types.RateChangeAlert) error {
+	// Take the title and color from the Discord embed, so an alert looks the same in both
+	embed := alert.ToDiscordEmbed().Embeds[0]
+
+	fields := []slackText{
+		mrkdwn("*Current Rate*\n" + alert.FormatRate(alert.CurrentRate)),
+		mrkdwn("*Previous Rate*\n" + alert.FormatRate(alert.PreviousRate)),
+		mrkdwn("*Change*\n" + alert.FormatPoints(alert.ChangePercent)),
+		mrkdwn("*Market Pair*\n" + slackEscaper.Replace(alert.MarketPair)),
+	}
+	if alert.Severity == types.SeverityCritical && alert.CriticalRate > 0 {
+		fields = append(fields, mrkdwn("*Critical Level*\n"+alert.FormatRate(alert.CriticalRate)))
+	}
+	if t := alert.Threshold; t != nil {
+		value := "*Threshold*\n" + t.Description
+		if !t.SetAt.IsZero() {
+			value += fmt.Sprintf("\nSet %s by %s", t.SetAt.UTC().Format("2 Jan 2006 15:04 MST"), slackEscaper.Replace(t.SetBy))
+		}
+		fields = append(fields, mrkdwn(value))
+	}
+
+	footer := fmt.Sprintf("Vault `%s`", slackEscaper.Replace(alert.VaultID))
+	if alert.ID != "" {
+		footer += fmt.Sprintf(" · Alert `%s`, acknowledge with /ack in Discord", alert.ID)
+	}
+
+	return postJSON(ctx, s.httpClient, s.webhookURL, nil, slackMessage{
+		Text: slackEscaper.Replace(summary(alert)),
+		Attachments: []slackAttachment{{
+			Color: fmt.Sprintf("#%06x", embed.Color),
+			Blocks: []slackBlock{
+				{Type: "section", Text: &slackText{Type: "mrkdwn", Text: "*" + slackEscaper.Replace(embed.Title) + "*"}},
+				{Type: "section", Fields: fields},
+				{Type: "context", Elements: []slackText{mrkdwn(footer)}},
+			},
+		}},
+	})
+}
+
+func (s *SlackSink) Resolve(ctx context.Context, alert *types.RateChangeAlert) error {
+	text := fmt.Sprintf("Recovered: %s borrow rate is back at %s", slackEscaper.Replace(alert.Nickname), alert.FormatRate(alert.CurrentRate))
+	return postJSON(ctx, s.httpClient, s.webhookURL, nil, slackMessage{
+		Text: text,
+		Attachments: []slackAttachment{{
+			Color:  "#00ff00", // Green for recovery
+			Blocks: []slackBlock{{Type: "section", Text: &slackText{Type: "mrkdwn", Text: "✅ " + text}}},
+		}},
+	})
+}
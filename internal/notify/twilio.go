@@ -0,0 +1,92 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/morrisonbrett/SummerRateChecker/internal/config"
+	"github.com/morrisonbrett/SummerRateChecker/internal/types"
+)
+
+const twilioAPIURL = "https://api.twilio.com/2010-04-01"
+
+// TwilioSink texts alerts to a list of phone numbers. It is only registered for
+// critical alerts so liquidation-risk notifications reach someone without Discord open.
+type TwilioSink struct {
+	accountSID string
+	authToken  string
+	from       string
+	to         []string
+	httpClient *http.Client
+}
+
+func NewTwilioSink(cfg config.Twilio, httpClient *http.Client) *TwilioSink {
+	return &TwilioSink{
+		accountSID: cfg.AccountSID,
+		authToken:  cfg.AuthToken,
+		from:       cfg.FromNumber,
+		to:         cfg.ToNumbers,
+		httpClient: httpClient,
+	}
+}
+
+func (t *TwilioSink) Name() string {
+	return "twilio"
+}
+
+func (t *TwilioSink) Send(ctx context.Context, alert *types.RateChangeAlert) error {
+	return t.sendAll(ctx, summary(alert))
+}
+
+func (t *TwilioSink) Resolve(ctx context.Context, alert *types.RateChangeAlert) error {
+	return t.sendAll(ctx, fmt.Sprintf("Recovered: %s borrow rate is back at %s", alert.Nickname, alert.FormatRate(alert.CurrentRate)))
+}
+
+// sendAll texts every recipient, returning the failures together
+func (t *TwilioSink) sendAll(ctx context.Context, body string) error {
+	var failures []error
+	for _, to := range t.to {
+		if err := t.sendSMS(ctx, to, body); err != nil {
+			failures = append(failures, fmt.Errorf("%s: %w", to, err))
+		}
+	}
+
+	if len(failures) > 0 {
+		// Wrapped, so delivery stats can still read the status code of a failure
+		return fmt.Errorf("failed to text %d recipient(s): %w", len(failures), errors.Join(failures...))
+	}
+	return nil
+}
+
+func (t *TwilioSink) sendSMS(ctx context.Context, to, body string) error {
+	form := url.Values{}
+	form.Set("From", t.from)
+	form.Set("To", to)
+	form.Set("Body", body)
+
+	messagesURL := fmt.Sprintf("%s/Accounts/%s/Messages.json", twilioAPIURL, url.PathEscape(t.accountSID))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, messagesURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(t.accountSID, t.authToken)
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return &StatusError{StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+
+	return nil
+}
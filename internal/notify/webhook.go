@@ -0,0 +1,121 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/morrisonbrett/SummerRateChecker/internal/config"
+	"github.com/morrisonbrett/SummerRateChecker/internal/types"
+)
+
+// Webhook payload formats
+const (
+	WebhookFormatJSON    = "json"    // The versioned alert payload, types.AlertPayload
+	WebhookFormatDiscord = "discord" // The same embeds the vault's Discord webhook gets
+	WebhookFormatBoth    = "both"    // Discord embeds with the alert payload under "alert"
+	WebhookFormatSimple  = "simple"  // Flat key/value JSON for IFTTT and Zapier catch hooks
+)
+
+// WebhookSink posts alerts to any HTTP endpoint, so automation can act on them without parsing
+// Discord embeds
+type WebhookSink struct {
+	url        string
+	format     string
+	headers    map[string]string
+	httpClient *http.Client
+}
+
+// simplePayload is a flat alert for no-code tools. IFTTT's Webhooks service only passes on
+// value1-value3, so those carry the gist; Zapier maps any top-level key, so the rest are raw values.
+type simplePayload struct {
+	Value1 string `json:"value1"` // Vault nickname
+	Value2 string `json:"value2"` // Current borrow rate, formatted, e.g. "5.80%"
+	Value3 string `json:"value3"` // One-line summary
+
+	Event        string  `json:"event"`
+	AlertID      string  `json:"alert_id"`
+	VaultID      string  `json:"vault_id"`
+	Nickname     string  `json:"nickname"`
+	MarketPair   string  `json:"market_pair"`
+	Severity     string  `json:"severity"`
+	PreviousRate float64 `json:"previous_rate"`
+	CurrentRate  float64 `json:"current_rate"`
+	ChangePoints float64 `json:"change_points"`
+	Timestamp    string  `json:"timestamp"`
+}
+
+func newSimplePayload(alert *types.RateChangeAlert, event string) simplePayload {
+	payload := alert.Payload(event)
+	value3 := summary(alert)
+	if event == types.AlertEventResolved {
+		value3 = fmt.Sprintf("Recovered: %s borrow rate is back at %s", alert.Nickname, alert.FormatRate(alert.CurrentRate))
+	}
+	return simplePayload{
+		Value1:       alert.Nickname,
+		Value2:       alert.FormatRate(alert.CurrentRate),
+		Value3:       value3,
+		Event:        event,
+		AlertID:      alert.ID,
+		VaultID:      alert.VaultID,
+		Nickname:     alert.Nickname,
+		MarketPair:   alert.MarketPair,
+		Severity:     string(payload.Severity),
+		PreviousRate: alert.PreviousRate,
+		CurrentRate:  alert.CurrentRate,
+		ChangePoints: alert.ChangePercent,
+		Timestamp:    payload.Timestamp.Format(time.RFC3339),
+	}
+}
+
+// webhookBothPayload is a Discord webhook payload with the machine-readable alert alongside, for
+// Discord-compatible endpoints that also feed automation
+type webhookBothPayload struct {
+	*types.DiscordWebhookPayload
+	Alert *types.AlertPayload `json:"alert"`
+}
+
+func NewWebhookSink(cfg config.Webhook, httpClient *http.Client) *WebhookSink {
+	headers := make(map[string]string)
+	if cfg.AuthHeader != "" {
+		headers["Authorization"] = cfg.AuthHeader
+	}
+	return &WebhookSink{
+		url:        cfg.URL,
+		format:     cfg.Format,
+		headers:    headers,
+		httpClient: httpClient,
+	}
+}
+
+func (w *WebhookSink) Name() string {
+	return "webhook"
+}
+
+func (w *WebhookSink) Send(ctx context.Context, alert *types.RateChangeAlert) error {
+	switch w.format {
+	case WebhookFormatDiscord:
+		return postJSON(ctx, w.httpClient, w.url, w.headers, alert.ToDiscordEmbed())
+	case WebhookFormatBoth:
+		return postJSON(ctx, w.httpClient, w.url, w.headers, webhookBothPayload{
+			DiscordWebhookPayload: alert.ToDiscordEmbed(),
+			Alert:                 alert.Payload(types.AlertEventRaised),
+		})
+	case WebhookFormatSimple:
+		return postJSON(ctx, w.httpClient, w.url, w.headers, newSimplePayload(alert, types.AlertEventRaised))
+	}
+	return postJSON(ctx, w.httpClient, w.url, w.headers, alert.Payload(types.AlertEventRaised))
+}
+
+// Resolve tells JSON consumers a critical rate has recovered. Discord-format endpoints already
+// see the recovery message on the vault's own webhook, so they aren't sent anything.
+func (w *WebhookSink) Resolve(ctx context.Context, alert *types.RateChangeAlert) error {
+	switch w.format {
+	case WebhookFormatDiscord:
+		return nil
+	case WebhookFormatSimple:
+		return postJSON(ctx, w.httpClient, w.url, w.headers, newSimplePayload(alert, types.AlertEventResolved))
+	}
+	return postJSON(ctx, w.httpClient, w.url, w.headers, alert.Payload(types.AlertEventResolved))
+}
@@ -0,0 +1,175 @@
+package protocols
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/morrisonbrett/SummerRateChecker/internal/types"
+	"go.uber.org/zap"
+)
+
+// cacheTTL is how long pool data is reused. Both endpoints return every pool DefiLlama tracks, so
+// they're fetched at most this often rather than once per alert.
+const cacheTTL = 15 * time.Minute
+
+// Client fetches borrow rates on other lending protocols from DefiLlama's yields API, so alerts
+// can show whether refinancing elsewhere is an option
+type Client struct {
+	apiURL     string
+	protocols  []string
+	httpClient *http.Client
+	logger     *zap.SugaredLogger
+
+	mu        sync.Mutex
+	pools     []pool
+	fetchedAt time.Time
+}
+
+// pool is a lending pool joined from the /pools and /lendBorrow endpoints
+type pool struct {
+	project    string
+	symbol     string
+	tvlUSD     float64
+	borrowRate float64
+}
+
+// poolsResponse is the /pools endpoint's response
+type poolsResponse struct {
+	Data []struct {
+		Pool    string  `json:"pool"`
+		Chain   string  `json:"chain"`
+		Project string  `json:"project"`
+		Symbol  string  `json:"symbol"`
+		TVLUsd  float64 `json:"tvlUsd"`
+	} `json:"data"`
+}
+
+// lendBorrowPool is an entry in the /lendBorrow endpoint's response
+type lendBorrowPool struct {
+	Pool          string   `json:"pool"`
+	APYBaseBorrow *float64 `json:"apyBaseBorrow"`
+	Borrowable    *bool    `json:"borrowable"`
+}
+
+// NewClient returns a client for the yields API at apiURL, comparing the given DefiLlama project
+// slugs (e.g. "aave-v3", "spark")
+func NewClient(apiURL string, protocols []string, logger *zap.SugaredLogger) *Client {
+	return &Client{
+		apiURL:     strings.TrimRight(apiURL, "/"),
+		protocols:  protocols,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		logger:     logger,
+	}
+}
+
+// GetBorrowRates returns the borrow APY for loanSymbol on each configured protocol on Ethereum
+// mainnet that lends it, in the configured order. When a protocol has several pools for the asset,
+// the largest is used.
+func (c *Client) GetBorrowRates(ctx context.Context, loanSymbol string) ([]types.ProtocolRate, error) {
+	pools, err := c.getPools(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var rates []types.ProtocolRate
+	for _, project := range c.protocols {
+		var best *pool
+		for idx := range pools {
+			p := &pools[idx]
+			if p.project == project && strings.EqualFold(p.symbol, loanSymbol) && (best == nil || p.tvlUSD > best.tvlUSD) {
+				best = p
+			}
+		}
+		if best != nil {
+			rates = append(rates, types.ProtocolRate{Protocol: displayName(project), BorrowRate: best.borrowRate})
+		}
+	}
+	return rates, nil
+}
+
+// getPools returns the cached pools, refreshing them once they're older than cacheTTL
+func (c *Client) getPools(ctx context.Context) ([]pool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.pools != nil && time.Since(c.fetchedAt) < cacheTTL {
+		return c.pools, nil
+	}
+
+	var info poolsResponse
+	if err := c.get(ctx, "/pools", &info); err != nil {
+		return nil, err
+	}
+	var rates []lendBorrowPool
+	if err := c.get(ctx, "/lendBorrow", &rates); err != nil {
+		return nil, err
+	}
+
+	borrowRates := make(map[string]float64, len(rates))
+	for _, rate := range rates {
+		if rate.APYBaseBorrow == nil || (rate.Borrowable != nil && !*rate.Borrowable) {
+			continue
+		}
+		borrowRates[rate.Pool] = *rate.APYBaseBorrow
+	}
+
+	wanted := make(map[string]bool, len(c.protocols))
+	for _, project := range c.protocols {
+		wanted[project] = true
+	}
+
+	pools := make([]pool, 0)
+	for _, p := range info.Data {
+		borrowRate, ok := borrowRates[p.Pool]
+		if !ok || p.Chain != "Ethereum" || !wanted[p.Project] {
+			continue
+		}
+		pools = append(pools, pool{project: p.Project, symbol: p.Symbol, tvlUSD: p.TVLUsd, borrowRate: borrowRate})
+	}
+
+	c.logger.Infof("Fetched %d comparison pools from DefiLlama", len(pools))
+	c.pools = pools
+	c.fetchedAt = time.Now()
+	return pools, nil
+}
+
+// get decodes the JSON response from path into out
+func (c *Client) get(ctx context.Context, path string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.apiURL+path, nil)
+	if err != nil {
+		return fmt.Errorf("failed to build request for %s: %w", path, err)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("DefiLlama API error for %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("DefiLlama API returned status %d for %s", resp.StatusCode, path)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	return nil
+}
+
+// displayName turns a DefiLlama project slug into the name shown in alerts, e.g. "aave-v3" → "Aave v3"
+func displayName(project string) string {
+	switch project {
+	case "aave-v3":
+		return "Aave v3"
+	case "aave-v2":
+		return "Aave v2"
+	case "spark":
+		return "Spark"
+	case "compound-v3":
+		return "Compound v3"
+	}
+	return project
+}
@@ -0,0 +1,468 @@
+package rules
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Variables lists the names a rule expression can reference, with a description of each
+var Variables = map[string]string{
+	"borrowApy":   "current borrow APY in %",
+	"supplyApy":   "current supply APY in %",
+	"utilization": "borrowed / supplied, from 0 to 1",
+	"change24h":   "borrow APY change over the last 24 hours, in percentage points",
+	"volatility":  "standard deviation of hourly borrow APYs over the last 7 days, in percentage points",
+	"supplyUsd":   "total supplied to the market in USD",
+	"borrowUsd":   "total borrowed from the market in USD",
+	"badDebtUsd":  "realized plus unrealized bad debt in USD",
+	"debtUsd":     "the Summer.fi position's debt in USD",
+	"ltv":         "the Summer.fi position's loan-to-value in %",
+	"lltv":        "the market's liquidation loan-to-value in %",
+	"headroom":    "lltv minus the Summer.fi position's ltv, in percentage points",
+}
+
+// VariableNames returns the variable names in alphabetical order
+func VariableNames() []string {
+	names := make([]string, 0, len(Variables))
+	for name := range Variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Env maps variable names to their values for one evaluation
+type Env map[string]float64
+
+// Expression is a parsed rule such as `borrowApy > 8 && utilization > 0.95 || change24h > 1.5`.
+// It supports numbers, the names in Variables, + - * /, comparisons (< <= > >= == !=),
+// && || ! and parentheses, with the usual precedence.
+type Expression struct {
+	source string
+	root   node
+}
+
+// Parse compiles source, rejecting syntax errors and unknown variables
+func Parse(source string) (*Expression, error) {
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q at position %d", p.tokens[p.pos].text, p.tokens[p.pos].pos+1)
+	}
+	if !root.boolean() {
+		return nil, fmt.Errorf("expression must be a condition, e.g. `borrowApy > 8`")
+	}
+
+	return &Expression{source: strings.TrimSpace(source), root: root}, nil
+}
+
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Eval reports whether the expression holds for env. Variables missing from env are an error,
+// so a rule never fires on data that wasn't available.
+func (e *Expression) Eval(env Env) (bool, error) {
+	value, err := e.root.eval(env)
+	if err != nil {
+		return false, err
+	}
+	return value != 0, nil
+}
+
+// Lexer
+
+type tokenKind int
+
+const (
+	tokenNumber tokenKind = iota
+	tokenIdent
+	tokenOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+var operators = []string{"&&", "||", "<=", ">=", "==", "!=", "<", ">", "!", "+", "-", "*", "/", "(", ")"}
+
+func tokenize(source string) ([]token, error) {
+	var tokens []token
+	runes := []rune(source)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsDigit(r) || r == '.':
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: string(runes[start:i]), pos: start})
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: string(runes[start:i]), pos: start})
+		default:
+			matched := false
+			for _, op := range operators {
+				if strings.HasPrefix(string(runes[i:]), op) {
+					tokens = append(tokens, token{kind: tokenOp, text: op, pos: i})
+					i += len([]rune(op))
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected character %q at position %d", r, i+1)
+			}
+		}
+	}
+
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("expression is empty")
+	}
+	return tokens, nil
+}
+
+// Parser
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek(ops ...string) (string, bool) {
+	if p.pos >= len(p.tokens) || p.tokens[p.pos].kind != tokenOp {
+		return "", false
+	}
+	for _, op := range ops {
+		if p.tokens[p.pos].text == op {
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.peek("||"); !ok {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		if left, err = newLogical("||", left, right); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.peek("&&"); !ok {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		if left, err = newLogical("&&", left, right); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *parser) parseNot() (node, error) {
+	if _, ok := p.peek("!"); ok {
+		p.pos++
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		if !operand.boolean() {
+			return nil, fmt.Errorf("`!` needs a condition")
+		}
+		return notNode{operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	op, ok := p.peek("<", "<=", ">", ">=", "==", "!=")
+	if !ok {
+		return left, nil
+	}
+	p.pos++
+	right, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	if left.boolean() || right.boolean() {
+		return nil, fmt.Errorf("`%s` compares numbers, not conditions", op)
+	}
+	return compareNode{op, left, right}, nil
+}
+
+func (p *parser) parseSum() (node, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.peek("+", "-")
+		if !ok {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		if left, err = newArithmetic(op, left, right); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *parser) parseTerm() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.peek("*", "/")
+		if !ok {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if left, err = newArithmetic(op, left, right); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if _, ok := p.peek("-"); ok {
+		p.pos++
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return newArithmetic("-", numberNode(0), operand)
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("expression ends unexpectedly")
+	}
+	tok := p.tokens[p.pos]
+	p.pos++
+
+	switch tok.kind {
+	case tokenNumber:
+		value, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", tok.text)
+		}
+		return numberNode(value), nil
+	case tokenIdent:
+		if _, ok := Variables[tok.text]; !ok {
+			return nil, fmt.Errorf("unknown variable %q (available: %s)", tok.text, strings.Join(VariableNames(), ", "))
+		}
+		return variableNode(tok.text), nil
+	}
+
+	if tok.text == "(" {
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := p.peek(")"); !ok {
+			return nil, fmt.Errorf("missing `)` for `(` at position %d", tok.pos+1)
+		}
+		p.pos++
+		return inner, nil
+	}
+	return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos+1)
+}
+
+// AST
+
+type node interface {
+	eval(env Env) (float64, error)
+	boolean() bool // Whether the node yields a condition rather than a number
+}
+
+type numberNode float64
+
+func (n numberNode) eval(Env) (float64, error) { return float64(n), nil }
+func (n numberNode) boolean() bool             { return false }
+
+type variableNode string
+
+func (n variableNode) eval(env Env) (float64, error) {
+	value, ok := env[string(n)]
+	if !ok {
+		return 0, fmt.Errorf("%s is not available", string(n))
+	}
+	return value, nil
+}
+func (n variableNode) boolean() bool { return false }
+
+type arithmeticNode struct {
+	op          string
+	left, right node
+}
+
+func newArithmetic(op string, left, right node) (node, error) {
+	if left.boolean() || right.boolean() {
+		return nil, fmt.Errorf("`%s` needs numbers, not conditions", op)
+	}
+	return arithmeticNode{op, left, right}, nil
+}
+
+func (n arithmeticNode) eval(env Env) (float64, error) {
+	left, err := n.left.eval(env)
+	if err != nil {
+		return 0, err
+	}
+	right, err := n.right.eval(env)
+	if err != nil {
+		return 0, err
+	}
+	switch n.op {
+	case "+":
+		return left + right, nil
+	case "-":
+		return left - right, nil
+	case "*":
+		return left * right, nil
+	default:
+		if right == 0 {
+			return 0, fmt.Errorf("division by zero")
+		}
+		return left / right, nil
+	}
+}
+func (n arithmeticNode) boolean() bool { return false }
+
+type compareNode struct {
+	op          string
+	left, right node
+}
+
+func (n compareNode) eval(env Env) (float64, error) {
+	left, err := n.left.eval(env)
+	if err != nil {
+		return 0, err
+	}
+	right, err := n.right.eval(env)
+	if err != nil {
+		return 0, err
+	}
+
+	var result bool
+	switch n.op {
+	case "<":
+		result = left < right
+	case "<=":
+		result = left <= right
+	case ">":
+		result = left > right
+	case ">=":
+		result = left >= right
+	case "==":
+		result = left == right
+	default:
+		result = left != right
+	}
+	return boolValue(result), nil
+}
+func (n compareNode) boolean() bool { return true }
+
+type logicalNode struct {
+	op          string
+	left, right node
+}
+
+func newLogical(op string, left, right node) (node, error) {
+	if !left.boolean() || !right.boolean() {
+		return nil, fmt.Errorf("`%s` joins conditions, e.g. `borrowApy > 8 %s utilization > 0.9`", op, op)
+	}
+	return logicalNode{op, left, right}, nil
+}
+
+func (n logicalNode) eval(env Env) (float64, error) {
+	left, err := n.left.eval(env)
+	if err != nil {
+		return 0, err
+	}
+	// Short-circuit like Go does
+	if n.op == "&&" && left == 0 {
+		return 0, nil
+	}
+	if n.op == "||" && left != 0 {
+		return 1, nil
+	}
+	return n.right.eval(env)
+}
+func (n logicalNode) boolean() bool { return true }
+
+type notNode struct {
+	operand node
+}
+
+func (n notNode) eval(env Env) (float64, error) {
+	value, err := n.operand.eval(env)
+	if err != nil {
+		return 0, err
+	}
+	return boolValue(value == 0), nil
+}
+func (n notNode) boolean() bool { return true }
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,163 @@
+package stats
+
+import (
+	"math"
+	"time"
+
+	"github.com/morrisonbrett/SummerRateChecker/internal/types"
+)
+
+// year is the period borrow APYs are quoted over
+const year = 365 * 24 * time.Hour
+
+// Percentile returns where rate ranks among the borrow rates in samples, from 0 to 100.
+// Samples equal to rate count as half below, so a flat history places it at the 50th percentile.
+func Percentile(samples []types.RateSample, rate float64) float64 {
+	if len(samples) == 0 {
+		return 0
+	}
+
+	var below, equal int
+	for _, sample := range samples {
+		switch {
+		case sample.BorrowRate < rate:
+			below++
+		case sample.BorrowRate == rate:
+			equal++
+		}
+	}
+
+	return (float64(below) + float64(equal)/2) / float64(len(samples)) * 100
+}
+
+// Summary holds the range and net movement of borrow rates over a window
+type Summary struct {
+	Low       float64
+	High      float64
+	Mean      float64
+	NetChange float64 // Last sample minus first sample, in percentage points
+}
+
+// Summarize computes the low, high, mean, and net change of the borrow rates in samples.
+// Samples must be in chronological order. ok is false when samples is empty.
+func Summarize(samples []types.RateSample) (summary Summary, ok bool) {
+	if len(samples) == 0 {
+		return Summary{}, false
+	}
+
+	summary.Low = samples[0].BorrowRate
+	summary.High = samples[0].BorrowRate
+	var total float64
+	for _, sample := range samples {
+		if sample.BorrowRate < summary.Low {
+			summary.Low = sample.BorrowRate
+		}
+		if sample.BorrowRate > summary.High {
+			summary.High = sample.BorrowRate
+		}
+		total += sample.BorrowRate
+	}
+	summary.Mean = total / float64(len(samples))
+	summary.NetChange = samples[len(samples)-1].BorrowRate - samples[0].BorrowRate
+
+	return summary, true
+}
+
+// Volatility returns the standard deviation of the check-to-check borrow rate changes in samples,
+// in percentage points. Samples must be in chronological order. ok is false with fewer than two samples.
+func Volatility(samples []types.RateSample) (volatility float64, ok bool) {
+	if len(samples) < 2 {
+		return 0, false
+	}
+
+	changes := make([]float64, 0, len(samples)-1)
+	var sum float64
+	for i := 1; i < len(samples); i++ {
+		change := samples[i].BorrowRate - samples[i-1].BorrowRate
+		changes = append(changes, change)
+		sum += change
+	}
+
+	mean := sum / float64(len(changes))
+	var variance float64
+	for _, change := range changes {
+		variance += (change - mean) * (change - mean)
+	}
+	variance /= float64(len(changes))
+
+	return math.Sqrt(variance), true
+}
+
+// VolatilityWindow is the period a market's volatility index is computed over
+const VolatilityWindow = 7 * 24 * time.Hour
+
+// VolatilityIndex returns the standard deviation of hourly borrow rates in samples, in percentage
+// points. Samples within each clock hour are averaged first, so vaults checked more often than
+// hourly don't score differently. Samples must be in chronological order. ok is false with fewer
+// than two hours of data.
+func VolatilityIndex(samples []types.RateSample) (index float64, ok bool) {
+	var hourly []types.RateSample
+	var hour time.Time
+	var sum float64
+	var count int
+	flush := func() {
+		if count > 0 {
+			hourly = append(hourly, types.RateSample{Timestamp: hour, BorrowRate: sum / float64(count)})
+		}
+	}
+	for _, sample := range samples {
+		if start := sample.Timestamp.Truncate(time.Hour); !start.Equal(hour) {
+			flush()
+			hour, sum, count = start, 0, 0
+		}
+		sum += sample.BorrowRate
+		count++
+	}
+	flush()
+
+	if len(hourly) < 2 {
+		return 0, false
+	}
+	_, index, ok = MeanStdDev(hourly)
+	return index, ok
+}
+
+// MeanStdDev returns the mean and standard deviation of the borrow rates in samples.
+// ok is false when samples is empty.
+func MeanStdDev(samples []types.RateSample) (mean, stddev float64, ok bool) {
+	if len(samples) == 0 {
+		return 0, 0, false
+	}
+
+	for _, sample := range samples {
+		mean += sample.BorrowRate
+	}
+	mean /= float64(len(samples))
+
+	var variance float64
+	for _, sample := range samples {
+		variance += (sample.BorrowRate - mean) * (sample.BorrowRate - mean)
+	}
+	variance /= float64(len(samples))
+
+	return mean, math.Sqrt(variance), true
+}
+
+// AccruedInterest estimates the interest owed on a constant debt of principal between the first
+// sample and until, compounding each sample's borrow APY over the time until the next sample.
+// Samples must be in chronological order; without samples nothing has accrued.
+func AccruedInterest(samples []types.RateSample, principal float64, until time.Time) float64 {
+	var interest float64
+	for i, sample := range samples {
+		end := until
+		if i+1 < len(samples) {
+			end = samples[i+1].Timestamp
+		}
+		if !end.After(sample.Timestamp) {
+			continue
+		}
+		years := float64(end.Sub(sample.Timestamp)) / float64(year)
+		interest += principal * (math.Pow(1+sample.BorrowRate/100, years) - 1)
+	}
+	return interest
+}
@@ -1,202 +1,790 @@
-package storage
-
-import (
-	"encoding/json"
-	"fmt"
-	"os"
-	"path/filepath"
-	"sync"
-	"time"
-
-	"github.com/morrisonbrett/SummerRateChecker/internal/types"
-)
-
-type FileStorage struct {
-	mu         sync.RWMutex
-	vaults     map[string]*types.VaultConfig
-	lastRates  map[string]float64
-	dataDir    string
-	vaultsFile string
-	ratesFile  string
-}
-
-func NewFileStorage(dataDir string) (*FileStorage, error) {
-	if dataDir == "" {
-		dataDir = "data"
-	}
-
-	// Create data directory if it doesn't exist
-	if err := os.MkdirAll(dataDir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create data directory: %w", err)
-	}
-
-	fs := &FileStorage{
-		vaults:     make(map[string]*types.VaultConfig),
-		lastRates:  make(map[string]float64),
-		dataDir:    dataDir,
-		vaultsFile: filepath.Join(dataDir, "vaults.json"),
-		ratesFile:  filepath.Join(dataDir, "rates.json"),
-	}
-
-	// Load existing data
-	if err := fs.loadFromDisk(); err != nil {
-		return nil, fmt.Errorf("failed to load data from disk: %w", err)
-	}
-
-	return fs, nil
-}
-
-func (fs *FileStorage) AddVault(vault *types.VaultConfig) error {
-	fs.mu.Lock()
-	defer fs.mu.Unlock()
-
-	vault.CreatedAt = time.Now()
-	fs.vaults[vault.VaultID] = vault
-	return fs.saveVaultsToDisk()
-}
-
-func (fs *FileStorage) RemoveVault(vaultID string) error {
-	fs.mu.Lock()
-	defer fs.mu.Unlock()
-
-	delete(fs.vaults, vaultID)
-	delete(fs.lastRates, vaultID)
-
-	if err := fs.saveVaultsToDisk(); err != nil {
-		return err
-	}
-	return fs.saveRatesToDisk()
-}
-
-func (fs *FileStorage) GetVault(vaultID string) (*types.VaultConfig, error) {
-	fs.mu.RLock()
-	defer fs.mu.RUnlock()
-
-	vault, exists := fs.vaults[vaultID]
-	if !exists {
-		return nil, nil
-	}
-	return vault, nil
-}
-
-func (fs *FileStorage) GetAllVaults() ([]*types.VaultConfig, error) {
-	fs.mu.RLock()
-	defer fs.mu.RUnlock()
-
-	vaults := make([]*types.VaultConfig, 0, len(fs.vaults))
-	for _, vault := range fs.vaults {
-		vaults = append(vaults, vault)
-	}
-	return vaults, nil
-}
-
-func (fs *FileStorage) UpdateLastRate(vaultID string, rate float64) error {
-	fs.mu.Lock()
-	defer fs.mu.Unlock()
-
-	fs.lastRates[vaultID] = rate
-	return fs.saveRatesToDisk()
-}
-
-func (fs *FileStorage) GetLastRate(vaultID string) (float64, bool) {
-	fs.mu.RLock()
-	defer fs.mu.RUnlock()
-
-	rate, exists := fs.lastRates[vaultID]
-	return rate, exists
-}
-
-func (fs *FileStorage) GetAllLastRates() map[string]float64 {
-	fs.mu.RLock()
-	defer fs.mu.RUnlock()
-
-	rates := make(map[string]float64)
-	for k, v := range fs.lastRates {
-		rates[k] = v
-	}
-	return rates
-}
-
-func (fs *FileStorage) loadFromDisk() error {
-	// Load vaults
-	if err := fs.loadVaultsFromDisk(); err != nil {
-		return err
-	}
-
-	// Load rates
-	if err := fs.loadRatesFromDisk(); err != nil {
-		return err
-	}
-
-	return nil
-}
-
-func (fs *FileStorage) loadVaultsFromDisk() error {
-	if _, err := os.Stat(fs.vaultsFile); os.IsNotExist(err) {
-		// File doesn't exist, start with empty vaults
-		return nil
-	}
-
-	data, err := os.ReadFile(fs.vaultsFile)
-	if err != nil {
-		return fmt.Errorf("failed to read vaults file: %w", err)
-	}
-
-	if len(data) == 0 {
-		return nil
-	}
-
-	if err := json.Unmarshal(data, &fs.vaults); err != nil {
-		return fmt.Errorf("failed to unmarshal vaults: %w", err)
-	}
-
-	return nil
-}
-
-func (fs *FileStorage) loadRatesFromDisk() error {
-	if _, err := os.Stat(fs.ratesFile); os.IsNotExist(err) {
-		// File doesn't exist, start with empty rates
-		return nil
-	}
-
-	data, err := os.ReadFile(fs.ratesFile)
-	if err != nil {
-		return fmt.Errorf("failed to read rates file: %w", err)
-	}
-
-	if len(data) == 0 {
-		return nil
-	}
-
-	if err := json.Unmarshal(data, &fs.lastRates); err != nil {
-		return fmt.Errorf("failed to unmarshal rates: %w", err)
-	}
-
-	return nil
-}
-
-func (fs *FileStorage) saveVaultsToDisk() error {
-	data, err := json.MarshalIndent(fs.vaults, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal vaults: %w", err)
-	}
-
-	if err := os.WriteFile(fs.vaultsFile, data, 0644); err != nil {
-		return fmt.Errorf("failed to write vaults file: %w", err)
-	}
-
-	return nil
-}
-
-func (fs *FileStorage) saveRatesToDisk() error {
-	data, err := json.MarshalIndent(fs.lastRates, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal rates: %w", err)
-	}
-
-	if err := os.WriteFile(fs.ratesFile, data, 0644); err != nil {
-		return fmt.Errorf("failed to write rates file: %w", err)
-	}
-
-	return nil
-}
+package storage
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/morrisonbrett/SummerRateChecker/internal/types"
+)
+
+type FileStorage struct {
+	mu           sync.RWMutex
+	vaults       map[string]*types.VaultConfig
+	lastRates    map[string]float64
+	history      map[string][]types.RateSample
+	alerts       []*types.RateChangeAlert
+	audit        []*types.AuditEntry
+	delivery     map[string]map[string]*types.DeliveryStats
+	settings     types.Settings
+	watches      map[string]*types.MarketWatch
+	rules        map[string]*types.EnrollRule
+	tokens       map[string]*types.APIToken
+	dataDir      string
+	vaultsFile   string
+	ratesFile    string
+	historyFile  string
+	alertsFile   string
+	auditFile    string
+	deliveryFile string
+	settingsFile string
+	watchesFile  string
+	rulesFile    string
+	tokensFile   string
+	backupDir    string
+
+	lastCompaction time.Time
+	recoveries     []RecoveryEvent
+
+	// Rates and history change for every vault on every check, so they're written once per Flush
+	ratesDirty   bool
+	historyDirty bool
+
+	// readOnly is set for a mirror of another instance's data directory, which it must never write
+	readOnly bool
+}
+
+// ErrReadOnly is returned by changes to a mirror's storage
+var ErrReadOnly = errors.New("storage is a read-only mirror")
+
+// NewFileStorage loads the data files in dataDir. Corrupt files are restored from the newest
+// snapshot in backupDir that has a readable copy; see Recoveries.
+func NewFileStorage(dataDir, backupDir string) (*FileStorage, error) {
+	if dataDir == "" {
+		dataDir = "data"
+	}
+
+	// Create data directory if it doesn't exist
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	fs := newFileStorage(dataDir, backupDir)
+
+	// Load existing data
+	if err := fs.loadFromDisk(); err != nil {
+		return nil, fmt.Errorf("failed to load data from disk: %w", err)
+	}
+
+	return fs, nil
+}
+
+// NewMirrorStorage opens another instance's data directory without ever writing to it. Changes
+// fail with ErrReadOnly; call Reload to pick up what the other instance has written since.
+func NewMirrorStorage(dataDir string) (*FileStorage, error) {
+	if _, err := os.Stat(dataDir); err != nil {
+		return nil, fmt.Errorf("failed to open mirrored data directory: %w", err)
+	}
+
+	fs := newFileStorage(dataDir, "")
+	fs.readOnly = true
+	if err := fs.loadFromDisk(); err != nil {
+		return nil, fmt.Errorf("failed to load mirrored data: %w", err)
+	}
+
+	return fs, nil
+}
+
+// Reload re-reads every data file of a mirror. A file caught mid-write fails to parse and keeps
+// its previous contents until the next reload.
+func (fs *FileStorage) Reload() error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	return fs.loadFromDisk()
+}
+
+func newFileStorage(dataDir, backupDir string) *FileStorage {
+	return &FileStorage{
+		vaults:       make(map[string]*types.VaultConfig),
+		lastRates:    make(map[string]float64),
+		history:      make(map[string][]types.RateSample),
+		delivery:     make(map[string]map[string]*types.DeliveryStats),
+		watches:      make(map[string]*types.MarketWatch),
+		rules:        make(map[string]*types.EnrollRule),
+		tokens:       make(map[string]*types.APIToken),
+		dataDir:      dataDir,
+		vaultsFile:   filepath.Join(dataDir, "vaults.json"),
+		ratesFile:    filepath.Join(dataDir, "rates.json"),
+		historyFile:  filepath.Join(dataDir, "history.json"),
+		alertsFile:   filepath.Join(dataDir, "alerts.json"),
+		auditFile:    filepath.Join(dataDir, "audit.json"),
+		deliveryFile: filepath.Join(dataDir, "delivery.json"),
+		settingsFile: filepath.Join(dataDir, "settings.json"),
+		watchesFile:  filepath.Join(dataDir, "watches.json"),
+		rulesFile:    filepath.Join(dataDir, "enroll_rules.json"),
+		tokensFile:   filepath.Join(dataDir, "api_tokens.json"),
+		backupDir:    backupDir,
+	}
+}
+
+func (fs *FileStorage) AddVault(vault *types.VaultConfig) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	// AddVault also updates existing vaults, which keep their original enrollment time
+	if existing, ok := fs.vaults[vault.VaultID]; ok && !existing.CreatedAt.IsZero() && !existing.Trashed() {
+		vault.CreatedAt = existing.CreatedAt
+	} else {
+		vault.CreatedAt = time.Now()
+	}
+	fs.vaults[vault.VaultID] = vault.Clone()
+	return fs.saveVaultsToDisk()
+}
+
+func (fs *FileStorage) RemoveVault(vaultID string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	fs.removeVault(vaultID)
+	return fs.saveRemoval()
+}
+
+// removeVault deletes a vault and everything keyed by its ID. Callers hold the lock and call saveRemoval.
+func (fs *FileStorage) removeVault(vaultID string) {
+	delete(fs.vaults, vaultID)
+	delete(fs.lastRates, vaultID)
+	delete(fs.history, vaultID)
+	delete(fs.delivery, vaultID)
+}
+
+func (fs *FileStorage) saveRemoval() error {
+	if err := fs.saveVaultsToDisk(); err != nil {
+		return err
+	}
+	if err := fs.saveRatesToDisk(); err != nil {
+		return err
+	}
+	return fs.saveHistoryToDisk()
+}
+
+func (fs *FileStorage) TrashVault(vaultID string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if err := trashVault(fs.vaults, vaultID); err != nil {
+		return err
+	}
+	return fs.saveVaultsToDisk()
+}
+
+func (fs *FileStorage) RestoreVault(vaultID string) (*types.VaultConfig, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	vault, err := restoreVault(fs.vaults, vaultID)
+	if err != nil {
+		return nil, err
+	}
+	return vault, fs.saveVaultsToDisk()
+}
+
+func (fs *FileStorage) GetTrashedVaults() []*types.VaultConfig {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	return trashedVaults(fs.vaults)
+}
+
+func (fs *FileStorage) PurgeTrash(cutoff time.Time) ([]*types.VaultConfig, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	purged := expiredTrash(fs.vaults, cutoff)
+	if len(purged) == 0 {
+		return nil, nil
+	}
+	for _, vault := range purged {
+		fs.removeVault(vault.VaultID)
+	}
+	return purged, fs.saveRemoval()
+}
+
+func (fs *FileStorage) GetVault(vaultID string) (*types.VaultConfig, error) {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	vault, exists := fs.vaults[vaultID]
+	if !exists || vault.Trashed() {
+		return nil, nil
+	}
+	return vault.Clone(), nil
+}
+
+func (fs *FileStorage) GetAllVaults() ([]*types.VaultConfig, error) {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	vaults := make([]*types.VaultConfig, 0, len(fs.vaults))
+	for _, vault := range fs.vaults {
+		if !vault.Trashed() {
+			vaults = append(vaults, vault.Clone())
+		}
+	}
+	return vaults, nil
+}
+
+func (fs *FileStorage) UpdateVault(vaultID string, update func(vault *types.VaultConfig) error) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	vault, exists := fs.vaults[vaultID]
+	if !exists || vault.Trashed() {
+		return fmt.Errorf("vault %s not found", vaultID)
+	}
+	updated := vault.Clone()
+	if err := update(updated); err != nil {
+		return err
+	}
+	fs.vaults[vaultID] = updated
+	return fs.saveVaultsToDisk()
+}
+
+func (fs *FileStorage) UpdateVaults(updates map[string]func(vault *types.VaultConfig) error) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	changed, err := applyVaultUpdates(fs.vaults, updates)
+	if changed {
+		if saveErr := fs.saveVaultsToDisk(); saveErr != nil {
+			return saveErr
+		}
+	}
+	return err
+}
+
+func (fs *FileStorage) UpdateLastRate(vaultID string, rate float64) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	fs.lastRates[vaultID] = rate
+	fs.ratesDirty = true
+	return nil
+}
+
+func (fs *FileStorage) GetLastRate(vaultID string) (float64, bool) {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	rate, exists := fs.lastRates[vaultID]
+	return rate, exists
+}
+
+func (fs *FileStorage) GetAllLastRates() map[string]float64 {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	rates := make(map[string]float64)
+	for k, v := range fs.lastRates {
+		rates[k] = v
+	}
+	return rates
+}
+
+func (fs *FileStorage) GetHistoryVaultIDs() []string {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	return historyKeys(fs.history)
+}
+
+func (fs *FileStorage) AppendRateHistory(vaultID string, sample types.RateSample) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	fs.history[vaultID] = append(fs.history[vaultID], sample)
+
+	// Downsample older samples periodically so history doesn't grow unbounded
+	if now := time.Now(); now.Sub(fs.lastCompaction) >= compactionInterval {
+		compactHistory(fs.history, now)
+		fs.lastCompaction = now
+	}
+	fs.historyDirty = true
+	return nil
+}
+
+// Flush writes buffered rate and history updates. The monitor calls it after every check,
+// so a crash loses at most the current cycle's samples.
+func (fs *FileStorage) Flush() error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if fs.ratesDirty {
+		if err := fs.saveRatesToDisk(); err != nil {
+			return err
+		}
+	}
+	if fs.historyDirty {
+		if err := fs.saveHistoryToDisk(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (fs *FileStorage) GetRateHistory(vaultID string, since time.Time) []types.RateSample {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	return samplesSince(fs.history[vaultID], since)
+}
+
+func (fs *FileStorage) RecordAlert(alert *types.RateChangeAlert) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	fs.alerts = appendAlert(fs.alerts, alert)
+	return fs.saveAlertsToDisk()
+}
+
+func (fs *FileStorage) GetRecentAlerts(limit int) []*types.RateChangeAlert {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	return recentAlerts(fs.alerts, limit)
+}
+
+func (fs *FileStorage) GetAlert(alertID string) *types.RateChangeAlert {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	return findAlert(fs.alerts, alertID)
+}
+
+func (fs *FileStorage) AcknowledgeAlert(alertID, userID string) (*types.RateChangeAlert, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	alert, err := acknowledgeAlert(fs.alerts, alertID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return alert, fs.saveAlertsToDisk()
+}
+
+func (fs *FileStorage) RecordAudit(entry *types.AuditEntry) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	fs.audit = appendAudit(fs.audit, entry)
+	return fs.saveAuditToDisk()
+}
+
+func (fs *FileStorage) GetAuditLog(limit int) []*types.AuditEntry {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	return recentAudit(fs.audit, limit)
+}
+
+func (fs *FileStorage) MarkAuditUndone(entryID string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if err := markAuditUndone(fs.audit, entryID); err != nil {
+		return err
+	}
+	return fs.saveAuditToDisk()
+}
+
+func (fs *FileStorage) RecordDelivery(vaultID string, result types.DeliveryResult) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	applyDelivery(fs.delivery, vaultID, result)
+	return fs.saveDeliveryToDisk()
+}
+
+func (fs *FileStorage) GetDeliveryStats(vaultID string) []types.DeliveryStats {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	return deliveryStats(fs.delivery[vaultID])
+}
+
+func (fs *FileStorage) GetSettings() types.Settings {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	return fs.settings
+}
+
+func (fs *FileStorage) UpdateSettings(update func(settings *types.Settings)) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	updated := fs.settings.Clone()
+	update(&updated)
+	fs.settings = updated
+	return fs.saveSettingsToDisk()
+}
+
+func (fs *FileStorage) AddMarketWatch(watch *types.MarketWatch) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	fs.watches[watch.ID] = watch.Clone()
+	return fs.saveWatchesToDisk()
+}
+
+func (fs *FileStorage) RemoveMarketWatch(watchID string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	delete(fs.watches, watchID)
+	return fs.saveWatchesToDisk()
+}
+
+func (fs *FileStorage) UpdateMarketWatch(watchID string, update func(watch *types.MarketWatch)) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	watch, exists := fs.watches[watchID]
+	if !exists {
+		return fmt.Errorf("market watch %s not found", watchID)
+	}
+	updated := watch.Clone()
+	update(updated)
+	fs.watches[watchID] = updated
+	return fs.saveWatchesToDisk()
+}
+
+func (fs *FileStorage) GetMarketWatches() []*types.MarketWatch {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	return sortedWatches(fs.watches)
+}
+
+func (fs *FileStorage) AddEnrollRule(rule *types.EnrollRule) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	fs.rules[rule.ID] = rule.Clone()
+	return fs.saveRulesToDisk()
+}
+
+func (fs *FileStorage) RemoveEnrollRule(ruleID string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	delete(fs.rules, ruleID)
+	return fs.saveRulesToDisk()
+}
+
+func (fs *FileStorage) GetEnrollRules() []*types.EnrollRule {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	return sortedRules(fs.rules)
+}
+
+func (fs *FileStorage) AddAPIToken(token *types.APIToken) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	fs.tokens[token.ID] = token.Clone()
+	return fs.saveTokensToDisk()
+}
+
+func (fs *FileStorage) RemoveAPIToken(tokenID string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	delete(fs.tokens, tokenID)
+	return fs.saveTokensToDisk()
+}
+
+func (fs *FileStorage) GetAPITokens() []*types.APIToken {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	return sortedTokens(fs.tokens)
+}
+
+func (fs *FileStorage) FindAPIToken(secret string) *types.APIToken {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	return findToken(fs.tokens, secret)
+}
+
+func (fs *FileStorage) TouchAPIToken(tokenID string, t time.Time) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if err := touchToken(fs.tokens, tokenID, t); err != nil {
+		return err
+	}
+	return fs.saveTokensToDisk()
+}
+
+// Snapshot writes a gzipped tar of every data file to w. Writes are blocked while it runs,
+// so the files are consistent with each other.
+func (fs *FileStorage) Snapshot(w io.Writer) error {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	files := []string{
+		fs.vaultsFile, fs.ratesFile, fs.historyFile, fs.alertsFile, fs.auditFile, fs.deliveryFile,
+		fs.settingsFile, fs.watchesFile, fs.rulesFile, fs.tokensFile,
+	}
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+
+		header := &tar.Header{
+			Name:    filepath.Base(path),
+			Mode:    0600,
+			Size:    int64(len(data)),
+			ModTime: time.Now(),
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return fmt.Errorf("failed to write snapshot: %w", err)
+		}
+		if _, err := tw.Write(data); err != nil {
+			return fmt.Errorf("failed to write snapshot: %w", err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	return gz.Close()
+}
+
+func (fs *FileStorage) loadFromDisk() error {
+	// Load vaults
+	if err := fs.loadVaultsFromDisk(); err != nil {
+		return err
+	}
+
+	// Load rates
+	if err := fs.loadRatesFromDisk(); err != nil {
+		return err
+	}
+
+	// Load rate history
+	if err := fs.loadHistoryFromDisk(); err != nil {
+		return err
+	}
+
+	// Load alert log
+	if err := fs.loadAlertsFromDisk(); err != nil {
+		return err
+	}
+
+	// Load audit log
+	if err := fs.loadAuditFromDisk(); err != nil {
+		return err
+	}
+
+	// Load delivery stats
+	if err := fs.loadDeliveryFromDisk(); err != nil {
+		return err
+	}
+
+	// Load settings
+	if err := fs.loadSettingsFromDisk(); err != nil {
+		return err
+	}
+
+	// Load market watches
+	if err := fs.loadWatchesFromDisk(); err != nil {
+		return err
+	}
+
+	// Load auto-enroll rules
+	if err := fs.loadRulesFromDisk(); err != nil {
+		return err
+	}
+
+	// Load API tokens
+	if err := fs.loadTokensFromDisk(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (fs *FileStorage) loadVaultsFromDisk() error {
+	return fs.loadFile(fs.vaultsFile, "vaults", func(data json.RawMessage) error {
+		vaults := make(map[string]*types.VaultConfig)
+		if err := json.Unmarshal(data, &vaults); err != nil {
+			return err
+		}
+		fs.vaults = vaults
+		return nil
+	})
+}
+
+func (fs *FileStorage) loadRatesFromDisk() error {
+	return fs.loadFile(fs.ratesFile, "rates", func(data json.RawMessage) error {
+		lastRates := make(map[string]float64)
+		if err := json.Unmarshal(data, &lastRates); err != nil {
+			return err
+		}
+		fs.lastRates = lastRates
+		return nil
+	})
+}
+
+func (fs *FileStorage) loadHistoryFromDisk() error {
+	return fs.loadFile(fs.historyFile, "history", func(data json.RawMessage) error {
+		history := make(map[string][]types.RateSample)
+		if err := json.Unmarshal(data, &history); err != nil {
+			return err
+		}
+		fs.history = history
+		return nil
+	})
+}
+
+func (fs *FileStorage) loadAlertsFromDisk() error {
+	return fs.loadFile(fs.alertsFile, "alerts", func(data json.RawMessage) error {
+		var alerts []*types.RateChangeAlert
+		if err := json.Unmarshal(data, &alerts); err != nil {
+			return err
+		}
+		fs.alerts = alerts
+		return nil
+	})
+}
+
+func (fs *FileStorage) loadAuditFromDisk() error {
+	return fs.loadFile(fs.auditFile, "audit", func(data json.RawMessage) error {
+		var audit []*types.AuditEntry
+		if err := json.Unmarshal(data, &audit); err != nil {
+			return err
+		}
+		fs.audit = audit
+		return nil
+	})
+}
+
+func (fs *FileStorage) loadDeliveryFromDisk() error {
+	return fs.loadFile(fs.deliveryFile, "delivery", func(data json.RawMessage) error {
+		delivery := make(map[string]map[string]*types.DeliveryStats)
+		if err := json.Unmarshal(data, &delivery); err != nil {
+			return err
+		}
+		fs.delivery = delivery
+		return nil
+	})
+}
+
+func (fs *FileStorage) loadSettingsFromDisk() error {
+	return fs.loadFile(fs.settingsFile, "settings", func(data json.RawMessage) error {
+		var settings types.Settings
+		if err := json.Unmarshal(data, &settings); err != nil {
+			return err
+		}
+		fs.settings = settings
+		return nil
+	})
+}
+
+func (fs *FileStorage) loadWatchesFromDisk() error {
+	return fs.loadFile(fs.watchesFile, "watches", func(data json.RawMessage) error {
+		watches := make(map[string]*types.MarketWatch)
+		if err := json.Unmarshal(data, &watches); err != nil {
+			return err
+		}
+		fs.watches = watches
+		return nil
+	})
+}
+
+func (fs *FileStorage) loadRulesFromDisk() error {
+	return fs.loadFile(fs.rulesFile, "enroll rules", func(data json.RawMessage) error {
+		rules := make(map[string]*types.EnrollRule)
+		if err := json.Unmarshal(data, &rules); err != nil {
+			return err
+		}
+		fs.rules = rules
+		return nil
+	})
+}
+
+func (fs *FileStorage) loadTokensFromDisk() error {
+	return fs.loadFile(fs.tokensFile, "API tokens", func(data json.RawMessage) error {
+		tokens := make(map[string]*types.APIToken)
+		if err := json.Unmarshal(data, &tokens); err != nil {
+			return err
+		}
+		fs.tokens = tokens
+		return nil
+	})
+}
+
+func (fs *FileStorage) saveVaultsToDisk() error {
+	return fs.writeFile(fs.vaultsFile, "vaults", fs.vaults, true, 0644)
+}
+
+func (fs *FileStorage) saveRatesToDisk() error {
+	if err := fs.writeFile(fs.ratesFile, "rates", fs.lastRates, true, 0644); err != nil {
+		return err
+	}
+	fs.ratesDirty = false
+	return nil
+}
+
+func (fs *FileStorage) saveHistoryToDisk() error {
+	if err := fs.writeFile(fs.historyFile, "history", fs.history, false, 0644); err != nil {
+		return err
+	}
+	fs.historyDirty = false
+	return nil
+}
+
+func (fs *FileStorage) saveAlertsToDisk() error {
+	return fs.writeFile(fs.alertsFile, "alerts", fs.alerts, true, 0644)
+}
+
+func (fs *FileStorage) saveAuditToDisk() error {
+	return fs.writeFile(fs.auditFile, "audit", fs.audit, true, 0644)
+}
+
+func (fs *FileStorage) saveDeliveryToDisk() error {
+	return fs.writeFile(fs.deliveryFile, "delivery", fs.delivery, true, 0644)
+}
+
+func (fs *FileStorage) saveSettingsToDisk() error {
+	return fs.writeFile(fs.settingsFile, "settings", fs.settings, true, 0644)
+}
+
+func (fs *FileStorage) saveWatchesToDisk() error {
+	return fs.writeFile(fs.watchesFile, "watches", fs.watches, true, 0644)
+}
+
+func (fs *FileStorage) saveRulesToDisk() error {
+	return fs.writeFile(fs.rulesFile, "enroll rules", fs.rules, true, 0644)
+}
+
+func (fs *FileStorage) saveTokensToDisk() error {
+	// Only hashes are stored, but keep the file private anyway
+	return fs.writeFile(fs.tokensFile, "API tokens", fs.tokens, true, 0600)
+}
+
+// writeFile writes one data file, unless this is a mirror
+func (fs *FileStorage) writeFile(path, name string, v interface{}, indent bool, perm os.FileMode) error {
+	if fs.readOnly {
+		return ErrReadOnly
+	}
+	return writeVersioned(path, name, v, indent, perm)
+}
@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/morrisonbrett/SummerRateChecker/internal/types"
+)
+
+// History retention tiers: samples newer than rawRetention are kept as recorded,
+// samples newer than hourlyRetention are averaged into hourly buckets, and
+// anything older is averaged into daily buckets and kept forever.
+const (
+	rawRetention       = 7 * 24 * time.Hour
+	hourlyRetention    = 90 * 24 * time.Hour
+	compactionInterval = time.Hour
+)
+
+// downsampleHistory collapses samples into the retention tiers relative to now.
+// Samples must be in chronological order; the result is too. Already-downsampled
+// buckets pass through unchanged, so running it repeatedly is safe.
+func downsampleHistory(samples []types.RateSample, now time.Time) []types.RateSample {
+	if len(samples) == 0 {
+		return samples
+	}
+
+	rawCutoff := now.Add(-rawRetention)
+	hourlyCutoff := now.Add(-hourlyRetention)
+
+	result := make([]types.RateSample, 0, len(samples))
+	var bucket []types.RateSample
+	var bucketStart time.Time
+
+	flush := func() {
+		if len(bucket) > 0 {
+			result = append(result, averageSamples(bucketStart, bucket))
+			bucket = bucket[:0]
+		}
+	}
+
+	for _, sample := range samples {
+		var start time.Time
+		switch {
+		case !sample.Timestamp.Before(rawCutoff):
+			flush()
+			result = append(result, sample)
+			continue
+		case !sample.Timestamp.Before(hourlyCutoff):
+			start = sample.Timestamp.UTC().Truncate(time.Hour)
+		default:
+			start = sample.Timestamp.UTC().Truncate(24 * time.Hour)
+		}
+
+		if !start.Equal(bucketStart) {
+			flush()
+			bucketStart = start
+		}
+		bucket = append(bucket, sample)
+	}
+	flush()
+
+	return result
+}
+
+// averageSamples merges a bucket into a single sample stamped with the bucket start
+func averageSamples(start time.Time, bucket []types.RateSample) types.RateSample {
+	if len(bucket) == 1 && bucket[0].Timestamp.Equal(start) {
+		return bucket[0]
+	}
+
+	var borrow, supply, supplyUSD, borrowUSD float64
+	for _, sample := range bucket {
+		borrow += sample.BorrowRate
+		supply += sample.SupplyRate
+		supplyUSD += sample.SupplyUSD
+		borrowUSD += sample.BorrowUSD
+	}
+	n := float64(len(bucket))
+
+	return types.RateSample{
+		Timestamp:  start,
+		BorrowRate: borrow / n,
+		SupplyRate: supply / n,
+		SupplyUSD:  supplyUSD / n,
+		BorrowUSD:  borrowUSD / n,
+	}
+}
+
+// compactHistory downsamples every vault's history in place
+func compactHistory(history map[string][]types.RateSample, now time.Time) {
+	for vaultID, samples := range history {
+		history[vaultID] = downsampleHistory(samples, now)
+	}
+}
@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"fmt"
+
+	"github.com/morrisonbrett/SummerRateChecker/internal/config"
+	"github.com/morrisonbrett/SummerRateChecker/internal/types"
+)
+
+// QuotaError is returned when enrolling a vault would go past a [limits] quota. Its message is
+// meant for whoever tried to enroll it.
+type QuotaError struct {
+	Reason string
+}
+
+func (e *QuotaError) Error() string {
+	return e.Reason
+}
+
+// Quota caps how many vaults may be enrolled in one server, and by one user in a server. Zero
+// means unlimited.
+type Quota struct {
+	MaxPerGuild    int
+	MaxPerUser     int
+	PrimaryGuildID string // The server vaults enrolled before servers were recorded belong to
+}
+
+// NewQuota returns the quota set under [limits]
+func NewQuota(cfg *config.Config) Quota {
+	return Quota{
+		MaxPerGuild:    cfg.Limits.MaxVaultsPerGuild,
+		MaxPerUser:     cfg.Limits.MaxVaultsPerUser,
+		PrimaryGuildID: cfg.Discord.GuildID,
+	}
+}
+
+// Check returns a *QuotaError if enrolling vault would take its server, or the
+// user who enrolled it, past the quota
+func (q Quota) Check(store Storage, vault *types.VaultConfig) error {
+	if q.MaxPerGuild <= 0 && q.MaxPerUser <= 0 {
+		return nil
+	}
+
+	vaults, err := store.GetAllVaults()
+	if err != nil {
+		return fmt.Errorf("error checking vaults: %w", err)
+	}
+
+	guildID := vault.Guild(q.PrimaryGuildID)
+	inGuild, byUser := 0, 0
+	for _, enrolled := range vaults {
+		if enrolled.VaultID == vault.VaultID || enrolled.Guild(q.PrimaryGuildID) != guildID {
+			continue
+		}
+		inGuild++
+		if vault.EnrolledBy != "" && enrolled.EnrolledBy == vault.EnrolledBy {
+			byUser++
+		}
+	}
+
+	if q.MaxPerGuild > 0 && inGuild >= q.MaxPerGuild {
+		return &QuotaError{Reason: fmt.Sprintf("this server has reached its limit of %d enrolled vaults; unenroll one first", q.MaxPerGuild)}
+	}
+	if q.MaxPerUser > 0 && byUser >= q.MaxPerUser {
+		return &QuotaError{Reason: fmt.Sprintf("the enrolling user has reached the limit of %d enrolled vaults in this server; unenroll one first", q.MaxPerUser)}
+	}
+	return nil
+}
+
+// EnrollVault adds a new vault if the quota allows it. /enroll, auto-enroll rules and POST /vaults
+// all enroll through it.
+func EnrollVault(store Storage, vault *types.VaultConfig, quota Quota) error {
+	if err := quota.Check(store, vault); err != nil {
+		return err
+	}
+	return store.AddVault(vault)
+}
+
+// RestoreVaultWithin re-enrolls a trashed vault, like RestoreVault, if the quota allows it
+func RestoreVaultWithin(store Storage, vaultID string, quota Quota) (*types.VaultConfig, error) {
+	for _, trashed := range store.GetTrashedVaults() {
+		if trashed.VaultID == vaultID {
+			if err := quota.Check(store, trashed); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+	return store.RestoreVault(vaultID)
+}
@@ -0,0 +1,120 @@
+package storage
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/morrisonbrett/SummerRateChecker/internal/backup"
+)
+
+// RecoveryEvent records a data file that couldn't be parsed at startup
+type RecoveryEvent struct {
+	File          string
+	Error         string
+	QuarantinedTo string // Where the corrupt file was moved
+	RestoredFrom  string // Backup the file was restored from, empty if it started empty
+}
+
+func (e RecoveryEvent) String() string {
+	outcome := "no backup had a readable copy, so it starts empty"
+	if e.RestoredFrom != "" {
+		outcome = "restored from " + filepath.Base(e.RestoredFrom)
+	}
+	return fmt.Sprintf("%s was corrupt (%s); moved to %s and %s", filepath.Base(e.File), e.Error, filepath.Base(e.QuarantinedTo), outcome)
+}
+
+// Recoveries returns the files that were corrupt at startup and how each was recovered
+func (fs *FileStorage) Recoveries() []RecoveryEvent {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	return append([]RecoveryEvent(nil), fs.recoveries...)
+}
+
+// loadFile reads a data file and hands its migrated contents to decode. A missing file leaves
+// the defaults in place. A file that fails to parse is quarantined and replaced with the newest
+// backup copy that does parse, so one bad write doesn't stop the bot from starting.
+func (fs *FileStorage) loadFile(path, name string, decode func(data json.RawMessage) error) error {
+	data, ok, err := readVersioned(path, name)
+	if err != nil {
+		return err
+	}
+	if !ok || string(data) == "null" {
+		// File doesn't exist yet, start with the defaults
+		return nil
+	}
+
+	parseErr := decode(data)
+	if parseErr == nil {
+		return nil
+	}
+	if fs.readOnly {
+		// A mirror reads files the other instance may be halfway through writing, and mustn't
+		// move them aside
+		return fmt.Errorf("failed to parse %s file: %w", name, parseErr)
+	}
+
+	event := RecoveryEvent{
+		File:          path,
+		Error:         parseErr.Error(),
+		QuarantinedTo: fmt.Sprintf("%s.corrupt-%s", path, time.Now().UTC().Format("20060102T150405Z")),
+	}
+	if err := os.Rename(path, event.QuarantinedTo); err != nil {
+		return fmt.Errorf("failed to parse %s file (%v) and failed to quarantine it: %w", name, parseErr, err)
+	}
+
+	snapshots, _ := backup.ListLocal(fs.backupDir)
+	for i := len(snapshots) - 1; i >= 0; i-- {
+		raw, err := readFromSnapshot(snapshots[i], filepath.Base(path))
+		if err != nil || raw == nil {
+			continue
+		}
+		data, err := parseVersioned(raw, name)
+		if err != nil || decode(data) != nil {
+			continue
+		}
+		if err := os.WriteFile(path, raw, 0600); err != nil {
+			return fmt.Errorf("failed to write restored %s file: %w", name, err)
+		}
+		event.RestoredFrom = snapshots[i]
+		break
+	}
+
+	fs.recoveries = append(fs.recoveries, event)
+	return nil
+}
+
+// readFromSnapshot returns the contents of fileName inside a backup snapshot, or nil if it isn't there
+func readFromSnapshot(snapshotPath, fileName string) ([]byte, error) {
+	f, err := os.Open(snapshotPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Name == fileName {
+			return io.ReadAll(tr)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/morrisonbrett/SummerRateChecker/internal/metrics"
+)
+
+// currentSchemaVersion is written to every data file. Bump it and add a migration to
+// schemaMigrations whenever a stored type changes incompatibly (a renamed or retyped field).
+const currentSchemaVersion = 1
+
+// migration upgrades one file's data from the previous schema version
+type migration func(data json.RawMessage) (json.RawMessage, error)
+
+// schemaMigrations holds, per data file name, the migration that upgrades data *from* each
+// version. Versions without an entry need no change for that file. Files written before
+// versioning existed are version 0 and only need wrapping, so there are no migrations yet.
+//
+// For example, renaming VaultConfig.Nickname to Label would bump currentSchemaVersion to 2 and
+// add an entry under "vaults" for version 1 that rewrites each vault's "nickname" key to "label".
+var schemaMigrations = map[string]map[int]migration{}
+
+// versionedFile is the on-disk envelope around each file's data
+type versionedFile struct {
+	SchemaVersion *int            `json:"schema_version"`
+	Data          json.RawMessage `json:"data"`
+}
+
+// readVersioned reads path and returns its data migrated to the current schema.
+// ok is false if the file doesn't exist or is empty.
+func readVersioned(path, name string) (data json.RawMessage, ok bool, err error) {
+	raw, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		metrics.StorageErrors.WithLabelValues(name, "read").Inc()
+		return nil, false, fmt.Errorf("failed to read %s file: %w", name, err)
+	}
+	metrics.StorageReads.WithLabelValues(name).Inc()
+	metrics.StorageFileBytes.WithLabelValues(name).Set(float64(len(raw)))
+	if len(raw) == 0 {
+		return nil, false, nil
+	}
+
+	data, err = parseVersioned(raw, name)
+	return data, err == nil, err
+}
+
+// parseVersioned unwraps a data file's contents and migrates them to the current schema
+func parseVersioned(raw []byte, name string) (data json.RawMessage, err error) {
+	// Files from before versioning hold the data directly
+	version := 0
+	data = raw
+	var envelope versionedFile
+	if err := json.Unmarshal(raw, &envelope); err == nil && envelope.SchemaVersion != nil {
+		version = *envelope.SchemaVersion
+		data = envelope.Data
+	}
+
+	if version > currentSchemaVersion {
+		return nil, fmt.Errorf("%s file has schema version %d but this build only understands up to %d; upgrade the bot", name, version, currentSchemaVersion)
+	}
+
+	for ; version < currentSchemaVersion; version++ {
+		migrate, ok := schemaMigrations[name][version]
+		if !ok {
+			continue
+		}
+		if data, err = migrate(data); err != nil {
+			return nil, fmt.Errorf("failed to migrate %s file from schema version %d: %w", name, version, err)
+		}
+	}
+
+	return data, nil
+}
+
+// writeVersioned writes v to path wrapped in the current schema version
+func writeVersioned(path, name string, v interface{}, indent bool, perm os.FileMode) error {
+	start := time.Now()
+	size, err := encodeAndWrite(path, name, v, indent, perm)
+	if err != nil {
+		metrics.StorageErrors.WithLabelValues(name, "write").Inc()
+		return err
+	}
+
+	metrics.StorageWrites.WithLabelValues(name).Inc()
+	metrics.StorageWriteDuration.WithLabelValues(name).Observe(time.Since(start).Seconds())
+	metrics.StorageFileBytes.WithLabelValues(name).Set(float64(size))
+	return nil
+}
+
+func encodeAndWrite(path, name string, v interface{}, indent bool, perm os.FileMode) (int, error) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal %s: %w", name, err)
+	}
+
+	version := currentSchemaVersion
+	envelope := versionedFile{SchemaVersion: &version, Data: payload}
+
+	var data []byte
+	if indent {
+		data, err = json.MarshalIndent(envelope, "", "  ")
+	} else {
+		data, err = json.Marshal(envelope)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal %s: %w", name, err)
+	}
+
+	if err := writeFileAtomic(path, data, perm); err != nil {
+		return 0, fmt.Errorf("failed to write %s file: %w", name, err)
+	}
+	return len(data), nil
+}
+
+// writeFileAtomic writes data to a temporary file beside path and renames it into place, so a
+// crash mid-write leaves the previous file intact rather than a truncated one
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	// Removing fails harmlessly once the rename has succeeded
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
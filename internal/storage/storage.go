@@ -1,98 +1,763 @@
-package storage
-
-import (
-	"sync"
-	"time"
-
-	"github.com/morrisonbrett/SummerRateChecker/internal/types"
-)
-
-type Storage interface {
-	AddVault(vault *types.VaultConfig) error
-	RemoveVault(vaultID string) error
-	GetVault(vaultID string) (*types.VaultConfig, error)
-	GetAllVaults() ([]*types.VaultConfig, error)
-	UpdateLastRate(vaultID string, rate float64) error
-	GetLastRate(vaultID string) (float64, bool)
-	GetAllLastRates() map[string]float64
-}
-
-type InMemoryStorage struct {
-	mu        sync.RWMutex
-	vaults    map[string]*types.VaultConfig
-	lastRates map[string]float64
-}
-
-func NewInMemoryStorage() *InMemoryStorage {
-	return &InMemoryStorage{
-		vaults:    make(map[string]*types.VaultConfig),
-		lastRates: make(map[string]float64),
-	}
-}
-
-func (s *InMemoryStorage) AddVault(vault *types.VaultConfig) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	vault.CreatedAt = time.Now()
-	s.vaults[vault.VaultID] = vault
-	return nil
-}
-
-func (s *InMemoryStorage) RemoveVault(vaultID string) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	delete(s.vaults, vaultID)
-	delete(s.lastRates, vaultID)
-	return nil
-}
-
-func (s *InMemoryStorage) GetVault(vaultID string) (*types.VaultConfig, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
-	vault, exists := s.vaults[vaultID]
-	if !exists {
-		return nil, nil
-	}
-	return vault, nil
-}
-
-func (s *InMemoryStorage) GetAllVaults() ([]*types.VaultConfig, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
-	vaults := make([]*types.VaultConfig, 0, len(s.vaults))
-	for _, vault := range s.vaults {
-		vaults = append(vaults, vault)
-	}
-	return vaults, nil
-}
-
-func (s *InMemoryStorage) UpdateLastRate(vaultID string, rate float64) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	s.lastRates[vaultID] = rate
-	return nil
-}
-
-func (s *InMemoryStorage) GetLastRate(vaultID string) (float64, bool) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
-	rate, exists := s.lastRates[vaultID]
-	return rate, exists
-}
-
-func (s *InMemoryStorage) GetAllLastRates() map[string]float64 {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
-	rates := make(map[string]float64)
-	for k, v := range s.lastRates {
-		rates[k] = v
-	}
-	return rates
-}
+package storage
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/morrisonbrett/SummerRateChecker/internal/types"
+)
+
+type Storage interface {
+	// AddVault enrolls a vault, or replaces an enrolled one wholesale
+	AddVault(vault *types.VaultConfig) error
+	RemoveVault(vaultID string) error
+	// GetVault and GetAllVaults return copies; changing them doesn't change the stored vault
+	GetVault(vaultID string) (*types.VaultConfig, error)
+	GetAllVaults() ([]*types.VaultConfig, error)
+	// TrashVault unenrolls a vault but keeps it, with its rates and history, until PurgeTrash.
+	// Trashed vaults are hidden from GetVault, GetAllVaults, and UpdateVault.
+	TrashVault(vaultID string) error
+	// RestoreVault re-enrolls a trashed vault
+	RestoreVault(vaultID string) (*types.VaultConfig, error)
+	// GetTrashedVaults returns copies of the trashed vaults, most recently unenrolled first
+	GetTrashedVaults() []*types.VaultConfig
+	// PurgeTrash permanently removes vaults trashed before cutoff and returns them
+	PurgeTrash(cutoff time.Time) ([]*types.VaultConfig, error)
+	// UpdateVault applies update to the current stored vault under the storage lock and persists
+	// the result, so concurrent changes to different fields all land. If update returns an error
+	// the vault is left unchanged and the error is returned. update must not call back into storage.
+	UpdateVault(vaultID string, update func(vault *types.VaultConfig) error) error
+	// UpdateVaults is UpdateVault for several vaults, persisted together. Vaults that are no longer
+	// enrolled are skipped; if an update fails, the rest are still applied and its error returned.
+	UpdateVaults(updates map[string]func(vault *types.VaultConfig) error) error
+	UpdateLastRate(vaultID string, rate float64) error
+	GetLastRate(vaultID string) (float64, bool)
+	GetAllLastRates() map[string]float64
+	AppendRateHistory(vaultID string, sample types.RateSample) error
+	GetRateHistory(vaultID string, since time.Time) []types.RateSample
+	GetHistoryVaultIDs() []string
+	RecordAlert(alert *types.RateChangeAlert) error
+	GetRecentAlerts(limit int) []*types.RateChangeAlert
+	GetAlert(alertID string) *types.RateChangeAlert
+	AcknowledgeAlert(alertID, userID string) (*types.RateChangeAlert, error)
+	RecordAudit(entry *types.AuditEntry) error
+	// GetAuditLog returns up to limit audit entries, newest first. A limit of 0 returns all of them.
+	GetAuditLog(limit int) []*types.AuditEntry
+	// MarkAuditUndone records that /undo reverted the entry
+	MarkAuditUndone(entryID string) error
+	RecordDelivery(vaultID string, result types.DeliveryResult) error
+	GetDeliveryStats(vaultID string) []types.DeliveryStats
+	GetSettings() types.Settings
+	// UpdateSettings applies update to a copy of the current settings and saves the result under
+	// the lock, so concurrent changes to different settings all land. update must not call back
+	// into storage.
+	UpdateSettings(update func(settings *types.Settings)) error
+	// AddMarketWatch stores a copy of the watch, replacing any with the same ID
+	AddMarketWatch(watch *types.MarketWatch) error
+	RemoveMarketWatch(watchID string) error
+	// UpdateMarketWatch applies update to a copy of the stored watch and saves it. A watch removed
+	// meanwhile isn't brought back. update must not call back into storage.
+	UpdateMarketWatch(watchID string, update func(watch *types.MarketWatch)) error
+	// GetMarketWatches returns copies; save changes with UpdateMarketWatch
+	GetMarketWatches() []*types.MarketWatch
+	AddEnrollRule(rule *types.EnrollRule) error
+	RemoveEnrollRule(ruleID string) error
+	// GetEnrollRules returns copies; changing them doesn't change the stored rules
+	GetEnrollRules() []*types.EnrollRule
+	AddAPIToken(token *types.APIToken) error
+	RemoveAPIToken(tokenID string) error
+	// GetAPITokens and FindAPIToken return copies; use TouchAPIToken to record a token's use
+	GetAPITokens() []*types.APIToken
+	FindAPIToken(secret string) *types.APIToken
+	// TouchAPIToken sets the token's last-used time to t
+	TouchAPIToken(tokenID string, t time.Time) error
+	// Flush persists rate and history updates, which implementations may buffer until called
+	Flush() error
+}
+
+// maxAlertLog caps how many past alerts are retained
+const maxAlertLog = 1000
+
+// maxAuditLog caps how many audit entries are retained
+const maxAuditLog = 1000
+
+type InMemoryStorage struct {
+	mu        sync.RWMutex
+	vaults    map[string]*types.VaultConfig
+	lastRates map[string]float64
+	history   map[string][]types.RateSample
+	alerts    []*types.RateChangeAlert
+	audit     []*types.AuditEntry
+	delivery  map[string]map[string]*types.DeliveryStats
+	settings  types.Settings
+	watches   map[string]*types.MarketWatch
+	rules     map[string]*types.EnrollRule
+	tokens    map[string]*types.APIToken
+
+	lastCompaction time.Time
+}
+
+func NewInMemoryStorage() *InMemoryStorage {
+	return &InMemoryStorage{
+		vaults:    make(map[string]*types.VaultConfig),
+		lastRates: make(map[string]float64),
+		history:   make(map[string][]types.RateSample),
+		delivery:  make(map[string]map[string]*types.DeliveryStats),
+		watches:   make(map[string]*types.MarketWatch),
+		rules:     make(map[string]*types.EnrollRule),
+		tokens:    make(map[string]*types.APIToken),
+	}
+}
+
+func (s *InMemoryStorage) AddVault(vault *types.VaultConfig) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// AddVault also updates existing vaults, which keep their original enrollment time
+	if existing, ok := s.vaults[vault.VaultID]; ok && !existing.CreatedAt.IsZero() && !existing.Trashed() {
+		vault.CreatedAt = existing.CreatedAt
+	} else {
+		vault.CreatedAt = time.Now()
+	}
+	s.vaults[vault.VaultID] = vault.Clone()
+	return nil
+}
+
+func (s *InMemoryStorage) RemoveVault(vaultID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.removeVault(vaultID)
+	return nil
+}
+
+// removeVault deletes a vault and everything keyed by its ID. Callers hold the lock.
+func (s *InMemoryStorage) removeVault(vaultID string) {
+	delete(s.vaults, vaultID)
+	delete(s.lastRates, vaultID)
+	delete(s.history, vaultID)
+	delete(s.delivery, vaultID)
+}
+
+func (s *InMemoryStorage) TrashVault(vaultID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return trashVault(s.vaults, vaultID)
+}
+
+func (s *InMemoryStorage) RestoreVault(vaultID string) (*types.VaultConfig, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return restoreVault(s.vaults, vaultID)
+}
+
+func (s *InMemoryStorage) GetTrashedVaults() []*types.VaultConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return trashedVaults(s.vaults)
+}
+
+func (s *InMemoryStorage) PurgeTrash(cutoff time.Time) ([]*types.VaultConfig, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	purged := expiredTrash(s.vaults, cutoff)
+	for _, vault := range purged {
+		s.removeVault(vault.VaultID)
+	}
+	return purged, nil
+}
+
+func (s *InMemoryStorage) GetVault(vaultID string) (*types.VaultConfig, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	vault, exists := s.vaults[vaultID]
+	if !exists || vault.Trashed() {
+		return nil, nil
+	}
+	return vault.Clone(), nil
+}
+
+func (s *InMemoryStorage) GetAllVaults() ([]*types.VaultConfig, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	vaults := make([]*types.VaultConfig, 0, len(s.vaults))
+	for _, vault := range s.vaults {
+		if !vault.Trashed() {
+			vaults = append(vaults, vault.Clone())
+		}
+	}
+	return vaults, nil
+}
+
+func (s *InMemoryStorage) UpdateVault(vaultID string, update func(vault *types.VaultConfig) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	vault, exists := s.vaults[vaultID]
+	if !exists || vault.Trashed() {
+		return fmt.Errorf("vault %s not found", vaultID)
+	}
+	updated := vault.Clone()
+	if err := update(updated); err != nil {
+		return err
+	}
+	s.vaults[vaultID] = updated
+	return nil
+}
+
+func (s *InMemoryStorage) UpdateVaults(updates map[string]func(vault *types.VaultConfig) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := applyVaultUpdates(s.vaults, updates)
+	return err
+}
+
+// applyVaultUpdates applies updates to the enrolled vaults in vaults and reports whether any changed
+func applyVaultUpdates(vaults map[string]*types.VaultConfig, updates map[string]func(vault *types.VaultConfig) error) (bool, error) {
+	var firstErr error
+	changed := false
+	for vaultID, update := range updates {
+		vault, exists := vaults[vaultID]
+		if !exists || vault.Trashed() {
+			continue
+		}
+		updated := vault.Clone()
+		if err := update(updated); err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("failed to update vault %s: %w", vaultID, err)
+			}
+			continue
+		}
+		vaults[vaultID] = updated
+		changed = true
+	}
+	return changed, firstErr
+}
+
+func (s *InMemoryStorage) UpdateLastRate(vaultID string, rate float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastRates[vaultID] = rate
+	return nil
+}
+
+func (s *InMemoryStorage) GetLastRate(vaultID string) (float64, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rate, exists := s.lastRates[vaultID]
+	return rate, exists
+}
+
+func (s *InMemoryStorage) GetAllLastRates() map[string]float64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rates := make(map[string]float64)
+	for k, v := range s.lastRates {
+		rates[k] = v
+	}
+	return rates
+}
+
+func (s *InMemoryStorage) GetHistoryVaultIDs() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return historyKeys(s.history)
+}
+
+func (s *InMemoryStorage) AppendRateHistory(vaultID string, sample types.RateSample) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.history[vaultID] = append(s.history[vaultID], sample)
+
+	// Downsample older samples periodically so history doesn't grow unbounded
+	if now := time.Now(); now.Sub(s.lastCompaction) >= compactionInterval {
+		compactHistory(s.history, now)
+		s.lastCompaction = now
+	}
+	return nil
+}
+
+func (s *InMemoryStorage) GetRateHistory(vaultID string, since time.Time) []types.RateSample {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return samplesSince(s.history[vaultID], since)
+}
+
+func (s *InMemoryStorage) RecordAlert(alert *types.RateChangeAlert) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.alerts = appendAlert(s.alerts, alert)
+	return nil
+}
+
+func (s *InMemoryStorage) GetRecentAlerts(limit int) []*types.RateChangeAlert {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return recentAlerts(s.alerts, limit)
+}
+
+func (s *InMemoryStorage) GetAlert(alertID string) *types.RateChangeAlert {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return findAlert(s.alerts, alertID)
+}
+
+func (s *InMemoryStorage) AcknowledgeAlert(alertID, userID string) (*types.RateChangeAlert, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return acknowledgeAlert(s.alerts, alertID, userID)
+}
+
+func (s *InMemoryStorage) RecordAudit(entry *types.AuditEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.audit = appendAudit(s.audit, entry)
+	return nil
+}
+
+func (s *InMemoryStorage) GetAuditLog(limit int) []*types.AuditEntry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return recentAudit(s.audit, limit)
+}
+
+func (s *InMemoryStorage) MarkAuditUndone(entryID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return markAuditUndone(s.audit, entryID)
+}
+
+func (s *InMemoryStorage) RecordDelivery(vaultID string, result types.DeliveryResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	applyDelivery(s.delivery, vaultID, result)
+	return nil
+}
+
+func (s *InMemoryStorage) GetDeliveryStats(vaultID string) []types.DeliveryStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return deliveryStats(s.delivery[vaultID])
+}
+
+func (s *InMemoryStorage) GetSettings() types.Settings {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.settings
+}
+
+func (s *InMemoryStorage) UpdateSettings(update func(settings *types.Settings)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	updated := s.settings.Clone()
+	update(&updated)
+	s.settings = updated
+	return nil
+}
+
+func (s *InMemoryStorage) AddMarketWatch(watch *types.MarketWatch) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.watches[watch.ID] = watch.Clone()
+	return nil
+}
+
+func (s *InMemoryStorage) RemoveMarketWatch(watchID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.watches, watchID)
+	return nil
+}
+
+func (s *InMemoryStorage) UpdateMarketWatch(watchID string, update func(watch *types.MarketWatch)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	watch, exists := s.watches[watchID]
+	if !exists {
+		return fmt.Errorf("market watch %s not found", watchID)
+	}
+	updated := watch.Clone()
+	update(updated)
+	s.watches[watchID] = updated
+	return nil
+}
+
+func (s *InMemoryStorage) GetMarketWatches() []*types.MarketWatch {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return sortedWatches(s.watches)
+}
+
+func (s *InMemoryStorage) AddEnrollRule(rule *types.EnrollRule) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rules[rule.ID] = rule.Clone()
+	return nil
+}
+
+func (s *InMemoryStorage) RemoveEnrollRule(ruleID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.rules, ruleID)
+	return nil
+}
+
+func (s *InMemoryStorage) GetEnrollRules() []*types.EnrollRule {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return sortedRules(s.rules)
+}
+
+func (s *InMemoryStorage) Flush() error {
+	return nil
+}
+
+func (s *InMemoryStorage) AddAPIToken(token *types.APIToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tokens[token.ID] = token.Clone()
+	return nil
+}
+
+func (s *InMemoryStorage) RemoveAPIToken(tokenID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.tokens, tokenID)
+	return nil
+}
+
+func (s *InMemoryStorage) GetAPITokens() []*types.APIToken {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return sortedTokens(s.tokens)
+}
+
+func (s *InMemoryStorage) FindAPIToken(secret string) *types.APIToken {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return findToken(s.tokens, secret)
+}
+
+func (s *InMemoryStorage) TouchAPIToken(tokenID string, t time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return touchToken(s.tokens, tokenID, t)
+}
+
+// trashVault marks an enrolled vault as unenrolled without deleting it
+func trashVault(vaults map[string]*types.VaultConfig, vaultID string) error {
+	vault, exists := vaults[vaultID]
+	if !exists || vault.Trashed() {
+		return fmt.Errorf("vault %s not found", vaultID)
+	}
+	trashed := vault.Clone()
+	trashed.DeletedAt = time.Now()
+	vaults[vaultID] = trashed
+	return nil
+}
+
+// restoreVault clears a trashed vault's deletion time and returns a copy of it
+func restoreVault(vaults map[string]*types.VaultConfig, vaultID string) (*types.VaultConfig, error) {
+	vault, exists := vaults[vaultID]
+	if !exists || !vault.Trashed() {
+		return nil, fmt.Errorf("vault %s is not in the trash", vaultID)
+	}
+	restored := vault.Clone()
+	restored.DeletedAt = time.Time{}
+	vaults[vaultID] = restored
+	return restored.Clone(), nil
+}
+
+// trashedVaults returns copies of the trashed vaults, most recently unenrolled first
+func trashedVaults(vaults map[string]*types.VaultConfig) []*types.VaultConfig {
+	var result []*types.VaultConfig
+	for _, vault := range vaults {
+		if vault.Trashed() {
+			result = append(result, vault.Clone())
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].DeletedAt.After(result[j].DeletedAt)
+	})
+	return result
+}
+
+// expiredTrash returns copies of the vaults trashed before cutoff
+func expiredTrash(vaults map[string]*types.VaultConfig, cutoff time.Time) []*types.VaultConfig {
+	var result []*types.VaultConfig
+	for _, vault := range vaults {
+		if vault.Trashed() && vault.DeletedAt.Before(cutoff) {
+			result = append(result, vault.Clone())
+		}
+	}
+	return result
+}
+
+// WebhookInUse reports whether any enrolled or trashed vault, or any auto-enroll rule, posts to webhookURL.
+// Auto-enrolled vaults share their rule's webhook and trashed vaults keep theirs for /restore.
+func WebhookInUse(store Storage, webhookURL string) bool {
+	if webhookURL == "" {
+		return false
+	}
+	vaults, err := store.GetAllVaults()
+	if err != nil {
+		// Err on the side of keeping the webhook
+		return true
+	}
+	for _, vault := range append(vaults, store.GetTrashedVaults()...) {
+		for _, url := range vault.Webhooks() {
+			if url == webhookURL {
+				return true
+			}
+		}
+	}
+	for _, rule := range store.GetEnrollRules() {
+		if rule.WebhookURL == webhookURL {
+			return true
+		}
+	}
+	return false
+}
+
+// applyDelivery folds a result into the vault's per-sink stats
+func applyDelivery(delivery map[string]map[string]*types.DeliveryStats, vaultID string, result types.DeliveryResult) {
+	sinks, exists := delivery[vaultID]
+	if !exists {
+		sinks = make(map[string]*types.DeliveryStats)
+		delivery[vaultID] = sinks
+	}
+	stats, exists := sinks[result.Sink]
+	if !exists {
+		stats = &types.DeliveryStats{}
+		sinks[result.Sink] = stats
+	}
+	stats.Apply(result)
+}
+
+// deliveryStats copies a vault's per-sink stats, sorted by sink name
+func deliveryStats(sinks map[string]*types.DeliveryStats) []types.DeliveryStats {
+	result := make([]types.DeliveryStats, 0, len(sinks))
+	for _, stats := range sinks {
+		result = append(result, *stats)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Sink < result[j].Sink })
+	return result
+}
+
+// appendAlert adds a copy of alert to the log, dropping the oldest entries beyond maxAlertLog.
+// The caller keeps its alert, so acknowledging the logged one later never writes to it.
+func appendAlert(alerts []*types.RateChangeAlert, alert *types.RateChangeAlert) []*types.RateChangeAlert {
+	copied := *alert
+	alerts = append(alerts, &copied)
+	if len(alerts) > maxAlertLog {
+		alerts = alerts[len(alerts)-maxAlertLog:]
+	}
+	return alerts
+}
+
+// sortedWatches returns copies of the watches ordered by creation time
+func sortedWatches(watches map[string]*types.MarketWatch) []*types.MarketWatch {
+	result := make([]*types.MarketWatch, 0, len(watches))
+	for _, watch := range watches {
+		result = append(result, watch.Clone())
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+	return result
+}
+
+// historyKeys returns the vault IDs that have rate history, sorted
+func historyKeys(history map[string][]types.RateSample) []string {
+	ids := make([]string, 0, len(history))
+	for vaultID := range history {
+		ids = append(ids, vaultID)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// sortedRules returns copies of the auto-enroll rules ordered by creation time
+func sortedRules(rules map[string]*types.EnrollRule) []*types.EnrollRule {
+	result := make([]*types.EnrollRule, 0, len(rules))
+	for _, rule := range rules {
+		result = append(result, rule.Clone())
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+	return result
+}
+
+// sortedTokens returns the API tokens ordered by creation time
+func sortedTokens(tokens map[string]*types.APIToken) []*types.APIToken {
+	result := make([]*types.APIToken, 0, len(tokens))
+	for _, token := range tokens {
+		result = append(result, token.Clone())
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+	return result
+}
+
+// findToken returns the token whose secret hashes to the stored hash, or nil
+func findToken(tokens map[string]*types.APIToken, secret string) *types.APIToken {
+	hash := types.HashTokenSecret(secret)
+	for _, token := range tokens {
+		if subtle.ConstantTimeCompare([]byte(token.SecretHash), []byte(hash)) == 1 {
+			return token.Clone()
+		}
+	}
+	return nil
+}
+
+// touchToken sets the last-used time of the stored token, replacing it so copies handed out
+// earlier aren't changed
+func touchToken(tokens map[string]*types.APIToken, tokenID string, t time.Time) error {
+	token, exists := tokens[tokenID]
+	if !exists {
+		return fmt.Errorf("API token %s not found", tokenID)
+	}
+	touched := token.Clone()
+	touched.LastUsedAt = t
+	tokens[tokenID] = touched
+	return nil
+}
+
+// appendAudit adds entry to the log, dropping the oldest entries beyond maxAuditLog
+func appendAudit(audit []*types.AuditEntry, entry *types.AuditEntry) []*types.AuditEntry {
+	copied := *entry
+	audit = append(audit, &copied)
+	if len(audit) > maxAuditLog {
+		audit = audit[len(audit)-maxAuditLog:]
+	}
+	return audit
+}
+
+// recentAudit returns copies of up to limit audit entries, newest first. A limit of 0 returns all of them.
+func recentAudit(audit []*types.AuditEntry, limit int) []*types.AuditEntry {
+	if limit <= 0 || limit > len(audit) {
+		limit = len(audit)
+	}
+
+	result := make([]*types.AuditEntry, 0, limit)
+	for i := len(audit) - 1; i >= 0 && len(result) < limit; i-- {
+		copied := *audit[i]
+		result = append(result, &copied)
+	}
+	return result
+}
+
+// markAuditUndone stamps the audit entry with the time it was undone
+func markAuditUndone(audit []*types.AuditEntry, entryID string) error {
+	for _, entry := range audit {
+		if entry.ID == entryID {
+			entry.UndoneAt = time.Now()
+			return nil
+		}
+	}
+	return fmt.Errorf("audit entry %s not found", entryID)
+}
+
+// loggedAlert returns the logged alert with the given ID itself, or nil. Only callers holding the
+// storage lock may use it; everything handed out of storage is a copy.
+func loggedAlert(alerts []*types.RateChangeAlert, alertID string) *types.RateChangeAlert {
+	for i := len(alerts) - 1; i >= 0; i-- {
+		if alerts[i].ID == alertID {
+			return alerts[i]
+		}
+	}
+	return nil
+}
+
+// findAlert returns a copy of the logged alert with the given ID, or nil
+func findAlert(alerts []*types.RateChangeAlert, alertID string) *types.RateChangeAlert {
+	alert := loggedAlert(alerts, alertID)
+	if alert == nil {
+		return nil
+	}
+	copied := *alert
+	return &copied
+}
+
+// acknowledgeAlert marks the logged alert as acknowledged by userID and returns a copy of it.
+// Acknowledging twice keeps the first record.
+func acknowledgeAlert(alerts []*types.RateChangeAlert, alertID, userID string) (*types.RateChangeAlert, error) {
+	alert := loggedAlert(alerts, alertID)
+	if alert == nil {
+		return nil, fmt.Errorf("alert %s not found", alertID)
+	}
+	if !alert.Acknowledged() {
+		alert.AckedBy = userID
+		alert.AckedAt = time.Now()
+	}
+	copied := *alert
+	return &copied, nil
+}
+
+// recentAlerts returns copies of up to limit alerts, newest first. A limit of 0 returns all of them.
+func recentAlerts(alerts []*types.RateChangeAlert, limit int) []*types.RateChangeAlert {
+	if limit <= 0 || limit > len(alerts) {
+		limit = len(alerts)
+	}
+
+	result := make([]*types.RateChangeAlert, 0, limit)
+	for i := len(alerts) - 1; i >= 0 && len(result) < limit; i-- {
+		copied := *alerts[i]
+		result = append(result, &copied)
+	}
+	return result
+}
+
+// samplesSince returns a copy of the samples recorded at or after since.
+// History is kept in chronological order, so everything after the first match is included.
+func samplesSince(samples []types.RateSample, since time.Time) []types.RateSample {
+	for i, sample := range samples {
+		if !sample.Timestamp.Before(since) {
+			result := make([]types.RateSample, len(samples)-i)
+			copy(result, samples[i:])
+			return result
+		}
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package summerfi
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/machinebox/graphql"
+	"github.com/morrisonbrett/SummerRateChecker/internal/types"
+	"go.uber.org/zap"
+)
+
+// Client fetches position metadata from Summer.fi's GraphQL API, for details the Morpho market
+// doesn't expose: the borrower's own debt and collateral and the automation they configured
+type Client struct {
+	client *graphql.Client
+	logger *zap.SugaredLogger
+}
+
+// PositionResponse is a position as returned by the API
+type PositionResponse struct {
+	Position *struct {
+		Owner           string `json:"owner"`
+		CollateralToken struct {
+			Symbol string `json:"symbol"`
+		} `json:"collateralToken"`
+		DebtToken struct {
+			Symbol string `json:"symbol"`
+		} `json:"debtToken"`
+		Collateral       float64 `json:"collateral"`
+		CollateralUsd    float64 `json:"collateralUsd"`
+		Debt             float64 `json:"debt"`
+		DebtUsd          float64 `json:"debtUsd"`
+		LiquidationPrice float64 `json:"liquidationPrice"`
+		Triggers         []struct {
+			ID           string  `json:"id"`
+			Kind         string  `json:"kind"`
+			TriggerPrice float64 `json:"triggerPrice"`
+		} `json:"triggers"`
+	} `json:"position"`
+}
+
+func NewClient(apiURL string, logger *zap.SugaredLogger) *Client {
+	return &Client{
+		client: graphql.NewClient(apiURL),
+		logger: logger,
+	}
+}
+
+// GetPosition fetches the Summer.fi position stored under vaultID, a key from types.VaultKey
+func (c *Client) GetPosition(ctx context.Context, vaultID string) (*types.Position, error) {
+	network, protocol, positionID := types.ParseVaultKey(vaultID)
+	chainID, ok := types.ChainID(network)
+	if !ok {
+		return nil, fmt.Errorf("unknown network %q for vault %s", network, vaultID)
+	}
+
+	req := graphql.NewRequest(`
+		query GetPosition($vaultId: String!, $protocol: String!, $chainId: Int!) {
+			position(vaultId: $vaultId, protocol: $protocol, chainId: $chainId) {
+				owner
+				collateralToken {
+					symbol
+				}
+				debtToken {
+					symbol
+				}
+				collateral
+				collateralUsd
+				debt
+				debtUsd
+				liquidationPrice
+				triggers(where: { active: true }) {
+					id
+					kind
+					triggerPrice
+				}
+			}
+		}
+	`)
+	req.Var("vaultId", positionID)
+	req.Var("protocol", protocol)
+	req.Var("chainId", chainID)
+
+	var resp PositionResponse
+	if err := c.client.Run(ctx, req, &resp); err != nil {
+		return nil, fmt.Errorf("Summer.fi API error for vault %s: %w", vaultID, err)
+	}
+	if resp.Position == nil {
+		return nil, fmt.Errorf("no Summer.fi position found for vault %s", vaultID)
+	}
+
+	p := resp.Position
+	position := &types.Position{
+		Owner:            p.Owner,
+		CollateralSymbol: p.CollateralToken.Symbol,
+		Collateral:       p.Collateral,
+		CollateralUSD:    p.CollateralUsd,
+		DebtSymbol:       p.DebtToken.Symbol,
+		Debt:             p.Debt,
+		DebtUSD:          p.DebtUsd,
+		LiquidationPrice: p.LiquidationPrice,
+		FetchedAt:        time.Now(),
+	}
+	for _, trigger := range p.Triggers {
+		position.Automations = append(position.Automations, types.Automation{
+			ID:           trigger.ID,
+			Kind:         trigger.Kind,
+			TriggerPrice: trigger.TriggerPrice,
+		})
+	}
+
+	c.logger.Infof("Fetched Summer.fi position for vault %s: %s", vaultID, position.Describe())
+	return position, nil
+}
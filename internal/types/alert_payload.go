@@ -0,0 +1,190 @@
+package types
+
+import "time"
+
+// AlertSchema identifies the version of AlertPayload. Fields may be added within a version, but
+// renaming, removing or changing the meaning of one needs a new version, so automation can rely on
+// what it parses.
+const AlertSchema = "summer-rate-checker/alert/v1"
+
+// Alert payload events
+const (
+	AlertEventRaised   = "alert"    // The rate crossed the vault's threshold or critical level
+	AlertEventResolved = "resolved" // A critical rate dropped back below the critical level
+)
+
+// AlertPayload is the machine-readable form of an alert, described by AlertJSONSchema. Rates are
+// in percent and changes in percentage points; optional sections are omitted when unknown.
+type AlertPayload struct {
+	Schema    string               `json:"schema"`
+	Event     string               `json:"event"`
+	ID        string               `json:"id"`
+	Timestamp time.Time            `json:"timestamp"`
+	Severity  Severity             `json:"severity"`
+	Vault     AlertPayloadVault    `json:"vault"`
+	Rate      AlertPayloadRate     `json:"rate"`
+	Threshold *AlertThreshold      `json:"threshold,omitempty"` // Set on warnings and escalations
+	Context   *AlertPayloadContext `json:"context,omitempty"`
+}
+
+// AlertPayloadVault identifies the vault an alert is for
+type AlertPayloadVault struct {
+	ID         string `json:"id"`
+	Nickname   string `json:"nickname"`
+	MarketPair string `json:"market_pair,omitempty"`
+}
+
+// AlertPayloadRate is the rate move that raised an alert
+type AlertPayloadRate struct {
+	Previous      float64 `json:"previous"`
+	Current       float64 `json:"current"`
+	ChangePoints  float64 `json:"change_points"`
+	CriticalLevel float64 `json:"critical_level,omitempty"` // Set on critical alerts
+}
+
+// AlertPayloadContext is what the bot knew about the market when it alerted
+type AlertPayloadContext struct {
+	Percentile           float64        `json:"percentile,omitempty"`
+	PercentileDays       int            `json:"percentile_days,omitempty"`
+	High24h              *float64       `json:"high_24h,omitempty"`
+	Low24h               *float64       `json:"low_24h,omitempty"`
+	Change24h            *float64       `json:"change_24h,omitempty"`
+	Utilization          float64        `json:"utilization,omitempty"`
+	ProjectedUtilization float64        `json:"projected_utilization,omitempty"`
+	ProjectedRate        float64        `json:"projected_rate,omitempty"`
+	Band                 *RateBand      `json:"band,omitempty"`
+	Automations          []string       `json:"automations,omitempty"`
+	Alternatives         []ProtocolRate `json:"alternatives,omitempty"`
+	SustainedChecks      int            `json:"sustained_checks,omitempty"`
+}
+
+// Payload converts the alert to its machine-readable form for event
+func (a *RateChangeAlert) Payload(event string) *AlertPayload {
+	severity := a.Severity
+	if severity == "" {
+		severity = SeverityWarning
+	}
+	payload := &AlertPayload{
+		Schema:    AlertSchema,
+		Event:     event,
+		ID:        a.ID,
+		Timestamp: a.Timestamp.UTC(),
+		Severity:  severity,
+		Vault: AlertPayloadVault{
+			ID:         a.VaultID,
+			Nickname:   a.Nickname,
+			MarketPair: a.MarketPair,
+		},
+		Rate: AlertPayloadRate{
+			Previous:      a.PreviousRate,
+			Current:       a.CurrentRate,
+			ChangePoints:  a.ChangePercent,
+			CriticalLevel: a.CriticalRate,
+		},
+		Threshold: a.Threshold,
+	}
+
+	details := AlertPayloadContext{
+		Percentile:           a.Percentile,
+		PercentileDays:       a.PercentileDays,
+		Utilization:          a.Utilization,
+		ProjectedUtilization: a.ProjectedUtilization,
+		ProjectedRate:        a.ProjectedRate,
+		Band:                 a.Band,
+		Automations:          a.Automations,
+		Alternatives:         a.Alternatives,
+		SustainedChecks:      a.SustainedChecks,
+	}
+	if a.Has24h {
+		high, low, change := a.High24h, a.Low24h, a.Change24h
+		details.High24h, details.Low24h, details.Change24h = &high, &low, &change
+	}
+	if a.PercentileDays > 0 || a.Has24h || a.Utilization != 0 || a.Band != nil ||
+		len(a.Automations) > 0 || len(a.Alternatives) > 0 || a.SustainedChecks > 0 {
+		payload.Context = &details
+	}
+	return payload
+}
+
+// AlertJSONSchema is the JSON Schema for AlertPayload, served at /schema/alert/v1
+const AlertJSONSchema = `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "$id": "summer-rate-checker/alert/v1",
+  "title": "SummerRateChecker alert",
+  "description": "A borrow rate alert. Rates are in percent and changes in percentage points.",
+  "type": "object",
+  "required": ["schema", "event", "id", "timestamp", "severity", "vault", "rate"],
+  "properties": {
+    "schema": {"const": "summer-rate-checker/alert/v1"},
+    "event": {"enum": ["alert", "resolved"], "description": "resolved is sent when a critical rate drops back below the critical level"},
+    "id": {"type": "string", "description": "Short alert ID, as used by /ack"},
+    "timestamp": {"type": "string", "format": "date-time"},
+    "severity": {"enum": ["warning", "critical"]},
+    "vault": {
+      "type": "object",
+      "required": ["id", "nickname"],
+      "properties": {
+        "id": {"type": "string", "description": "Vault ID, as used in commands"},
+        "nickname": {"type": "string"},
+        "market_pair": {"type": "string", "description": "e.g. WBTC-USDC"}
+      }
+    },
+    "rate": {
+      "type": "object",
+      "required": ["previous", "current", "change_points"],
+      "properties": {
+        "previous": {"type": "number"},
+        "current": {"type": "number"},
+        "change_points": {"type": "number"},
+        "critical_level": {"type": "number", "description": "The critical level crossed, on critical alerts"}
+      }
+    },
+    "threshold": {
+      "type": "object",
+      "description": "The threshold a warning or escalation was evaluated against, and who last set it",
+      "required": ["percent", "description"],
+      "properties": {
+        "percent": {"type": "number"},
+        "mode": {"enum": ["absolute", "relative"]},
+        "description": {"type": "string", "description": "e.g. 0.5% or 10.0% relative"},
+        "set_at": {"type": "string", "format": "date-time", "description": "When the threshold was last changed, or the vault enrolled"},
+        "set_by": {"type": "string", "description": "Who set it, e.g. alice via /enroll"},
+        "set_by_id": {"type": "string", "description": "Their Discord user ID, when known"}
+      }
+    },
+    "context": {
+      "type": "object",
+      "properties": {
+        "percentile": {"type": "number", "minimum": 0, "maximum": 100},
+        "percentile_days": {"type": "integer"},
+        "high_24h": {"type": "number"},
+        "low_24h": {"type": "number"},
+        "change_24h": {"type": "number"},
+        "utilization": {"type": "number"},
+        "projected_utilization": {"type": "number"},
+        "projected_rate": {"type": "number"},
+        "band": {
+          "type": "object",
+          "properties": {
+            "low": {"type": "number"},
+            "high": {"type": "number"},
+            "source": {"type": "string"}
+          }
+        },
+        "automations": {"type": "array", "items": {"type": "string"}},
+        "alternatives": {
+          "type": "array",
+          "items": {
+            "type": "object",
+            "properties": {
+              "protocol": {"type": "string"},
+              "borrow_rate": {"type": "number"}
+            }
+          }
+        },
+        "sustained_checks": {"type": "integer", "description": "On escalations, how many checks the breach has lasted"}
+      }
+    }
+  }
+}
+`
@@ -0,0 +1,29 @@
+package types
+
+import "time"
+
+// AlertRule is a per-vault expression, evaluated every check, that alerts when it becomes true
+type AlertRule struct {
+	ID         string    `json:"id"`
+	Expression string    `json:"expression"`
+	Active     bool      `json:"active,omitempty"` // Whether the expression held on the last check, so it alerts only on the transition
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func NewAlertRule(expression string) *AlertRule {
+	return &AlertRule{
+		ID:         newShortID(),
+		Expression: expression,
+		CreatedAt:  time.Now(),
+	}
+}
+
+// FindRule returns the vault's rule with the given ID, or nil
+func (v *VaultConfig) FindRule(ruleID string) *AlertRule {
+	for _, rule := range v.Rules {
+		if rule.ID == ruleID {
+			return rule
+		}
+	}
+	return nil
+}
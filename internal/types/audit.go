@@ -0,0 +1,65 @@
+package types
+
+import (
+	"fmt"
+	"time"
+)
+
+// AuditAction is a kind of change recorded in the audit log
+type AuditAction string
+
+const (
+	AuditEnroll    AuditAction = "enroll"
+	AuditUnenroll  AuditAction = "unenroll"
+	AuditThreshold AuditAction = "threshold"
+)
+
+// AuditEntry records a change a user made with a command, with enough detail for /undo to revert it
+type AuditEntry struct {
+	ID        string      `json:"id"`
+	Timestamp time.Time   `json:"timestamp"`
+	UserID    string      `json:"user_id"`
+	Action    AuditAction `json:"action"`
+	VaultID   string      `json:"vault_id"`
+	Nickname  string      `json:"nickname,omitempty"`
+
+	// Threshold changes record the setting before and after
+	OldThreshold     float64       `json:"old_threshold,omitempty"`
+	OldThresholdMode ThresholdMode `json:"old_threshold_mode,omitempty"`
+	NewThreshold     float64       `json:"new_threshold,omitempty"`
+	NewThresholdMode ThresholdMode `json:"new_threshold_mode,omitempty"`
+
+	UndoneAt time.Time `json:"undone_at,omitempty"` // When /undo reverted the change; zero while it stands
+}
+
+// NewAuditEntry records userID performing action on vault
+func NewAuditEntry(userID string, action AuditAction, vault *VaultConfig) *AuditEntry {
+	return &AuditEntry{
+		ID:        newShortID(),
+		Timestamp: time.Now(),
+		UserID:    userID,
+		Action:    action,
+		VaultID:   vault.VaultID,
+		Nickname:  vault.Nickname,
+	}
+}
+
+// Undone reports whether /undo has reverted the change
+func (e *AuditEntry) Undone() bool {
+	return !e.UndoneAt.IsZero()
+}
+
+// Describe summarizes the change, e.g. "changed the threshold of `1234` from 0.5% to 5.0%"
+func (e *AuditEntry) Describe() string {
+	switch e.Action {
+	case AuditEnroll:
+		return fmt.Sprintf("enrolled `%s` (\"%s\")", e.VaultID, e.Nickname)
+	case AuditUnenroll:
+		return fmt.Sprintf("unenrolled `%s` (\"%s\")", e.VaultID, e.Nickname)
+	case AuditThreshold:
+		old := &VaultConfig{ThresholdPercent: e.OldThreshold, ThresholdMode: e.OldThresholdMode}
+		updated := &VaultConfig{ThresholdPercent: e.NewThreshold, ThresholdMode: e.NewThresholdMode}
+		return fmt.Sprintf("changed the threshold of `%s` from %s to %s", e.VaultID, old.DescribeThreshold(), updated.DescribeThreshold())
+	}
+	return fmt.Sprintf("%s `%s`", e.Action, e.VaultID)
+}
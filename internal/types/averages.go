@@ -0,0 +1,94 @@
+package types
+
+import (
+	"fmt"
+	"time"
+)
+
+// RateAverages are a market's trailing average APYs, in percent, as reported by the Morpho API.
+// A zero means the API didn't report that window, e.g. for a market younger than a month.
+type RateAverages struct {
+	Daily   float64 `json:"daily,omitempty"`
+	Weekly  float64 `json:"weekly,omitempty"`
+	Monthly float64 `json:"monthly,omitempty"`
+}
+
+// Known reports whether the API reported any of the averages
+func (a RateAverages) Known() bool {
+	return a.Daily != 0 || a.Weekly != 0 || a.Monthly != 0
+}
+
+// Describe formats the averages in format, e.g. "24h 4.12% · 7d 4.30% · 30d 4.05%"
+func (a RateAverages) Describe(format RateFormat) string {
+	show := func(rate float64) string {
+		if rate == 0 {
+			return "n/a"
+		}
+		return format.Rate(rate)
+	}
+	return fmt.Sprintf("24h %s · 7d %s · 30d %s", show(a.Daily), show(a.Weekly), show(a.Monthly))
+}
+
+// RateSource selects which borrow APY a vault's threshold is checked against
+type RateSource string
+
+const (
+	// RateSpot checks the instantaneous APY (the default)
+	RateSpot RateSource = "spot"
+	// RateDailyAverage checks the API's trailing 24h average APY, so brief spikes don't alert
+	RateDailyAverage RateSource = "daily_average"
+	// RateTrailingAverage checks the mean of the vault's recorded rates over its last AverageHours,
+	// so only sustained moves alert
+	RateTrailingAverage RateSource = "trailing_average"
+)
+
+// DefaultAverageHours is the trailing average window for vaults that haven't set one
+const DefaultAverageHours = 6
+
+// MaxAverageHours bounds the trailing average window to a week
+const MaxAverageHours = 168
+
+// ParseRateSource validates a rate source name; empty means the default
+func ParseRateSource(s string) (RateSource, error) {
+	switch RateSource(s) {
+	case "", RateSpot:
+		return RateSpot, nil
+	case RateDailyAverage, RateTrailingAverage:
+		return RateSource(s), nil
+	default:
+		return "", fmt.Errorf("unknown rate source %q", s)
+	}
+}
+
+// AverageWindow returns the trailing average window for RateTrailingAverage
+func (v *VaultConfig) AverageWindow() time.Duration {
+	hours := v.AverageHours
+	if hours <= 0 {
+		hours = DefaultAverageHours
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+// DescribeRateSource returns a short human-readable description of the vault's rate source,
+// e.g. "6h average APY"
+func (v *VaultConfig) DescribeRateSource() string {
+	switch v.RateSource {
+	case RateDailyAverage:
+		return "24h average APY"
+	case RateTrailingAverage:
+		return fmt.Sprintf("%.0fh average APY", v.AverageWindow().Hours())
+	default:
+		return "instantaneous APY"
+	}
+}
+
+// AlertRate returns the borrow rate the vault's threshold, critical level and escalations are
+// checked against. Vaults using the daily average fall back to the instantaneous APY when the API
+// doesn't report one. Trailing averages need the vault's history, so monitor.AlertRate computes
+// them; here they're treated like the daily average.
+func (v *VaultConfig) AlertRate(data *MarketData) float64 {
+	if v.RateSource != RateSpot && v.RateSource != "" && data.BorrowAverages.Daily != 0 {
+		return data.BorrowAverages.Daily
+	}
+	return data.BorrowRate
+}
@@ -0,0 +1,39 @@
+package types
+
+import "fmt"
+
+// BaselineStrategy selects which rate a vault's alerts are measured against
+type BaselineStrategy string
+
+const (
+	// BaselineLastAlert compares against the rate that last triggered an alert (the default)
+	BaselineLastAlert BaselineStrategy = "last_alert"
+	// BaselinePreviousCheck compares against the rate seen on the previous check
+	BaselinePreviousCheck BaselineStrategy = "previous_check"
+	// BaselineDailyOpen compares against the first rate recorded each UTC day
+	BaselineDailyOpen BaselineStrategy = "daily_open"
+)
+
+// ParseBaselineStrategy validates a strategy name; empty means the default
+func ParseBaselineStrategy(s string) (BaselineStrategy, error) {
+	switch BaselineStrategy(s) {
+	case "", BaselineLastAlert:
+		return BaselineLastAlert, nil
+	case BaselinePreviousCheck, BaselineDailyOpen:
+		return BaselineStrategy(s), nil
+	default:
+		return "", fmt.Errorf("unknown baseline strategy %q", s)
+	}
+}
+
+// Describe returns a short human-readable description of the strategy
+func (b BaselineStrategy) Describe() string {
+	switch b {
+	case BaselinePreviousCheck:
+		return "previous check"
+	case BaselineDailyOpen:
+		return "daily open (UTC)"
+	default:
+		return "last alert"
+	}
+}
@@ -0,0 +1,108 @@
+package types
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CheckRequest describes which vaults a manual check covers. The zero value checks every vault;
+// setting VaultID or Tag limits the check to that vault or to vaults carrying that tag.
+type CheckRequest struct {
+	VaultID string
+	Tag     string
+}
+
+// CheckSummary reports what a completed check did
+type CheckSummary struct {
+	Request  CheckRequest
+	Checked  int           // Vaults whose market data was fetched and processed
+	Failed   int           // Vaults whose market data couldn't be fetched
+	Deferred int           // Vaults due for a check but left for a later cycle by the call budget
+	Alerts   int           // Alerts raised, including ones held by a schedule or maintenance
+	Duration time.Duration // How long the check took
+	Err      error         // Set when the check couldn't run at all, e.g. storage or API errors
+}
+
+// Describe summarizes the outcome, e.g. "checked 4 vaults, 1 failed, 2 alerts fired in 3s"
+func (s CheckSummary) Describe() string {
+	if s.Err != nil {
+		return fmt.Sprintf("check of %s failed after %s: %v", s.Request.Describe(), s.Duration.Round(time.Second), s.Err)
+	}
+	deferred := ""
+	if s.Deferred > 0 {
+		deferred = fmt.Sprintf(", %d deferred", s.Deferred)
+	}
+	return fmt.Sprintf("checked %d %s, %d failed%s, %d %s fired in %s",
+		s.Checked, plural(s.Checked, "vault", "vaults"), s.Failed, deferred, s.Alerts, plural(s.Alerts, "alert", "alerts"),
+		s.Duration.Round(time.Second))
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
+
+// Scoped reports whether the request targets a subset of vaults
+func (r CheckRequest) Scoped() bool {
+	return r.VaultID != "" || r.Tag != ""
+}
+
+// Matches reports whether vault is covered by the request
+func (r CheckRequest) Matches(vault *VaultConfig) bool {
+	if r.VaultID != "" && vault.VaultID != r.VaultID {
+		return false
+	}
+	if r.Tag != "" && !vault.HasTag(r.Tag) {
+		return false
+	}
+	return true
+}
+
+// Describe names what the request checks, e.g. "vault `1234`" or "vaults tagged `treasury`"
+func (r CheckRequest) Describe() string {
+	switch {
+	case r.VaultID != "" && r.Tag != "":
+		return fmt.Sprintf("vault `%s` (if tagged `%s`)", r.VaultID, r.Tag)
+	case r.VaultID != "":
+		return fmt.Sprintf("vault `%s`", r.VaultID)
+	case r.Tag != "":
+		return fmt.Sprintf("vaults tagged `%s`", r.Tag)
+	}
+	return "all vaults"
+}
+
+// NormalizeTag lowercases and trims a tag so "Treasury " and "treasury" match
+func NormalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+// ParseTags splits a comma-separated list into normalized, de-duplicated, sorted tags
+func ParseTags(list string) []string {
+	seen := make(map[string]bool)
+	var tags []string
+	for _, part := range strings.Split(list, ",") {
+		tag := NormalizeTag(part)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// HasTag reports whether the vault carries tag
+func (v *VaultConfig) HasTag(tag string) bool {
+	tag = NormalizeTag(tag)
+	for _, t := range v.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
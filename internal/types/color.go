@@ -0,0 +1,50 @@
+package types
+
+import "math"
+
+// Colors an alert's embed slides between as its rate moves from the bottom of the vault's normal
+// range, through the middle, to the top. Beyond the range the color keeps deepening until the rate
+// is half a range width outside it.
+const (
+	colorBelowBand = 0x008000 // Deep green: far below normal, good for borrowers
+	colorInBand    = 0xf1c40f // Yellow: in the middle of the normal range
+	colorAboveBand = 0xff0000 // Red: far above normal
+)
+
+// RateBand is the range a vault's borrow rate normally sits in, in percent
+type RateBand struct {
+	Low    float64 `json:"low"`
+	High   float64 `json:"high"`
+	Source string  `json:"source,omitempty"` // How the band was chosen, e.g. "configured" or "30-day average ± 2σ"
+}
+
+// Position places rate relative to the band: -0.5 and 0.5 are its edges, 0 its middle, and -1 and
+// 1 half a band width outside it. Results are clamped to [-1, 1].
+func (b *RateBand) Position(rate float64) float64 {
+	width := b.High - b.Low
+	if width <= 0 {
+		return 0
+	}
+	position := (rate - (b.Low+b.High)/2) / width
+	return math.Max(-1, math.Min(1, position))
+}
+
+// BandColor returns the embed color for rate on the gradient across band
+func BandColor(band *RateBand, rate float64) int {
+	position := band.Position(rate)
+	if position < 0 {
+		return blendColor(colorInBand, colorBelowBand, -position)
+	}
+	return blendColor(colorInBand, colorAboveBand, position)
+}
+
+// blendColor interpolates each RGB channel from a to b; t of 0 gives a and 1 gives b
+func blendColor(a, b int, t float64) int {
+	var color int
+	for shift := 16; shift >= 0; shift -= 8 {
+		from := float64((a >> shift) & 0xff)
+		to := float64((b >> shift) & 0xff)
+		color |= int(math.Round(from+(to-from)*t)) << shift
+	}
+	return color
+}
@@ -0,0 +1,102 @@
+package types
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// CommandKind names an action the bot asks the monitor to perform
+type CommandKind string
+
+const (
+	CommandCheckAll   CommandKind = "check-all"   // Check every vault now
+	CommandCheckVault CommandKind = "check-vault" // Check one vault or a tagged group now
+	CommandPauseAll   CommandKind = "pause-all"   // Skip scheduled checks for a while
+
+	CommandResolveCritical CommandKind = "resolve-critical" // Resolve a critical alert whose level was turned off
+)
+
+// lastCommandID numbers commands so replies can be matched to the command that caused them
+var lastCommandID atomic.Uint64
+
+// MonitorCommand is sent from the bot to the monitor over the command bus
+type MonitorCommand struct {
+	ID       uint64
+	Kind     CommandKind
+	Check    CheckRequest         // Which vaults to check, for check-all and check-vault
+	PauseFor time.Duration        // How long to pause, for pause-all; zero resumes scheduled checks
+	VaultID  string               // The vault to resolve, for resolve-critical
+	Reply    chan<- CommandResult // Receives the outcome once the command is handled (optional, should be buffered)
+}
+
+// CommandResult reports how the monitor handled a command. ID and Kind match the command's.
+type CommandResult struct {
+	ID      uint64
+	Kind    CommandKind
+	Check   CheckSummary // Set for check commands
+	Message string       // Outcome of other commands, e.g. "scheduled checks paused until 15:04 UTC"
+	Err     error
+}
+
+// NewCommand returns a command of kind with a fresh ID
+func NewCommand(kind CommandKind) MonitorCommand {
+	return MonitorCommand{ID: lastCommandID.Add(1), Kind: kind}
+}
+
+// NewCheckCommand returns a check-all or check-vault command for req, depending on its scope
+func NewCheckCommand(req CheckRequest) MonitorCommand {
+	kind := CommandCheckAll
+	if req.Scoped() {
+		kind = CommandCheckVault
+	}
+	cmd := NewCommand(kind)
+	cmd.Check = req
+	return cmd
+}
+
+// NewPauseCommand returns a pause-all command; a zero duration resumes scheduled checks
+func NewPauseCommand(duration time.Duration) MonitorCommand {
+	cmd := NewCommand(CommandPauseAll)
+	cmd.PauseFor = duration
+	return cmd
+}
+
+// NewResolveCriticalCommand returns a resolve-critical command for vaultID
+func NewResolveCriticalCommand(vaultID string) MonitorCommand {
+	cmd := NewCommand(CommandResolveCritical)
+	cmd.VaultID = vaultID
+	return cmd
+}
+
+// IsCheck reports whether the command runs a rate check
+func (c MonitorCommand) IsCheck() bool {
+	return c.Kind == CommandCheckAll || c.Kind == CommandCheckVault
+}
+
+// Respond sends result to the requester, if it asked for one, stamped with the command's ID and
+// kind. It never blocks the monitor.
+func (c MonitorCommand) Respond(result CommandResult) {
+	if c.Reply == nil {
+		return
+	}
+	result.ID = c.ID
+	result.Kind = c.Kind
+	select {
+	case c.Reply <- result:
+	default:
+	}
+}
+
+// Describe names the command for logs, e.g. "#12 check-vault (vault `1234`)"
+func (c MonitorCommand) Describe() string {
+	switch {
+	case c.IsCheck():
+		return fmt.Sprintf("#%d %s (%s)", c.ID, c.Kind, c.Check.Describe())
+	case c.Kind == CommandPauseAll && c.PauseFor > 0:
+		return fmt.Sprintf("#%d %s (%s)", c.ID, c.Kind, c.PauseFor)
+	case c.Kind == CommandResolveCritical:
+		return fmt.Sprintf("#%d %s (vault `%s`)", c.ID, c.Kind, c.VaultID)
+	}
+	return fmt.Sprintf("#%d %s", c.ID, c.Kind)
+}
@@ -0,0 +1,42 @@
+package types
+
+import "time"
+
+// DeliveryResult is the outcome of a single alert delivery attempt to one sink
+type DeliveryResult struct {
+	Sink       string        `json:"sink"`
+	Success    bool          `json:"success"`
+	StatusCode int           `json:"status_code,omitempty"` // HTTP status, when the sink got a response
+	Latency    time.Duration `json:"latency"`
+	Error      string        `json:"error,omitempty"`
+	Timestamp  time.Time     `json:"timestamp"`
+}
+
+// DeliveryStats aggregates the delivery results for one vault and sink
+type DeliveryStats struct {
+	Sink           string    `json:"sink"`
+	Successes      int       `json:"successes"`
+	Failures       int       `json:"failures"`
+	LastSuccess    bool      `json:"last_success"`
+	LastStatusCode int       `json:"last_status_code,omitempty"`
+	LastLatencyMs  int64     `json:"last_latency_ms"`
+	LastError      string    `json:"last_error,omitempty"`
+	LastAttemptAt  time.Time `json:"last_attempt_at"`
+	LastSuccessAt  time.Time `json:"last_success_at,omitempty"`
+}
+
+// Apply folds a delivery result into the stats
+func (d *DeliveryStats) Apply(result DeliveryResult) {
+	d.Sink = result.Sink
+	d.LastSuccess = result.Success
+	d.LastStatusCode = result.StatusCode
+	d.LastLatencyMs = result.Latency.Milliseconds()
+	d.LastError = result.Error
+	d.LastAttemptAt = result.Timestamp
+	if result.Success {
+		d.Successes++
+		d.LastSuccessAt = result.Timestamp
+	} else {
+		d.Failures++
+	}
+}
@@ -0,0 +1,117 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// EnrollRule automatically enrolls every Morpho market for an asset pair
+type EnrollRule struct {
+	ID               string    `json:"id"`
+	CollateralSymbol string    `json:"collateral_symbol"`
+	LoanSymbol       string    `json:"loan_symbol"`
+	ThresholdPercent float64   `json:"threshold_percent"`
+	ChannelID        string    `json:"channel_id"`
+	WebhookURL       string    `json:"webhook_url,omitempty"` // Shared by every vault the rule enrolls
+	GuildID          string    `json:"guild_id,omitempty"`    // Server the rule was created in; its vaults count against that server
+	CreatedByID      string    `json:"created_by_id,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+func NewEnrollRule(collateral, loan string, threshold float64, channelID, webhookURL, guildID, createdByID string) *EnrollRule {
+	return &EnrollRule{
+		ID:               newShortID(),
+		CollateralSymbol: collateral,
+		LoanSymbol:       loan,
+		ThresholdPercent: threshold,
+		ChannelID:        channelID,
+		WebhookURL:       webhookURL,
+		GuildID:          guildID,
+		CreatedByID:      createdByID,
+		CreatedAt:        time.Now(),
+	}
+}
+
+// Clone returns a copy of the rule
+func (r *EnrollRule) Clone() *EnrollRule {
+	clone := *r
+	return &clone
+}
+
+// Pair returns the rule's pair in COLLATERAL-LOAN form
+func (r *EnrollRule) Pair() string {
+	return r.CollateralSymbol + "-" + r.LoanSymbol
+}
+
+// Matches reports whether market is for the rule's pair
+func (r *EnrollRule) Matches(market *MarketInfo) bool {
+	return strings.EqualFold(market.CollateralSymbol, r.CollateralSymbol) &&
+		strings.EqualFold(market.LoanSymbol, r.LoanSymbol)
+}
+
+// NewVault builds the vault enrolled for market. Markets have no Summer.fi position,
+// so the market's unique key doubles as the vault ID.
+func (r *EnrollRule) NewVault(market *MarketInfo) *VaultConfig {
+	shortKey := market.UniqueKey
+	if len(shortKey) > 10 {
+		shortKey = shortKey[:10] + "…"
+	}
+
+	return &VaultConfig{
+		VaultID:          market.UniqueKey,
+		Nickname:         fmt.Sprintf("%s (%s)", market.MarketPair(), shortKey),
+		ThresholdPercent: r.ThresholdPercent,
+		ChannelID:        r.ChannelID,
+		WebhookURL:       r.WebhookURL,
+		MorphoMarketKey:  market.UniqueKey,
+		MarketPair:       market.MarketPair(),
+		FallbackUserID:   r.CreatedByID,
+		GuildID:          r.GuildID,
+		EnrolledBy:       r.CreatedByID,
+		EnrollRuleID:     r.ID,
+		EnrollSource:     EnrollSourceAutoEnroll,
+	}
+}
+
+// EnrollSource records how a vault came to be enrolled
+type EnrollSource string
+
+const (
+	EnrollSourceCommand    EnrollSource = "command"     // /enroll
+	EnrollSourceSetup      EnrollSource = "setup"       // The /setup wizard
+	EnrollSourceAPI        EnrollSource = "api"         // POST /vaults
+	EnrollSourceAutoEnroll EnrollSource = "auto-enroll" // An auto-enroll rule
+)
+
+// DescribeEnrollment says who enrolled the vault and how, e.g. "alice via /enroll". Mentions are
+// avoided so listing vaults doesn't ping anyone.
+func (v *VaultConfig) DescribeEnrollment() string {
+	who := v.EnrolledByName
+	if who == "" && v.EnrolledBy != "" {
+		who = "user " + v.EnrolledBy
+	}
+
+	switch v.EnrollSource {
+	case EnrollSourceAutoEnroll:
+		return fmt.Sprintf("auto-enroll rule `%s`", v.EnrollRuleID)
+	case EnrollSourceSetup:
+		return who + " via /setup"
+	case EnrollSourceAPI:
+		if who == "" {
+			return "the HTTP API"
+		}
+		return who + " via the HTTP API"
+	case EnrollSourceCommand:
+		return who + " via /enroll"
+	}
+
+	// Vaults enrolled before the source was recorded
+	if v.EnrollRuleID != "" {
+		return fmt.Sprintf("auto-enroll rule `%s`", v.EnrollRuleID)
+	}
+	if who == "" {
+		return "unknown"
+	}
+	return who
+}
@@ -0,0 +1,82 @@
+package types
+
+import (
+	"fmt"
+	"time"
+)
+
+// Position is a Summer.fi position's state as reported by the Summer.fi API, which knows the
+// borrower's own collateral and debt rather than just the market they sit in
+type Position struct {
+	Owner            string       `json:"owner,omitempty"`
+	CollateralSymbol string       `json:"collateral_symbol"`
+	Collateral       float64      `json:"collateral"` // In collateral tokens
+	CollateralUSD    float64      `json:"collateral_usd"`
+	DebtSymbol       string       `json:"debt_symbol"`
+	Debt             float64      `json:"debt"` // In loan tokens
+	DebtUSD          float64      `json:"debt_usd"`
+	LiquidationPrice float64      `json:"liquidation_price,omitempty"` // Collateral price in USD at which the position is liquidated
+	Automations      []Automation `json:"automations,omitempty"`       // Active Summer.fi automation triggers
+	FetchedAt        time.Time    `json:"fetched_at"`
+}
+
+// Automation is a trigger configured on the position in Summer.fi, e.g. a stop-loss
+type Automation struct {
+	ID           string  `json:"id"`
+	Kind         string  `json:"kind"`                    // As named by Summer.fi, e.g. "stop-loss" or "auto-sell"
+	TriggerPrice float64 `json:"trigger_price,omitempty"` // Collateral price in USD the trigger executes at, if price-based
+}
+
+// Describe names the automation, e.g. "stop-loss at $58.00K"
+func (a Automation) Describe() string {
+	if a.TriggerPrice > 0 {
+		return fmt.Sprintf("%s at %s", a.Kind, FormatUSD(a.TriggerPrice))
+	}
+	return a.Kind
+}
+
+// LTV returns the position's loan-to-value in percent, or 0 without collateral
+func (p *Position) LTV() float64 {
+	if p.CollateralUSD <= 0 {
+		return 0
+	}
+	return p.DebtUSD / p.CollateralUSD * 100
+}
+
+// Headroom returns how far, in percentage points, the position's LTV is below lltv, the market's
+// liquidation LTV in percent. Unlike the LTV itself it means the same on markets with different LLTVs.
+func (p *Position) Headroom(lltv float64) float64 {
+	return lltv - p.LTV()
+}
+
+// PriceBuffer returns how far, in percent, the collateral price can fall before the position's
+// LTV reaches lltv, or 0 without an LLTV
+func (p *Position) PriceBuffer(lltv float64) float64 {
+	if lltv <= 0 {
+		return 0
+	}
+	return (1 - p.LTV()/lltv) * 100
+}
+
+// Describe summarizes the position, e.g. "1.5 WBTC ($90.00K) backing 40000 USDC ($40.00K), LTV 44.4%"
+func (p *Position) Describe() string {
+	return fmt.Sprintf("%.4g %s (%s) backing %.6g %s (%s), LTV %.1f%%",
+		p.Collateral, p.CollateralSymbol, FormatUSD(p.CollateralUSD),
+		p.Debt, p.DebtSymbol, FormatUSD(p.DebtUSD), p.LTV())
+}
+
+// DescribeAutomations describes each active automation trigger
+func (p *Position) DescribeAutomations() []string {
+	descriptions := make([]string, 0, len(p.Automations))
+	for _, automation := range p.Automations {
+		descriptions = append(descriptions, automation.Describe())
+	}
+	return descriptions
+}
+
+// Clone returns a deep copy of the position
+func (p *Position) Clone() *Position {
+	clone := *p
+	clone.Automations = append([]Automation(nil), p.Automations...)
+	return &clone
+}
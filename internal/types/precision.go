@@ -0,0 +1,43 @@
+package types
+
+// Rates can be shown with between MinRateDecimals and MaxRateDecimals decimal places
+const (
+	MinRateDecimals = 2
+	MaxRateDecimals = 4
+)
+
+// ClampDecimals limits decimals to the supported range; zero (unset) becomes MinRateDecimals
+func ClampDecimals(decimals int) int {
+	if decimals < MinRateDecimals {
+		return MinRateDecimals
+	}
+	if decimals > MaxRateDecimals {
+		return MaxRateDecimals
+	}
+	return decimals
+}
+
+// Decimals returns how many decimal places the vault's rates are shown with: its own /precision
+// setting, or global when it has none
+func (v *VaultConfig) Decimals(global int) int {
+	if v.RateDecimals != 0 {
+		return ClampDecimals(v.RateDecimals)
+	}
+	return ClampDecimals(global)
+}
+
+// RateFormat returns the format the vault's rates are shown in: global, at the vault's own
+// /precision if it has one
+func (v *VaultConfig) RateFormat(global RateFormat) RateFormat {
+	return global.WithDecimals(v.RateDecimals)
+}
+
+// FormatRate formats a rate in the alert's format
+func (r *RateChangeAlert) FormatRate(rate float64) string {
+	return r.Format.Rate(rate)
+}
+
+// FormatPoints formats a change in percentage points in the alert's format
+func (r *RateChangeAlert) FormatPoints(change float64) string {
+	return r.Format.Points(change)
+}
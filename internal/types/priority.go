@@ -0,0 +1,42 @@
+package types
+
+import "fmt"
+
+// Priority ranks a vault for the API call budget: when the monitor can't check every due vault,
+// higher priorities go first and low-priority vaults are checked less often
+type Priority string
+
+const (
+	PriorityHigh   Priority = "high"
+	PriorityNormal Priority = "normal" // The default
+	PriorityLow    Priority = "low"
+)
+
+var priorityRank = map[Priority]int{
+	PriorityLow:    0,
+	PriorityNormal: 1,
+	PriorityHigh:   2,
+}
+
+// ParsePriority validates a priority name; empty means normal
+func ParsePriority(s string) (Priority, error) {
+	switch priority := Priority(s); priority {
+	case "":
+		return PriorityNormal, nil
+	case PriorityHigh, PriorityNormal, PriorityLow:
+		return priority, nil
+	}
+	return "", fmt.Errorf("unknown priority %q, use high, normal, or low", s)
+}
+
+// Outranks reports whether p is checked before other. An unset priority is normal.
+func (p Priority) Outranks(other Priority) bool {
+	return p.rank() > other.rank()
+}
+
+func (p Priority) rank() int {
+	if rank, ok := priorityRank[p]; ok {
+		return rank
+	}
+	return priorityRank[PriorityNormal]
+}
@@ -0,0 +1,143 @@
+package types
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// RateUnit is the unit rates and rate changes are shown in
+type RateUnit string
+
+const (
+	// UnitPercent shows rates like "5.12%" and changes like "+0.06 pp" (the default)
+	UnitPercent RateUnit = "percent"
+	// UnitBasisPoints shows rates like "512 bps" and changes like "+6 bps"
+	UnitBasisPoints RateUnit = "bps"
+)
+
+// ParseRateUnit validates a rate unit name; empty means percent
+func ParseRateUnit(s string) (RateUnit, error) {
+	switch RateUnit(strings.ToLower(s)) {
+	case "", UnitPercent:
+		return UnitPercent, nil
+	case UnitBasisPoints:
+		return UnitBasisPoints, nil
+	default:
+		return "", fmt.Errorf("unknown rate unit %q (use percent or bps)", s)
+	}
+}
+
+// Locale picks the decimal and thousands separators numbers are written with
+type Locale string
+
+const (
+	LocaleEnglish Locale = "en" // 1,234.56 (the default)
+	LocaleGerman  Locale = "de" // 1.234,56
+	LocaleFrench  Locale = "fr" // 1 234,56
+)
+
+// ParseLocale validates a locale name; empty means English
+func ParseLocale(s string) (Locale, error) {
+	switch Locale(strings.ToLower(s)) {
+	case "", LocaleEnglish:
+		return LocaleEnglish, nil
+	case LocaleGerman, LocaleFrench:
+		return Locale(strings.ToLower(s)), nil
+	default:
+		return "", fmt.Errorf("unknown locale %q (use en, de or fr)", s)
+	}
+}
+
+// separators returns the locale's decimal and thousands separators
+func (l Locale) separators() (string, string) {
+	switch l {
+	case LocaleGerman:
+		return ",", "."
+	case LocaleFrench:
+		return ",", "\u202f" // Narrow no-break space, so a number never wraps
+	default:
+		return ".", ","
+	}
+}
+
+// RateFormat says how rates and rate changes are written. Everything that shows a rate to users
+// (alerts, notification sinks, commands, reports) formats it through one, so a display option
+// added here applies everywhere.
+type RateFormat struct {
+	Decimals int      `json:"decimals,omitempty"` // Decimal places in percent, MinRateDecimals-MaxRateDecimals (0 = MinRateDecimals)
+	Unit     RateUnit `json:"unit,omitempty"`     // Empty = UnitPercent
+	Locale   Locale   `json:"locale,omitempty"`   // Empty = LocaleEnglish
+}
+
+// WithDecimals returns a copy of the format with decimals places, unless decimals is zero (unset)
+func (f RateFormat) WithDecimals(decimals int) RateFormat {
+	if decimals != 0 {
+		f.Decimals = decimals
+	}
+	return f
+}
+
+// Rate formats a rate given in percent, e.g. "5.12%" or "512 bps"
+func (f RateFormat) Rate(rate float64) string {
+	if f.Unit == UnitBasisPoints {
+		return f.number(rate*100, f.bpsDecimals(), false) + " bps"
+	}
+	return f.number(rate, ClampDecimals(f.Decimals), false) + "%"
+}
+
+// Points formats a signed change given in percentage points, e.g. "+0.06 pp" or "+6 bps"
+func (f RateFormat) Points(change float64) string {
+	if f.Unit == UnitBasisPoints {
+		return f.number(change*100, f.bpsDecimals(), true) + " bps"
+	}
+	return f.number(change, ClampDecimals(f.Decimals), true) + " pp"
+}
+
+// Distance formats the size of a change given in percentage points, without a sign, e.g.
+// "0.06 percentage points" or "6 basis points"
+func (f RateFormat) Distance(change float64) string {
+	if f.Unit == UnitBasisPoints {
+		return f.number(math.Abs(change)*100, f.bpsDecimals(), false) + " basis points"
+	}
+	return f.number(math.Abs(change), ClampDecimals(f.Decimals), false) + " percentage points"
+}
+
+// bpsDecimals keeps basis points to the same resolution as percent: 2 decimals in percent is
+// whole basis points
+func (f RateFormat) bpsDecimals() int {
+	return ClampDecimals(f.Decimals) - MinRateDecimals
+}
+
+// number writes value with decimals places and the locale's separators. Signed numbers always
+// carry a sign, and one that rounds to zero is written "+0", never "-0".
+func (f RateFormat) number(value float64, decimals int, signed bool) string {
+	digits := strconv.FormatFloat(math.Abs(value), 'f', decimals, 64)
+	negative := value < 0 && strings.Trim(digits, "0.") != ""
+
+	whole, fraction := digits, ""
+	if dot := strings.IndexByte(digits, '.'); dot >= 0 {
+		whole, fraction = digits[:dot], digits[dot+1:]
+	}
+	decimal, thousands := f.Locale.separators()
+
+	var b strings.Builder
+	switch {
+	case negative:
+		b.WriteString("-")
+	case signed:
+		b.WriteString("+")
+	}
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteString(thousands)
+		}
+		b.WriteRune(digit)
+	}
+	if fraction != "" {
+		b.WriteString(decimal)
+		b.WriteString(fraction)
+	}
+	return b.String()
+}
@@ -0,0 +1,36 @@
+package types
+
+// ChannelRoute sends a vault's alerts of one severity to a channel other than the vault's own
+type ChannelRoute struct {
+	ChannelID  string `json:"channel_id"`
+	WebhookURL string `json:"webhook_url"`
+}
+
+// WebhookFor returns the webhook alerts of severity are posted to: the route set with /route, or
+// else the vault's own webhook. Notices that aren't alerts, like liquidity swings, always use the
+// vault's own webhook.
+func (v *VaultConfig) WebhookFor(severity Severity) string {
+	if route := v.Routes[severity]; route != nil && route.WebhookURL != "" {
+		return route.WebhookURL
+	}
+	return v.WebhookURL
+}
+
+// ChannelFor returns the channel alerts of severity are posted to
+func (v *VaultConfig) ChannelFor(severity Severity) string {
+	if route := v.Routes[severity]; route != nil && route.WebhookURL != "" {
+		return route.ChannelID
+	}
+	return v.ChannelID
+}
+
+// Webhooks returns every webhook the vault posts through, its own first
+func (v *VaultConfig) Webhooks() []string {
+	webhooks := []string{v.WebhookURL}
+	for _, severity := range []Severity{SeverityWarning, SeverityCritical} {
+		if route := v.Routes[severity]; route != nil && route.WebhookURL != "" {
+			webhooks = append(webhooks, route.WebhookURL)
+		}
+	}
+	return webhooks
+}
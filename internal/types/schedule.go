@@ -0,0 +1,108 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// AlertSchedule restricts when a vault's alerts are delivered. Alerts raised outside
+// the window are held and summarized when the window next opens.
+type AlertSchedule struct {
+	Days      []time.Weekday `json:"days,omitempty"` // Empty means every day
+	StartHour int            `json:"start_hour"`     // Inclusive, 0-23
+	EndHour   int            `json:"end_hour"`       // Exclusive, 1-24
+	Timezone  string         `json:"timezone,omitempty"`
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Location returns the schedule's time zone, falling back to UTC
+func (s *AlertSchedule) Location() *time.Location {
+	if s.Timezone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// Allows reports whether t falls inside the delivery window
+func (s *AlertSchedule) Allows(t time.Time) bool {
+	local := t.In(s.Location())
+
+	if len(s.Days) > 0 {
+		dayAllowed := false
+		for _, day := range s.Days {
+			if local.Weekday() == day {
+				dayAllowed = true
+				break
+			}
+		}
+		if !dayAllowed {
+			return false
+		}
+	}
+
+	return local.Hour() >= s.StartHour && local.Hour() < s.EndHour
+}
+
+// String describes the schedule, e.g. "mon-fri 08:00-20:00 America/New_York"
+func (s *AlertSchedule) String() string {
+	days := "every day"
+	if len(s.Days) > 0 {
+		names := make([]string, 0, len(s.Days))
+		for _, day := range s.Days {
+			names = append(names, strings.ToLower(day.String()[:3]))
+		}
+		days = strings.Join(names, ",")
+	}
+	return fmt.Sprintf("%s %02d:00-%02d:00 %s", days, s.StartHour, s.EndHour, s.Location())
+}
+
+// ParseWeekdays parses "all", a range like "mon-fri", or a list like "mon,wed,fri"
+func ParseWeekdays(spec string) ([]time.Weekday, error) {
+	spec = strings.ToLower(strings.TrimSpace(spec))
+	if spec == "" || spec == "all" {
+		return nil, nil
+	}
+
+	if from, to, isRange := strings.Cut(spec, "-"); isRange {
+		start, ok := weekdayNames[from]
+		if !ok {
+			return nil, fmt.Errorf("unknown day %q", from)
+		}
+		end, ok := weekdayNames[to]
+		if !ok {
+			return nil, fmt.Errorf("unknown day %q", to)
+		}
+		var days []time.Weekday
+		for day := start; ; day = (day + 1) % 7 {
+			days = append(days, day)
+			if day == end {
+				break
+			}
+		}
+		return days, nil
+	}
+
+	var days []time.Weekday
+	for _, name := range strings.Split(spec, ",") {
+		day, ok := weekdayNames[strings.TrimSpace(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown day %q", name)
+		}
+		days = append(days, day)
+	}
+	return days, nil
+}
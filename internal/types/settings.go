@@ -0,0 +1,131 @@
+package types
+
+import (
+	"strings"
+	"time"
+)
+
+// Settings holds bot-wide state that is changed through commands rather than the config file
+type Settings struct {
+	MaintenanceFrom  time.Time                `json:"maintenance_from,omitempty"`  // Start of a scheduled maintenance window (zero = already started)
+	MaintenanceUntil time.Time                `json:"maintenance_until,omitempty"` // Alert delivery is silenced until this time
+	MaintenanceBy    string                   `json:"maintenance_by,omitempty"`    // Discord user who started maintenance
+	MaintenanceEvent *MaintenanceEvent        `json:"maintenance_event,omitempty"` // Discord scheduled event announcing the window, if one was created
+	Guilds           map[string]GuildSettings `json:"guilds,omitempty"`            // Per-server defaults chosen with /setup, by guild ID
+	WeeklyReportAt   time.Time                `json:"weekly_report_at,omitempty"`  // When the last weekly report was posted
+}
+
+// MaintenanceEvent identifies the Discord scheduled event announcing a maintenance window
+type MaintenanceEvent struct {
+	GuildID string `json:"guild_id,omitempty"`
+	EventID string `json:"event_id,omitempty"`
+}
+
+// GuildSettings holds a server's defaults chosen with /setup
+type GuildSettings struct {
+	AlertChannelID       string    `json:"alert_channel_id,omitempty"`       // Channel /enroll uses when none is given
+	DefaultThreshold     float64   `json:"default_threshold,omitempty"`      // Threshold /enroll uses when none is given (0 = required)
+	CheckIntervalMinutes int       `json:"check_interval_minutes,omitempty"` // Minutes between checks of this server's vaults (0 = global interval)
+	FooterText           string    `json:"footer_text,omitempty"`            // Name shown in embed footers instead of DefaultFooter, set with /branding
+	FooterIconURL        string    `json:"footer_icon_url,omitempty"`        // Image shown beside embed footers, set with /branding
+	SetupBy              string    `json:"setup_by,omitempty"`               // Discord user who last changed these settings
+	SetupAt              time.Time `json:"setup_at,omitempty"`               // When these settings were last changed
+}
+
+// DefaultFooter starts the footer of the bot's embeds, unless a server sets its own name with /branding
+const DefaultFooter = "SummerRateChecker"
+
+// BrandFooter returns the footer text and icon for the server's embeds: text's leading
+// DefaultFooter is replaced with the server's FooterText, if it has one
+func (g GuildSettings) BrandFooter(text string) (string, string) {
+	if g.FooterText != "" && strings.HasPrefix(text, DefaultFooter) {
+		text = g.FooterText + strings.TrimPrefix(text, DefaultFooter)
+	}
+	return text, g.FooterIconURL
+}
+
+// Brand returns copies of embeds with the server's footer branding applied
+func (g GuildSettings) Brand(embeds []DiscordEmbed) []DiscordEmbed {
+	branded := make([]DiscordEmbed, len(embeds))
+	for i, embed := range embeds {
+		if embed.Footer != nil {
+			footer := *embed.Footer
+			footer.Text, footer.IconURL = g.BrandFooter(footer.Text)
+			embed.Footer = &footer
+		}
+		branded[i] = embed
+	}
+	return branded
+}
+
+// InMaintenance reports whether alert delivery is silenced at t
+func (s Settings) InMaintenance(t time.Time) bool {
+	return !t.Before(s.MaintenanceFrom) && t.Before(s.MaintenanceUntil)
+}
+
+// MaintenanceScheduled reports whether a maintenance window is set to start after t
+func (s Settings) MaintenanceScheduled(t time.Time) bool {
+	return t.Before(s.MaintenanceFrom) && t.Before(s.MaintenanceUntil)
+}
+
+// WithoutMaintenance returns a copy of the settings with any maintenance window cleared
+func (s Settings) WithoutMaintenance() Settings {
+	s.MaintenanceFrom = time.Time{}
+	s.MaintenanceUntil = time.Time{}
+	s.MaintenanceBy = ""
+	s.MaintenanceEvent = nil
+	return s
+}
+
+// WithMaintenance returns a copy of the settings with the maintenance window, and its event, taken
+// from window
+func (s Settings) WithMaintenance(window Settings) Settings {
+	s.MaintenanceFrom = window.MaintenanceFrom
+	s.MaintenanceUntil = window.MaintenanceUntil
+	s.MaintenanceBy = window.MaintenanceBy
+	s.MaintenanceEvent = window.MaintenanceEvent
+	return s
+}
+
+// Guild returns the /setup defaults for guildID, or zero values if the server hasn't run /setup
+func (s Settings) Guild(guildID string) GuildSettings {
+	return s.Guilds[guildID]
+}
+
+// WithGuild returns a copy of the settings with guildID's defaults replaced. The map is copied so
+// the settings held by storage aren't changed before they are saved.
+func (s Settings) WithGuild(guildID string, guild GuildSettings) Settings {
+	guilds := make(map[string]GuildSettings, len(s.Guilds)+1)
+	for id, g := range s.Guilds {
+		guilds[id] = g
+	}
+	guilds[guildID] = guild
+	s.Guilds = guilds
+	return s
+}
+
+// Clone returns a copy of the settings that shares nothing with the original
+func (s Settings) Clone() Settings {
+	if s.MaintenanceEvent != nil {
+		event := *s.MaintenanceEvent
+		s.MaintenanceEvent = &event
+	}
+	if s.Guilds != nil {
+		guilds := make(map[string]GuildSettings, len(s.Guilds))
+		for id, g := range s.Guilds {
+			guilds[id] = g
+		}
+		s.Guilds = guilds
+	}
+	return s
+}
+
+// CheckInterval returns how often vaults in guildID are checked. A server can only slow checks
+// down, since the monitor never runs more often than globalMinutes.
+func (s Settings) CheckInterval(guildID string, globalMinutes int) time.Duration {
+	minutes := globalMinutes
+	if guild := s.Guild(guildID); guild.CheckIntervalMinutes > minutes {
+		minutes = guild.CheckIntervalMinutes
+	}
+	return time.Duration(minutes) * time.Minute
+}
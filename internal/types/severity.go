@@ -0,0 +1,61 @@
+package types
+
+import "fmt"
+
+// Severity ranks how urgent an alert is
+type Severity string
+
+const (
+	SeverityWarning  Severity = "warning"  // Rate moved beyond the vault's threshold
+	SeverityCritical Severity = "critical" // Rate reached the vault's critical level
+)
+
+var severityRank = map[Severity]int{
+	SeverityWarning:  1,
+	SeverityCritical: 2,
+}
+
+// AtLeast reports whether s is as urgent as min or more.
+// An unset severity is treated as a warning.
+func (s Severity) AtLeast(min Severity) bool {
+	if s == "" {
+		s = SeverityWarning
+	}
+	return severityRank[s] >= severityRank[min]
+}
+
+// Label returns the severity capitalized for messages, e.g. "Critical"
+func (s Severity) Label() string {
+	if s == SeverityCritical {
+		return "Critical"
+	}
+	return "Warning"
+}
+
+// CriticalMention is who a vault's critical alerts ping beyond the channel itself
+type CriticalMention string
+
+const (
+	MentionNone     CriticalMention = ""         // Don't ping anyone
+	MentionHere     CriticalMention = "here"     // Ping members currently online in the channel
+	MentionEveryone CriticalMention = "everyone" // Ping every member who can see the channel
+)
+
+// ParseCriticalMention parses a /critical-ping mention choice
+func ParseCriticalMention(s string) (CriticalMention, error) {
+	switch mention := CriticalMention(s); mention {
+	case MentionNone, MentionHere, MentionEveryone:
+		return mention, nil
+	case "none":
+		return MentionNone, nil
+	}
+	return "", fmt.Errorf("unknown mention %q, use none, here, or everyone", s)
+}
+
+// Tag returns the mention as message text, e.g. "@here"; empty for MentionNone
+func (m CriticalMention) Tag() string {
+	if m == MentionNone {
+		return ""
+	}
+	return "@" + string(m)
+}
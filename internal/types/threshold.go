@@ -0,0 +1,136 @@
+package types
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Threshold bounds. Stablecoin borrow markets routinely move in hundredths of a percent, so
+// thresholds go down to 0.01.
+const (
+	MinThreshold = 0.01
+	MaxThreshold = 100.0
+)
+
+// thresholdEpsilon absorbs float error in rate differences and parsed input, so a 5.00% → 5.01%
+// move (0.00999... in float64) still meets a 0.01 threshold
+const thresholdEpsilon = 1e-9
+
+// ThresholdMode selects how a vault's ThresholdPercent is interpreted
+type ThresholdMode string
+
+const (
+	// ThresholdAbsolute treats the threshold as percentage points (5.0% → 5.5% is a 0.5 move). The default.
+	ThresholdAbsolute ThresholdMode = "absolute"
+	// ThresholdRelative treats the threshold as a percentage of the baseline (5.0% → 5.5% is a 10% move)
+	ThresholdRelative ThresholdMode = "relative"
+)
+
+// ParseThresholdMode validates a mode name; empty means the default
+func ParseThresholdMode(s string) (ThresholdMode, error) {
+	switch ThresholdMode(s) {
+	case "", ThresholdAbsolute:
+		return ThresholdAbsolute, nil
+	case ThresholdRelative:
+		return ThresholdRelative, nil
+	default:
+		return "", fmt.Errorf("unknown threshold mode %q", s)
+	}
+}
+
+// ValidateThreshold checks that a threshold is a finite number within bounds with at most two
+// decimal places
+func ValidateThreshold(threshold float64) error {
+	if math.IsNaN(threshold) || math.IsInf(threshold, 0) ||
+		threshold < MinThreshold-thresholdEpsilon || threshold > MaxThreshold+thresholdEpsilon {
+		return fmt.Errorf("threshold must be between %s and %s", FormatThreshold(MinThreshold), FormatThreshold(MaxThreshold))
+	}
+	if math.Abs(threshold*100-math.Round(threshold*100)) > 1e-6 {
+		return fmt.Errorf("threshold can have at most two decimal places, e.g. 0.05")
+	}
+	return nil
+}
+
+// ParseThreshold parses and validates a threshold typed into a form, e.g. "0.05" or "0.05%"
+func ParseThreshold(s string) (float64, error) {
+	threshold, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(s), "%"), 64)
+	if err != nil {
+		return 0, fmt.Errorf("threshold must be a number, e.g. 0.5")
+	}
+	return threshold, ValidateThreshold(threshold)
+}
+
+// FormatThreshold formats a threshold with one decimal place, or two when it has hundredths,
+// e.g. "0.5" or "0.05"
+func FormatThreshold(threshold float64) string {
+	if math.Abs(threshold*10-math.Round(threshold*10)) > 1e-6 {
+		return fmt.Sprintf("%.2f", threshold)
+	}
+	return fmt.Sprintf("%.1f", threshold)
+}
+
+// ExceedsThreshold reports whether a move from baseline to rate meets the vault's threshold
+func (v *VaultConfig) ExceedsThreshold(baseline, rate float64) bool {
+	if v.ThresholdMode == ThresholdRelative {
+		if baseline == 0 {
+			return false
+		}
+		return math.Abs(rate-baseline)/baseline*100 >= v.ThresholdPercent-thresholdEpsilon
+	}
+	return math.Abs(rate-baseline) >= v.ThresholdPercent-thresholdEpsilon
+}
+
+// DescribeThreshold formats the threshold with its unit, e.g. "0.5%", "0.05%" or "10.0% relative"
+func (v *VaultConfig) DescribeThreshold() string {
+	if v.ThresholdMode == ThresholdRelative {
+		return FormatThreshold(v.ThresholdPercent) + "% relative"
+	}
+	return FormatThreshold(v.ThresholdPercent) + "%"
+}
+
+// AlertThreshold records the threshold an alert was evaluated against and who set it, so a shared
+// server can see from the alert itself why it fired
+type AlertThreshold struct {
+	Percent     float64       `json:"percent"`
+	Mode        ThresholdMode `json:"mode,omitempty"`
+	Description string        `json:"description"`         // e.g. "0.5%" or "10.0% relative"
+	SetAt       time.Time     `json:"set_at,omitempty"`    // When it was last changed, or the vault was enrolled
+	SetBy       string        `json:"set_by,omitempty"`    // Who set it, e.g. "alice" or "alice via /enroll", without mentions
+	SetByID     string        `json:"set_by_id,omitempty"` // Their Discord user ID, when known
+}
+
+// SetThreshold changes the threshold and records userID (going by name) as having changed it
+func (v *VaultConfig) SetThreshold(percent float64, mode ThresholdMode, userID, name string) {
+	v.ThresholdPercent = percent
+	v.ThresholdMode = mode
+	v.ThresholdSetAt = time.Now()
+	v.ThresholdSetBy = userID
+	v.ThresholdSetName = name
+}
+
+// AlertThreshold returns the vault's current threshold and who last set it. A threshold unchanged
+// since enrollment is credited to whoever enrolled the vault.
+func (v *VaultConfig) AlertThreshold() *AlertThreshold {
+	threshold := &AlertThreshold{
+		Percent:     v.ThresholdPercent,
+		Mode:        v.ThresholdMode,
+		Description: v.DescribeThreshold(),
+	}
+	if v.ThresholdSetAt.IsZero() {
+		threshold.SetAt = v.CreatedAt
+		threshold.SetBy = v.DescribeEnrollment()
+		threshold.SetByID = v.EnrolledBy
+		return threshold
+	}
+
+	threshold.SetAt = v.ThresholdSetAt
+	threshold.SetBy = v.ThresholdSetName
+	if threshold.SetBy == "" {
+		threshold.SetBy = "user " + v.ThresholdSetBy
+	}
+	threshold.SetByID = v.ThresholdSetBy
+	return threshold
+}
@@ -1,132 +1,719 @@
-package types
-
-import (
-	"fmt"
-	"math"
-	"time"
-)
-
-// VaultConfig represents a vault being monitored
-type VaultConfig struct {
-	VaultID          string    `json:"vault_id"`
-	Nickname         string    `json:"nickname"`
-	ThresholdPercent float64   `json:"threshold_percent"`
-	ChannelID        string    `json:"channel_id"`
-	WebhookURL       string    `json:"webhook_url,omitempty"` // Discord webhook URL for this vault's channel
-	CreatedAt        time.Time `json:"created_at"`
-	MorphoMarketKey  string    `json:"morpho_market_key,omitempty"` // The Morpho market unique key for this vault
-	MarketPair       string    `json:"market_pair,omitempty"`       // The market pair (e.g., "WBTC-USDC")
-	LastAlertRate    float64   `json:"last_alert_rate,omitempty"`   // The rate that last triggered an alert
-}
-
-// MarketData represents the current market data for a vault
-type MarketData struct {
-	VaultID         string    `json:"vault_id"`
-	MorphoMarketKey string    `json:"morpho_market_key"`
-	BorrowRate      float64   `json:"borrow_rate"`
-	SupplyRate      float64   `json:"supply_rate"`
-	Timestamp       time.Time `json:"timestamp"`
-}
-
-type RateChangeAlert struct {
-	VaultID       string    `json:"vault_id"`
-	Nickname      string    `json:"nickname"`
-	MarketPair    string    `json:"market_pair,omitempty"` // The market pair (e.g., "WBTC-USDC")
-	PreviousRate  float64   `json:"previous_rate"`
-	CurrentRate   float64   `json:"current_rate"`
-	ChangePercent float64   `json:"change_percent"`
-	Timestamp     time.Time `json:"timestamp"`
-}
-
-func NewRateChangeAlert(vaultID, nickname, marketPair string, prevRate, currRate float64) *RateChangeAlert {
-	changePoints := currRate - prevRate // This is now in percentage points
-	return &RateChangeAlert{
-		VaultID:       vaultID,
-		Nickname:      nickname,
-		MarketPair:    marketPair,
-		PreviousRate:  prevRate,
-		CurrentRate:   currRate,
-		ChangePercent: changePoints, // This is now in percentage points
-		Timestamp:     time.Now(),
-	}
-}
-
-func (r *RateChangeAlert) ToDiscordMessage() string {
-	icon := "📈"
-	direction := "increased"
-	if r.ChangePercent < 0 {
-		icon = "📉"
-		direction = "decreased"
-	}
-
-	return fmt.Sprintf(
-		"%s **Rate Alert: %s**\n\n"+
-			"**Current Rate: %.2f%%**\n"+
-			"Previous Rate: %.2f%%\n"+
-			"Change: %s by %.2f percentage points\n\n"+
-			"<t:%d:R>",
-		icon,
-		r.Nickname,
-		r.CurrentRate,
-		r.PreviousRate,
-		direction,
-		math.Abs(r.ChangePercent),
-		r.Timestamp.Unix(),
-	)
-}
-
-type DiscordEmbed struct {
-	Title       string              `json:"title"`
-	Description string              `json:"description"`
-	Color       int                 `json:"color"`
-	Fields      []DiscordEmbedField `json:"fields"`
-	Timestamp   string              `json:"timestamp"`
-	Footer      *DiscordEmbedFooter `json:"footer,omitempty"`
-}
-
-type DiscordEmbedField struct {
-	Name   string `json:"name"`
-	Value  string `json:"value"`
-	Inline bool   `json:"inline"`
-}
-
-type DiscordEmbedFooter struct {
-	Text string `json:"text"`
-}
-
-type DiscordWebhookPayload struct {
-	Embeds []DiscordEmbed `json:"embeds"`
-}
-
-func (r *RateChangeAlert) ToDiscordEmbed() *DiscordWebhookPayload {
-	color := 0xff0000 // Red for increase (bad for borrowers)
-	if r.ChangePercent < 0 {
-		color = 0x00ff00 // Green for decrease (good for borrowers)
-	}
-
-	embed := DiscordEmbed{
-		Title:       fmt.Sprintf("Rate Alert: %s", r.Nickname),
-		Description: r.ToDiscordMessage(),
-		Color:       color,
-		Fields: []DiscordEmbedField{
-			{
-				Name:   "Vault ID",
-				Value:  r.VaultID,
-				Inline: true,
-			},
-			{
-				Name:   "Market Pair",
-				Value:  r.MarketPair,
-				Inline: true,
-			},
-		},
-		Timestamp: r.Timestamp.Format(time.RFC3339),
-		Footer: &DiscordEmbedFooter{
-			Text: "SummerRateChecker",
-		},
-	}
-
-	return &DiscordWebhookPayload{
-		Embeds: []DiscordEmbed{embed},
-	}
-}
+package types
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// VaultConfig represents a vault being monitored
+type VaultConfig struct {
+	VaultID          string           `json:"vault_id"`
+	Nickname         string           `json:"nickname"`
+	ThresholdPercent float64          `json:"threshold_percent"`
+	ThresholdMode    ThresholdMode    `json:"threshold_mode,omitempty"`        // How ThresholdPercent is applied (empty = absolute)
+	RateSource       RateSource       `json:"rate_source,omitempty"`           // Which borrow APY the threshold is checked against (empty = spot)
+	AverageHours     int              `json:"average_hours,omitempty"`         // Window for the trailing_average rate source (0 = DefaultAverageHours)
+	ThresholdSetAt   time.Time        `json:"threshold_set_at,omitempty"`      // When /threshold or /undo last changed the threshold (zero = unchanged since enrollment)
+	ThresholdSetBy   string           `json:"threshold_set_by,omitempty"`      // Discord user who last changed the threshold
+	ThresholdSetName string           `json:"threshold_set_by_name,omitempty"` // Their name, as it was at the time
+	ChannelID        string           `json:"channel_id"`
+	ChannelCreated   bool             `json:"channel_created,omitempty"`   // ChannelID was created for this vault by /enroll create_channel
+	WebhookURL       string           `json:"webhook_url,omitempty"`       // Discord webhook URL for this vault's channel
+	SlackWebhookURL  string           `json:"slack_webhook_url,omitempty"` // Slack incoming webhook alerts are mirrored to, set with /slack
+	CreatedAt        time.Time        `json:"created_at"`
+	MorphoMarketKey  string           `json:"morpho_market_key,omitempty"`  // The Morpho market unique key for this vault
+	MarketPair       string           `json:"market_pair,omitempty"`        // The market pair (e.g., "WBTC-USDC")
+	LastAlertRate    float64          `json:"last_alert_rate,omitempty"`    // The rate that last triggered an alert
+	LastAlertAt      time.Time        `json:"last_alert_at,omitempty"`      // When the last threshold alert was raised
+	CooldownMinutes  int              `json:"cooldown_minutes,omitempty"`   // Minimum time between threshold alerts, set with /cooldown (0 = none)
+	LastCheckedAt    time.Time        `json:"last_checked_at,omitempty"`    // When market data was last fetched successfully
+	FailureCount     int              `json:"failure_count,omitempty"`      // Consecutive checks where market data couldn't be fetched
+	LiquidityAlertAt time.Time        `json:"liquidity_alert_at,omitempty"` // When the last supply/borrow swing alert was sent
+	KnownWarnings    []string         `json:"known_warnings,omitempty"`     // Market warning types already alerted on
+	BadDebtUSD       float64          `json:"bad_debt_usd,omitempty"`       // Market bad debt when last checked
+	RiskObserved     bool             `json:"risk_observed,omitempty"`      // Whether KnownWarnings and BadDebtUSD have been recorded once
+	BorrowAverages   RateAverages     `json:"borrow_averages"`              // Market's average borrow APYs when last checked
+	NearKink         bool             `json:"near_kink,omitempty"`          // Whether utilization is past the kink warning level
+	LLTV             float64          `json:"lltv,omitempty"`               // Market's liquidation LTV in percent when last checked
+	LowHeadroom      bool             `json:"low_headroom,omitempty"`       // Whether the position's LTV is within headroom_margin of the LLTV
+	Rules            []*AlertRule     `json:"rules,omitempty"`              // Composite alert conditions set with /rule
+	Disabled         bool             `json:"disabled,omitempty"`           // Set after too many consecutive failures; cleared with /enable
+	Paused           bool             `json:"paused,omitempty"`             // Alerts aren't delivered, set with /pause; rates are still recorded
+	PausedUntil      time.Time        `json:"paused_until,omitempty"`       // When a timed /pause ends (zero = until /resume)
+	BaselineStrategy BaselineStrategy `json:"baseline_strategy,omitempty"`  // What alerts compare against (empty = last alert)
+	CriticalRate     float64          `json:"critical_rate,omitempty"`      // Borrow rate at or above which alerts are critical (0 disables)
+	CriticalActive   bool             `json:"critical_active,omitempty"`    // Whether the rate is currently at or above CriticalRate
+	CriticalMention  CriticalMention  `json:"critical_mention,omitempty"`   // @here or @everyone on critical alerts, set by admins with /critical-ping
+	CriticalTTS      bool             `json:"critical_tts,omitempty"`       // Read critical alerts aloud with Discord text-to-speech
+	FallbackUserID   string           `json:"fallback_user_id,omitempty"`   // Discord user to DM when webhook delivery keeps failing
+	EnrollRuleID     string           `json:"enroll_rule_id,omitempty"`     // Auto-enroll rule that created this vault, if any
+	EnrolledBy       string           `json:"enrolled_by,omitempty"`        // Discord user who ran /enroll, if enrolled by a user
+	EnrolledByName   string           `json:"enrolled_by_name,omitempty"`   // Name of whoever enrolled the vault, as it was at the time
+	EnrollSource     EnrollSource     `json:"enroll_source,omitempty"`      // How the vault was enrolled (empty for vaults enrolled before this was recorded)
+	GuildID          string           `json:"guild_id,omitempty"`           // Discord server the vault was enrolled in
+	DeletedAt        time.Time        `json:"deleted_at,omitempty"`         // When /unenroll moved the vault to the trash; zero while enrolled
+	RateDecimals     int              `json:"rate_decimals,omitempty"`      // Decimal places rates are shown with, set with /precision (0 = global)
+	Notes            string           `json:"notes,omitempty"`              // Free-text context set with /enroll or /note, e.g. "main treasury loop"
+	Tags             []string         `json:"tags,omitempty"`               // Lowercase group names set with /tag, for scoped /check
+	Priority         Priority         `json:"priority,omitempty"`           // Order under the API call budget, set with /priority (empty = normal)
+	Position         *Position        `json:"position,omitempty"`           // Latest Summer.fi position metadata (nil when summerfi.api_url is unset)
+	DebtUSD          float64          `json:"debt_usd,omitempty"`           // Debt set with /debt, for interest estimates without Summer.fi position data
+
+	Band          *RateBand          `json:"band,omitempty"`           // Normal borrow rate range set with /band, used to color alerts (nil = from history)
+	AlertSchedule *AlertSchedule     `json:"alert_schedule,omitempty"` // Restricts when alerts are delivered (nil = always)
+	HeldAlerts    []*RateChangeAlert `json:"held_alerts,omitempty"`    // Alerts raised outside the schedule, awaiting summary
+
+	Routes map[Severity]*ChannelRoute `json:"routes,omitempty"` // Channels alerts of a severity go to instead of ChannelID, set with /route
+
+	EscalationRoleID string  `json:"escalation_role_id,omitempty"` // Role mentioned on escalated alerts
+	EscalationUserID string  `json:"escalation_user_id,omitempty"` // User DMed on escalated alerts
+	BreachBaseline   float64 `json:"breach_baseline,omitempty"`    // Rate before the current breach began (0 = no breach)
+	BreachChecks     int     `json:"breach_checks,omitempty"`      // Consecutive checks the rate has stayed beyond the threshold
+	BreachEscalated  bool    `json:"breach_escalated,omitempty"`   // Whether the current breach has already been escalated
+
+	OpenAlertMessages []string `json:"open_alert_messages,omitempty"` // Webhook messages for the current breach, edited once it clears
+	CriticalMessageID string   `json:"critical_message_id,omitempty"` // Webhook message for the active critical alert
+	CriticalAlertID   string   `json:"critical_alert_id,omitempty"`   // ID of the active critical alert, re-pinged until acknowledged
+}
+
+// IsStale reports whether the vault hasn't been checked successfully within maxAge.
+// Vaults that have never been checked are stale once they are older than maxAge.
+func (v *VaultConfig) IsStale(now time.Time, maxAge time.Duration) bool {
+	if v.LastCheckedAt.IsZero() {
+		return now.Sub(v.CreatedAt) > maxAge
+	}
+	return now.Sub(v.LastCheckedAt) > maxAge
+}
+
+// AlertsPaused reports whether /pause is holding back the vault's alerts at t
+func (v *VaultConfig) AlertsPaused(t time.Time) bool {
+	return v.Paused && (v.PausedUntil.IsZero() || t.Before(v.PausedUntil))
+}
+
+// CooldownEnds returns when the vault may next raise a threshold alert; zero when it has no
+// cooldown or hasn't alerted yet
+func (v *VaultConfig) CooldownEnds() time.Time {
+	if v.CooldownMinutes <= 0 || v.LastAlertAt.IsZero() {
+		return time.Time{}
+	}
+	return v.LastAlertAt.Add(time.Duration(v.CooldownMinutes) * time.Minute)
+}
+
+// InCooldown reports whether /cooldown is holding back threshold alerts at t
+func (v *VaultConfig) InCooldown(t time.Time) bool {
+	return t.Before(v.CooldownEnds())
+}
+
+// Guild returns the server the vault belongs to. Vaults enrolled before servers were recorded
+// belong to primaryGuildID.
+func (v *VaultConfig) Guild(primaryGuildID string) string {
+	if v.GuildID == "" {
+		return primaryGuildID
+	}
+	return v.GuildID
+}
+
+// Debt returns the vault's debt in USD: the Summer.fi position's when known, otherwise the amount
+// set with /debt. Zero means the debt is unknown.
+func (v *VaultConfig) Debt() float64 {
+	if v.Position != nil {
+		return v.Position.DebtUSD
+	}
+	return v.DebtUSD
+}
+
+// LoanSymbol returns the loan asset of the vault's market pair, e.g. "USDC" for "WBTC-USDC";
+// empty when the pair is unknown
+func (v *VaultConfig) LoanSymbol() string {
+	if idx := strings.LastIndex(v.MarketPair, "-"); idx >= 0 {
+		return v.MarketPair[idx+1:]
+	}
+	return ""
+}
+
+// Trashed reports whether the vault was unenrolled and is waiting to be purged
+func (v *VaultConfig) Trashed() bool {
+	return !v.DeletedAt.IsZero()
+}
+
+// Clone returns a deep copy of the vault. Storage hands out clones so callers can't change stored
+// vaults, or race with each other, by modifying what they read.
+func (v *VaultConfig) Clone() *VaultConfig {
+	clone := *v
+	clone.KnownWarnings = append([]string(nil), v.KnownWarnings...)
+	clone.OpenAlertMessages = append([]string(nil), v.OpenAlertMessages...)
+	clone.Tags = append([]string(nil), v.Tags...)
+	if v.Rules != nil {
+		clone.Rules = make([]*AlertRule, len(v.Rules))
+		for i, rule := range v.Rules {
+			copied := *rule
+			clone.Rules[i] = &copied
+		}
+	}
+	if v.Band != nil {
+		band := *v.Band
+		clone.Band = &band
+	}
+	if v.Position != nil {
+		clone.Position = v.Position.Clone()
+	}
+	if v.Routes != nil {
+		clone.Routes = make(map[Severity]*ChannelRoute, len(v.Routes))
+		for severity, route := range v.Routes {
+			copied := *route
+			clone.Routes[severity] = &copied
+		}
+	}
+	if v.AlertSchedule != nil {
+		schedule := *v.AlertSchedule
+		schedule.Days = append([]time.Weekday(nil), v.AlertSchedule.Days...)
+		clone.AlertSchedule = &schedule
+	}
+	if v.HeldAlerts != nil {
+		clone.HeldAlerts = make([]*RateChangeAlert, len(v.HeldAlerts))
+		for i, alert := range v.HeldAlerts {
+			copied := *alert
+			clone.HeldAlerts[i] = &copied
+		}
+	}
+	return &clone
+}
+
+// CopyMonitorState applies the fields the monitor maintains that changed during a check: those
+// that differ between src, the monitor's copy, and base, the same vault as read when the check
+// started. Fields the monitor left alone keep their value in v, so a command that changed one
+// mid-check (e.g. /reset-baseline or /critical 0) isn't reverted, and user settings are never copied.
+func (v *VaultConfig) CopyMonitorState(src, base *VaultConfig) {
+	if v.MorphoMarketKey == "" {
+		v.MorphoMarketKey = src.MorphoMarketKey
+	}
+	if src.LastAlertRate != base.LastAlertRate {
+		v.LastAlertRate = src.LastAlertRate
+	}
+	if !src.LastAlertAt.Equal(base.LastAlertAt) {
+		v.LastAlertAt = src.LastAlertAt
+	}
+	if !src.LastCheckedAt.Equal(base.LastCheckedAt) {
+		v.LastCheckedAt = src.LastCheckedAt
+	}
+	if src.FailureCount != base.FailureCount {
+		v.FailureCount = src.FailureCount
+	}
+	if src.Disabled != base.Disabled {
+		v.Disabled = src.Disabled
+	}
+	if !src.LiquidityAlertAt.Equal(base.LiquidityAlertAt) {
+		v.LiquidityAlertAt = src.LiquidityAlertAt
+	}
+	if !reflect.DeepEqual(src.KnownWarnings, base.KnownWarnings) {
+		v.KnownWarnings = append([]string(nil), src.KnownWarnings...)
+	}
+	if src.BadDebtUSD != base.BadDebtUSD {
+		v.BadDebtUSD = src.BadDebtUSD
+	}
+	if src.RiskObserved != base.RiskObserved {
+		v.RiskObserved = src.RiskObserved
+	}
+	if src.BorrowAverages != base.BorrowAverages {
+		v.BorrowAverages = src.BorrowAverages
+	}
+	if src.NearKink != base.NearKink {
+		v.NearKink = src.NearKink
+	}
+	if src.LLTV != base.LLTV {
+		v.LLTV = src.LLTV
+	}
+	if src.LowHeadroom != base.LowHeadroom {
+		v.LowHeadroom = src.LowHeadroom
+	}
+	if src.Position != nil && !reflect.DeepEqual(src.Position, base.Position) {
+		v.Position = src.Position.Clone()
+	}
+	if src.CriticalActive != base.CriticalActive {
+		v.CriticalActive = src.CriticalActive
+	}
+	if src.CriticalMessageID != base.CriticalMessageID {
+		v.CriticalMessageID = src.CriticalMessageID
+	}
+	if src.CriticalAlertID != base.CriticalAlertID {
+		v.CriticalAlertID = src.CriticalAlertID
+	}
+	if src.BreachBaseline != base.BreachBaseline {
+		v.BreachBaseline = src.BreachBaseline
+	}
+	if src.BreachChecks != base.BreachChecks {
+		v.BreachChecks = src.BreachChecks
+	}
+	if src.BreachEscalated != base.BreachEscalated {
+		v.BreachEscalated = src.BreachEscalated
+	}
+	if !reflect.DeepEqual(src.OpenAlertMessages, base.OpenAlertMessages) {
+		v.OpenAlertMessages = append([]string(nil), src.OpenAlertMessages...)
+	}
+	if !reflect.DeepEqual(src.HeldAlerts, base.HeldAlerts) {
+		v.HeldAlerts = src.Clone().HeldAlerts
+	}
+
+	// Rules may have been added or removed meanwhile, so only carry over whether each one holds
+	for _, rule := range v.Rules {
+		checked := src.FindRule(rule.ID)
+		if checked == nil {
+			continue
+		}
+		if was := base.FindRule(rule.ID); was == nil || was.Active != checked.Active {
+			rule.Active = checked.Active
+		}
+	}
+}
+
+// MarketData represents the current market data for a vault
+type MarketData struct {
+	VaultID         string          `json:"vault_id"`
+	MorphoMarketKey string          `json:"morpho_market_key"`
+	BorrowRate      float64         `json:"borrow_rate"`
+	SupplyRate      float64         `json:"supply_rate"`
+	SupplyUSD       float64         `json:"supply_usd"`   // Total supplied to the market
+	BorrowUSD       float64         `json:"borrow_usd"`   // Total borrowed from the market
+	Utilization     float64         `json:"utilization"`  // Borrowed share of supply, in percent
+	IRMAddress      string          `json:"irm_address"`  // The market's interest rate model
+	LLTV            float64         `json:"lltv"`         // Liquidation loan-to-value, in percent
+	BadDebtUSD      float64         `json:"bad_debt_usd"` // Realized plus unrealized bad debt
+	BorrowAverages  RateAverages    `json:"borrow_averages"`
+	SupplyAverages  RateAverages    `json:"supply_averages"`
+	Warnings        []MarketWarning `json:"warnings,omitempty"`
+	Timestamp       time.Time       `json:"timestamp"`
+}
+
+// MarketWarning is a risk flag the Morpho API attaches to a market
+type MarketWarning struct {
+	Type  string `json:"type"`
+	Level string `json:"level"` // YELLOW or RED
+}
+
+// MarketInfo describes a Morpho market's parameters and current state
+type MarketInfo struct {
+	UniqueKey        string  `json:"unique_key"`
+	CollateralSymbol string  `json:"collateral_symbol"`
+	LoanSymbol       string  `json:"loan_symbol"`
+	LLTV             float64 `json:"lltv"` // Liquidation loan-to-value, in percent
+	OracleAddress    string  `json:"oracle_address"`
+	IRMAddress       string  `json:"irm_address"`
+	SupplyUSD        float64 `json:"supply_usd"`
+	BorrowUSD        float64 `json:"borrow_usd"`
+	Utilization      float64 `json:"utilization"` // In percent
+	BorrowRate       float64 `json:"borrow_rate"` // APY, in percent
+	SupplyRate       float64 `json:"supply_rate"` // APY, in percent
+
+	BorrowAverages RateAverages `json:"borrow_averages"`
+	SupplyAverages RateAverages `json:"supply_averages"`
+}
+
+// MarketPair returns the market in COLLATERAL-LOAN form, e.g. "WBTC-USDC"
+func (m *MarketInfo) MarketPair() string {
+	return m.CollateralSymbol + "-" + m.LoanSymbol
+}
+
+// RateSample is a single rate observation recorded in a vault's history
+type RateSample struct {
+	Timestamp  time.Time `json:"timestamp"`
+	BorrowRate float64   `json:"borrow_rate"`
+	SupplyRate float64   `json:"supply_rate"`
+	SupplyUSD  float64   `json:"supply_usd,omitempty"`
+	BorrowUSD  float64   `json:"borrow_usd,omitempty"`
+}
+
+type RateChangeAlert struct {
+	ID            string          `json:"id,omitempty"` // Short persistent ID used by /ack
+	VaultID       string          `json:"vault_id"`
+	Nickname      string          `json:"nickname"`
+	MarketPair    string          `json:"market_pair,omitempty"` // The market pair (e.g., "WBTC-USDC")
+	PreviousRate  float64         `json:"previous_rate"`
+	CurrentRate   float64         `json:"current_rate"`
+	ChangePercent float64         `json:"change_percent"`
+	Timestamp     time.Time       `json:"timestamp"`
+	Severity      Severity        `json:"severity,omitempty"`
+	CriticalRate  float64         `json:"critical_rate,omitempty"` // The critical level that was crossed, for critical alerts
+	Threshold     *AlertThreshold `json:"threshold,omitempty"`     // The threshold a warning or escalation was evaluated against
+	Format        RateFormat      `json:"format,omitempty"`        // How rates are shown; set from the vault's settings when dispatched
+
+	// Percentile is where CurrentRate ranks within the recent rate history (0-100).
+	// PercentileDays is the size of that window; zero means no history was available.
+	Percentile     float64 `json:"percentile,omitempty"`
+	PercentileDays int     `json:"percentile_days,omitempty"`
+
+	// Intraday context computed from the last 24 hours of history; Has24h is false when unavailable
+	Has24h    bool    `json:"has_24h,omitempty"`
+	High24h   float64 `json:"high_24h,omitempty"`
+	Low24h    float64 `json:"low_24h,omitempty"`
+	Change24h float64 `json:"change_24h,omitempty"`
+
+	// ProjectedRate is the borrow rate the market's IRM would charge at ProjectedUtilization (both in percent),
+	// alongside the market's current utilization. Zero when no projection was made.
+	Utilization          float64 `json:"utilization,omitempty"`
+	ProjectedUtilization float64 `json:"projected_utilization,omitempty"`
+	ProjectedRate        float64 `json:"projected_rate,omitempty"`
+
+	// Band is the vault's normal rate range, which the embed color is graded against. Nil when
+	// neither a configured band nor enough history was available.
+	Band *RateBand `json:"band,omitempty"`
+
+	// Automations describes Summer.fi automation configured on the position, e.g. "stop-loss at $58.00K",
+	// so users don't act on an alert the protocol will already handle
+	Automations []string `json:"automations,omitempty"`
+
+	// Alternatives are borrow rates for the same loan asset on other protocols, shown in the footer
+	// so recipients can see at a glance whether refinancing is an option
+	Alternatives []ProtocolRate `json:"alternatives,omitempty"`
+
+	// SustainedChecks is set on escalations: how many consecutive checks the breach has lasted
+	SustainedChecks int `json:"sustained_checks,omitempty"`
+
+	// MessageID is the Discord message the alert was posted as, when delivered through the webhook
+	MessageID string `json:"message_id,omitempty"`
+
+	// AckedBy is the Discord user who acknowledged the alert; empty while unacknowledged
+	AckedBy string    `json:"acked_by,omitempty"`
+	AckedAt time.Time `json:"acked_at,omitempty"`
+}
+
+// ProtocolRate is the borrow rate for an asset on another lending protocol
+type ProtocolRate struct {
+	Protocol   string  `json:"protocol"`    // Display name, e.g. "Aave v3"
+	BorrowRate float64 `json:"borrow_rate"` // APY, in percent
+}
+
+func NewRateChangeAlert(vaultID, nickname, marketPair string, prevRate, currRate float64) *RateChangeAlert {
+	changePoints := currRate - prevRate // This is now in percentage points
+	return &RateChangeAlert{
+		ID:            newShortID(),
+		VaultID:       vaultID,
+		Nickname:      nickname,
+		MarketPair:    marketPair,
+		PreviousRate:  prevRate,
+		CurrentRate:   currRate,
+		ChangePercent: changePoints, // This is now in percentage points
+		Timestamp:     time.Now(),
+		Severity:      SeverityWarning,
+	}
+}
+
+// NewCriticalAlert creates an alert for a rate that reached the vault's critical level
+func NewCriticalAlert(vaultID, nickname, marketPair string, prevRate, currRate, criticalRate float64) *RateChangeAlert {
+	alert := NewRateChangeAlert(vaultID, nickname, marketPair, prevRate, currRate)
+	alert.Severity = SeverityCritical
+	alert.CriticalRate = criticalRate
+	return alert
+}
+
+// newShortID returns a short random hex ID that is easy to type, e.g. into /ack
+func newShortID() string {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%08x", time.Now().UnixNano()&0xffffffff)
+	}
+	return hex.EncodeToString(b)
+}
+
+// Acknowledged reports whether someone has acknowledged the alert
+func (r *RateChangeAlert) Acknowledged() bool {
+	return r.AckedBy != ""
+}
+
+// DedupKey identifies alerts that report the same move on the same vault, so one raised twice by
+// overlapping checks can be recognized
+func (r *RateChangeAlert) DedupKey() string {
+	return fmt.Sprintf("%s|%s|%d|%.4f|%.4f", r.VaultID, r.Severity, r.SustainedChecks, r.PreviousRate, r.CurrentRate)
+}
+
+// NewEscalationAlert creates a follow-up for a breach that has persisted since baselineRate
+func NewEscalationAlert(vaultID, nickname, marketPair string, baselineRate, currRate float64, checks int) *RateChangeAlert {
+	alert := NewRateChangeAlert(vaultID, nickname, marketPair, baselineRate, currRate)
+	alert.SustainedChecks = checks
+	return alert
+}
+
+// SetPercentile attaches historical percentile context to the alert
+func (r *RateChangeAlert) SetPercentile(percentile float64, days int) {
+	r.Percentile = percentile
+	r.PercentileDays = days
+}
+
+// SetProjection attaches an IRM projection of the borrow rate at a higher utilization
+func (r *RateChangeAlert) SetProjection(utilization, projectedUtilization, projectedRate float64) {
+	r.Utilization = utilization
+	r.ProjectedUtilization = projectedUtilization
+	r.ProjectedRate = projectedRate
+}
+
+// Set24hRange attaches the 24h high, low, and net change to the alert
+func (r *RateChangeAlert) Set24hRange(high, low, change float64) {
+	r.Has24h = true
+	r.High24h = high
+	r.Low24h = low
+	r.Change24h = change
+}
+
+func (r *RateChangeAlert) ToDiscordMessage() string {
+	icon := "📈"
+	direction := "increased"
+	if r.ChangePercent < 0 {
+		icon = "📉"
+		direction = "decreased"
+	}
+
+	return fmt.Sprintf(
+		"%s **Rate Alert: %s**\n\n"+
+			"**Current Rate: %s**\n"+
+			"Previous Rate: %s\n"+
+			"Change: %s by %s\n\n"+
+			"<t:%d:R>",
+		icon,
+		r.Nickname,
+		r.FormatRate(r.CurrentRate),
+		r.FormatRate(r.PreviousRate),
+		direction,
+		r.Format.Distance(r.ChangePercent),
+		r.Timestamp.Unix(),
+	)
+}
+
+type DiscordEmbed struct {
+	Title       string              `json:"title"`
+	Description string              `json:"description"`
+	Color       int                 `json:"color"`
+	Fields      []DiscordEmbedField `json:"fields"`
+	Timestamp   string              `json:"timestamp"`
+	Footer      *DiscordEmbedFooter `json:"footer,omitempty"`
+	Image       *DiscordEmbedImage  `json:"image,omitempty"`
+}
+
+type DiscordEmbedField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+type DiscordEmbedFooter struct {
+	Text    string `json:"text"`
+	IconURL string `json:"icon_url,omitempty"`
+}
+
+// DiscordEmbedImage shows an image in an embed; attachment://name refers to a file sent with the message
+type DiscordEmbedImage struct {
+	URL string `json:"url"`
+}
+
+type DiscordWebhookPayload struct {
+	Content         string                  `json:"content,omitempty"`
+	TTS             bool                    `json:"tts,omitempty"` // Read Content aloud to members viewing the channel
+	Embeds          []DiscordEmbed          `json:"embeds"`
+	AllowedMentions *DiscordAllowedMentions `json:"allowed_mentions,omitempty"`
+	Components      []DiscordComponent      `json:"components,omitempty"`
+}
+
+// DiscordComponent is a message component; buttons must be nested inside an action row
+type DiscordComponent struct {
+	Type       int                `json:"type"`
+	Style      int                `json:"style,omitempty"`
+	Label      string             `json:"label,omitempty"`
+	CustomID   string             `json:"custom_id,omitempty"`
+	Components []DiscordComponent `json:"components,omitempty"`
+}
+
+// AckButtonPrefix prefixes the custom ID of an alert's Ack button; the alert ID follows it
+const AckButtonPrefix = "ack:"
+
+// DiscordAllowedMentions limits which mentions in Content actually ping
+type DiscordAllowedMentions struct {
+	Parse []string `json:"parse,omitempty"` // Mention types parsed from Content; "everyone" covers @here too
+	Roles []string `json:"roles,omitempty"`
+	Users []string `json:"users,omitempty"`
+}
+
+func (r *RateChangeAlert) ToDiscordEmbed() *DiscordWebhookPayload {
+	color := 0xff0000 // Red for increase (bad for borrowers)
+	if r.ChangePercent < 0 {
+		color = 0x00ff00 // Green for decrease (good for borrowers)
+	}
+	if r.Band != nil {
+		// Grade by where the rate sits, so a small move within the normal range looks milder
+		color = BandColor(r.Band, r.CurrentRate)
+	}
+
+	title := fmt.Sprintf("Rate Alert: %s", r.Nickname)
+	if r.SustainedChecks > 0 {
+		color = 0xff8c00 // Orange for escalations
+		title = fmt.Sprintf("⏫ Sustained Rate Alert: %s", r.Nickname)
+	}
+	if r.Severity == SeverityCritical {
+		color = 0x8b0000 // Dark red for critical
+		title = fmt.Sprintf("🚨 Critical Rate Alert: %s", r.Nickname)
+	}
+
+	embed := DiscordEmbed{
+		Title:       title,
+		Description: r.ToDiscordMessage(),
+		Color:       color,
+		Fields: []DiscordEmbedField{
+			{
+				Name:   "Vault ID",
+				Value:  r.VaultID,
+				Inline: true,
+			},
+			{
+				Name:   "Market Pair",
+				Value:  r.MarketPair,
+				Inline: true,
+			},
+		},
+		Timestamp: r.Timestamp.Format(time.RFC3339),
+		Footer: &DiscordEmbedFooter{
+			Text: DefaultFooter,
+		},
+	}
+	if r.ID != "" {
+		embed.Footer.Text = fmt.Sprintf("%s • Alert %s", DefaultFooter, r.ID)
+	}
+	if len(r.Alternatives) > 0 {
+		alternatives := make([]string, 0, len(r.Alternatives))
+		for _, alt := range r.Alternatives {
+			alternatives = append(alternatives, fmt.Sprintf("%s: %s", alt.Protocol, r.FormatRate(alt.BorrowRate)))
+		}
+		embed.Footer.Text += "\nElsewhere: " + strings.Join(alternatives, ", ")
+	}
+
+	if r.Severity == SeverityCritical {
+		embed.Fields = append(embed.Fields, DiscordEmbedField{
+			Name:   "Critical Level",
+			Value:  r.FormatRate(r.CriticalRate),
+			Inline: true,
+		})
+	}
+
+	if r.Threshold != nil {
+		value := r.Threshold.Description
+		if !r.Threshold.SetAt.IsZero() {
+			value += fmt.Sprintf("\nSet <t:%d:R> by %s", r.Threshold.SetAt.Unix(), r.Threshold.SetBy)
+		}
+		embed.Fields = append(embed.Fields, DiscordEmbedField{
+			Name:   "Threshold",
+			Value:  value,
+			Inline: true,
+		})
+	}
+
+	if r.SustainedChecks > 0 {
+		embed.Fields = append(embed.Fields, DiscordEmbedField{
+			Name:   "Sustained For",
+			Value:  fmt.Sprintf("%d consecutive checks", r.SustainedChecks),
+			Inline: true,
+		})
+	}
+
+	if r.Has24h {
+		embed.Fields = append(embed.Fields,
+			DiscordEmbedField{
+				Name:   "24h High",
+				Value:  r.FormatRate(r.High24h),
+				Inline: true,
+			},
+			DiscordEmbedField{
+				Name:   "24h Low",
+				Value:  r.FormatRate(r.Low24h),
+				Inline: true,
+			},
+			DiscordEmbedField{
+				Name:   "24h Change",
+				Value:  r.FormatPoints(r.Change24h),
+				Inline: true,
+			},
+		)
+	}
+
+	if r.Band != nil {
+		embed.Fields = append(embed.Fields, DiscordEmbedField{
+			Name:   "Normal Range",
+			Value:  fmt.Sprintf("%s – %s (%s)", r.FormatRate(r.Band.Low), r.FormatRate(r.Band.High), r.Band.Source),
+			Inline: false,
+		})
+	}
+
+	if r.ProjectedUtilization > 0 {
+		embed.Fields = append(embed.Fields, DiscordEmbedField{
+			Name: "Rate Projection",
+			Value: fmt.Sprintf("Utilization is %.1f%%; at %.0f%% the borrow rate would be ~%s",
+				r.Utilization, r.ProjectedUtilization, r.FormatRate(r.ProjectedRate)),
+			Inline: false,
+		})
+	}
+
+	if r.PercentileDays > 0 {
+		embed.Fields = append(embed.Fields, DiscordEmbedField{
+			Name: "Historical Context",
+			Value: fmt.Sprintf("Current rate is in the %s percentile of the last %d days",
+				ordinal(int(math.Round(r.Percentile))), r.PercentileDays),
+			Inline: false,
+		})
+	}
+
+	if len(r.Automations) > 0 {
+		embed.Fields = append(embed.Fields, DiscordEmbedField{
+			Name:   "Summer.fi Automation",
+			Value:  fmt.Sprintf("Note: %s configured on this position", strings.Join(r.Automations, ", ")),
+			Inline: false,
+		})
+	}
+
+	payload := &DiscordWebhookPayload{
+		Embeds: []DiscordEmbed{embed},
+	}
+	if r.ID != "" && !r.Acknowledged() {
+		payload.Components = []DiscordComponent{{
+			Type: 1, // Action row
+			Components: []DiscordComponent{{
+				Type:     2, // Button
+				Style:    1, // Primary
+				Label:    "Ack",
+				CustomID: AckButtonPrefix + r.ID,
+			}},
+		}}
+	}
+	return payload
+}
+
+// ordinal formats n with its English ordinal suffix (1st, 2nd, 3rd, 4th, ...)
+func ordinal(n int) string {
+	suffix := "th"
+	switch n % 100 {
+	case 11, 12, 13:
+	default:
+		switch n % 10 {
+		case 1:
+			suffix = "st"
+		case 2:
+			suffix = "nd"
+		case 3:
+			suffix = "rd"
+		}
+	}
+	return fmt.Sprintf("%d%s", n, suffix)
+}
+
+// FormatUSD abbreviates a dollar amount, e.g. $1.23M
+func FormatUSD(amount float64) string {
+	switch {
+	case amount >= 1e9:
+		return fmt.Sprintf("$%.2fB", amount/1e9)
+	case amount >= 1e6:
+		return fmt.Sprintf("$%.2fM", amount/1e6)
+	case amount >= 1e3:
+		return fmt.Sprintf("$%.2fK", amount/1e3)
+	default:
+		return fmt.Sprintf("$%.2f", amount)
+	}
+}
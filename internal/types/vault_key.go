@@ -0,0 +1,85 @@
+package types
+
+import "strings"
+
+// Summer.fi numbers positions per network and protocol, so vault 1234 on Base and vault 1234 on
+// Ethereum are different positions. Vaults are stored under a key combining all three.
+const (
+	DefaultNetwork  = "ethereum"
+	DefaultProtocol = "morphoblue"
+)
+
+// EthereumChainID is the chain ID of DefaultNetwork, where market keys and pairs given directly
+// are looked up
+const EthereumChainID = 1
+
+// chainIDs maps the network names used in Summer.fi URLs to chain IDs
+var chainIDs = map[string]int{
+	"ethereum": EthereumChainID,
+	"optimism": 10,
+	"base":     8453,
+	"arbitrum": 42161,
+}
+
+// ChainID returns the chain ID of a network named as in Summer.fi URLs. ok is false for networks
+// the bot doesn't know.
+func ChainID(network string) (id int, ok bool) {
+	id, ok = chainIDs[strings.ToLower(network)]
+	return id, ok
+}
+
+// VaultKey returns the storage key for Summer.fi position id on network and protocol. Ethereum
+// Morpho Blue positions, the only ones enrolled before keys were composite, keep their bare ID
+// so existing vaults and their history are unaffected; others are "network:protocol:id", e.g.
+// "base:morphoblue:1234". An empty network or protocol means the default.
+func VaultKey(network, protocol, id string) string {
+	network = strings.ToLower(network)
+	protocol = strings.ToLower(protocol)
+	if (network == "" || network == DefaultNetwork) && (protocol == "" || protocol == DefaultProtocol) {
+		return id
+	}
+	if network == "" {
+		network = DefaultNetwork
+	}
+	if protocol == "" {
+		protocol = DefaultProtocol
+	}
+	return network + ":" + protocol + ":" + id
+}
+
+// ParseVaultKey splits a key made by VaultKey. Bare IDs are on the default network and protocol.
+func ParseVaultKey(key string) (network, protocol, id string) {
+	parts := strings.SplitN(key, ":", 3)
+	if len(parts) != 3 {
+		return DefaultNetwork, DefaultProtocol, key
+	}
+	return parts[0], parts[1], parts[2]
+}
+
+// PositionID returns the vault's Summer.fi position number, without its network and protocol
+func (v *VaultConfig) PositionID() string {
+	_, _, id := ParseVaultKey(v.VaultID)
+	return id
+}
+
+// HasPosition reports whether the vault is a Summer.fi position. Vaults enrolled by market key or
+// pair, auto-enrolled ones included, use the market's unique key as their ID and have none.
+func (v *VaultConfig) HasPosition() bool {
+	id := v.PositionID()
+	if id == "" {
+		return false
+	}
+	for _, c := range id {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// ChainID returns the chain ID of the network the vault's market is on. Bare IDs and market-keyed
+// vaults are on Ethereum.
+func (v *VaultConfig) ChainID() (int, bool) {
+	network, _, _ := ParseVaultKey(v.VaultID)
+	return ChainID(network)
+}
@@ -0,0 +1,56 @@
+package types
+
+import (
+	"strings"
+	"time"
+)
+
+// MarketWatch alerts a channel when a previously unseen Morpho market for an asset pair appears
+type MarketWatch struct {
+	ID               string    `json:"id"`
+	CollateralSymbol string    `json:"collateral_symbol"`
+	LoanSymbol       string    `json:"loan_symbol"`
+	ChannelID        string    `json:"channel_id"`
+	WebhookURL       string    `json:"webhook_url,omitempty"`
+	KnownMarkets     []string  `json:"known_markets,omitempty"` // Unique keys already seen for this pair
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+func NewMarketWatch(collateral, loan, channelID, webhookURL string) *MarketWatch {
+	return &MarketWatch{
+		ID:               newShortID(),
+		CollateralSymbol: collateral,
+		LoanSymbol:       loan,
+		ChannelID:        channelID,
+		WebhookURL:       webhookURL,
+		CreatedAt:        time.Now(),
+	}
+}
+
+// Clone returns a deep copy of the watch, so storage and its callers never share KnownMarkets
+func (w *MarketWatch) Clone() *MarketWatch {
+	clone := *w
+	clone.KnownMarkets = append([]string(nil), w.KnownMarkets...)
+	return &clone
+}
+
+// Pair returns the watched pair in COLLATERAL-LOAN form
+func (w *MarketWatch) Pair() string {
+	return w.CollateralSymbol + "-" + w.LoanSymbol
+}
+
+// Matches reports whether market is for the watched pair
+func (w *MarketWatch) Matches(market *MarketInfo) bool {
+	return strings.EqualFold(market.CollateralSymbol, w.CollateralSymbol) &&
+		strings.EqualFold(market.LoanSymbol, w.LoanSymbol)
+}
+
+// Knows reports whether the market with uniqueKey has already been seen
+func (w *MarketWatch) Knows(uniqueKey string) bool {
+	for _, key := range w.KnownMarkets {
+		if key == uniqueKey {
+			return true
+		}
+	}
+	return false
+}
@@ -1,38 +1,75 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
+	"github.com/morrisonbrett/SummerRateChecker/internal/backup"
 	"github.com/morrisonbrett/SummerRateChecker/internal/bot"
 	"github.com/morrisonbrett/SummerRateChecker/internal/config"
+	"github.com/morrisonbrett/SummerRateChecker/internal/events"
+	"github.com/morrisonbrett/SummerRateChecker/internal/homeassistant"
+	"github.com/morrisonbrett/SummerRateChecker/internal/httpapi"
+	"github.com/morrisonbrett/SummerRateChecker/internal/integrity"
 	"github.com/morrisonbrett/SummerRateChecker/internal/monitor"
+	"github.com/morrisonbrett/SummerRateChecker/internal/morpho"
 	"github.com/morrisonbrett/SummerRateChecker/internal/storage"
 	"go.uber.org/zap"
 )
 
 func main() {
+	profile := flag.String("profile", "", "layer config.<profile>.toml over config.toml (default $SUMMER_PROFILE)")
+	flag.Parse()
+	args := flag.Args()
+
 	// Initialize logger
 	logger, _ := zap.NewProduction()
 	defer logger.Sync()
 	sugar := logger.Sugar()
 
 	// Load configuration
+	config.SetProfile(*profile)
 	cfg, err := config.Load()
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
+	// `verify [--repair]` checks the data directory and exits without starting the bot
+	if len(args) > 0 && args[0] == "verify" {
+		os.Exit(runVerify(cfg, sugar, args[1:]))
+	}
+
 	sugar.Info("SummerRateChecker starting up")
 
-	// Initialize storage with persistence
-	store, err := storage.NewFileStorage("data")
+	// Initialize storage with persistence. A mirror follows another instance's data directory instead.
+	var store *storage.FileStorage
+	if cfg.Mirror.Enabled() {
+		store, err = storage.NewMirrorStorage(cfg.Mirror.DataDir)
+	} else {
+		store, err = storage.NewFileStorage("data", cfg.Backup.LocalDir)
+	}
 	if err != nil {
 		log.Fatalf("Failed to initialize storage: %v", err)
 	}
 	sugar.Info("Initialized persistent storage")
+	defer store.Flush()
+	recoveries := store.Recoveries()
+	for _, event := range recoveries {
+		sugar.Errorf("Recovered corrupt data file: %s", event)
+	}
+
+	// Snapshot the data directory on a schedule, optionally off-host. A mirror leaves that to the
+	// instance that owns the data.
+	if cfg.Backup.Enabled && !cfg.Mirror.Enabled() {
+		backups := backup.New(&cfg.Backup, store, sugar)
+		go backups.Start(context.Background())
+	}
 
 	// Initialize Discord bot
 	discordBot, err := bot.New(cfg, store, sugar)
@@ -47,12 +84,51 @@ func main() {
 	}
 	defer discordBot.Stop()
 
-	// Initialize and start monitor
-	rateMonitor := monitor.New(cfg, store, sugar)
-	rateMonitor.SetCheckTrigger(discordBot.GetCheckTrigger())
+	// Tell operators about any data file that had to be recovered
+	for _, event := range recoveries {
+		discordBot.SendOpsMessage(fmt.Sprintf("🩹 Storage recovery at startup: %s", event))
+	}
+
+	// Live events are published by the monitor and streamed by the HTTP API
+	broker := events.NewBroker()
 
-	// Start the monitoring loop
-	go rateMonitor.Start()
+	if cfg.Mirror.Enabled() {
+		// The mirrored instance checks rates and sends alerts; a mirror only follows its data
+		sugar.Infof("Mirroring %s, reloading every %ds", cfg.Mirror.DataDir, cfg.Mirror.ReloadSeconds)
+		go followMirror(store, time.Duration(cfg.Mirror.ReloadSeconds)*time.Second, sugar)
+	} else {
+		// Initialize and start monitor
+		rateMonitor := monitor.New(cfg, store, sugar)
+		rateMonitor.SetCommandBus(discordBot.Commands())
+		rateMonitor.SetDirectMessenger(discordBot)
+		rateMonitor.SetEventBroker(broker)
+		// Custom alert logic can be compiled in with rateMonitor.RegisterEvaluator(...)
+
+		// Publish vaults to Home Assistant via MQTT discovery
+		if cfg.HomeAssistant.Enabled {
+			bridge := homeassistant.New(&cfg.HomeAssistant, sugar)
+			if err := bridge.Start(broker); err != nil {
+				log.Fatalf("Failed to start Home Assistant bridge: %v", err)
+			}
+			defer bridge.Stop()
+		}
+
+		// Start the monitoring loop
+		go rateMonitor.Start()
+	}
+
+	// Start the optional HTTP API
+	if cfg.HTTP.Enabled {
+		apiServer, err := httpapi.New(cfg, store, broker, sugar)
+		if err != nil {
+			log.Fatalf("Failed to create HTTP API: %v", err)
+		}
+		apiServer.SetController(discordBot)
+		if err := apiServer.Start(); err != nil {
+			log.Fatalf("Failed to start HTTP API: %v", err)
+		}
+		defer apiServer.Stop()
+	}
 
 	sugar.Info("SummerRateChecker is now running. Press CTRL-C to exit.")
 
@@ -63,3 +139,65 @@ func main() {
 
 	sugar.Info("Shutting down SummerRateChecker")
 }
+
+// followMirror re-reads a mirrored data directory every interval
+func followMirror(store *storage.FileStorage, interval time.Duration, logger *zap.SugaredLogger) {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := store.Reload(); err != nil {
+			logger.Warnf("Failed to reload mirrored data, keeping what was loaded: %v", err)
+		}
+	}
+}
+
+// runVerify reports integrity issues in the data directory and optionally repairs them.
+// Missing webhooks can't be recreated without Discord; use /verify for those.
+func runVerify(cfg *config.Config, logger *zap.SugaredLogger, args []string) int {
+	flags := flag.NewFlagSet("verify", flag.ExitOnError)
+	repair := flags.Bool("repair", false, "prune orphaned data and look up missing market keys (stop the bot first)")
+	flags.Parse(args)
+
+	store, err := storage.NewFileStorage("data", cfg.Backup.LocalDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to open storage: %v\n", err)
+		return 1
+	}
+
+	issues, err := integrity.Check(store)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Verify failed: %v\n", err)
+		return 1
+	}
+	if len(issues) == 0 {
+		fmt.Println("No integrity issues found")
+		return 0
+	}
+
+	for _, issue := range issues {
+		fmt.Println(issue)
+	}
+	if !*repair {
+		fmt.Printf("%d issue(s) found; run with --repair to fix what can be fixed\n", len(issues))
+		return 1
+	}
+
+	client := morpho.NewClient(cfg.Morpho.APIURL, logger)
+	remaining, err := integrity.Repair(context.Background(), store, issues, client, nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Repair failed: %v\n", err)
+		return 1
+	}
+	fmt.Printf("Repaired %d of %d issue(s)\n", len(issues)-len(remaining), len(issues))
+	for _, issue := range remaining {
+		fmt.Println("Still needs attention:", issue)
+	}
+	if len(remaining) > 0 {
+		return 1
+	}
+	return 0
+}